@@ -0,0 +1,105 @@
+"use client";
+
+import { useState, type FormEvent } from "react";
+import { useRouter } from "next/navigation";
+import { motion } from "framer-motion";
+import { isAxiosError } from "axios";
+import Navbar from "@/components/layout/Navbar";
+import Button from "@/components/ui/Button";
+import { useSubscription } from "@/lib/subscription";
+import type { ErrorResponse } from "@/lib/types";
+import { cn } from "@/lib/utils";
+
+export default function LoginPage() {
+  const router = useRouter();
+  const { login, register } = useSubscription();
+  const [mode, setMode] = useState<"login" | "register">("login");
+  const [email, setEmail] = useState("");
+  const [password, setPassword] = useState("");
+  const [submitting, setSubmitting] = useState(false);
+  const [error, setError] = useState<string | null>(null);
+
+  async function handleSubmit(e: FormEvent) {
+    e.preventDefault();
+    setSubmitting(true);
+    setError(null);
+    try {
+      await (mode === "login" ? login : register)(email, password);
+      // Only follow same-site paths
+      const next = new URLSearchParams(window.location.search).get("next");
+      router.push(next?.startsWith("/") && !next.startsWith("//") ? next : "/puzzles");
+    } catch (err) {
+      const body = isAxiosError<ErrorResponse>(err) ? err.response?.data : undefined;
+      setError(body?.details || body?.error || "Could not reach the server");
+    } finally {
+      setSubmitting(false);
+    }
+  }
+
+  const inputClass =
+    "w-full rounded-xl border border-white/[0.08] bg-white/[0.03] px-4 py-2.5 text-sm text-[var(--text-primary)] placeholder:text-[var(--text-muted)] focus:border-[var(--accent-gold)]/40 focus:outline-none";
+
+  return (
+    <div className="min-h-screen bg-[var(--bg-deep)]">
+      <Navbar />
+
+      <div className="mx-auto max-w-sm px-6 py-16">
+        <motion.div
+          initial={{ opacity: 0, y: 20 }}
+          animate={{ opacity: 1, y: 0 }}
+          className="rounded-2xl border border-white/[0.08] bg-white/[0.02] p-8"
+        >
+          <div className="mb-6 text-center">
+            <div className="mb-3 text-4xl">♔</div>
+            <h1 className="font-serif text-2xl font-bold text-[var(--text-primary)]">
+              {mode === "login" ? "Sign in" : "Create account"}
+            </h1>
+            <p className="mt-2 text-xs text-[var(--text-muted)]">
+              Your plan and puzzle rating follow your account
+            </p>
+          </div>
+
+          <form onSubmit={handleSubmit} className="space-y-3">
+            <input
+              type="email"
+              required
+              autoComplete="email"
+              placeholder="Email"
+              value={email}
+              onChange={(e) => setEmail(e.target.value)}
+              className={inputClass}
+            />
+            <input
+              type="password"
+              required
+              autoComplete={mode === "login" ? "current-password" : "new-password"}
+              placeholder="Password"
+              value={password}
+              onChange={(e) => setPassword(e.target.value)}
+              className={inputClass}
+            />
+
+            {error && <p className="text-xs text-red-400">{error}</p>}
+
+            <Button type="submit" variant="gold" fullWidth loading={submitting}>
+              {mode === "login" ? "Sign in" : "Create account"}
+            </Button>
+          </form>
+
+          <button
+            onClick={() => {
+              setMode((m) => (m === "login" ? "register" : "login"));
+              setError(null);
+            }}
+            className={cn(
+              "mt-5 w-full text-center text-xs text-[var(--text-muted)] transition-colors",
+              "hover:text-[var(--accent-gold)]"
+            )}
+          >
+            {mode === "login" ? "No account yet? Create one" : "Already have an account? Sign in"}
+          </button>
+        </motion.div>
+      </div>
+    </div>
+  );
+}
@@ -1,35 +1,60 @@
 "use client";
 
-import { useState } from "react";
+import { useEffect, useState } from "react";
 import { useRouter } from "next/navigation";
 import { motion } from "framer-motion";
 import Navbar from "@/components/layout/Navbar";
 import { plans, useSubscription, type PlanId } from "@/lib/subscription";
 import { cn } from "@/lib/utils";
 
+// Hosted checkout pages (e.g. Stripe payment links) for each paid plan. The
+// billing webhook grants the plan to the account passed as
+// client_reference_id once the payment goes through.
+const checkoutUrls: Partial<Record<PlanId, string | undefined>> = {
+  pro: process.env.NEXT_PUBLIC_CHECKOUT_URL_PRO,
+  elite: process.env.NEXT_PUBLIC_CHECKOUT_URL_ELITE,
+};
+const billingPortalUrl = process.env.NEXT_PUBLIC_BILLING_PORTAL_URL;
+
 export default function PricingPage() {
   const router = useRouter();
-  const { plan: currentPlan, upgrade } = useSubscription();
+  const { user, plan: currentPlan, refresh } = useSubscription();
   const [billing, setBilling] = useState<"monthly" | "yearly">("monthly");
   const [processing, setProcessing] = useState<PlanId | null>(null);
-  const [justUpgraded, setJustUpgraded] = useState<PlanId | null>(null);
+  const [notice, setNotice] = useState<string | null>(null);
+
+  // Back from checkout: the webhook may have changed the plan already
+  useEffect(() => {
+    if (new URLSearchParams(window.location.search).has("checkout")) {
+      refresh();
+    }
+  }, [refresh]);
 
-  async function handleSelect(planId: PlanId) {
+  function handleSelect(planId: PlanId) {
     if (planId === currentPlan) return;
+    if (!user) {
+      router.push("/login?next=/pricing");
+      return;
+    }
     if (planId === "free") {
-      upgrade("free");
+      if (billingPortalUrl) {
+        window.location.href = billingPortalUrl;
+      } else {
+        setNotice("Cancel your subscription from the billing portal.");
+      }
       return;
     }
 
-    // Simulate payment processing
+    const url = checkoutUrls[planId];
+    if (!url) {
+      setNotice("Checkout is not available right now.");
+      return;
+    }
     setProcessing(planId);
-    await new Promise((r) => setTimeout(r, 1800));
-    upgrade(planId);
-    setProcessing(null);
-    setJustUpgraded(planId);
-
-    // Redirect to AI page after a moment
-    setTimeout(() => router.push("/puzzles/ai"), 1500);
+    const checkout = new URL(url);
+    checkout.searchParams.set("client_reference_id", user.id);
+    checkout.searchParams.set("prefilled_email", user.email);
+    window.location.href = checkout.toString();
   }
 
   const accentByPlan: Record<PlanId, { border: string; bg: string; text: string; glow: string; btn: string }> = {
@@ -105,20 +130,14 @@ export default function PricingPage() {
           </div>
         </motion.div>
 
-        {/* Success banner */}
-        {justUpgraded && (
+        {/* Notice banner */}
+        {notice && (
           <motion.div
             initial={{ opacity: 0, scale: 0.95 }}
             animate={{ opacity: 1, scale: 1 }}
-            className="mx-auto mb-8 max-w-md rounded-xl border border-green-500/30 bg-green-500/[0.06] p-4 text-center"
+            className="mx-auto mb-8 max-w-md rounded-xl border border-amber-500/30 bg-amber-500/[0.06] p-4 text-center"
           >
-            <div className="mb-1 text-2xl">🎉</div>
-            <p className="text-sm font-semibold text-green-400">
-              Welcome to {plans.find((p) => p.id === justUpgraded)?.name}!
-            </p>
-            <p className="mt-1 text-xs text-[var(--text-muted)]">
-              Redirecting to AI Puzzles...
-            </p>
+            <p className="text-sm text-amber-400">{notice}</p>
           </motion.div>
         )}
 
@@ -220,7 +239,9 @@ export default function PricingPage() {
         {/* FAQ hint */}
         <div className="mt-16 text-center">
           <p className="text-xs text-[var(--text-muted)]">
-            This is a demo subscription flow. No real payment is processed.
+            {user
+              ? `Signed in as ${user.email}. Plans are applied to your account once payment goes through.`
+              : "Sign in to choose a plan."}
           </p>
         </div>
       </div>
@@ -5,12 +5,14 @@ import { usePathname } from "next/navigation";
 import { motion, AnimatePresence } from "framer-motion";
 import { cn } from "@/lib/utils";
 import { useTheme } from "@/lib/theme";
+import { useSubscription } from "@/lib/subscription";
 import { navItems, themeOptions } from "@/lib/navbar-config";
 import { useNavbarController } from "@/hooks/useNavbarController";
 
 export default function Navbar() {
   const pathname = usePathname();
   const { theme, setTheme } = useTheme();
+  const { user, logout } = useSubscription();
   const {
     showThemeMenu,
     setShowThemeMenu,
@@ -138,6 +140,27 @@ export default function Navbar() {
             </AnimatePresence>
           </div>
 
+          {/* Account */}
+          {user ? (
+            <button
+              onClick={() => logout()}
+              className="hidden h-9 items-center gap-2 rounded-full px-3 text-[13px] font-medium text-[var(--text-muted)] transition-all hover:bg-[var(--bg-card)] hover:text-[var(--text-secondary)] md:flex"
+              title={`Signed in as ${user.email}`}
+            >
+              <span className="flex h-6 w-6 items-center justify-center rounded-full bg-[var(--accent-gold)]/15 text-[11px] uppercase text-[var(--accent-gold)]">
+                {user.email.charAt(0)}
+              </span>
+              Sign out
+            </button>
+          ) : (
+            <Link
+              href={`/login?next=${encodeURIComponent(pathname)}`}
+              className="hidden h-9 items-center rounded-full px-3 text-[13px] font-medium text-[var(--text-muted)] transition-all hover:bg-[var(--bg-card)] hover:text-[var(--text-secondary)] md:flex"
+            >
+              Sign in
+            </Link>
+          )}
+
           {/* Mobile hamburger */}
           <button
             onClick={() => setMobileOpen((v) => !v)}
@@ -203,6 +226,28 @@ export default function Navbar() {
                 );
               })}
 
+              {/* Mobile account */}
+              {user ? (
+                <button
+                  onClick={() => {
+                    logout();
+                    setMobileOpen(false);
+                  }}
+                  className="flex items-center gap-3 rounded-xl px-3 py-2.5 text-left text-sm font-medium text-[var(--text-muted)] transition-all hover:bg-white/[0.03] hover:text-[var(--text-secondary)]"
+                >
+                  <span className="text-base">⏻</span>
+                  <span className="truncate">Sign out ({user.email})</span>
+                </button>
+              ) : (
+                <Link
+                  href={`/login?next=${encodeURIComponent(pathname)}`}
+                  className="flex items-center gap-3 rounded-xl px-3 py-2.5 text-sm font-medium text-[var(--text-muted)] transition-all hover:bg-white/[0.03] hover:text-[var(--text-secondary)]"
+                >
+                  <span className="text-base">♙</span>
+                  <span>Sign in</span>
+                </Link>
+              )}
+
               {/* Mobile theme switcher */}
               <div className="mt-2 border-t border-[var(--border-subtle)] pt-3">
                 <div className="px-3 pb-2 text-[10px] font-semibold uppercase tracking-widest text-[var(--text-muted)]">
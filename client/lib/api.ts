@@ -1,5 +1,12 @@
-import axios from "axios";
-import type { Puzzle, DifficultyLevel, AIPuzzleRequest } from "./types";
+import axios, { type AxiosError, type InternalAxiosRequestConfig } from "axios";
+import type {
+  Puzzle,
+  DifficultyLevel,
+  AIPuzzleRequest,
+  AuthResponse,
+  UserProfile,
+} from "./types";
+import { getTokens, setTokens } from "./auth";
 
 // In the browser the Next.js rewrite proxies /api/* to the Go backend.
 // NEXT_PUBLIC_API_URL is only needed when calling the backend directly
@@ -15,17 +22,103 @@ const api = axios.create({
   headers: { "Content-Type": "application/json" },
 });
 
-// Attach premium header automatically when user has a paid plan.
+// Random per-browser ID so the backend does not serve an anonymous player the
+// same puzzle twice.
+const DEVICE_ID_KEY = "chess-puzzles-device-id";
+
+function getDeviceId(): string {
+  let id = localStorage.getItem(DEVICE_ID_KEY);
+  if (!id) {
+    id = crypto.randomUUID();
+    localStorage.setItem(DEVICE_ID_KEY, id);
+  }
+  return id;
+}
+
+// Attach the signed-in user's access token; the backend reads the plan from it.
 api.interceptors.request.use((config) => {
   if (typeof window !== "undefined") {
-    const plan = localStorage.getItem("chess-puzzles-plan");
-    if (plan === "pro" || plan === "elite") {
-      config.headers["X-Premium-User"] = "true";
+    const tokens = getTokens();
+    if (tokens) {
+      config.headers.Authorization = `Bearer ${tokens.accessToken}`;
     }
+    config.headers["X-Device-ID"] = getDeviceId();
   }
   return config;
 });
 
+// Concurrent 401s share one refresh, since refresh tokens are single use.
+let refreshing: Promise<boolean> | null = null;
+
+function refreshTokens(): Promise<boolean> {
+  refreshing ??= (async () => {
+    const tokens = getTokens();
+    if (!tokens) return false;
+    try {
+      const { data } = await axios.post<AuthResponse>(
+        `${baseURL}/auth/refresh`,
+        { refreshToken: tokens.refreshToken },
+        { headers: { "Content-Type": "application/json" } }
+      );
+      setTokens(data.tokens);
+      return true;
+    } catch {
+      setTokens(null); // refresh token expired or revoked – sign out
+      return false;
+    } finally {
+      refreshing = null;
+    }
+  })();
+  return refreshing;
+}
+
+// Renew an expired access token once and replay the request.
+api.interceptors.response.use(undefined, async (error: AxiosError) => {
+  const config = error.config as (InternalAxiosRequestConfig & { _retried?: boolean }) | undefined;
+  if (
+    error.response?.status !== 401 ||
+    !config ||
+    config._retried ||
+    config.url?.startsWith("/auth/") ||
+    !getTokens()
+  ) {
+    throw error;
+  }
+  config._retried = true;
+  if (!(await refreshTokens())) throw error;
+  return api(config);
+});
+
+// ---------- Auth API ----------
+
+export async function login(email: string, password: string): Promise<UserProfile> {
+  const { data } = await api.post<AuthResponse>("/auth/login", { email, password });
+  setTokens(data.tokens);
+  return data.user;
+}
+
+export async function register(email: string, password: string): Promise<UserProfile> {
+  const { data } = await api.post<AuthResponse>("/auth/register", { email, password });
+  setTokens(data.tokens);
+  return data.user;
+}
+
+export async function logout(): Promise<void> {
+  const tokens = getTokens();
+  setTokens(null);
+  if (!tokens) return;
+  try {
+    await api.post("/auth/logout", { refreshToken: tokens.refreshToken });
+  } catch {
+    // the access token expires on its own
+  }
+}
+
+export async function getMe(): Promise<UserProfile> {
+  const { data } = await api.get<UserProfile>("/me");
+  return data;
+}
+
 export async function getPuzzleByDifficulty(
   difficulty: DifficultyLevel = "medium"
 ): Promise<Puzzle> {
@@ -68,12 +161,30 @@ export interface SessionData {
   difficulty: string;
   fen: string;
   moves: string[];
+  verified: boolean;
   move_index: number;
   solved: boolean;
   failed: boolean;
   hints_used: number;
   started_at: string;
   updated_at: string;
+  score: number;
+  rating_change?: number;
+  scored_at?: string;
+}
+
+export interface SessionHint {
+  level: number;
+  kind: "theme" | "piece" | "square" | "move";
+  move_index: number;
+  theme?: string;
+  theme_name?: string;
+  piece?: string;
+  square?: string;
+  move?: string;
+  san?: string;
+  hints_used: number;
+  repeated?: boolean;
 }
 
 export async function createSession(body: {
@@ -91,14 +202,31 @@ export async function createSession(body: {
   }
 }
 
-export async function updateSession(
+// Moves must reach the server in the order they were played.
+let pendingMove: Promise<unknown> = Promise.resolve();
+
+export function playSessionMove(
   id: string,
-  body: { move_index: number; solved: boolean; failed: boolean; hints_used: number }
-): Promise<void> {
+  body: { move: string; hints_used: number }
+): Promise<SessionData | null> {
+  const next = pendingMove.then(async () => {
+    try {
+      const { data } = await api.post<SessionData>(`/session/${id}/move`, body);
+      return data;
+    } catch {
+      return null; // the server checks moves for rating only – don't block the game
+    }
+  });
+  pendingMove = next;
+  return next;
+}
+
+export async function requestHint(id: string): Promise<SessionHint | null> {
   try {
-    await api.put(`/session/${id}`, body);
+    const { data } = await api.post<SessionHint>(`/session/${id}/hint`);
+    return data;
   } catch {
-    // silently ignore
+    return null;
   }
 }
 
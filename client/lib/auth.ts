@@ -0,0 +1,37 @@
+import type { AuthTokens } from "./types";
+
+// Tokens of the signed-in user. The backend decides the plan from the access
+// token, so nothing about the plan is kept on the client.
+const TOKENS_KEY = "chess-puzzles-auth";
+
+type Listener = (tokens: AuthTokens | null) => void;
+const listeners = new Set<Listener>();
+
+export function getTokens(): AuthTokens | null {
+  if (typeof window === "undefined") return null;
+  try {
+    const raw = localStorage.getItem(TOKENS_KEY);
+    return raw ? (JSON.parse(raw) as AuthTokens) : null;
+  } catch {
+    return null;
+  }
+}
+
+export function setTokens(tokens: AuthTokens | null): void {
+  try {
+    if (tokens) {
+      localStorage.setItem(TOKENS_KEY, JSON.stringify(tokens));
+    } else {
+      localStorage.removeItem(TOKENS_KEY);
+    }
+  } catch {
+    // storage unavailable – the tokens only last for this page
+  }
+  listeners.forEach((fn) => fn(tokens));
+}
+
+/** Calls fn whenever the user signs in, refreshes or signs out. */
+export function onTokensChange(fn: Listener): () => void {
+  listeners.add(fn);
+  return () => listeners.delete(fn);
+}
@@ -7,7 +7,7 @@ import {
   generateAIPuzzle,
   getDatasetPuzzle,
   createSession,
-  updateSession,
+  playSessionMove,
   deleteSession,
 } from "./api";
 import {
@@ -330,7 +330,7 @@ export const usePuzzleStore = create<PuzzleState>((set, get) => ({
           moveHistory: newHistory, historyViewIndex: -1,
         });
         const sid = get().sessionId;
-        if (sid) updateSession(sid, { move_index: newMoveIndex, solved: true, failed: false, hints_used: get().hintsUsed });
+        if (sid) playSessionMove(sid, { move: attemptUCI, hints_used: get().hintsUsed });
       } else {
         playMoveAudio(game, captured);
         set({
@@ -342,6 +342,8 @@ export const usePuzzleStore = create<PuzzleState>((set, get) => ({
           moveHistory: newHistory, historyViewIndex: -1,
           waitingForOpponent: true,
         });
+        const sid = get().sessionId;
+        if (sid) playSessionMove(sid, { move: attemptUCI, hints_used: get().hintsUsed });
         setTimeout(() => get().makeComputerMove(), 400);
       }
       return true;
@@ -357,7 +359,7 @@ export const usePuzzleStore = create<PuzzleState>((set, get) => ({
         streak: 0, hintSquare: null,
       });
       const sid = get().sessionId;
-      if (sid) updateSession(sid, { move_index: moveIndex, solved: false, failed: true, hints_used: get().hintsUsed });
+      if (sid) playSessionMove(sid, { move: attemptUCI, hints_used: get().hintsUsed });
 
       setTimeout(() => {
         const { failed: stillFailed, puzzle: p } = get();
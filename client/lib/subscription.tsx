@@ -8,9 +8,13 @@ import {
   useCallback,
   type ReactNode,
 } from "react";
+import * as authApi from "./api";
+import { getTokens, onTokensChange } from "./auth";
+import type { PlanId, UserProfile } from "./types";
+
+export type { PlanId };
 
 // ─── Plan definitions ───────────────────────────────────────────────
-export type PlanId = "free" | "pro" | "elite";
 
 export interface Plan {
   id: PlanId;
@@ -67,57 +71,73 @@ export const plans: Plan[] = [
 ];
 
 // ─── Context ────────────────────────────────────────────────────────
+// The plan comes from the signed-in account; the backend grants it through
+// billing webhooks and checks it on every gated request.
 interface SubscriptionState {
+  user: UserProfile | null;
   plan: PlanId;
   hasAIAccess: boolean;
-  /** Simulated upgrade — persists in localStorage. */
-  upgrade: (plan: PlanId) => void;
-  /** Reset back to free. */
-  downgrade: () => void;
+  login: (email: string, password: string) => Promise<void>;
+  register: (email: string, password: string) => Promise<void>;
+  logout: () => Promise<void>;
+  /** Reloads the account, e.g. after checkout changed the plan. */
+  refresh: () => Promise<void>;
 }
 
 const SubscriptionContext = createContext<SubscriptionState>({
+  user: null,
   plan: "free",
   hasAIAccess: false,
-  upgrade: () => { },
-  downgrade: () => { },
+  login: async () => { },
+  register: async () => { },
+  logout: async () => { },
+  refresh: async () => { },
 });
 
-const STORAGE_KEY = "chess-puzzles-plan";
-
 export function SubscriptionProvider({ children }: { children: ReactNode }) {
-  const [plan, setPlan] = useState<PlanId>("free");
+  const [user, setUser] = useState<UserProfile | null>(null);
 
-  // Hydrate from localStorage on mount
-  useEffect(() => {
+  const refresh = useCallback(async () => {
+    if (!getTokens()) {
+      setUser(null);
+      return;
+    }
     try {
-      const saved = localStorage.getItem(STORAGE_KEY) as PlanId | null;
-      if (saved && ["free", "pro", "elite"].includes(saved)) {
-        setPlan(saved);
-      }
+      setUser(await authApi.getMe());
     } catch {
-      // SSR or storage unavailable
+      // signed out by a failed refresh, or the backend is down
+      if (!getTokens()) setUser(null);
     }
   }, []);
 
-  const upgrade = useCallback((newPlan: PlanId) => {
-    setPlan(newPlan);
-    try {
-      localStorage.setItem(STORAGE_KEY, newPlan);
-    } catch { }
+  // Load the account on mount and drop it when the tokens go away
+  useEffect(() => {
+    refresh();
+    return onTokensChange((tokens) => {
+      if (!tokens) setUser(null);
+    });
+  }, [refresh]);
+
+  const login = useCallback(async (email: string, password: string) => {
+    setUser(await authApi.login(email, password));
   }, []);
 
-  const downgrade = useCallback(() => {
-    setPlan("free");
-    try {
-      localStorage.removeItem(STORAGE_KEY);
-    } catch { }
+  const register = useCallback(async (email: string, password: string) => {
+    setUser(await authApi.register(email, password));
+  }, []);
+
+  const logout = useCallback(async () => {
+    await authApi.logout();
+    setUser(null);
   }, []);
 
+  const plan: PlanId = user?.plan ?? "free";
   const hasAIAccess = plan === "pro" || plan === "elite";
 
   return (
-    <SubscriptionContext.Provider value={{ plan, hasAIAccess, upgrade, downgrade }}>
+    <SubscriptionContext.Provider
+      value={{ user, plan, hasAIAccess, login, register, logout, refresh }}
+    >
       {children}
     </SubscriptionContext.Provider>
   );
@@ -13,16 +13,32 @@ export interface Puzzle {
   gameUrl?: string;
   difficulty: DifficultyLevel;
   source: string;
+  filter?: PuzzleFilter;
+  promptVersion?: string; // AI prompt template the filter came from, such as "filter/v1"
+}
+
+// Structured query the AI pipeline derives from a prompt. Send it back in
+// AIPuzzleRequest.filter to rerun it without the model.
+export interface PuzzleFilter {
+  minRating?: number;
+  maxRating?: number;
+  themes?: string[];
+  excludeThemes?: string[];
+  opening?: string;
+  color?: "white" | "black";
+  maxMoves?: number;
 }
 
 export interface AIPuzzleRequest {
   prompt: string;
   difficulty: DifficultyLevel;
+  filter?: PuzzleFilter;
 }
 
 export interface ErrorResponse {
   error: string;
   details?: string;
+  filter?: PuzzleFilter;
 }
 
 export type PuzzleSource = "lichess" | "dataset" | "ai";
@@ -33,3 +49,31 @@ export interface PuzzleAttempt {
   correct: boolean;
   timestamp: number;
 }
+
+// ---------- Accounts ----------
+
+export type PlanId = "free" | "pro" | "elite";
+
+export interface UserProfile {
+  id: string;
+  email: string;
+  plan: PlanId;
+  createdAt: string;
+  planStatus?: string;
+  trialEndsAt?: string;
+  graceUntil?: string;
+  rating: number;
+  ratedSessions: number;
+}
+
+export interface AuthTokens {
+  accessToken: string;
+  refreshToken: string;
+  tokenType: string;
+  expiresIn: number; // access token lifetime in seconds
+}
+
+export interface AuthResponse {
+  user: UserProfile;
+  tokens: AuthTokens;
+}
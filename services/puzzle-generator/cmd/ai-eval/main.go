@@ -0,0 +1,343 @@
+// Command ai-eval runs a golden set of prompts through the AI puzzle pipeline
+// (GenerateFromAI) against one LLM provider and reports how often the puzzle
+// is acceptable, how often the model's filter cannot be parsed, latency and
+// token usage and cost. Each run is saved as JSON and compared with the
+// previous one, so a prompt template change (internal/prompts) can be judged
+// before it ships.
+//
+//	go run ./cmd/ai-eval -provider nvidia
+//	go run ./cmd/ai-eval -provider local -model qwen2.5:7b -baseline data/eval/20261018T101500.000Z-nvidia.json
+//	go run ./cmd/ai-eval -prompts prompts/ # try templates not yet deployed
+//	go run ./cmd/ai-eval -label 5 # label each case with puzzles of the index
+//
+// Runs use the vector index of RAG_INDEX_PATH when set, so the puzzles a
+// filter finds are the same from one run to the next; without it random
+// dataset batches are scanned and accuracy varies between runs.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"slices"
+	"time"
+
+	"github.com/chess-puzzle-next/puzzle-generator/internal/config"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/logging"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/models"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/prompts"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/retrieval"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/services"
+	"github.com/chess-puzzle-next/puzzle-generator/pkg/huggingface"
+	"github.com/chess-puzzle-next/puzzle-generator/pkg/llm"
+)
+
+// Case is one golden prompt. A puzzle is acceptable when its ID is listed in
+// Accept; cases without labels accept any puzzle that matches Expect.
+type Case struct {
+	Name       string                 `json:"name"`
+	Prompt     string                 `json:"prompt"`
+	Difficulty models.DifficultyLevel `json:"difficulty,omitempty"`
+	Accept     []string               `json:"accept,omitempty"`
+	Expect     *models.PuzzleFilter   `json:"expect,omitempty"`
+}
+
+func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("ai-eval: %v", err)
+	}
+	defaultProvider := "fake"
+	if len(cfg.LLM.Providers) > 0 {
+		defaultProvider = cfg.LLM.Providers[0]
+	}
+	golden := flag.String("golden", "eval/golden.json", "golden set of prompts")
+	provider := flag.String("provider", defaultProvider, "LLM provider to evaluate: nvidia, openrouter, local or fake (defaults to the first of LLM_PROVIDERS)")
+	model := flag.String("model", "", "model to use instead of the provider's configured one")
+	runs := flag.String("runs", "data/eval", "directory the run is saved to")
+	baseline := flag.String("baseline", "latest", `run to compare with: a run file, "latest" (the last run saved in -runs) or "none"`)
+	promptsDir := flag.String("prompts", cfg.Prompts.Dir, "prompt templates overriding the built-in ones (defaults to PROMPTS_DIR)")
+	labelN := flag.Int("label", 0, "add up to n puzzles of the index matching each case's expect filter, closest to its prompt first, to its accept labels and exit")
+	flag.Parse()
+
+	// Keep stdout for the report. The pipeline's warnings about fallbacks
+	// are expected here and show up in the results.
+	if err := logging.Setup(logging.Config{Format: logging.FormatText, Level: slog.LevelError}, os.Stderr); err != nil {
+		log.Fatalf("ai-eval: %v", err)
+	}
+	// Setup routes the log package through slog at Info level, which the
+	// Error level would drop; keep log.Fatalf visible.
+	log.SetOutput(os.Stderr)
+
+	cases, err := readGolden(*golden)
+	if err != nil {
+		log.Fatalf("ai-eval: %v", err)
+	}
+	if *labelN > 0 {
+		if cfg.RAG.IndexPath == "" {
+			log.Fatalf("ai-eval: -label needs RAG_INDEX_PATH")
+		}
+		r, err := newRetriever(cfg.RAG)
+		if err != nil {
+			log.Fatalf("ai-eval: %v", err)
+		}
+		if err := labelGolden(context.Background(), r, *golden, cases, *labelN); err != nil {
+			log.Fatalf("ai-eval: %v", err)
+		}
+		return
+	}
+	p, err := newProvider(cfg.LLM, *provider, *model)
+	if err != nil {
+		log.Fatalf("ai-eval: %v", err)
+	}
+	if !p.Configured() {
+		log.Fatalf("ai-eval: provider %s is not configured", *provider)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	store, err := prompts.Load(*promptsDir)
+	if err != nil {
+		log.Fatalf("ai-eval: %v", err)
+	}
+
+	// A failover of one provider prices the completions.
+	priced := llm.NewFailover(p)
+	priced.Prices = cfg.LLM.Prices
+	m := &meter{Provider: priced}
+	opts := []services.Option{services.WithRAGTopK(cfg.RAG.TopK), services.WithPrompts(store)}
+	index := ""
+	if cfg.RAG.IndexPath != "" {
+		r, err := newRetriever(cfg.RAG)
+		if err != nil {
+			log.Fatalf("ai-eval: %v", err)
+		}
+		opts = append(opts, services.WithRetriever(r))
+		index = cfg.RAG.IndexPath
+	} else {
+		fmt.Fprintln(os.Stderr, "ai-eval: RAG_INDEX_PATH is not set; random dataset batches make runs hard to compare")
+	}
+	dataset := huggingface.New(
+		huggingface.WithBaseURL(cfg.HuggingFace.BaseURL),
+		huggingface.WithDataset(cfg.HuggingFace.Dataset),
+		huggingface.WithConfig(cfg.HuggingFace.Config),
+		huggingface.WithSplit(cfg.HuggingFace.Split),
+		huggingface.WithTimeout(cfg.HuggingFace.Timeout),
+	)
+	svc := services.New(nil, m, dataset, opts...)
+
+	run := &Run{StartedAt: time.Now().UTC(), Golden: *golden, Provider: p.Name(), Index: index}
+	for i, c := range cases {
+		if ctx.Err() != nil {
+			break
+		}
+		fmt.Fprintf(os.Stderr, "\r%d/%d %-40s", i+1, len(cases), c.Name)
+		run.Results = append(run.Results, evaluate(ctx, svc, m, c))
+		if m.model != "" {
+			run.Model = m.model
+		}
+		if v := run.Results[len(run.Results)-1].PromptVersion; v != "" && !slices.Contains(run.PromptVersions, v) {
+			run.PromptVersions = append(run.PromptVersions, v)
+		}
+	}
+	fmt.Fprintln(os.Stderr)
+	if ctx.Err() != nil {
+		log.Fatalf("ai-eval: interrupted after %d of %d cases", len(run.Results), len(cases))
+	}
+	run.Summary = summarize(run.Results)
+
+	var base *Run
+	switch *baseline {
+	case "none":
+	case "latest":
+		base, err = latestRun(*runs)
+	default:
+		base, err = loadRun(*baseline)
+	}
+	if err != nil {
+		log.Fatalf("ai-eval: %v", err)
+	}
+
+	path, err := run.Save(*runs)
+	if err != nil {
+		log.Fatalf("ai-eval: %v", err)
+	}
+	printReport(os.Stdout, run, base)
+	fmt.Printf("\nsaved %s\n", path)
+}
+
+// evaluate runs one case and judges the puzzle it gets.
+func evaluate(ctx context.Context, svc *services.PuzzleService, m *meter, c Case) Result {
+	difficulty := c.Difficulty
+	if difficulty == "" {
+		difficulty = models.DifficultyMedium
+	}
+	m.calls, m.failed, m.usage, m.promptVersion = 0, 0, llm.Usage{}, ""
+	start := time.Now()
+	p, err := svc.GenerateFromAI(ctx, models.AIPuzzleRequest{Prompt: c.Prompt, Difficulty: difficulty})
+
+	res := Result{
+		Name:             c.Name,
+		LatencyMs:        time.Since(start).Milliseconds(),
+		ModelCalls:       m.calls,
+		PromptTokens:     m.usage.PromptTokens,
+		CompletionTokens: m.usage.CompletionTokens,
+		CostUSD:          m.usage.Cost,
+		PromptVersion:    m.promptVersion,
+		Labeled:          len(c.Accept) > 0,
+	}
+	// The pipeline falls back to the prompt's keywords when the model gave
+	// no valid filter; when the model answered, that is a parse failure,
+	// whether or not the keywords then found a puzzle.
+	source := ""
+	var noMatch *services.NoMatchError
+	switch {
+	case err == nil:
+		source = p.Source
+	case errors.As(err, &noMatch):
+		source = noMatch.Source
+		res.Filter = noMatch.Filter
+	}
+	res.ParseFailure = source == "ai-rag-fallback" && m.failed == 0
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	res.PuzzleID = p.ID
+	res.Filter = p.Filter
+	if res.Labeled {
+		res.Correct = slices.Contains(c.Accept, p.ID)
+	} else {
+		res.Correct = c.Expect.Matches(p)
+	}
+	return res
+}
+
+func readGolden(path string) ([]Case, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cases []Case
+	if err := json.Unmarshal(data, &cases); err != nil {
+		return nil, fmt.Errorf("read golden set %s: %w", path, err)
+	}
+	for i, c := range cases {
+		if c.Name == "" || c.Prompt == "" {
+			return nil, fmt.Errorf("golden set %s: case %d needs a name and a prompt", path, i)
+		}
+		if len(c.Accept) == 0 && c.Expect == nil {
+			return nil, fmt.Errorf("golden set %s: case %s labels no acceptable puzzle (accept or expect)", path, c.Name)
+		}
+	}
+	if len(cases) == 0 {
+		return nil, fmt.Errorf("golden set %s is empty", path)
+	}
+	return cases, nil
+}
+
+// labelGolden adds to the accept labels of each case with an expect filter
+// the n puzzles of the index that match it and are closest to its prompt,
+// and rewrites the golden set. Review the labels before committing them:
+// they are the puzzles a perfect filter would let retrieval pick.
+func labelGolden(ctx context.Context, r *retrieval.Retriever, path string, cases []Case, n int) error {
+	for i := range cases {
+		c := &cases[i]
+		if c.Expect == nil {
+			continue
+		}
+		found, err := r.Retrieve(ctx, c.Prompt, n, c.Expect.Matches)
+		if err != nil {
+			return fmt.Errorf("label %s: %w", c.Name, err)
+		}
+		added := 0
+		for _, p := range found {
+			if !slices.Contains(c.Accept, p.ID) {
+				c.Accept = append(c.Accept, p.ID)
+				added++
+			}
+		}
+		fmt.Fprintf(os.Stderr, "%-40s %d labels (+%d)\n", c.Name, len(c.Accept), added)
+	}
+	data, err := json.MarshalIndent(cases, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// newProvider builds the named provider from the server's LLM settings.
+// model, when set, replaces its model for every task.
+func newProvider(cfg config.LLMConfig, name, model string) (llm.Provider, error) {
+	settings := func(p config.LLMProviderConfig) []llm.Option {
+		opts := []llm.Option{
+			llm.WithBaseURL(p.BaseURL),
+			llm.WithAPIKey(p.APIKey),
+			llm.WithTimeout(p.Timeout),
+		}
+		if model != "" {
+			return append(opts, llm.WithModel(model))
+		}
+		return append(opts, llm.WithModel(p.Model), llm.WithTaskModels(p.TaskModels))
+	}
+	switch name {
+	case "nvidia":
+		return llm.NewNVIDIA(settings(cfg.NVIDIA)...), nil
+	case "openrouter":
+		return llm.NewOpenRouter(settings(cfg.OpenRouter)...), nil
+	case "local":
+		return llm.NewOpenAICompatible(name, settings(cfg.Local)...), nil
+	case "fake":
+		return llm.NewFake(nil, cfg.FakeReply), nil
+	default:
+		return nil, fmt.Errorf("unknown provider %q", name)
+	}
+}
+
+// newRetriever loads the vector index and the embedder it was built with.
+func newRetriever(cfg config.RAGConfig) (*retrieval.Retriever, error) {
+	emb, err := retrieval.NewEmbedder(cfg.Embeddings, nil)
+	if err != nil {
+		return nil, err
+	}
+	idx, err := retrieval.Load(cfg.IndexPath)
+	if err != nil {
+		return nil, err
+	}
+	return retrieval.NewRetriever(idx, emb)
+}
+
+// meter is the provider under evaluation, counting the calls and tokens of
+// the current case. It does not stream, so completions are whole. Cases run
+// one at a time.
+type meter struct {
+	llm.Provider
+
+	calls         int
+	failed        int
+	usage         llm.Usage
+	model         string // model of the last completion
+	promptVersion string // prompt template version of the last completion
+}
+
+func (m *meter) Chat(ctx context.Context, r llm.Request) (*llm.Response, error) {
+	resp, err := m.Provider.Chat(ctx, r)
+	m.calls++
+	if err != nil {
+		m.failed++
+		return nil, err
+	}
+	m.usage.PromptTokens += resp.Usage.PromptTokens
+	m.usage.CompletionTokens += resp.Usage.CompletionTokens
+	m.usage.Cost += resp.Usage.Cost
+	m.model = resp.Model
+	m.promptVersion = resp.PromptVersion
+	return resp, nil
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/chess-puzzle-next/puzzle-generator/internal/models"
+)
+
+// Run is one evaluation, saved as JSON.
+type Run struct {
+	StartedAt time.Time `json:"startedAt"`
+	Golden    string    `json:"golden"`
+	Provider  string    `json:"provider"`
+	Model     string    `json:"model,omitempty"`
+	Index     string    `json:"index,omitempty"` // vector index the filters ran against; empty for the dataset
+	// PromptVersions are the prompt template versions the model was given.
+	PromptVersions []string `json:"promptVersions,omitempty"`
+	Summary        Summary  `json:"summary"`
+	Results        []Result `json:"results"`
+}
+
+// Result is the outcome of one golden case.
+type Result struct {
+	Name             string               `json:"name"`
+	PuzzleID         string               `json:"puzzleId,omitempty"`
+	Filter           *models.PuzzleFilter `json:"filter,omitempty"`
+	Labeled          bool                 `json:"labeled,omitempty"` // the case lists acceptable puzzle IDs
+	Correct          bool                 `json:"correct"`
+	ParseFailure     bool                 `json:"parseFailure,omitempty"` // the model answered but gave no valid filter
+	Error            string               `json:"error,omitempty"`
+	ModelCalls       int                  `json:"modelCalls"` // 2 when the first answer was retried
+	LatencyMs        int64                `json:"latencyMs"`
+	PromptTokens     int                  `json:"promptTokens"`
+	CompletionTokens int                  `json:"completionTokens"`
+	CostUSD          float64              `json:"costUsd"` // from LLM_PRICES
+	PromptVersion    string               `json:"promptVersion,omitempty"`
+}
+
+// Summary aggregates the results of a run.
+type Summary struct {
+	Cases            int     `json:"cases"`
+	Correct          int     `json:"correct"`
+	Accuracy         float64 `json:"accuracy"`
+	Labeled          int     `json:"labeled"` // cases judged against puzzle ID labels
+	LabeledCorrect   int     `json:"labeledCorrect"`
+	LabelAccuracy    float64 `json:"labelAccuracy"`
+	ParseFailures    int     `json:"parseFailures"`
+	ParseFailureRate float64 `json:"parseFailureRate"`
+	Retried          int     `json:"retried"`
+	Errors           int     `json:"errors"`
+	LatencyP50Ms     int64   `json:"latencyP50Ms"`
+	LatencyP95Ms     int64   `json:"latencyP95Ms"`
+	PromptTokens     int     `json:"promptTokens"`
+	CompletionTokens int     `json:"completionTokens"`
+	CostUSD          float64 `json:"costUsd"`
+}
+
+func summarize(results []Result) Summary {
+	s := Summary{Cases: len(results)}
+	latencies := make([]int64, 0, len(results))
+	for _, r := range results {
+		if r.Correct {
+			s.Correct++
+		}
+		if r.Labeled {
+			s.Labeled++
+			if r.Correct {
+				s.LabeledCorrect++
+			}
+		}
+		if r.ParseFailure {
+			s.ParseFailures++
+		}
+		if r.ModelCalls > 1 {
+			s.Retried++
+		}
+		if r.Error != "" {
+			s.Errors++
+		}
+		s.PromptTokens += r.PromptTokens
+		s.CompletionTokens += r.CompletionTokens
+		s.CostUSD += r.CostUSD
+		latencies = append(latencies, r.LatencyMs)
+	}
+	if s.Cases > 0 {
+		s.Accuracy = float64(s.Correct) / float64(s.Cases)
+		s.ParseFailureRate = float64(s.ParseFailures) / float64(s.Cases)
+		if s.Labeled > 0 {
+			s.LabelAccuracy = float64(s.LabeledCorrect) / float64(s.Labeled)
+		}
+		slices.Sort(latencies)
+		s.LatencyP50Ms = percentile(latencies, 0.50)
+		s.LatencyP95Ms = percentile(latencies, 0.95)
+	}
+	return s
+}
+
+// percentile returns the nearest-rank percentile of sorted values.
+func percentile(sorted []int64, p float64) int64 {
+	i := int(float64(len(sorted))*p+0.5) - 1
+	return sorted[min(max(i, 0), len(sorted)-1)]
+}
+
+// Save writes the run to dir as <start time>-<provider>.json and returns its
+// path. Names sort in run order.
+func (r *Run) Save(dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("save run: %w", err)
+	}
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("save run: %w", err)
+	}
+	path := filepath.Join(dir, r.StartedAt.Format("20060102T150405.000Z")+"-"+r.Provider+".json")
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return "", fmt.Errorf("save run: %w", err)
+	}
+	return path, nil
+}
+
+func loadRun(path string) (*Run, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("load run: %w", err)
+	}
+	var r Run
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("load run %s: %w", path, err)
+	}
+	return &r, nil
+}
+
+// latestRun loads the last run saved in dir, or returns nil when there is
+// none.
+func latestRun(dir string) (*Run, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil || len(paths) == 0 {
+		return nil, err
+	}
+	slices.Sort(paths)
+	return loadRun(paths[len(paths)-1])
+}
+
+// printReport writes the summary of run and each case to w, with the change
+// from base when set.
+func printReport(w io.Writer, run, base *Run) {
+	fmt.Fprintf(w, "provider %s", run.Provider)
+	if run.Model != "" {
+		fmt.Fprintf(w, " (%s)", run.Model)
+	}
+	source := "dataset"
+	if run.Index != "" {
+		source = run.Index
+	}
+	fmt.Fprintf(w, ", %d cases from %s, puzzles from %s\n", run.Summary.Cases, run.Golden, source)
+	if len(run.PromptVersions) > 0 {
+		fmt.Fprintf(w, "prompts %s\n", strings.Join(run.PromptVersions, ", "))
+	}
+	if base != nil {
+		fmt.Fprintf(w, "compared with %s run of %s (%s", base.Provider, base.StartedAt.Format(time.RFC3339), base.Model)
+		if len(base.PromptVersions) > 0 {
+			fmt.Fprintf(w, ", prompts %s", strings.Join(base.PromptVersions, ", "))
+		}
+		fmt.Fprintln(w, ")")
+	}
+	fmt.Fprintln(w)
+
+	s := run.Summary
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	row := func(name, value string, delta string) {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", name, value, delta)
+	}
+	perCase := func(n int) float64 { return float64(n) / float64(max(s.Cases, 1)) }
+	var b Summary
+	if base != nil {
+		b = base.Summary
+	}
+	basePerCase := func(n int) float64 { return float64(n) / float64(max(b.Cases, 1)) }
+	delta := func(format string, now, then float64) string {
+		if base == nil {
+			return ""
+		}
+		return fmt.Sprintf(format, now-then)
+	}
+
+	row("accuracy", fmt.Sprintf("%.1f%% (%d/%d)", 100*s.Accuracy, s.Correct, s.Cases), delta("%+.1f pts", 100*s.Accuracy, 100*b.Accuracy))
+	row("label accuracy", fmt.Sprintf("%.1f%% (%d/%d)", 100*s.LabelAccuracy, s.LabeledCorrect, s.Labeled), delta("%+.1f pts", 100*s.LabelAccuracy, 100*b.LabelAccuracy))
+	row("parse failures", fmt.Sprintf("%.1f%% (%d)", 100*s.ParseFailureRate, s.ParseFailures), delta("%+.1f pts", 100*s.ParseFailureRate, 100*b.ParseFailureRate))
+	row("retried", fmt.Sprintf("%d", s.Retried), delta("%+.0f", float64(s.Retried), float64(b.Retried)))
+	row("errors", fmt.Sprintf("%d", s.Errors), delta("%+.0f", float64(s.Errors), float64(b.Errors)))
+	row("latency p50", fmt.Sprintf("%dms", s.LatencyP50Ms), delta("%+.0fms", float64(s.LatencyP50Ms), float64(b.LatencyP50Ms)))
+	row("latency p95", fmt.Sprintf("%dms", s.LatencyP95Ms), delta("%+.0fms", float64(s.LatencyP95Ms), float64(b.LatencyP95Ms)))
+	row("prompt tokens/case", fmt.Sprintf("%.0f", perCase(s.PromptTokens)), delta("%+.0f", perCase(s.PromptTokens), basePerCase(b.PromptTokens)))
+	row("completion tokens/case", fmt.Sprintf("%.0f", perCase(s.CompletionTokens)), delta("%+.0f", perCase(s.CompletionTokens), basePerCase(b.CompletionTokens)))
+	row("cost", fmt.Sprintf("$%.4f", s.CostUSD), delta("%+.4f", s.CostUSD, b.CostUSD))
+	tw.Flush()
+	fmt.Fprintln(w)
+
+	before := make(map[string]Result)
+	if base != nil {
+		for _, r := range base.Results {
+			before[r.Name] = r
+		}
+	}
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, r := range run.Results {
+		mark := "FAIL"
+		if r.Correct {
+			mark = "ok"
+		}
+		detail := r.PuzzleID
+		switch {
+		case r.Error != "":
+			detail = r.Error
+		case r.ParseFailure:
+			detail += " (keyword fallback)"
+		}
+		change := ""
+		if prev, ok := before[r.Name]; ok && prev.Correct != r.Correct {
+			change = "newly failing"
+			if r.Correct {
+				change = "newly passing"
+			}
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%dms\t%s\t%s\n", mark, r.Name, r.LatencyMs, detail, change)
+	}
+	tw.Flush()
+}
@@ -0,0 +1,134 @@
+// Command billing-stub stands in for the billing provider during local
+// development. It renders the signed fixture events in testdata/ and posts
+// them to the webhook endpoint, in the order given on the command line.
+//
+//	go run ./cmd/billing-stub -user <user-id> checkout_completed subscription_updated
+//
+// With no fixture names it plays the whole lifecycle: checkout, trial,
+// activation, a failed invoice and cancellation.
+package main
+
+import (
+	"bytes"
+	"embed"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/chess-puzzle-next/puzzle-generator/internal/billing"
+)
+
+//go:embed testdata/*.json
+var fixtures embed.FS
+
+var lifecycle = []string{
+	"checkout_completed",
+	"subscription_trialing",
+	"subscription_updated",
+	"invoice_payment_failed",
+	"subscription_deleted",
+}
+
+// fixtureData fills the placeholders of a fixture.
+type fixtureData struct {
+	EventID        string
+	Created        int64
+	UserID         string
+	CustomerID     string
+	SubscriptionID string
+	PriceID        string
+	Plan           string
+	TrialEnd       int64
+}
+
+func main() {
+	url := flag.String("url", "http://localhost:8080/api/v1/billing/webhook", "webhook endpoint")
+	secret := flag.String("secret", os.Getenv("STRIPE_WEBHOOK_SECRET"), "webhook signing secret")
+	userID := flag.String("user", "", "user ID the checkout belongs to (required)")
+	customer := flag.String("customer", "cus_stub", "billing customer ID")
+	plan := flag.String("plan", "pro", "plan purchased at checkout")
+	price := flag.String("price", "", "price ID sent on subscription events")
+	resend := flag.Bool("resend", false, "post every event twice to exercise idempotency")
+	flag.Parse()
+
+	if *userID == "" || *secret == "" {
+		fmt.Fprintln(os.Stderr, "billing-stub: -user and -secret (or STRIPE_WEBHOOK_SECRET) are required")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	names := flag.Args()
+	if len(names) == 0 {
+		names = lifecycle
+	}
+
+	now := time.Now()
+	data := fixtureData{
+		UserID:         *userID,
+		CustomerID:     *customer,
+		SubscriptionID: "sub_stub_" + *customer,
+		PriceID:        *price,
+		Plan:           *plan,
+		TrialEnd:       now.Add(14 * 24 * time.Hour).Unix(),
+	}
+
+	for i, name := range names {
+		// Events are a second apart so the service sees them in order.
+		data.EventID = fmt.Sprintf("evt_stub_%d_%s", now.UnixNano(), name)
+		data.Created = now.Unix() + int64(i)
+
+		payload, err := render(name, data)
+		if err != nil {
+			log.Fatalf("billing-stub: %v", err)
+		}
+		sends := 1
+		if *resend {
+			sends = 2
+		}
+		for range sends {
+			if err := post(*url, *secret, name, payload); err != nil {
+				log.Fatalf("billing-stub: %v", err)
+			}
+		}
+	}
+}
+
+func render(name string, data fixtureData) ([]byte, error) {
+	tmpl, err := template.ParseFS(fixtures, "testdata/"+strings.TrimSuffix(name, ".json")+".json")
+	if err != nil {
+		return nil, fmt.Errorf("load fixture %q: %w", name, err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("render fixture %q: %w", name, err)
+	}
+	return buf.Bytes(), nil
+}
+
+func post(url, secret, name string, payload []byte) error {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(billing.SignatureHeader, billing.Sign(payload, secret, time.Now()))
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("post %s: %w", name, err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	fmt.Printf("%-24s %d %s\n", name, resp.StatusCode, strings.TrimSpace(string(body)))
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s rejected with status %d", name, resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+// Command build-index builds the vector index used by the RAG pipeline. It
+// reads puzzles from the Lichess puzzle CSV export (-csv) or samples them
+// evenly across the HuggingFace dataset, describes each one as text and
+// embeds it with the embedder configured by EMBEDDINGS_PROVIDER, so the
+// server must run with the same embedder settings.
+//
+//	go run ./cmd/build-index -out data/puzzles.idx -limit 20000
+//	go run ./cmd/build-index -csv lichess_db_puzzle.csv -min-plays 500
+//
+// The index is written through a temporary file, so a running server is
+// never left with a partial one.
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/chess-puzzle-next/puzzle-generator/internal/config"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/models"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/retrieval"
+	"github.com/chess-puzzle-next/puzzle-generator/pkg/huggingface"
+)
+
+// pageSize is the most rows the datasets server returns per call.
+const pageSize = 100
+
+func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("build-index: %v", err)
+	}
+	defaultOut := cfg.RAG.IndexPath
+	if defaultOut == "" {
+		defaultOut = "data/puzzles.idx"
+	}
+	out := flag.String("out", defaultOut, "index file to write (defaults to RAG_INDEX_PATH)")
+	csvPath := flag.String("csv", "", "Lichess puzzle CSV export to read instead of the HuggingFace dataset")
+	limit := flag.Int("limit", 20000, "puzzles to index")
+	minPlays := flag.Int("min-plays", 0, "skip puzzles played fewer times")
+	batch := flag.Int("batch", 256, "puzzles embedded per embedder call")
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	keep := func(p *models.Puzzle) bool { return p.NbPlays >= *minPlays }
+	var puzzles []*models.Puzzle
+	if *csvPath != "" {
+		puzzles, err = readCSV(*csvPath, *limit, keep)
+	} else {
+		puzzles, err = sampleDataset(ctx, cfg.HuggingFace, *limit, keep)
+	}
+	if err != nil {
+		log.Fatalf("build-index: %v", err)
+	}
+	if len(puzzles) == 0 {
+		log.Fatalf("build-index: no puzzles to index")
+	}
+
+	emb, err := retrieval.NewEmbedder(cfg.RAG.Embeddings, nil)
+	if err != nil {
+		log.Fatalf("build-index: %v", err)
+	}
+	start := time.Now()
+	idx, err := retrieval.Build(ctx, emb, puzzles, *batch, func(done int) {
+		fmt.Fprintf(os.Stderr, "\rembedded %d/%d", done, len(puzzles))
+	})
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		log.Fatalf("build-index: %v", err)
+	}
+	if err := idx.Save(*out); err != nil {
+		log.Fatalf("build-index: %v", err)
+	}
+	fmt.Printf("indexed %d puzzles with %s in %s: %s\n", len(idx.Entries), idx.Embedder, time.Since(start).Round(time.Millisecond), *out)
+}
+
+// readCSV reads up to limit puzzles accepted by keep from a Lichess puzzle
+// CSV export, whose columns match the dataset's.
+func readCSV(path string, limit int, keep func(*models.Puzzle) bool) ([]*models.Puzzle, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.ReuseRecord = true
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read CSV header: %w", err)
+	}
+	header = append([]string(nil), header...)
+
+	var puzzles []*models.Puzzle
+	for len(puzzles) < limit {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read CSV: %w", err)
+		}
+		row := make(map[string]any, len(header))
+		for i, col := range header {
+			if i < len(record) {
+				row[col] = record[i]
+			}
+		}
+		if p, err := huggingface.ParseRow(row); err == nil && keep(p) {
+			puzzles = append(puzzles, p)
+		}
+	}
+	return puzzles, nil
+}
+
+// sampleDataset reads pages spread evenly over the whole dataset, so the
+// index covers every rating and theme rather than the first rows.
+func sampleDataset(ctx context.Context, cfg config.HuggingFaceConfig, limit int, keep func(*models.Puzzle) bool) ([]*models.Puzzle, error) {
+	client := huggingface.New(
+		huggingface.WithBaseURL(cfg.BaseURL),
+		huggingface.WithDataset(cfg.Dataset),
+		huggingface.WithConfig(cfg.Config),
+		huggingface.WithSplit(cfg.Split),
+		huggingface.WithTimeout(cfg.Timeout),
+	)
+	total, err := client.Count(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pages := (limit + pageSize - 1) / pageSize
+	stride := max(total/pages, pageSize)
+
+	seen := make(map[string]bool)
+	var puzzles []*models.Puzzle
+	for offset := 0; offset < total && len(puzzles) < limit; offset += stride {
+		page, err := client.Puzzles(ctx, offset, pageSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range page {
+			if !seen[p.ID] && keep(p) && len(puzzles) < limit {
+				seen[p.ID] = true
+				puzzles = append(puzzles, p)
+			}
+		}
+		fmt.Fprintf(os.Stderr, "\rfetched %d/%d puzzles", len(puzzles), limit)
+	}
+	fmt.Fprintln(os.Stderr)
+	return puzzles, nil
+}
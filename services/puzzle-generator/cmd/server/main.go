@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"errors"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
@@ -10,38 +11,70 @@ import (
 	"time"
 
 	"github.com/chess-puzzle-next/puzzle-generator/internal/config"
-	"github.com/labstack/gommon/log"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/logging"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/tracing"
 )
 
 // @title Puzzle Generator API
 // @version 1.0
-// @description Chess puzzle generator service (Lichess, AI via NVIDIA Inference, and Hugging Face dataset).
+// @description Chess puzzle generator service (Lichess, AI via NVIDIA Inference, OpenRouter or a local OpenAI-compatible model, and Hugging Face dataset).
 // @BasePath /api/v1
 // @schemes https
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description Access token from /auth/login, as "Bearer <token>"
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
+// @description Developer API key issued by an admin (cpk_...)
+
+var logger = logging.For("server")
 
 func main() {
 	cfg, err := config.Load()
 	if err != nil {
-		log.Fatalf("failed to load config: %v", err)
+		fatal("failed to load config", err)
+	}
+	if err := logging.Setup(logging.Config{
+		Format: cfg.Logging.Format,
+		Level:  cfg.Logging.Level,
+		Levels: cfg.Logging.Levels,
+	}, os.Stdout); err != nil {
+		fatal("failed to set up logging", err)
+	}
+
+	// Tracing comes first: clients created by newServer pick up the global
+	// tracer provider.
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		Exporter:    cfg.Tracing.Exporter,
+		ServiceName: cfg.Tracing.ServiceName,
+	})
+	if err != nil {
+		fatal("failed to set up tracing", err)
 	}
 
-	e := newServer(cfg)
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+
+	e := newServer(workersCtx, cfg)
 
 	srv := &http.Server{
 		Addr:         ":" + cfg.Server.Port,
 		ReadTimeout:  cfg.Server.ReadTimeout,
 		WriteTimeout: cfg.Server.WriteTimeout,
+		ErrorLog:     slog.NewLogLogger(logger.Handler(), slog.LevelWarn),
 	}
 
 	go func() {
-		e.Logger.Infof("puzzle-generator listening on :%s", cfg.Server.Port)
+		logger.Info("puzzle-generator listening", "port", cfg.Server.Port)
 		if cfg.Lichess.APIToken != "" {
-			e.Logger.Info("Lichess API token configured difficulty filtering enabled")
+			logger.Info("Lichess API token configured, difficulty filtering enabled")
 		} else {
-			e.Logger.Warn("No LICHESS_API_TOKEN set using anonymous random puzzles (repeats may still occur)")
+			logger.Warn("No LICHESS_API_TOKEN set, using anonymous random puzzles (repeats may still occur)")
 		}
 		if err := e.StartServer(srv); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			e.Logger.Fatalf("server error: %v", err)
+			fatal("server error", err)
 		}
 	}()
 
@@ -49,11 +82,20 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
-	e.Logger.Info("shutting down server…")
+	logger.Info("shutting down server")
+	stopWorkers()
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
 	if err := e.Shutdown(ctx); err != nil {
-		e.Logger.Errorf("graceful shutdown failed: %v", err)
+		logger.Error("graceful shutdown failed", "err", err)
+	}
+	if err := shutdownTracing(ctx); err != nil {
+		logger.Error("flush traces", "err", err)
 	}
 }
+
+func fatal(msg string, err error) {
+	logger.Error(msg, "err", err)
+	os.Exit(1)
+}
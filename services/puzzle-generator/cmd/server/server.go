@@ -1,73 +1,453 @@
 package main
 
 import (
-	"fmt"
+	"context"
+	"crypto/rand"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"time"
 
 	_ "github.com/chess-puzzle-next/puzzle-generator/docs"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/aiusage"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/apikeys"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/auth"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/billing"
 	"github.com/chess-puzzle-next/puzzle-generator/internal/config"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/entitlements"
 	"github.com/chess-puzzle-next/puzzle-generator/internal/handlers"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/health"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/logging"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/metrics"
 	custmw "github.com/chess-puzzle-next/puzzle-generator/internal/middleware"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/prompts"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/puzzlecache"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/ratelimit"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/retrieval"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/seen"
 	"github.com/chess-puzzle-next/puzzle-generator/internal/services"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/tracing"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/webhooks"
 	"github.com/chess-puzzle-next/puzzle-generator/pkg/huggingface"
 	"github.com/chess-puzzle-next/puzzle-generator/pkg/lichess"
-	"github.com/chess-puzzle-next/puzzle-generator/pkg/nvidia"
+	"github.com/chess-puzzle-next/puzzle-generator/pkg/llm"
 	redispkg "github.com/chess-puzzle-next/puzzle-generator/pkg/redis"
+	"github.com/chess-puzzle-next/puzzle-generator/pkg/resilient"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
-	"github.com/labstack/gommon/log"
 	httpSwagger "github.com/swaggo/http-swagger/v2"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
-func newServer(cfg *config.Config) *echo.Echo {
+// newServer wires the Echo app. Background workers started here stop when ctx
+// is cancelled.
+func newServer(ctx context.Context, cfg *config.Config) *echo.Echo {
+	lichessRT, lichessCircuit := upstreamTransport(cfg.Upstream, metrics.UpstreamLichess)
+	datasetRT, datasetCircuit := upstreamTransport(cfg.Upstream, metrics.UpstreamHuggingFace)
+
 	lichessOpts := []lichess.Option{
 		lichess.WithBaseURL(cfg.Lichess.BaseURL),
 		lichess.WithTimeout(cfg.Lichess.Timeout),
+		lichess.WithTransport(lichessRT),
 	}
 	if cfg.Lichess.APIToken != "" {
 		lichessOpts = append(lichessOpts, lichess.WithAPIToken(cfg.Lichess.APIToken))
 	}
 
-	svc := services.New(
-		lichess.New(lichessOpts...),
-		nvidia.New(
-			nvidia.WithBaseURL(cfg.NVIDIA.BaseURL),
-			nvidia.WithAPIKey(cfg.NVIDIA.APIKey),
-			nvidia.WithModel(cfg.NVIDIA.Model),
-			nvidia.WithTimeout(cfg.NVIDIA.Timeout),
-		),
-		huggingface.New(
-			huggingface.WithBaseURL(cfg.HuggingFace.BaseURL),
-			huggingface.WithDataset(cfg.HuggingFace.Dataset),
-			huggingface.WithConfig(cfg.HuggingFace.Config),
-			huggingface.WithSplit(cfg.HuggingFace.Split),
-			huggingface.WithTimeout(cfg.HuggingFace.Timeout),
-		),
+	dataset := huggingface.New(
+		huggingface.WithBaseURL(cfg.HuggingFace.BaseURL),
+		huggingface.WithDataset(cfg.HuggingFace.Dataset),
+		huggingface.WithConfig(cfg.HuggingFace.Config),
+		huggingface.WithSplit(cfg.HuggingFace.Split),
+		huggingface.WithTimeout(cfg.HuggingFace.Timeout),
+		huggingface.WithTransport(datasetRT),
 	)
-	puzzleHandler := handlers.NewPuzzleHandler(svc)
 
 	// Redis (optional — degrades gracefully)
 	redisClient := redispkg.NewOptional(cfg.Redis.URL)
 	if redisClient != nil {
-		fmt.Println(" Redis connected")
+		logger.Info("Redis connected")
 	} else {
-		fmt.Println(" Redis unavailable — sessions disabled")
+		logger.Warn("Redis unavailable, sessions disabled")
+	}
+	metrics.RegisterRedis(redisClient)
+
+	// Outbound webhooks (endpoints and delivery log in Redis)
+	var hooks *webhooks.Service
+	var events services.EventPublisher
+	var puzzleOpts []services.Option
+	if redisClient != nil {
+		hooks = webhooks.New(redisClient, webhooks.Config{
+			Workers:        cfg.Webhooks.Workers,
+			QueueSize:      cfg.Webhooks.QueueSize,
+			MaxAttempts:    cfg.Webhooks.MaxAttempts,
+			RetryBaseDelay: cfg.Webhooks.RetryBaseDelay,
+			Timeout:        cfg.Webhooks.Timeout,
+			LogRetention:   cfg.Webhooks.LogRetention,
+			AllowPrivate:   cfg.Webhooks.AllowPrivate,
+		})
+		go hooks.Run(ctx)
+		events = hooks
+		puzzleOpts = append(puzzleOpts, services.WithEvents(hooks))
+	}
+	webhookHandler := handlers.NewWebhookHandler(hooks)
+
+	lc := lichess.New(lichessOpts...)
+	ai, aiCircuit := newLLM(cfg.LLM, cfg.Upstream)
+
+	// AI usage and cost per user and day, shared through Redis so the daily
+	// spend limit is global
+	var usageStore aiusage.Store
+	if redisClient != nil {
+		usageStore = redisClient
+	}
+	aiUsage := aiusage.New(usageStore, cfg.AIUsage.DailySpendLimit, cfg.AIUsage.Retention)
+	if cfg.AIUsage.DailySpendLimit > 0 && len(cfg.LLM.Prices) == 0 {
+		logger.Warn("AI_DAILY_SPEND_LIMIT is set but LLM_PRICES is empty; every call costs 0 and the limit is never reached")
+	}
+	usageHandler := handlers.NewUsageHandler(aiUsage)
+
+	// Puzzle cache: in-process LRU, shared through Redis when available
+	var cacheStore puzzlecache.Store
+	if redisClient != nil {
+		cacheStore = redisClient
+	}
+	puzzleCache := puzzlecache.New(cfg.PuzzleCache.Size, cacheStore, cfg.PuzzleCache.TTL)
+
+	// Puzzles served per viewer: in Redis when available
+	var seenStore seen.Store
+	if redisClient != nil {
+		seenStore = redisClient
+	}
+	seenTracker := seen.New(seenStore, cfg.Seen.Limit, cfg.Seen.TTL, cfg.Seen.MemoryViewers)
+
+	puzzleOpts = append(puzzleOpts,
+		services.WithSourceChains(cfg.Sources.Chains),
+		services.WithCache(puzzleCache),
+		services.WithSeen(seenTracker),
+	)
+	if redisClient != nil {
+		puzzleOpts = append(puzzleOpts,
+			services.WithStore(redisClient, int64(cfg.Sources.StoreLimit)),
+			services.WithExplanations(redisClient, cfg.Explain.CacheTTL),
+		)
+	}
+	puzzleOpts = append(puzzleOpts, services.WithRAGTopK(cfg.RAG.TopK))
+	if cfg.RAG.IndexPath != "" {
+		if r, err := newRetriever(cfg.RAG, cfg.Upstream); err != nil {
+			logger.Error("RAG index unavailable, candidates will be random dataset rows", "path", cfg.RAG.IndexPath, "err", err)
+		} else {
+			logger.Info("RAG index loaded", "path", cfg.RAG.IndexPath, "puzzles", r.Len())
+			puzzleOpts = append(puzzleOpts, services.WithRetriever(r))
+		}
+	}
+	promptStore, err := prompts.Load(cfg.Prompts.Dir)
+	if err != nil {
+		logger.Error("prompt templates unavailable, using the built-in ones", "dir", cfg.Prompts.Dir, "err", err)
+		promptStore = prompts.Builtin()
+	}
+	logger.Info("prompt templates loaded", "dir", cfg.Prompts.Dir, "versions", promptStore.Versions())
+	go promptStore.Run(ctx, cfg.Prompts.ReloadInterval)
+	puzzleOpts = append(puzzleOpts, services.WithPrompts(promptStore))
+	if cfg.Pools.Enabled {
+		puzzleOpts = append(puzzleOpts, services.WithPools(services.PoolConfig{
+			Size:     cfg.Pools.Size,
+			Interval: cfg.Pools.RefillInterval,
+			Sources:  cfg.Pools.Sources,
+		}))
+	}
+	svc := services.New(lc, aiUsage.Meter(ai), dataset, puzzleOpts...)
+	go svc.RunSourceSync(ctx, cfg.Sources.SyncInterval)
+	go svc.RunPools(ctx)
+	sourceHandler := handlers.NewSourceHandler(svc)
+
+	// Readiness: Lichess is required unless every chain using it can fall
+	// back to another source; the rest switch features off.
+	var aiProbe func(context.Context) error
+	if ai.Configured() {
+		aiProbe = ai.Ping
+	}
+	healthHandler := handlers.NewHealthHandler(health.NewChecker(cfg.Health.CacheTTL, cfg.Health.ProbeTimeout,
+		health.Check{
+			Name:               "redis",
+			DegradesGracefully: true,
+			Impact:             "sessions, accounts, API keys, webhooks, weekly challenge, shared quotas",
+			Probe: func(ctx context.Context) error {
+				if !redisClient.Healthy(ctx) {
+					return errors.New("redis: not reachable")
+				}
+				return nil
+			},
+		},
+		health.Check{
+			Name:               "lichess",
+			DegradesGracefully: svc.HasFallback(services.SourceLichess),
+			Impact:             "puzzles by difficulty, ID and daily puzzle come from the fallback sources",
+			Probe:              lc.Ping,
+			Circuit:            lichessCircuit,
+		},
+		health.Check{Name: "huggingface", DegradesGracefully: true, Impact: "dataset puzzles, AI puzzles, weekly challenge", Probe: dataset.Ping, Circuit: datasetCircuit},
+		health.Check{Name: "ai", DegradesGracefully: true, Impact: "AI puzzles", Probe: aiProbe, Circuit: aiCircuit},
+	))
+
+	// Sessions: hints, scoring and player ratings (need Redis)
+	var sessionService *services.SessionService
+	if redisClient != nil {
+		sessionService = services.NewSessionService(redisClient, svc, cfg.Redis.SessionTTL)
+	}
+	sessionHandler := handlers.NewSessionHandler(redisClient, cfg.Redis.SessionTTL, events, sessionService)
+
+	// Accounts (need Redis; access tokens are verified without it)
+	jwtSecret := []byte(cfg.Auth.JWTSecret)
+	if len(jwtSecret) == 0 {
+		jwtSecret = make([]byte, 32)
+		_, _ = rand.Read(jwtSecret)
+		logger.Warn("JWT_SECRET not set, using a random key; tokens will not survive a restart")
+	}
+	tokens := auth.NewTokens(jwtSecret, cfg.Auth.Issuer, cfg.Auth.AccessTokenTTL, cfg.Auth.RefreshTokenTTL)
+	var authSvc *services.AuthService
+	if redisClient != nil {
+		authSvc = services.NewAuthService(redisClient, tokens, cfg.Auth.AdminUserIDs)
+	}
+	authHandler := handlers.NewAuthHandler(authSvc)
+
+	// Developer API keys (X-API-Key), managed by admins
+	var keySvc *apikeys.Service
+	if redisClient != nil {
+		keySvc = apikeys.New(redisClient, redisClient)
 	}
-	sessionHandler := handlers.NewSessionHandler(redisClient, cfg.Redis.SessionTTL)
+	apiKeyHandler := handlers.NewAPIKeyHandler(keySvc)
+
+	// Plan entitlements and usage quotas (counters in Redis)
+	var counters entitlements.CounterStore
+	if redisClient != nil {
+		counters = redisClient
+	} else {
+		logger.Warn("Redis unavailable, usage quotas are counted per replica and reset on restart")
+	}
+	ent := entitlements.New(redisClient, counters)
+	entitlementsHandler := handlers.NewEntitlementsHandler(ent)
+	puzzleHandler := handlers.NewPuzzleHandler(svc, ent)
+	stormHandler := handlers.NewStormHandler(svc, redisClient, events, ent)
+
+	// Billing webhooks keep plans in sync with the payment provider
+	var billingSvc *billing.Service
+	if redisClient != nil && cfg.Billing.WebhookSecret != "" {
+		prices := make(map[string]entitlements.Plan)
+		for _, id := range cfg.Billing.ProPrices {
+			prices[id] = entitlements.PlanPro
+		}
+		for _, id := range cfg.Billing.ElitePrices {
+			prices[id] = entitlements.PlanElite
+		}
+		billingSvc = billing.NewService(redisClient, billing.Config{
+			WebhookSecret: cfg.Billing.WebhookSecret,
+			Tolerance:     cfg.Billing.SignatureTolerance,
+			GracePeriod:   cfg.Billing.GracePeriod,
+			Prices:        prices,
+		})
+	} else if cfg.Billing.WebhookSecret == "" {
+		logger.Warn("STRIPE_WEBHOOK_SECRET not set, billing webhooks disabled")
+	}
+	billingHandler := handlers.NewBillingHandler(billingSvc)
+
+	// Weekly challenge (needs Redis to share the puzzle set and scores)
+	var challengeSvc *services.ChallengeService
+	if redisClient != nil {
+		challengeSvc = services.NewChallengeService(dataset, redisClient, cfg.Challenge.Retention)
+		go challengeSvc.RunScheduler(ctx, cfg.Challenge.ScheduleInterval)
+	}
+	challengeHandler := handlers.NewChallengeHandler(challengeSvc)
 
 	e := echo.New()
 	e.HideBanner = true
-	e.Logger.SetLevel(log.INFO)
+	e.HidePort = true
+	e.IPExtractor = ipExtractor(cfg.Server.TrustedProxies)
+	// Echo's own messages go through the structured logger.
+	e.Logger.SetHeader("")
+	e.Logger.SetOutput(logging.Writer(logger, slog.LevelError))
 	e.Use(middleware.Recover())
 	e.Use(middleware.CORS())
+	e.Use(custmw.RequestID())
+	e.Use(custmw.Tracing())
 	e.Use(custmw.RequestLogger())
+	e.Use(custmw.Metrics())
 
 	e.GET("/", handlers.Root)
 	e.GET("/health", handlers.Health)
+	e.GET("/livez", healthHandler.Livez)
+	e.GET("/readyz", healthHandler.Readyz)
 	e.GET("/api/v1/health", handlers.Health)
 	e.GET("/swagger/*", echo.WrapHandler(httpSwagger.WrapHandler))
-	puzzleHandler.Register(e.Group("/api/v1"))
-	sessionHandler.Register(e.Group("/api/v1"))
+	e.GET("/metrics", echo.WrapHandler(metrics.Handler()))
+
+	api := e.Group("/api/v1", custmw.Authenticate(tokens), custmw.APIKey(keySvc), custmw.Viewer(), custmw.AIUsageUser())
+	if cfg.RateLimit.Enabled {
+		// Shared buckets in Redis; each replica falls back to its own while
+		// Redis is unreachable.
+		var buckets ratelimit.BucketStore
+		if redisClient != nil {
+			buckets = redisClient
+		}
+		api.Use(custmw.RateLimit(ratelimit.New(cfg.RateLimit.Budgets, buckets)))
+	}
+	authHandler.Register(api)
+	apiKeyHandler.Register(api)
+	entitlementsHandler.Register(api)
+	billingHandler.Register(api)
+	puzzleHandler.Register(api)
+	sessionHandler.Register(api)
+	challengeHandler.Register(api)
+	stormHandler.Register(api)
+	webhookHandler.Register(api)
+	sourceHandler.Register(api)
+	usageHandler.Register(api)
 
 	return e
 }
+
+// ipExtractor returns how the client IP is read, which anonymous rate limits
+// and viewers are keyed by. X-Forwarded-For is only believed when it was set
+// by one of the trusted proxies; otherwise any caller could send a new
+// address with every request.
+func ipExtractor(trusted []netip.Prefix) echo.IPExtractor {
+	if len(trusted) == 0 {
+		return echo.ExtractIPDirect()
+	}
+	opts := []echo.TrustOption{
+		echo.TrustLoopback(false),
+		echo.TrustLinkLocal(false),
+		echo.TrustPrivateNet(false),
+	}
+	for _, p := range trusted {
+		opts = append(opts, echo.TrustIPRange(&net.IPNet{
+			IP:   p.Addr().AsSlice(),
+			Mask: net.CIDRMask(p.Bits(), p.Addr().BitLen()),
+		}))
+	}
+	return echo.ExtractIPFromXFFHeader(opts...)
+}
+
+// upstreamTransport builds the transport of the named upstream: one span per
+// call, retries and a circuit breaker around it, and metrics for every
+// attempt. The returned func reports the circuit state for /readyz.
+func upstreamTransport(cfg config.UpstreamConfig, upstream string) (http.RoundTripper, func() string) {
+	retries := cfg.MaxRetries
+	if retries == 0 {
+		retries = -1 // zero means the default in resilient.Config
+	}
+	rt := resilient.New(upstream, resilient.Config{
+		MaxRetries:       retries,
+		BaseDelay:        cfg.RetryBaseDelay,
+		MaxDelay:         cfg.RetryMaxDelay,
+		FailureThreshold: cfg.BreakerFailures,
+		OpenTimeout:      cfg.BreakerOpenTimeout,
+		OnRetry: func(ctx context.Context, attempt int, reason string, delay time.Duration) {
+			metrics.UpstreamRetry(upstream, reason)
+			trace.SpanFromContext(ctx).AddEvent("retry", trace.WithAttributes(
+				attribute.Int("attempt", attempt),
+				attribute.String("reason", reason),
+				attribute.Int64("delay_ms", delay.Milliseconds()),
+			))
+			logger.DebugContext(ctx, "retrying upstream call", "upstream", upstream, "attempt", attempt, "reason", reason, "delay", delay)
+		},
+		OnStateChange: func(from, to resilient.State) {
+			metrics.CircuitTransition(upstream, to)
+			logger.Warn("upstream circuit state changed", "upstream", upstream, "from", from.String(), "to", to.String())
+		},
+	}, metrics.Transport(upstream, nil))
+	metrics.RegisterCircuit(upstream, rt)
+	return tracing.Transport(upstream, rt), func() string { return rt.State().String() }
+}
+
+// newRetriever loads the RAG vector index and the embedder it was built with.
+func newRetriever(cfg config.RAGConfig, up config.UpstreamConfig) (*retrieval.Retriever, error) {
+	var rt http.RoundTripper
+	if cfg.Embeddings.Provider == "api" {
+		rt, _ = upstreamTransport(up, metrics.UpstreamEmbeddings)
+	}
+	emb, err := retrieval.NewEmbedder(cfg.Embeddings, rt)
+	if err != nil {
+		return nil, err
+	}
+	idx, err := retrieval.Load(cfg.IndexPath)
+	if err != nil {
+		return nil, err
+	}
+	return retrieval.NewRetriever(idx, emb)
+}
+
+// newLLM builds the LLM providers in cfg.Providers order behind a failover,
+// each with its own upstream transport. The returned func reports the
+// circuit state of every provider for /readyz.
+func newLLM(cfg config.LLMConfig, up config.UpstreamConfig) (*llm.Failover, func() string) {
+	var (
+		providers []llm.Provider
+		upstreams []string
+		states    []func() string
+	)
+	opts := func(p config.LLMProviderConfig, upstream string) []llm.Option {
+		rt, state := upstreamTransport(up, upstream)
+		upstreams, states = append(upstreams, upstream), append(states, state)
+		return []llm.Option{
+			llm.WithBaseURL(p.BaseURL),
+			llm.WithAPIKey(p.APIKey),
+			llm.WithModel(p.Model),
+			llm.WithTaskModels(p.TaskModels),
+			llm.WithTimeout(p.Timeout),
+			llm.WithTransport(rt),
+		}
+	}
+	names := make(map[string]bool)
+	for _, name := range cfg.Providers {
+		if names[name] {
+			continue
+		}
+		names[name] = true
+
+		var p llm.Provider
+		n := len(states)
+		switch name {
+		case "nvidia":
+			p = llm.NewNVIDIA(opts(cfg.NVIDIA, metrics.UpstreamNVIDIA)...)
+		case "openrouter":
+			p = llm.NewOpenRouter(opts(cfg.OpenRouter, metrics.UpstreamOpenRouter)...)
+		case "local":
+			p = llm.NewOpenAICompatible(name, opts(cfg.Local, metrics.UpstreamLocalLLM)...)
+		case "fake":
+			p = llm.NewFake(nil, cfg.FakeReply)
+		default:
+			logger.Warn("ignoring unknown LLM provider", "provider", name)
+			continue
+		}
+		if !p.Configured() {
+			logger.Info("LLM provider not configured, skipping it", "provider", name)
+			// Its circuit would never trip, so /readyz does not report it.
+			upstreams, states = upstreams[:n], states[:n]
+			continue
+		}
+		providers = append(providers, p)
+	}
+
+	failover := llm.NewFailover(providers...)
+	failover.Prices = cfg.Prices
+	failover.OnFailure = func(ctx context.Context, provider string, err error) {
+		metrics.LLMFailover(provider)
+		logger.WarnContext(ctx, "llm provider failed, trying the next one", "provider", provider, "err", err)
+	}
+	return failover, func() string {
+		if len(states) == 1 {
+			return states[0]()
+		}
+		parts := make([]string, len(states))
+		for i, state := range states {
+			parts[i] = upstreams[i] + "=" + state()
+		}
+		return strings.Join(parts, ",")
+	}
+}
@@ -15,54 +15,84 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/health": {
+        "/admin/keys": {
             "get": {
-                "description": "Health probe endpoint",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Lists every API key, newest first, with today's usage",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "health"
+                    "admin"
                 ],
-                "summary": "Service health",
+                "summary": "List API keys",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.HealthResponse"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.APIKeyInfo"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     }
                 }
-            }
-        },
-        "/puzzle": {
-            "get": {
-                "description": "Returns a random puzzle (Lichess source) filtered by difficulty",
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Creates a developer API key. The key is only returned in this response; the server stores a hash. Scopes: puzzles:read, sessions:write, ai and webhooks (both require ownerId).",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "puzzle"
+                    "admin"
                 ],
-                "summary": "Get puzzle by difficulty",
+                "summary": "Issue API key",
                 "parameters": [
                     {
-                        "enum": [
-                            "easy",
-                            "medium",
-                            "hard"
-                        ],
-                        "type": "string",
-                        "description": "easy|medium|hard",
-                        "name": "difficulty",
-                        "in": "query"
+                        "description": "Key settings",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.CreateAPIKeyRequest"
+                        }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/models.Puzzle"
+                            "$ref": "#/definitions/models.APIKeyCreated"
                         }
                     },
                     "400": {
@@ -71,8 +101,20 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
-                    "502": {
-                        "description": "Bad Gateway",
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
@@ -80,45 +122,112 @@ const docTemplate = `{
                 }
             }
         },
-        "/puzzle/ai": {
-            "post": {
-                "description": "Uses a RAG pipeline: fetches candidate puzzles from the Lichess dataset and asks the NVIDIA model to select the best match for the user's prompt",
-                "consumes": [
-                    "application/json"
+        "/admin/keys/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "puzzle"
+                    "admin"
                 ],
-                "summary": "Generate puzzle from AI (RAG)",
+                "summary": "Get API key",
                 "parameters": [
                     {
-                        "description": "AI puzzle request",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
+                        "type": "string",
+                        "description": "Key ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.AIPuzzleRequest"
+                            "$ref": "#/definitions/models.APIKeyInfo"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
                         }
                     }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Revokes a key immediately. The record is kept for auditing.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Revoke API key",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Key ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Puzzle"
+                            "$ref": "#/definitions/models.APIKeyInfo"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
-                    "502": {
-                        "description": "Bad Gateway",
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
@@ -126,25 +235,36 @@ const docTemplate = `{
                 }
             }
         },
-        "/puzzle/daily": {
+        "/admin/sources": {
             "get": {
-                "description": "Returns Lichess daily puzzle",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Lists the puzzle sources (lichess, store, huggingface), whether each is enabled and configured, and the priority chain of each endpoint",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "puzzle"
+                    "admin"
                 ],
-                "summary": "Get daily puzzle",
+                "summary": "List puzzle sources",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Puzzle"
+                            "$ref": "#/definitions/models.PuzzleSources"
                         }
                     },
-                    "502": {
-                        "description": "Bad Gateway",
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
@@ -152,34 +272,52 @@ const docTemplate = `{
                 }
             }
         },
-        "/puzzle/dataset": {
-            "get": {
-                "description": "Returns one random puzzle from Hugging Face Lichess dataset",
+        "/admin/sources/{name}": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Switches a source on or off for every endpoint chain. Disabled sources are skipped and the next source of the chain answers. With Redis the switch applies to every replica.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "puzzle"
+                    "admin"
                 ],
-                "summary": "Get puzzle from dataset",
+                "summary": "Enable or disable a puzzle source",
                 "parameters": [
                     {
                         "enum": [
-                            "easy",
-                            "medium",
-                            "hard"
+                            "lichess",
+                            "store",
+                            "huggingface"
                         ],
                         "type": "string",
-                        "description": "easy|medium|hard",
-                        "name": "difficulty",
-                        "in": "query"
+                        "description": "Source",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New state",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UpdateSourceRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Puzzle"
+                            "$ref": "#/definitions/models.SourceStatus"
                         }
                     },
                     "400": {
@@ -188,8 +326,20 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
-                    "502": {
-                        "description": "Bad Gateway",
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
@@ -197,30 +347,42 @@ const docTemplate = `{
                 }
             }
         },
-        "/puzzle/{id}": {
+        "/admin/usage": {
             "get": {
-                "description": "Returns a puzzle by Lichess puzzle identifier",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Reports everyone's AI calls, tokens and cost per UTC day, newest first, with the cost of each model and the users who cost the most. Costs come from the LLM_PRICES table.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "puzzle"
+                    "admin"
                 ],
-                "summary": "Get puzzle by ID",
+                "summary": "AI usage report",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Puzzle ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "type": "integer",
+                        "default": 7,
+                        "description": "Days to report, today included (1-90)",
+                        "name": "days",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Top users listed per day (1-100)",
+                        "name": "top",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Puzzle"
+                            "$ref": "#/definitions/models.AIUsageReport"
                         }
                     },
                     "400": {
@@ -229,113 +391,2807 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
-                    "502": {
-                        "description": "Bad Gateway",
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "handlers.HealthResponse": {
-            "type": "object",
-            "properties": {
-                "service": {
-                    "type": "string"
-                },
-                "status": {
-                    "type": "string"
-                },
-                "version": {
-                    "type": "string"
+        },
+        "/auth/login": {
+            "post": {
+                "description": "Verifies email and password and returns an access/refresh token pair",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Log in",
+                "parameters": [
+                    {
+                        "description": "Credentials",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.LoginRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.AuthResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
                 }
             }
         },
-        "models.AIPuzzleRequest": {
-            "type": "object",
-            "properties": {
-                "difficulty": {
-                    "$ref": "#/definitions/models.DifficultyLevel"
-                },
+        "/auth/logout": {
+            "post": {
+                "description": "Revokes a refresh token",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Log out",
+                "parameters": [
+                    {
+                        "description": "Refresh token",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.RefreshRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/refresh": {
+            "post": {
+                "description": "Exchanges a refresh token for a new token pair. Each refresh token can be used once.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Refresh tokens",
+                "parameters": [
+                    {
+                        "description": "Refresh token",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.RefreshRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.AuthResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/register": {
+            "post": {
+                "description": "Creates an account on the free plan and returns an access/refresh token pair",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Register",
+                "parameters": [
+                    {
+                        "description": "Credentials",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.RegisterRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.AuthResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/billing/webhook": {
+            "post": {
+                "description": "Receives Stripe-compatible subscription events (checkout completed, subscription updated/deleted, invoice payment failed). The raw body must be signed in the Stripe-Signature header.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "billing"
+                ],
+                "summary": "Billing webhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "t=\u003cunix\u003e,v1=\u003chex HMAC-SHA256 of t.payload\u003e",
+                        "name": "Stripe-Signature",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.WebhookAck"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/challenge/weekly": {
+            "get": {
+                "description": "Returns this week's themed challenge: 10 puzzles of one theme spread across rating bands",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "challenge"
+                ],
+                "summary": "Current weekly challenge",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.WeeklyChallenge"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/challenge/weekly/attempts": {
+            "post": {
+                "description": "Records a solved or failed attempt at one challenge puzzle. Only the first attempt at each puzzle is scored, and a solve needs the player's moves, which must be the solution. Signed-in users are scored under their account; anonymous player IDs are kept apart from account IDs.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "challenge"
+                ],
+                "summary": "Submit a weekly challenge attempt",
+                "parameters": [
+                    {
+                        "description": "Attempt",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ChallengeAttemptRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ChallengeEntry"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/challenge/weekly/progress": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "challenge"
+                ],
+                "summary": "Player progress in the weekly challenge",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Player identifier (ignored when signed in)",
+                        "name": "playerId",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ChallengeEntry"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/challenge/weekly/{id}": {
+            "get": {
+                "description": "Returns the challenge published for an ISO week, e.g. 2026-W42",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "challenge"
+                ],
+                "summary": "Weekly challenge by week",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ISO week",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.WeeklyChallenge"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/challenge/weekly/{id}/results": {
+            "get": {
+                "description": "Returns the results table of a challenge once its week has closed",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "challenge"
+                ],
+                "summary": "Weekly challenge results",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ISO week",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ChallengeResults"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/health": {
+            "get": {
+                "description": "Health probe endpoint",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Service health",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.HealthResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/livez": {
+            "get": {
+                "description": "Reports that the process is up, with its build version and commit",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Liveness probe",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.LivenessResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/me": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Current user",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.UserProfile"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/me/entitlements": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Lists the features of the caller's plan with their quotas and usage in the current period",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Plan entitlements",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.EntitlementsResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/me/usage": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Lists the caller's AI calls, tokens and cost per UTC day, newest first. aiPaused is set while the service's daily AI spend limit is reached; AI puzzles then use the prompt's keywords and explanations are unavailable until resumesAt.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "AI usage",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "default": 7,
+                        "description": "Days to list, today included (1-90)",
+                        "name": "days",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.AIUsageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/puzzle": {
+            "get": {
+                "description": "Returns a random puzzle filtered by difficulty from the first source of the random chain that answers (default lichess, store, huggingface). servedBy and the X-Puzzle-Source header name that source.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "puzzle"
+                ],
+                "summary": "Get puzzle by difficulty",
+                "parameters": [
+                    {
+                        "enum": [
+                            "easy",
+                            "medium",
+                            "hard"
+                        ],
+                        "type": "string",
+                        "description": "easy|medium|hard",
+                        "name": "difficulty",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Puzzle"
+                        },
+                        "headers": {
+                            "X-Puzzle-Source": {
+                                "type": "string",
+                                "description": "Source that served the puzzle"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/puzzle/ai": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Asks the first healthy LLM provider (LLM_PROVIDERS order) to turn the prompt into a validated filter (rating range, required and excluded themes, opening, player color, maximum solution length), then runs it against the vector index or the Lichess dataset. The puzzle carries the filter; sending it back as filter, edited or not, reruns the query without the model. When nothing matches, the 404 response carries the filter.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "puzzle"
+                ],
+                "summary": "Generate puzzle from AI (RAG)",
+                "parameters": [
+                    {
+                        "description": "AI puzzle request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.AIPuzzleRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Puzzle"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "402": {
+                        "description": "Payment Required",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/puzzle/ai/stream": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Runs the same pipeline as POST /puzzle/ai and streams its progress as Server-Sent Events: thinking, token (model output, when the provider streams), filter, candidates, selected and validated, each with a models.AIProgressEvent. The stream ends with a puzzle event carrying the models.Puzzle, or an error event carrying a models.ErrorResponse. Closing the connection cancels the pipeline. Errors raised before the first event are answered as plain JSON.",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "puzzle"
+                ],
+                "summary": "Stream AI puzzle generation (SSE)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "What the puzzle should be about; required without filter",
+                        "name": "prompt",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "easy",
+                            "medium",
+                            "hard"
+                        ],
+                        "type": "string",
+                        "description": "easy|medium|hard",
+                        "name": "difficulty",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "models.PuzzleFilter as JSON, used instead of asking the model",
+                        "name": "filter",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.AIProgressEvent"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "402": {
+                        "description": "Payment Required",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/puzzle/daily": {
+            "get": {
+                "description": "Returns the Lichess daily puzzle, or the last one stored while Lichess is down",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "puzzle"
+                ],
+                "summary": "Get daily puzzle",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Puzzle"
+                        },
+                        "headers": {
+                            "X-Puzzle-Source": {
+                                "type": "string",
+                                "description": "Source that served the puzzle"
+                            }
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/puzzle/dataset": {
+            "get": {
+                "description": "Returns one random puzzle from Hugging Face Lichess dataset, or from the puzzle store while the dataset is down",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "puzzle"
+                ],
+                "summary": "Get puzzle from dataset",
+                "parameters": [
+                    {
+                        "enum": [
+                            "easy",
+                            "medium",
+                            "hard"
+                        ],
+                        "type": "string",
+                        "description": "easy|medium|hard",
+                        "name": "difficulty",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Puzzle"
+                        },
+                        "headers": {
+                            "X-Puzzle-Source": {
+                                "type": "string",
+                                "description": "Source that served the puzzle"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/puzzle/{id}": {
+            "get": {
+                "description": "Returns a puzzle by Lichess puzzle identifier from the puzzle cache, Lichess or the puzzle store",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "puzzle"
+                ],
+                "summary": "Get puzzle by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Puzzle ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Puzzle"
+                        },
+                        "headers": {
+                            "X-Cache": {
+                                "type": "string",
+                                "description": "HIT when served from the puzzle cache, MISS otherwise"
+                            },
+                            "X-Puzzle-Source": {
+                                "type": "string",
+                                "description": "Source that served the puzzle"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/puzzle/{id}/explain": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Explains why the solution works: a short summary and one explanation per solution move. The model gets the position, the solution in SAN, the themes and what each move changes on the board; answers mentioning a move that is illegal in the line are rejected. Explanations are cached per puzzle and language. Cached explanations are served while the daily AI spend limit is reached; others get 503 with Retry-After.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "puzzle"
+                ],
+                "summary": "Explain a puzzle's solution (AI)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Puzzle ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Explanation language",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/models.ExplainRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.PuzzleExplanation"
+                        },
+                        "headers": {
+                            "X-Cache": {
+                                "type": "string",
+                                "description": "HIT when served from the explanation cache, MISS otherwise"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "402": {
+                        "description": "Payment Required",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/puzzle/{id}/pgn": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the puzzle as a PGN file: the position in the SetUp and FEN tags, then the opponent's setup move and the solution. Each export uses one unit of the exports quota; failed exports give it back.",
+                "produces": [
+                    "application/x-chess-pgn"
+                ],
+                "tags": [
+                    "puzzle"
+                ],
+                "summary": "Export a puzzle as PGN",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Puzzle ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "PGN",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "402": {
+                        "description": "Payment Required",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/readyz": {
+            "get": {
+                "description": "Checks Redis, Lichess, the HuggingFace dataset and the AI provider (results cached briefly). Each dependency reports whether the service degrades gracefully without it.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Readiness probe",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ReadinessResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ReadinessResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/storm/runs": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Reports the result of a run started with POST /storm/start and publishes the storm.finished webhook event. Each run is reported once",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "storm"
+                ],
+                "summary": "Report storm run",
+                "parameters": [
+                    {
+                        "description": "Run result",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.StormRunReport"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/models.StormRun"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/storm/start": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Starts a Puzzle Storm run and returns its puzzles, easiest first. Each run takes one unit of the storm quota; report the result with POST /storm/runs before expiresAt",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "storm"
+                ],
+                "summary": "Start storm run",
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.StormStart"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "402": {
+                        "description": "Payment Required",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "List webhooks",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.WebhookEndpointInfo"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Registers a URL for outbound events: session.solved, session.failed, puzzle.daily_published, storm.finished. Deliveries are signed in X-Webhook-Signature (t=\u003cunix\u003e,v1=\u003chex HMAC-SHA256 of t.body\u003e) with the returned secret.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Register webhook",
+                "parameters": [
+                    {
+                        "description": "Endpoint",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.CreateWebhookRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.WebhookEndpointCreated"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Delete webhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Endpoint ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/{id}/deliveries": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Lists the latest deliveries to an endpoint, newest first, with their payload and outcome",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Webhook delivery log",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Endpoint ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.WebhookDeliveryInfo"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/{id}/deliveries/{deliveryId}/replay": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Sends a logged delivery again with the same payload and event ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Replay webhook delivery",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Endpoint ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Delivery ID",
+                        "name": "deliveryId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/models.WebhookDeliveryInfo"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "buildinfo.Info": {
+            "type": "object",
+            "properties": {
+                "buildTime": {
+                    "type": "string"
+                },
+                "commit": {
+                    "type": "string"
+                },
+                "goVersion": {
+                    "type": "string"
+                },
+                "modified": {
+                    "description": "built from a dirty tree",
+                    "type": "boolean"
+                },
+                "version": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.HealthResponse": {
+            "type": "object",
+            "properties": {
+                "commit": {
+                    "type": "string"
+                },
+                "service": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.LivenessResponse": {
+            "type": "object",
+            "properties": {
+                "build": {
+                    "$ref": "#/definitions/buildinfo.Info"
+                },
+                "service": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "uptimeSeconds": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.ReadinessResponse": {
+            "type": "object",
+            "properties": {
+                "build": {
+                    "$ref": "#/definitions/buildinfo.Info"
+                },
+                "dependencies": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/health.CheckResult"
+                    }
+                },
+                "service": {
+                    "type": "string"
+                },
+                "status": {
+                    "description": "ok, degraded or unavailable",
+                    "type": "string",
+                    "example": "degraded"
+                }
+            }
+        },
+        "health.CheckResult": {
+            "type": "object",
+            "properties": {
+                "checkedAt": {
+                    "type": "string"
+                },
+                "circuit": {
+                    "description": "closed, half-open or open",
+                    "type": "string",
+                    "example": "closed"
+                },
+                "degradesGracefully": {
+                    "type": "boolean"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "impact": {
+                    "type": "string"
+                },
+                "latencyMs": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "status": {
+                    "$ref": "#/definitions/health.Status"
+                }
+            }
+        },
+        "health.Status": {
+            "type": "string",
+            "enum": [
+                "up",
+                "down",
+                "disabled"
+            ],
+            "x-enum-comments": {
+                "StatusDisabled": "not configured, so never probed"
+            },
+            "x-enum-descriptions": [
+                "",
+                "",
+                "not configured, so never probed"
+            ],
+            "x-enum-varnames": [
+                "StatusUp",
+                "StatusDown",
+                "StatusDisabled"
+            ]
+        },
+        "models.AIProgressEvent": {
+            "type": "object",
+            "properties": {
+                "attempt": {
+                    "description": "1 for the first model call",
+                    "type": "integer"
+                },
+                "candidates": {
+                    "type": "integer"
+                },
+                "elapsedMs": {
+                    "type": "integer"
+                },
+                "fallback": {
+                    "type": "boolean"
+                },
+                "filter": {
+                    "description": "Filter is the query of the filter stage. Fallback marks a filter\ntaken from the prompt's keywords because the model gave no valid one.\nPromptVersion is the prompt template the model was given.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.PuzzleFilter"
+                        }
+                    ]
+                },
+                "index": {
+                    "description": "selected candidate",
+                    "type": "integer"
+                },
+                "model": {
+                    "type": "string"
+                },
+                "promptVersion": {
+                    "type": "string"
+                },
+                "provider": {
+                    "type": "string"
+                },
+                "puzzleId": {
+                    "type": "string"
+                },
+                "retriever": {
+                    "description": "index (vector search) or dataset (random batches)",
+                    "type": "string"
+                },
+                "stage": {
+                    "type": "string"
+                },
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.AIPuzzleRequest": {
+            "type": "object",
+            "properties": {
+                "difficulty": {
+                    "$ref": "#/definitions/models.DifficultyLevel"
+                },
+                "filter": {
+                    "description": "Filter, when set, is used as is instead of asking the model to derive\none from the prompt. The prompt is then optional.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.PuzzleFilter"
+                        }
+                    ]
+                },
                 "prompt": {
                     "type": "string"
                 }
             }
         },
-        "models.DifficultyLevel": {
-            "type": "string",
-            "enum": [
-                "easy",
-                "medium",
-                "hard"
-            ],
-            "x-enum-varnames": [
-                "DifficultyEasy",
-                "DifficultyMedium",
-                "DifficultyHard"
-            ]
-        },
-        "models.ErrorResponse": {
+        "models.AIUsage": {
+            "type": "object",
+            "properties": {
+                "calls": {
+                    "type": "integer"
+                },
+                "completionTokens": {
+                    "type": "integer"
+                },
+                "costUsd": {
+                    "type": "number"
+                },
+                "promptTokens": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.AIUsageDay": {
+            "type": "object",
+            "properties": {
+                "calls": {
+                    "type": "integer"
+                },
+                "completionTokens": {
+                    "type": "integer"
+                },
+                "costUsd": {
+                    "type": "number"
+                },
+                "date": {
+                    "type": "string",
+                    "example": "2026-10-18"
+                },
+                "promptTokens": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.AIUsageReport": {
+            "type": "object",
+            "properties": {
+                "aiPaused": {
+                    "type": "boolean"
+                },
+                "dailySpendLimitUsd": {
+                    "description": "DailySpendLimitUSD is the spend after which AI features are paused\nuntil the next UTC day; 0 means no limit.",
+                    "type": "number"
+                },
+                "days": {
+                    "description": "newest first",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.AIUsageReportDay"
+                    }
+                },
+                "resumesAt": {
+                    "type": "string"
+                },
+                "total": {
+                    "$ref": "#/definitions/models.AIUsage"
+                }
+            }
+        },
+        "models.AIUsageReportDay": {
+            "type": "object",
+            "properties": {
+                "calls": {
+                    "type": "integer"
+                },
+                "completionTokens": {
+                    "type": "integer"
+                },
+                "costUsd": {
+                    "type": "number"
+                },
+                "date": {
+                    "type": "string",
+                    "example": "2026-10-18"
+                },
+                "models": {
+                    "description": "most expensive first",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ModelAIUsage"
+                    }
+                },
+                "promptTokens": {
+                    "type": "integer"
+                },
+                "topUsers": {
+                    "description": "most expensive first",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.UserAIUsage"
+                    }
+                }
+            }
+        },
+        "models.AIUsageResponse": {
+            "type": "object",
+            "properties": {
+                "aiPaused": {
+                    "description": "AIPaused is set while the service's daily AI spend limit is reached.\nAI features resume at ResumesAt.",
+                    "type": "boolean"
+                },
+                "days": {
+                    "description": "newest first",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.AIUsageDay"
+                    }
+                },
+                "resumesAt": {
+                    "type": "string"
+                },
+                "total": {
+                    "$ref": "#/definitions/models.AIUsage"
+                }
+            }
+        },
+        "models.APIKeyCreated": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "dailyQuota": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "key": {
+                    "description": "send as the X-API-Key header; store it now, it cannot be shown again",
+                    "type": "string"
+                },
+                "lastUsedAt": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "ownerId": {
+                    "type": "string"
+                },
+                "prefix": {
+                    "description": "identifies the key without revealing it",
+                    "type": "string"
+                },
+                "revokedAt": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "usedToday": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.APIKeyInfo": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "dailyQuota": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "lastUsedAt": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "ownerId": {
+                    "type": "string"
+                },
+                "prefix": {
+                    "description": "identifies the key without revealing it",
+                    "type": "string"
+                },
+                "revokedAt": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "usedToday": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.AuthResponse": {
+            "type": "object",
+            "properties": {
+                "tokens": {
+                    "$ref": "#/definitions/models.TokenPair"
+                },
+                "user": {
+                    "$ref": "#/definitions/models.UserProfile"
+                }
+            }
+        },
+        "models.ChallengeAttempt": {
+            "type": "object",
+            "properties": {
+                "attemptedAt": {
+                    "type": "string"
+                },
+                "points": {
+                    "type": "integer"
+                },
+                "puzzleId": {
+                    "type": "string"
+                },
+                "solved": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "models.ChallengeAttemptRequest": {
+            "type": "object",
+            "properties": {
+                "moves": {
+                    "description": "the player's moves in UCI; required when solved",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "name": {
+                    "type": "string"
+                },
+                "playerId": {
+                    "type": "string"
+                },
+                "puzzleId": {
+                    "type": "string"
+                },
+                "solved": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "models.ChallengeEntry": {
+            "type": "object",
+            "properties": {
+                "attempted": {
+                    "type": "integer"
+                },
+                "attempts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ChallengeAttempt"
+                    }
+                },
+                "completed": {
+                    "type": "boolean"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "playerId": {
+                    "type": "string"
+                },
+                "score": {
+                    "type": "integer"
+                },
+                "solved": {
+                    "type": "integer"
+                },
+                "updatedAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ChallengePuzzle": {
+            "type": "object",
+            "properties": {
+                "band": {
+                    "type": "string"
+                },
+                "points": {
+                    "type": "integer"
+                },
+                "puzzle": {
+                    "$ref": "#/definitions/models.Puzzle"
+                }
+            }
+        },
+        "models.ChallengeResultRow": {
+            "type": "object",
+            "properties": {
+                "attempted": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "player": {
+                    "description": "short hash of the player ID",
+                    "type": "string"
+                },
+                "rank": {
+                    "type": "integer"
+                },
+                "score": {
+                    "type": "integer"
+                },
+                "solved": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.ChallengeResults": {
+            "type": "object",
+            "properties": {
+                "challengeId": {
+                    "type": "string"
+                },
+                "closedAt": {
+                    "type": "string"
+                },
+                "rows": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ChallengeResultRow"
+                    }
+                },
+                "theme": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.CreateAPIKeyRequest": {
+            "type": "object",
+            "properties": {
+                "dailyQuota": {
+                    "description": "requests per UTC day, 0 = unlimited",
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "ownerId": {
+                    "description": "account the key acts for; required for the \"ai\" scope",
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.CreateWebhookRequest": {
+            "type": "object",
+            "properties": {
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.DifficultyLevel": {
+            "type": "string",
+            "enum": [
+                "easy",
+                "medium",
+                "hard"
+            ],
+            "x-enum-varnames": [
+                "DifficultyEasy",
+                "DifficultyMedium",
+                "DifficultyHard"
+            ]
+        },
+        "models.EntitlementsResponse": {
+            "type": "object",
+            "properties": {
+                "features": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.FeatureEntitlement"
+                    }
+                },
+                "plan": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ErrorResponse": {
+            "type": "object",
+            "properties": {
+                "details": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "filter": {
+                    "description": "AI query that matched no puzzle, to refine",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.PuzzleFilter"
+                        }
+                    ]
+                }
+            }
+        },
+        "models.ExplainRequest": {
+            "type": "object",
+            "properties": {
+                "language": {
+                    "description": "Language of the explanation as an ISO 639-1 code, optionally with a\nregion (fr, pt-BR). Defaults to en.",
+                    "type": "string",
+                    "example": "en"
+                }
+            }
+        },
+        "models.FeatureEntitlement": {
+            "type": "object",
+            "properties": {
+                "enabled": {
+                    "type": "boolean"
+                },
+                "feature": {
+                    "type": "string"
+                },
+                "limit": {
+                    "type": "integer"
+                },
+                "period": {
+                    "type": "string"
+                },
+                "remaining": {
+                    "type": "integer"
+                },
+                "resetsAt": {
+                    "type": "string"
+                },
+                "unlimited": {
+                    "type": "boolean"
+                },
+                "used": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.LoginRequest": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ModelAIUsage": {
+            "type": "object",
+            "properties": {
+                "calls": {
+                    "type": "integer"
+                },
+                "completionTokens": {
+                    "type": "integer"
+                },
+                "costUsd": {
+                    "type": "number"
+                },
+                "model": {
+                    "type": "string",
+                    "example": "meta/llama-3.3-70b-instruct"
+                },
+                "promptTokens": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.MoveExplanation": {
+            "type": "object",
+            "properties": {
+                "explanation": {
+                    "type": "string"
+                },
+                "ply": {
+                    "description": "1 for the player's first move",
+                    "type": "integer"
+                },
+                "san": {
+                    "type": "string"
+                },
+                "side": {
+                    "description": "player or opponent",
+                    "type": "string"
+                },
+                "uci": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Puzzle": {
+            "type": "object",
+            "properties": {
+                "difficulty": {
+                    "$ref": "#/definitions/models.DifficultyLevel"
+                },
+                "fen": {
+                    "type": "string"
+                },
+                "filter": {
+                    "description": "query the AI pipeline ran to find the puzzle",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.PuzzleFilter"
+                        }
+                    ]
+                },
+                "gameUrl": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "initialPly": {
+                    "type": "integer"
+                },
+                "moves": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "nbPlays": {
+                    "type": "integer"
+                },
+                "openingTags": {
+                    "description": "Lichess opening names, such as Sicilian_Defense_Najdorf_Variation",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "popularity": {
+                    "type": "integer"
+                },
+                "promptVersion": {
+                    "description": "prompt template the model derived the filter with, such as filter/v1",
+                    "type": "string"
+                },
+                "rating": {
+                    "type": "integer"
+                },
+                "ratingDeviation": {
+                    "type": "integer"
+                },
+                "servedBy": {
+                    "description": "chain source that answered: lichess, store or huggingface",
+                    "type": "string"
+                },
+                "source": {
+                    "type": "string"
+                },
+                "themes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.PuzzleExplanation": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "language": {
+                    "type": "string"
+                },
+                "model": {
+                    "type": "string"
+                },
+                "moves": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.MoveExplanation"
+                    }
+                },
+                "provider": {
+                    "type": "string"
+                },
+                "puzzleId": {
+                    "type": "string"
+                },
+                "summary": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.PuzzleFilter": {
             "type": "object",
             "properties": {
-                "details": {
+                "color": {
+                    "description": "side the player solves for: white or black",
                     "type": "string"
                 },
-                "error": {
+                "excludeThemes": {
+                    "description": "theme keys the puzzle must not have",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "maxMoves": {
+                    "description": "most player moves in the solution",
+                    "type": "integer"
+                },
+                "maxRating": {
+                    "type": "integer"
+                },
+                "minRating": {
+                    "type": "integer"
+                },
+                "opening": {
+                    "description": "opening family or variation, such as \"Sicilian Defense\"",
                     "type": "string"
+                },
+                "themes": {
+                    "description": "theme keys the puzzle must all have",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
                 }
             }
         },
-        "models.Puzzle": {
+        "models.PuzzleSources": {
             "type": "object",
             "properties": {
-                "difficulty": {
-                    "$ref": "#/definitions/models.DifficultyLevel"
+                "chains": {
+                    "description": "endpoint (random, id, daily, dataset) → sources in order",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        }
+                    }
                 },
-                "fen": {
+                "sources": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.SourceStatus"
+                    }
+                }
+            }
+        },
+        "models.RefreshRequest": {
+            "type": "object",
+            "properties": {
+                "refreshToken": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.RegisterRequest": {
+            "type": "object",
+            "properties": {
+                "email": {
                     "type": "string"
                 },
-                "gameUrl": {
+                "password": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.SourceStatus": {
+            "type": "object",
+            "properties": {
+                "available": {
+                    "description": "configured in this deployment",
+                    "type": "boolean"
+                },
+                "enabled": {
+                    "description": "switched on by an admin",
+                    "type": "boolean"
+                },
+                "endpoints": {
+                    "description": "chains the source is part of",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "name": {
+                    "type": "string",
+                    "example": "lichess"
+                }
+            }
+        },
+        "models.StormRun": {
+            "type": "object",
+            "properties": {
+                "durationMs": {
+                    "type": "integer"
+                },
+                "failed": {
+                    "type": "integer"
+                },
+                "finishedAt": {
                     "type": "string"
                 },
                 "id": {
                     "type": "string"
                 },
-                "initialPly": {
+                "score": {
                     "type": "integer"
                 },
-                "moves": {
+                "solved": {
+                    "type": "integer"
+                },
+                "userId": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.StormRunReport": {
+            "type": "object",
+            "properties": {
+                "durationMs": {
+                    "type": "integer"
+                },
+                "failed": {
+                    "type": "integer"
+                },
+                "runId": {
+                    "description": "from POST /storm/start",
+                    "type": "string"
+                },
+                "score": {
+                    "type": "integer"
+                },
+                "solved": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.StormStart": {
+            "type": "object",
+            "properties": {
+                "expiresAt": {
+                    "description": "the run can no longer be reported after this",
+                    "type": "string"
+                },
+                "puzzles": {
                     "type": "array",
                     "items": {
-                        "type": "string"
+                        "$ref": "#/definitions/models.Puzzle"
                     }
                 },
-                "nbPlays": {
+                "runId": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.TokenPair": {
+            "type": "object",
+            "properties": {
+                "accessToken": {
+                    "type": "string"
+                },
+                "expiresIn": {
+                    "description": "access token lifetime in seconds",
                     "type": "integer"
                 },
-                "popularity": {
+                "refreshToken": {
+                    "type": "string"
+                },
+                "tokenType": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.UpdateSourceRequest": {
+            "type": "object",
+            "properties": {
+                "enabled": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "models.UserAIUsage": {
+            "type": "object",
+            "properties": {
+                "calls": {
+                    "type": "integer"
+                },
+                "completionTokens": {
+                    "type": "integer"
+                },
+                "costUsd": {
+                    "type": "number"
+                },
+                "promptTokens": {
+                    "type": "integer"
+                },
+                "userId": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.UserProfile": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "graceUntil": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "plan": {
+                    "type": "string"
+                },
+                "planStatus": {
+                    "description": "Subscription state, present once the user has been through checkout.",
+                    "type": "string"
+                },
+                "ratedSessions": {
                     "type": "integer"
                 },
                 "rating": {
+                    "description": "Puzzle rating, updated by finished sessions.",
                     "type": "integer"
                 },
-                "ratingDeviation": {
+                "trialEndsAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.WebhookAck": {
+            "type": "object",
+            "properties": {
+                "received": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "models.WebhookDeliveryInfo": {
+            "type": "object",
+            "properties": {
+                "attempts": {
                     "type": "integer"
                 },
-                "source": {
+                "createdAt": {
                     "type": "string"
                 },
-                "themes": {
+                "eventId": {
+                    "type": "string"
+                },
+                "eventType": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "lastAttemptAt": {
+                    "type": "string"
+                },
+                "lastError": {
+                    "type": "string"
+                },
+                "payload": {
+                    "type": "object"
+                },
+                "replayOf": {
+                    "type": "string"
+                },
+                "responseCode": {
+                    "type": "integer"
+                },
+                "status": {
+                    "description": "pending, succeeded, failed",
+                    "type": "string"
+                }
+            }
+        },
+        "models.WebhookEndpointCreated": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "secret": {
+                    "description": "verifies X-Webhook-Signature; store it now, it cannot be shown again",
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.WebhookEndpointInfo": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "events": {
                     "type": "array",
                     "items": {
                         "type": "string"
                     }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.WeeklyChallenge": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "endsAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "description": "ISO week, e.g. \"2026-W42\"",
+                    "type": "string"
+                },
+                "puzzles": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ChallengePuzzle"
+                    }
+                },
+                "startsAt": {
+                    "type": "string"
+                },
+                "theme": {
+                    "type": "string"
+                },
+                "themeName": {
+                    "type": "string"
                 }
             }
         }
+    },
+    "securityDefinitions": {
+        "ApiKeyAuth": {
+            "description": "Developer API key issued by an admin (cpk_...)",
+            "type": "apiKey",
+            "name": "X-API-Key",
+            "in": "header"
+        },
+        "BearerAuth": {
+            "description": "Access token from /auth/login, as \"Bearer \u003ctoken\u003e\"",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
+        }
     }
 }`
 
@@ -346,7 +3202,7 @@ var SwaggerInfo = &swag.Spec{
 	BasePath:         "/api/v1",
 	Schemes:          []string{"https"},
 	Title:            "Puzzle Generator API",
-	Description:      "Chess puzzle generator service (Lichess, AI via NVIDIA Inference, and Hugging Face dataset).",
+	Description:      "Chess puzzle generator service (Lichess, AI via NVIDIA Inference, OpenRouter or a local OpenAI-compatible model, and Hugging Face dataset).",
 	InfoInstanceName: "swagger",
 	SwaggerTemplate:  docTemplate,
 	LeftDelim:        "{{",
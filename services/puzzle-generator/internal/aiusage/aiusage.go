@@ -0,0 +1,266 @@
+// Package aiusage accounts for the tokens and cost of LLM calls per user and
+// per UTC day, and pauses AI features once the day's spend reaches a limit.
+// Usage lives in Redis so every replica shares it and the limit is global;
+// without Redis each replica keeps its own in memory.
+package aiusage
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"math"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"github.com/chess-puzzle-next/puzzle-generator/internal/logging"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/metrics"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/models"
+	"github.com/chess-puzzle-next/puzzle-generator/pkg/llm"
+	"github.com/chess-puzzle-next/puzzle-generator/pkg/redis"
+)
+
+var logger = logging.For("aiusage")
+
+// ErrSpendLimit is returned instead of a completion once the day's AI spend
+// reached the limit.
+var ErrSpendLimit = errors.New("aiusage: daily AI spend limit reached")
+
+// Store keeps usage counters per day.
+type Store interface {
+	RecordAIUsage(ctx context.Context, day, userID, model string, u redis.AIUsage, ttl time.Duration) (int64, error)
+	GetAIUsage(ctx context.Context, userID string, days ...string) ([]redis.AIUsage, error)
+	AIModelUsage(ctx context.Context, day string) (map[string]redis.AIUsage, error)
+	TopAIUsers(ctx context.Context, day string, n int) ([]redis.UserAIUsage, error)
+}
+
+type userKey struct{}
+
+// WithUser returns a copy of ctx whose model calls are accounted to userID.
+func WithUser(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, userKey{}, userID)
+}
+
+// User returns the user stored in ctx, or "".
+func User(ctx context.Context) string {
+	v, _ := ctx.Value(userKey{}).(string)
+	return v
+}
+
+// Tracker records AI usage and enforces the daily spend limit.
+type Tracker struct {
+	store     Store
+	limit     float64 // USD per UTC day; 0 for none
+	retention time.Duration
+	now       func() time.Time
+
+	pausedDay atomic.Pointer[string] // last day the pause was logged
+}
+
+// New returns a Tracker keeping usage for retention and pausing AI features
+// once a day's spend reaches dailyLimit USD (0 for no limit). store may be
+// nil, in which case usage is kept in process memory only.
+func New(store Store, dailyLimit float64, retention time.Duration) *Tracker {
+	if store == nil {
+		store = newMemoryStore(retention)
+	}
+	return &Tracker{store: store, limit: dailyLimit, retention: retention, now: time.Now}
+}
+
+// Meter is a provider whose completions are accounted by a Tracker. Calls
+// fail with ErrSpendLimit while the limit is reached.
+type Meter struct {
+	llm.Provider
+	t *Tracker
+}
+
+// Meter returns p with its completions accounted by t.
+func (t *Tracker) Meter(p llm.Provider) *Meter {
+	return &Meter{Provider: p, t: t}
+}
+
+// Chat checks the spend limit, then asks the provider and records the
+// usage of the completion.
+func (m *Meter) Chat(ctx context.Context, r llm.Request) (*llm.Response, error) {
+	if err := m.t.Allow(ctx); err != nil {
+		return nil, err
+	}
+	resp, err := m.Provider.Chat(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	m.t.Record(ctx, resp)
+	return resp, nil
+}
+
+// ChatStream is Chat for streamed completions.
+func (m *Meter) ChatStream(ctx context.Context, r llm.Request, onToken func(string)) (*llm.Response, error) {
+	if err := m.t.Allow(ctx); err != nil {
+		return nil, err
+	}
+	resp, err := llm.ChatStream(ctx, m.Provider, r, onToken)
+	if err != nil {
+		return nil, err
+	}
+	m.t.Record(ctx, resp)
+	return resp, nil
+}
+
+// Allow returns ErrSpendLimit when the day's spend reached the limit. It
+// allows calls when the spend cannot be read. Calls in flight when the limit
+// is reached still complete, so the spend can end slightly above it.
+func (t *Tracker) Allow(ctx context.Context) error {
+	if t.limit <= 0 {
+		return nil
+	}
+	paused, err := t.paused(ctx)
+	if err != nil {
+		logger.WarnContext(ctx, "read AI spend, allowing the call", "err", err)
+		return nil
+	}
+	if paused {
+		metrics.AISpendLimited()
+		return ErrSpendLimit
+	}
+	return nil
+}
+
+// Record adds the usage of a completion to its user's, its model's and the
+// day's totals.
+func (t *Tracker) Record(ctx context.Context, resp *llm.Response) {
+	metrics.LLMUsage(resp.Provider, resp.Model, resp.Usage.PromptTokens, resp.Usage.CompletionTokens, resp.Usage.Cost)
+	u := redis.AIUsage{
+		Calls:            1,
+		PromptTokens:     int64(resp.Usage.PromptTokens),
+		CompletionTokens: int64(resp.Usage.CompletionTokens),
+		CostMicros:       int64(math.Round(resp.Usage.Cost * 1e6)),
+	}
+	// The call is paid for even when the client went away meanwhile.
+	ctx = context.WithoutCancel(ctx)
+	day, _ := t.day()
+	if _, err := t.store.RecordAIUsage(ctx, day, User(ctx), resp.Model, u, t.retention); err != nil {
+		logger.WarnContext(ctx, "record AI usage", "user", User(ctx), "model", resp.Model, "err", err)
+	}
+}
+
+// Paused reports whether the daily spend limit is reached and, when it is,
+// when AI features resume.
+func (t *Tracker) Paused(ctx context.Context) (bool, *time.Time) {
+	if t.limit <= 0 {
+		return false, nil
+	}
+	paused, err := t.paused(ctx)
+	if err != nil || !paused {
+		return false, nil
+	}
+	_, resumesAt := t.day()
+	return true, &resumesAt
+}
+
+func (t *Tracker) paused(ctx context.Context) (bool, error) {
+	day, resumesAt := t.day()
+	spent, err := t.store.GetAIUsage(ctx, "", day)
+	if err != nil {
+		return false, err
+	}
+	if usd(spent[0].CostMicros) < t.limit {
+		return false, nil
+	}
+	if last := t.pausedDay.Swap(&day); last == nil || *last != day {
+		logger.WarnContext(ctx, "daily AI spend limit reached, AI features paused", "limit_usd", t.limit, "spent_usd", usd(spent[0].CostMicros), "resumes_at", resumesAt)
+	}
+	return true, nil
+}
+
+// UserUsage returns the AI usage of userID over the last days UTC days,
+// today included.
+func (t *Tracker) UserUsage(ctx context.Context, userID string, days int) (*models.AIUsageResponse, error) {
+	dates := t.days(days)
+	usage, err := t.store.GetAIUsage(ctx, userID, dates...)
+	if err != nil {
+		return nil, err
+	}
+	resp := &models.AIUsageResponse{Days: make([]models.AIUsageDay, len(dates))}
+	var total redis.AIUsage
+	for i, date := range dates {
+		resp.Days[i] = models.AIUsageDay{Date: date, AIUsage: toModel(usage[i])}
+		add(&total, usage[i])
+	}
+	resp.Total = toModel(total)
+	resp.AIPaused, resp.ResumesAt = t.Paused(ctx)
+	return resp, nil
+}
+
+// Report returns everyone's AI usage over the last days UTC days, with the
+// cost of each model and the top users of each day.
+func (t *Tracker) Report(ctx context.Context, days, top int) (*models.AIUsageReport, error) {
+	dates := t.days(days)
+	totals, err := t.store.GetAIUsage(ctx, "", dates...)
+	if err != nil {
+		return nil, err
+	}
+	report := &models.AIUsageReport{Days: make([]models.AIUsageReportDay, len(dates)), DailySpendLimitUSD: t.limit}
+	var total redis.AIUsage
+	for i, date := range dates {
+		d := models.AIUsageReportDay{Date: date, AIUsage: toModel(totals[i]), Models: []models.ModelAIUsage{}, TopUsers: []models.UserAIUsage{}}
+		byModel, err := t.store.AIModelUsage(ctx, date)
+		if err != nil {
+			return nil, err
+		}
+		for model, u := range byModel {
+			d.Models = append(d.Models, models.ModelAIUsage{Model: model, AIUsage: toModel(u)})
+		}
+		slices.SortFunc(d.Models, func(a, b models.ModelAIUsage) int {
+			return cmp.Or(cmp.Compare(b.CostUSD, a.CostUSD), cmp.Compare(b.Calls, a.Calls), cmp.Compare(a.Model, b.Model))
+		})
+		users, err := t.store.TopAIUsers(ctx, date, top)
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range users {
+			d.TopUsers = append(d.TopUsers, models.UserAIUsage{UserID: u.UserID, AIUsage: toModel(u.AIUsage)})
+		}
+		report.Days[i] = d
+		add(&total, totals[i])
+	}
+	report.Total = toModel(total)
+	report.AIPaused, report.ResumesAt = t.Paused(ctx)
+	return report, nil
+}
+
+// day returns the current UTC day and when it ends.
+func (t *Tracker) day() (string, time.Time) {
+	now := t.now().UTC()
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	return start.Format(time.DateOnly), start.AddDate(0, 0, 1)
+}
+
+// days returns the last n UTC days, today first.
+func (t *Tracker) days(n int) []string {
+	today := t.now().UTC()
+	out := make([]string, max(n, 1))
+	for i := range out {
+		out[i] = today.AddDate(0, 0, -i).Format(time.DateOnly)
+	}
+	return out
+}
+
+func usd(micros int64) float64 {
+	return float64(micros) / 1e6
+}
+
+func toModel(u redis.AIUsage) models.AIUsage {
+	return models.AIUsage{
+		Calls:            u.Calls,
+		PromptTokens:     u.PromptTokens,
+		CompletionTokens: u.CompletionTokens,
+		CostUSD:          usd(u.CostMicros),
+	}
+}
+
+func add(total *redis.AIUsage, u redis.AIUsage) {
+	total.Calls += u.Calls
+	total.PromptTokens += u.PromptTokens
+	total.CompletionTokens += u.CompletionTokens
+	total.CostMicros += u.CostMicros
+}
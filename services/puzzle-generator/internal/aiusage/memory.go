@@ -0,0 +1,105 @@
+package aiusage
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/chess-puzzle-next/puzzle-generator/pkg/redis"
+)
+
+// memoryStore is a process-local Store. It forgets on restart and is not
+// shared between replicas, so it is only a stand-in without Redis.
+type memoryStore struct {
+	retention time.Duration
+
+	mu   sync.Mutex
+	days map[string]*memoryDay
+}
+
+type memoryDay struct {
+	total  redis.AIUsage
+	users  map[string]redis.AIUsage
+	models map[string]redis.AIUsage
+}
+
+func newMemoryStore(retention time.Duration) *memoryStore {
+	return &memoryStore{retention: retention, days: make(map[string]*memoryDay)}
+}
+
+func (m *memoryStore) RecordAIUsage(_ context.Context, day, userID, model string, u redis.AIUsage, _ time.Duration) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	d, ok := m.days[day]
+	if !ok {
+		d = &memoryDay{users: make(map[string]redis.AIUsage), models: make(map[string]redis.AIUsage)}
+		m.days[day] = d
+		m.prune()
+	}
+	add(&d.total, u)
+	if userID != "" {
+		total := d.users[userID]
+		add(&total, u)
+		d.users[userID] = total
+	}
+	total := d.models[model]
+	add(&total, u)
+	d.models[model] = total
+	return d.total.CostMicros, nil
+}
+
+// prune drops the days older than the retention.
+func (m *memoryStore) prune() {
+	oldest := time.Now().UTC().Add(-m.retention).Format(time.DateOnly)
+	for day := range m.days {
+		if day < oldest {
+			delete(m.days, day)
+		}
+	}
+}
+
+func (m *memoryStore) GetAIUsage(_ context.Context, userID string, days ...string) ([]redis.AIUsage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]redis.AIUsage, len(days))
+	for i, day := range days {
+		d, ok := m.days[day]
+		switch {
+		case !ok:
+		case userID == "":
+			out[i] = d.total
+		default:
+			out[i] = d.users[userID]
+		}
+	}
+	return out, nil
+}
+
+func (m *memoryStore) AIModelUsage(_ context.Context, day string) (map[string]redis.AIUsage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if d, ok := m.days[day]; ok {
+		return maps.Clone(d.models), nil
+	}
+	return map[string]redis.AIUsage{}, nil
+}
+
+func (m *memoryStore) TopAIUsers(_ context.Context, day string, n int) ([]redis.UserAIUsage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	d, ok := m.days[day]
+	if !ok || n < 1 {
+		return nil, nil
+	}
+	out := make([]redis.UserAIUsage, 0, len(d.users))
+	for id, u := range d.users {
+		out = append(out, redis.UserAIUsage{UserID: id, AIUsage: u})
+	}
+	slices.SortFunc(out, func(a, b redis.UserAIUsage) int {
+		return cmp.Or(cmp.Compare(b.CostMicros, a.CostMicros), cmp.Compare(a.UserID, b.UserID))
+	})
+	return out[:min(n, len(out))], nil
+}
@@ -0,0 +1,50 @@
+// Package apikeys issues and verifies developer API keys for third-party
+// integrations such as club websites and chat bots.
+package apikeys
+
+import "strings"
+
+// Scope is a permission granted to an API key.
+type Scope string
+
+const (
+	ScopePuzzlesRead   Scope = "puzzles:read"   // fetch puzzles and weekly challenges
+	ScopeSessionsWrite Scope = "sessions:write" // create and update puzzle sessions
+	ScopeAI            Scope = "ai"             // AI puzzle generation and explanations, billed to the key owner's plan
+	ScopeWebhooks      Scope = "webhooks"       // manage the key owner's webhook endpoints
+)
+
+// Scopes lists every scope.
+var Scopes = []Scope{ScopePuzzlesRead, ScopeSessionsWrite, ScopeAI, ScopeWebhooks}
+
+// ParseScope returns the scope named s.
+func ParseScope(s string) (Scope, bool) {
+	for _, scope := range Scopes {
+		if string(scope) == s {
+			return scope, true
+		}
+	}
+	return "", false
+}
+
+// ScopeFor returns the scope an API key needs to call a route. path is the
+// matched route pattern (echo.Context.Path). The second result is false for
+// routes that API keys cannot call at all, such as account and admin routes.
+func ScopeFor(method, path string) (Scope, bool) {
+	path = strings.TrimPrefix(path, "/api/v1")
+	switch {
+	case method == "POST" && path == "/puzzle/ai",
+		method == "GET" && path == "/puzzle/ai/stream",
+		method == "POST" && path == "/puzzle/:id/explain":
+		return ScopeAI, true
+	case method == "GET" && strings.HasPrefix(path, "/puzzle"):
+		return ScopePuzzlesRead, true
+	case method == "GET" && strings.HasPrefix(path, "/challenge/"):
+		return ScopePuzzlesRead, true
+	case strings.HasPrefix(path, "/session"):
+		return ScopeSessionsWrite, true
+	case strings.HasPrefix(path, "/webhooks"):
+		return ScopeWebhooks, true
+	}
+	return "", false
+}
@@ -0,0 +1,331 @@
+package apikeys
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/chess-puzzle-next/puzzle-generator/internal/entitlements"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/logging"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/models"
+	"github.com/chess-puzzle-next/puzzle-generator/pkg/redis"
+)
+
+var logger = logging.For("apikeys")
+
+// KeyPrefix starts every API key: "cpk_<id>_<secret>".
+const KeyPrefix = "cpk_"
+
+// Store persists API keys and looks up their owners.
+type Store interface {
+	SaveAPIKey(ctx context.Context, k *redis.APIKey) error
+	GetAPIKey(ctx context.Context, id string) (*redis.APIKey, error)
+	ListAPIKeys(ctx context.Context) ([]*redis.APIKey, error)
+	TouchAPIKey(ctx context.Context, id string, at time.Time) error
+	GetUser(ctx context.Context, userID string) (*redis.User, error)
+}
+
+// CounterStore keeps the per-key daily request counters.
+type CounterStore interface {
+	IncrUsage(ctx context.Context, key string, ttl time.Duration) (int64, error)
+	DecrUsage(ctx context.Context, key string) error
+	GetUsage(ctx context.Context, keys ...string) ([]int64, error)
+}
+
+var (
+	ErrInvalidKey     = errors.New("apikeys: invalid API key")
+	ErrKeyRevoked     = errors.New("apikeys: API key has been revoked")
+	ErrKeyNotFound    = errors.New("apikeys: API key not found")
+	ErrQuotaExceeded  = errors.New("apikeys: daily quota exhausted")
+	ErrInvalidRequest = errors.New("apikeys: invalid request")
+)
+
+// Principal is the caller behind a verified API key.
+type Principal struct {
+	KeyID      string
+	Name       string
+	Scopes     []Scope
+	DailyQuota int
+
+	// Owner account, when the key acts for one.
+	OwnerID    string
+	OwnerEmail string
+	OwnerPlan  entitlements.Plan
+}
+
+// Has reports whether the key was granted scope.
+func (p *Principal) Has(scope Scope) bool {
+	return slices.Contains(p.Scopes, scope)
+}
+
+// Quota is a key's use of its daily quota.
+type Quota struct {
+	Limit    int
+	Used     int
+	ResetsAt time.Time
+}
+
+// Service issues, lists, revokes and verifies API keys.
+type Service struct {
+	store    Store
+	counters CounterStore
+	now      func() time.Time
+}
+
+// New returns an API key Service.
+func New(store Store, counters CounterStore) *Service {
+	return &Service{store: store, counters: counters, now: time.Now}
+}
+
+// Issue creates a key. The plaintext key is only ever part of the returned
+// value; the store keeps a SHA-256 hash of its secret.
+func (s *Service) Issue(ctx context.Context, createdBy string, req models.CreateAPIKeyRequest) (*models.APIKeyCreated, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > 100 {
+		return nil, fmt.Errorf("%w: name must be 1-100 characters", ErrInvalidRequest)
+	}
+	if req.DailyQuota < 0 {
+		return nil, fmt.Errorf("%w: dailyQuota must not be negative", ErrInvalidRequest)
+	}
+	if len(req.Scopes) == 0 {
+		return nil, fmt.Errorf("%w: at least one scope is required", ErrInvalidRequest)
+	}
+
+	var scopes []string
+	for _, raw := range req.Scopes {
+		scope, ok := ParseScope(raw)
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown scope %q", ErrInvalidRequest, raw)
+		}
+		if !slices.Contains(scopes, string(scope)) {
+			scopes = append(scopes, string(scope))
+		}
+	}
+
+	if req.OwnerID != "" {
+		owner, err := s.store.GetUser(ctx, req.OwnerID)
+		if err != nil {
+			return nil, err
+		}
+		if owner == nil {
+			return nil, fmt.Errorf("%w: owner %q does not exist", ErrInvalidRequest, req.OwnerID)
+		}
+	} else {
+		// AI generation is metered against a plan and webhooks belong to an
+		// account, so both need an owner.
+		for _, scope := range []Scope{ScopeAI, ScopeWebhooks} {
+			if slices.Contains(scopes, string(scope)) {
+				return nil, fmt.Errorf("%w: the %q scope requires an ownerId", ErrInvalidRequest, scope)
+			}
+		}
+	}
+
+	id, err := randomString(6, hex.EncodeToString)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := randomString(24, base64.RawURLEncoding.EncodeToString)
+	if err != nil {
+		return nil, err
+	}
+
+	key := &redis.APIKey{
+		ID:         id,
+		Name:       name,
+		OwnerID:    req.OwnerID,
+		SecretHash: hashSecret(secret),
+		Scopes:     scopes,
+		DailyQuota: req.DailyQuota,
+		CreatedBy:  createdBy,
+		CreatedAt:  s.now().UTC(),
+	}
+	if err := s.store.SaveAPIKey(ctx, key); err != nil {
+		return nil, err
+	}
+	logger.InfoContext(ctx, "api key issued", "key", key.ID, "name", key.Name, "scopes", key.Scopes, "by", createdBy)
+
+	return &models.APIKeyCreated{
+		APIKeyInfo: toInfo(key, 0),
+		Key:        KeyPrefix + id + "_" + secret,
+	}, nil
+}
+
+// List returns every key with today's usage, newest first.
+func (s *Service) List(ctx context.Context) ([]models.APIKeyInfo, error) {
+	keys, err := s.store.ListAPIKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	counters := make([]string, len(keys))
+	for i, k := range keys {
+		counters[i] = s.quotaKey(k.ID)
+	}
+	used, err := s.counters.GetUsage(ctx, counters...)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]models.APIKeyInfo, len(keys))
+	for i, k := range keys {
+		out[i] = toInfo(k, int(used[i]))
+	}
+	return out, nil
+}
+
+// Get returns one key with today's usage.
+func (s *Service) Get(ctx context.Context, id string) (*models.APIKeyInfo, error) {
+	key, err := s.store.GetAPIKey(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, ErrKeyNotFound
+	}
+	used, err := s.counters.GetUsage(ctx, s.quotaKey(id))
+	if err != nil {
+		return nil, err
+	}
+	info := toInfo(key, int(used[0]))
+	return &info, nil
+}
+
+// Revoke disables a key. Revoking twice keeps the first revocation time.
+func (s *Service) Revoke(ctx context.Context, id string) (*models.APIKeyInfo, error) {
+	key, err := s.store.GetAPIKey(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, ErrKeyNotFound
+	}
+	if key.RevokedAt == nil {
+		now := s.now().UTC()
+		key.RevokedAt = &now
+		if err := s.store.SaveAPIKey(ctx, key); err != nil {
+			return nil, err
+		}
+		logger.InfoContext(ctx, "api key revoked", "key", key.ID, "name", key.Name)
+	}
+	return s.Get(ctx, id)
+}
+
+// Authenticate verifies a presented key and records its use.
+func (s *Service) Authenticate(ctx context.Context, raw string) (*Principal, error) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(raw), KeyPrefix)
+	if !ok {
+		return nil, ErrInvalidKey
+	}
+	id, secret, ok := strings.Cut(rest, "_")
+	if !ok || id == "" || secret == "" {
+		return nil, ErrInvalidKey
+	}
+
+	key, err := s.store.GetAPIKey(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil || subtle.ConstantTimeCompare([]byte(hashSecret(secret)), []byte(key.SecretHash)) != 1 {
+		return nil, ErrInvalidKey
+	}
+	if key.RevokedAt != nil {
+		return nil, ErrKeyRevoked
+	}
+
+	p := &Principal{KeyID: key.ID, Name: key.Name, DailyQuota: key.DailyQuota}
+	for _, raw := range key.Scopes {
+		if scope, ok := ParseScope(raw); ok {
+			p.Scopes = append(p.Scopes, scope)
+		}
+	}
+	if key.OwnerID != "" {
+		owner, err := s.store.GetUser(ctx, key.OwnerID)
+		if err != nil {
+			return nil, err
+		}
+		if owner == nil {
+			// The account was removed; its keys go with it.
+			return nil, ErrKeyRevoked
+		}
+		p.OwnerID, p.OwnerEmail = owner.ID, owner.Email
+		p.OwnerPlan = entitlements.EffectivePlan(owner, s.now())
+	}
+
+	if err := s.store.TouchAPIKey(ctx, key.ID, s.now()); err != nil {
+		logger.WarnContext(ctx, "touch api key", "key", key.ID, "err", err)
+	}
+	return p, nil
+}
+
+// Consume counts one request against the key's daily quota. When the quota
+// is used up it returns ErrQuotaExceeded with the Quota still set. Keys
+// without a quota return a nil Quota.
+func (s *Service) Consume(ctx context.Context, p *Principal) (*Quota, error) {
+	if p.DailyQuota == 0 {
+		return nil, nil
+	}
+	resetsAt := s.dayEnd()
+	n, err := s.counters.IncrUsage(ctx, s.quotaKey(p.KeyID), resetsAt.Sub(s.now())+time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	q := &Quota{Limit: p.DailyQuota, Used: int(n), ResetsAt: resetsAt}
+	if q.Used > q.Limit {
+		_ = s.counters.DecrUsage(ctx, s.quotaKey(p.KeyID))
+		q.Used = q.Limit
+		return q, ErrQuotaExceeded
+	}
+	return q, nil
+}
+
+// Refund gives back one request of the daily quota.
+func (s *Service) Refund(ctx context.Context, p *Principal) {
+	if p.DailyQuota == 0 {
+		return
+	}
+	_ = s.counters.DecrUsage(ctx, s.quotaKey(p.KeyID))
+}
+
+func (s *Service) quotaKey(id string) string {
+	return "apikey:" + id + ":" + s.now().UTC().Format("2006-01-02")
+}
+
+func (s *Service) dayEnd() time.Time {
+	t := s.now().UTC()
+	return time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomString(n int, encode func([]byte) string) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("apikeys: generate key: %w", err)
+	}
+	return encode(b), nil
+}
+
+func toInfo(k *redis.APIKey, usedToday int) models.APIKeyInfo {
+	return models.APIKeyInfo{
+		ID:         k.ID,
+		Name:       k.Name,
+		Prefix:     KeyPrefix + k.ID,
+		OwnerID:    k.OwnerID,
+		Scopes:     k.Scopes,
+		DailyQuota: k.DailyQuota,
+		UsedToday:  usedToday,
+		CreatedAt:  k.CreatedAt,
+		LastUsedAt: k.LastUsedAt,
+		RevokedAt:  k.RevokedAt,
+	}
+}
@@ -0,0 +1,123 @@
+// Package auth issues and verifies the JWTs used to authenticate API users.
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// Token types carried in the "typ" claim so a refresh token can never be used
+// as an access token and vice versa.
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+)
+
+// RoleAdmin is the role claim of operators allowed to use admin endpoints.
+const RoleAdmin = "admin"
+
+// ErrInvalidToken is returned for malformed, expired or mis-typed tokens.
+var ErrInvalidToken = errors.New("auth: invalid or expired token")
+
+// Claims are the JWT claims issued by this service.
+type Claims struct {
+	Type  string `json:"typ"`
+	Email string `json:"email,omitempty"`
+	Plan  string `json:"plan,omitempty"`
+	Role  string `json:"role,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// Subject is the identity a token is issued for.
+type Subject struct {
+	UserID string
+	Email  string
+	Plan   string
+	Role   string
+}
+
+// Tokens signs and verifies HS256 access and refresh tokens.
+type Tokens struct {
+	secret     []byte
+	issuer     string
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+	now        func() time.Time
+}
+
+// NewTokens returns a token issuer signing with secret.
+func NewTokens(secret []byte, issuer string, accessTTL, refreshTTL time.Duration) *Tokens {
+	return &Tokens{
+		secret:     secret,
+		issuer:     issuer,
+		accessTTL:  accessTTL,
+		refreshTTL: refreshTTL,
+		now:        time.Now,
+	}
+}
+
+// AccessTTL returns the lifetime of access tokens.
+func (t *Tokens) AccessTTL() time.Duration { return t.accessTTL }
+
+// RefreshTTL returns the lifetime of refresh tokens.
+func (t *Tokens) RefreshTTL() time.Duration { return t.refreshTTL }
+
+// IssueAccess returns a signed access token for sub.
+func (t *Tokens) IssueAccess(sub Subject) (string, error) {
+	token, _, err := t.issue(sub, TokenTypeAccess, t.accessTTL)
+	return token, err
+}
+
+// IssueRefresh returns a signed refresh token for sub and its token ID, which
+// the caller stores so the token can be rotated or revoked.
+func (t *Tokens) IssueRefresh(sub Subject) (token, tokenID string, err error) {
+	return t.issue(sub, TokenTypeRefresh, t.refreshTTL)
+}
+
+// Parse verifies a token and checks that it has the expected type.
+func (t *Tokens) Parse(raw, wantType string) (*Claims, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(raw, claims, func(*jwt.Token) (any, error) {
+		return t.secret, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(t.issuer),
+		jwt.WithExpirationRequired(),
+		jwt.WithTimeFunc(t.now),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if claims.Type != wantType || claims.Subject == "" {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+func (t *Tokens) issue(sub Subject, typ string, ttl time.Duration) (string, string, error) {
+	now := t.now()
+	id := uuid.New().String()
+	claims := Claims{
+		Type:  typ,
+		Email: sub.Email,
+		Plan:  sub.Plan,
+		Role:  sub.Role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        id,
+			Issuer:    t.issuer,
+			Subject:   sub.UserID,
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(t.secret)
+	if err != nil {
+		return "", "", fmt.Errorf("auth: sign %s token: %w", typ, err)
+	}
+	return signed, id, nil
+}
@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestParse(t *testing.T) {
+	secret := []byte("test-secret")
+	start := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	tokens := NewTokens(secret, "puzzles", 15*time.Minute, 24*time.Hour)
+	tokens.now = func() time.Time { return start }
+
+	sub := Subject{UserID: "u1", Email: "a@example.com", Plan: "pro"}
+	access, err := tokens.IssueAccess(sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	refresh, _, err := tokens.IssueRefresh(sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims := func(iss string, exp time.Time) Claims {
+		return Claims{
+			Type: TokenTypeAccess,
+			RegisteredClaims: jwt.RegisteredClaims{
+				Issuer:    iss,
+				Subject:   "u1",
+				ExpiresAt: jwt.NewNumericDate(exp),
+			},
+		}
+	}
+	sign := func(method jwt.SigningMethod, key any, c Claims) string {
+		s, err := jwt.NewWithClaims(method, c).SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid := claims("puzzles", start.Add(time.Minute))
+
+	tests := []struct {
+		name  string
+		raw   string
+		typ   string
+		after time.Duration // clock advance before parsing
+		ok    bool
+	}{
+		{name: "access", raw: access, typ: TokenTypeAccess, ok: true},
+		{name: "refresh", raw: refresh, typ: TokenTypeRefresh, ok: true},
+		{name: "refresh as access", raw: refresh, typ: TokenTypeAccess},
+		{name: "access as refresh", raw: access, typ: TokenTypeRefresh},
+		{name: "expired", raw: access, typ: TokenTypeAccess, after: 16 * time.Minute},
+		{name: "HS512", raw: sign(jwt.SigningMethodHS512, secret, valid), typ: TokenTypeAccess},
+		{name: "ES256", raw: sign(jwt.SigningMethodES256, ecKey, valid), typ: TokenTypeAccess},
+		{name: "none", raw: sign(jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, valid), typ: TokenTypeAccess},
+		{name: "other secret", raw: sign(jwt.SigningMethodHS256, []byte("other"), valid), typ: TokenTypeAccess},
+		{name: "other issuer", raw: sign(jwt.SigningMethodHS256, secret, claims("other", start.Add(time.Minute))), typ: TokenTypeAccess},
+		{name: "no expiry", raw: sign(jwt.SigningMethodHS256, secret, Claims{Type: TokenTypeAccess, RegisteredClaims: jwt.RegisteredClaims{Issuer: "puzzles", Subject: "u1"}}), typ: TokenTypeAccess},
+		{name: "malformed", raw: "not.a.token", typ: TokenTypeAccess},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens.now = func() time.Time { return start.Add(tt.after) }
+			got, err := tokens.Parse(tt.raw, tt.typ)
+			if tt.ok {
+				if err != nil {
+					t.Fatalf("Parse() error = %v", err)
+				}
+				if got.Subject != sub.UserID || got.Plan != sub.Plan {
+					t.Fatalf("Parse() = %+v, want subject %q plan %q", got, sub.UserID, sub.Plan)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidToken) {
+				t.Fatalf("Parse() error = %v, want ErrInvalidToken", err)
+			}
+		})
+	}
+}
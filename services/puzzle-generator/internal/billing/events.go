@@ -0,0 +1,53 @@
+package billing
+
+import "encoding/json"
+
+// Event types handled by Service. Anything else is acknowledged and ignored.
+const (
+	EventCheckoutCompleted   = "checkout.session.completed"
+	EventSubscriptionCreated = "customer.subscription.created"
+	EventSubscriptionUpdated = "customer.subscription.updated"
+	EventSubscriptionDeleted = "customer.subscription.deleted"
+	EventInvoiceFailed       = "invoice.payment_failed"
+)
+
+// Event is the envelope of a webhook delivery.
+type Event struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Created int64  `json:"created"` // unix seconds
+	Data    struct {
+		Object json.RawMessage `json:"object"`
+	} `json:"data"`
+}
+
+// checkoutSession is the subset of a Checkout Session object we read.
+type checkoutSession struct {
+	ClientReferenceID string            `json:"client_reference_id"`
+	Customer          string            `json:"customer"`
+	Subscription      string            `json:"subscription"`
+	PaymentStatus     string            `json:"payment_status"`
+	Metadata          map[string]string `json:"metadata"`
+}
+
+// subscription is the subset of a Subscription object we read.
+type subscription struct {
+	ID       string            `json:"id"`
+	Customer string            `json:"customer"`
+	Status   string            `json:"status"`
+	TrialEnd int64             `json:"trial_end"`
+	Metadata map[string]string `json:"metadata"`
+	Items    struct {
+		Data []struct {
+			Price struct {
+				ID string `json:"id"`
+			} `json:"price"`
+		} `json:"data"`
+	} `json:"items"`
+}
+
+// invoice is the subset of an Invoice object we read.
+type invoice struct {
+	Customer     string `json:"customer"`
+	Subscription string `json:"subscription"`
+}
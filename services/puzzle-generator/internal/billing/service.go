@@ -0,0 +1,373 @@
+package billing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/chess-puzzle-next/puzzle-generator/internal/entitlements"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/logging"
+	"github.com/chess-puzzle-next/puzzle-generator/pkg/redis"
+)
+
+var logger = logging.For("billing")
+
+// Store persists users and processed event IDs.
+type Store interface {
+	GetUser(ctx context.Context, userID string) (*redis.User, error)
+	UpdateUser(ctx context.Context, userID string, fn func(*redis.User) error) (*redis.User, error)
+	LinkBillingCustomer(ctx context.Context, customerID, userID string) error
+	GetUserByBillingCustomer(ctx context.Context, customerID string) (*redis.User, error)
+	ClaimBillingEvent(ctx context.Context, eventID string, ttl time.Duration) (bool, error)
+	ReleaseBillingEvent(ctx context.Context, eventID string) error
+}
+
+// ErrMalformedEvent is returned for payloads that are not a valid event.
+var ErrMalformedEvent = errors.New("billing: malformed event")
+
+// eventTTL is how long processed event IDs are remembered. Providers stop
+// retrying a delivery well within this window.
+const eventTTL = 30 * 24 * time.Hour
+
+// Config holds webhook and plan-mapping settings.
+type Config struct {
+	WebhookSecret string
+	Tolerance     time.Duration                // maximum age of a signature timestamp
+	GracePeriod   time.Duration                // how long a past-due subscription keeps its plan
+	Prices        map[string]entitlements.Plan // price ID → plan
+}
+
+// Service applies billing events to user accounts.
+type Service struct {
+	store Store
+	cfg   Config
+	now   func() time.Time
+}
+
+// NewService returns a billing Service.
+func NewService(store Store, cfg Config) *Service {
+	return &Service{store: store, cfg: cfg, now: time.Now}
+}
+
+// HandleWebhook verifies a delivery and applies it. Redelivered events are
+// acknowledged without being applied twice; if applying fails the event is
+// released so the provider's retry can process it.
+func (s *Service) HandleWebhook(ctx context.Context, payload []byte, signature string) error {
+	if err := VerifySignature(payload, signature, s.cfg.WebhookSecret, s.cfg.Tolerance, s.now()); err != nil {
+		return err
+	}
+
+	var ev Event
+	if err := json.Unmarshal(payload, &ev); err != nil || ev.ID == "" || ev.Type == "" {
+		return ErrMalformedEvent
+	}
+
+	switch ev.Type {
+	case EventCheckoutCompleted, EventSubscriptionCreated, EventSubscriptionUpdated,
+		EventSubscriptionDeleted, EventInvoiceFailed:
+	default:
+		return nil
+	}
+
+	claimed, err := s.store.ClaimBillingEvent(ctx, ev.ID, eventTTL)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		logger.InfoContext(ctx, "event already processed", "event", ev.ID)
+		return nil
+	}
+
+	if err := s.apply(ctx, &ev); err != nil {
+		_ = s.store.ReleaseBillingEvent(ctx, ev.ID)
+		return err
+	}
+	return nil
+}
+
+func (s *Service) apply(ctx context.Context, ev *Event) error {
+	switch ev.Type {
+	case EventCheckoutCompleted:
+		var obj checkoutSession
+		if err := json.Unmarshal(ev.Data.Object, &obj); err != nil {
+			return ErrMalformedEvent
+		}
+		return s.checkoutCompleted(ctx, ev, &obj)
+	case EventSubscriptionCreated, EventSubscriptionUpdated:
+		var obj subscription
+		if err := json.Unmarshal(ev.Data.Object, &obj); err != nil {
+			return ErrMalformedEvent
+		}
+		return s.subscriptionUpdated(ctx, ev, &obj)
+	case EventSubscriptionDeleted:
+		var obj subscription
+		if err := json.Unmarshal(ev.Data.Object, &obj); err != nil {
+			return ErrMalformedEvent
+		}
+		return s.subscriptionDeleted(ctx, ev, &obj)
+	case EventInvoiceFailed:
+		var obj invoice
+		if err := json.Unmarshal(ev.Data.Object, &obj); err != nil {
+			return ErrMalformedEvent
+		}
+		return s.invoiceFailed(ctx, ev, &obj)
+	}
+	return nil
+}
+
+// checkoutCompleted links the billing customer to the user who started the
+// checkout (client_reference_id) and activates the purchased plan once the
+// checkout is paid.
+func (s *Service) checkoutCompleted(ctx context.Context, ev *Event, obj *checkoutSession) error {
+	userID := obj.ClientReferenceID
+	if userID == "" {
+		userID = obj.Metadata["user_id"]
+	}
+	user, err := s.store.GetUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		logger.WarnContext(ctx, "unknown user", "type", ev.Type, "event", ev.ID, "user", userID)
+		return nil
+	}
+	if obj.Customer != "" {
+		if err := s.store.LinkBillingCustomer(ctx, obj.Customer, userID); err != nil {
+			return fmt.Errorf("billing: link customer: %w", err)
+		}
+	}
+
+	return s.update(ctx, userID, ev, func(user *redis.User) error {
+		if obj.Customer != "" {
+			user.BillingCustomerID = obj.Customer
+		}
+		if obj.Subscription != "" {
+			user.BillingSubscriptionID = obj.Subscription
+		}
+		if stale(user, ev) || !paid(obj) {
+			// A newer subscription event already set the plan, or a delayed
+			// payment method completed the checkout before the money arrived
+			// and the subscription events grant the plan once it does; only
+			// the links above are saved.
+			return nil
+		}
+		if plan, ok := paidPlan(obj.Metadata["plan"]); ok {
+			user.Plan = string(plan)
+			if user.PlanStatus == "" || user.PlanStatus == string(entitlements.StatusCanceled) {
+				user.PlanStatus = string(entitlements.StatusActive)
+			}
+		}
+		return nil
+	})
+}
+
+// subscriptionUpdated mirrors the subscription's plan, status and trial.
+func (s *Service) subscriptionUpdated(ctx context.Context, ev *Event, obj *subscription) error {
+	userID, err := s.userFor(ctx, obj.Customer, obj.Metadata["user_id"])
+	if err != nil || userID == "" {
+		return err
+	}
+
+	return s.update(ctx, userID, ev, func(user *redis.User) error {
+		if stale(user, ev) {
+			return errSkipEvent
+		}
+		if obj.Customer != "" {
+			user.BillingCustomerID = obj.Customer
+		}
+		user.BillingSubscriptionID = obj.ID
+
+		user.TrialEndsAt = nil
+		if obj.TrialEnd > 0 {
+			t := time.Unix(obj.TrialEnd, 0).UTC()
+			user.TrialEndsAt = &t
+		}
+
+		// Only a subscription that is paid for, on trial or in its grace
+		// period changes the plan; an unpaid one must not grant it.
+		status := mapStatus(obj.Status)
+		switch status {
+		case entitlements.StatusActive, entitlements.StatusTrialing, entitlements.StatusPastDue:
+			if plan, ok := s.planFor(obj); ok {
+				user.Plan = string(plan)
+			}
+		}
+		switch status {
+		case entitlements.StatusActive, entitlements.StatusTrialing:
+			user.PlanStatus = string(status)
+			user.GraceUntil = nil
+		case entitlements.StatusPastDue:
+			user.PlanStatus = string(status)
+			s.startGrace(user, ev)
+		case entitlements.StatusIncomplete, entitlements.StatusCanceled:
+			user.PlanStatus = string(status)
+			user.GraceUntil = nil
+		}
+		return nil
+	})
+}
+
+// subscriptionDeleted ends the subscription and drops the user to free.
+// Deleting a subscription the user has since replaced changes nothing.
+func (s *Service) subscriptionDeleted(ctx context.Context, ev *Event, obj *subscription) error {
+	userID, err := s.userFor(ctx, obj.Customer, obj.Metadata["user_id"])
+	if err != nil || userID == "" {
+		return err
+	}
+
+	return s.update(ctx, userID, ev, func(user *redis.User) error {
+		if stale(user, ev) {
+			return errSkipEvent
+		}
+		if obj.ID != user.BillingSubscriptionID {
+			logger.InfoContext(ctx, "deleted subscription is not the user's current one, skipped", "event", ev.ID, "subscription", obj.ID)
+			return errSkipEvent
+		}
+		user.Plan = string(entitlements.PlanFree)
+		user.PlanStatus = string(entitlements.StatusCanceled)
+		user.BillingSubscriptionID = ""
+		user.TrialEndsAt = nil
+		user.GraceUntil = nil
+		return nil
+	})
+}
+
+// invoiceFailed marks the subscription past due. The plan stays available
+// until the grace period ends, giving the provider time to retry the charge.
+func (s *Service) invoiceFailed(ctx context.Context, ev *Event, obj *invoice) error {
+	userID, err := s.userFor(ctx, obj.Customer, "")
+	if err != nil || userID == "" {
+		return err
+	}
+
+	return s.update(ctx, userID, ev, func(user *redis.User) error {
+		if stale(user, ev) || user.PlanStatus == string(entitlements.StatusCanceled) {
+			return errSkipEvent
+		}
+		user.PlanStatus = string(entitlements.StatusPastDue)
+		s.startGrace(user, ev)
+		return nil
+	})
+}
+
+// userFor finds the ID of the user of a billing customer, falling back to
+// the user ID carried in the object's metadata, which it links to the
+// customer. Returns "" when no user is known.
+func (s *Service) userFor(ctx context.Context, customerID, userID string) (string, error) {
+	if customerID != "" {
+		user, err := s.store.GetUserByBillingCustomer(ctx, customerID)
+		if err != nil {
+			return "", err
+		}
+		if user != nil {
+			return user.ID, nil
+		}
+	}
+	if userID != "" {
+		user, err := s.store.GetUser(ctx, userID)
+		if err != nil || user == nil {
+			if user == nil {
+				logger.WarnContext(ctx, "unknown user", "user", userID)
+			}
+			return "", err
+		}
+		if customerID != "" {
+			if err := s.store.LinkBillingCustomer(ctx, customerID, user.ID); err != nil {
+				return "", fmt.Errorf("billing: link customer: %w", err)
+			}
+		}
+		return user.ID, nil
+	}
+	logger.WarnContext(ctx, "no user linked to customer", "customer", customerID)
+	return "", nil
+}
+
+// planFor resolves the plan of a subscription from its price, falling back
+// to the plan named in its metadata.
+func (s *Service) planFor(obj *subscription) (entitlements.Plan, bool) {
+	for _, item := range obj.Items.Data {
+		if plan, ok := s.cfg.Prices[item.Price.ID]; ok {
+			return plan, true
+		}
+	}
+	return paidPlan(obj.Metadata["plan"])
+}
+
+// startGrace opens the grace period unless one is already running.
+func (s *Service) startGrace(user *redis.User, ev *Event) {
+	if user.GraceUntil != nil {
+		return
+	}
+	until := time.Unix(ev.Created, 0).UTC().Add(s.cfg.GracePeriod)
+	user.GraceUntil = &until
+}
+
+// errSkipEvent aborts a user update that leaves the user as it is.
+var errSkipEvent = errors.New("billing: event skipped")
+
+// update applies fn to the user under UpdateUser, so concurrent events for
+// the same user do not overwrite each other, and records ev as the latest
+// event applied. fn runs again if the user changes meanwhile, so it must not
+// have side effects; it returns errSkipEvent to leave the user unchanged.
+func (s *Service) update(ctx context.Context, userID string, ev *Event, fn func(*redis.User) error) error {
+	user, err := s.store.UpdateUser(ctx, userID, func(u *redis.User) error {
+		if err := fn(u); err != nil {
+			return err
+		}
+		u.BillingEventAt = max(u.BillingEventAt, ev.Created)
+		return nil
+	})
+	switch {
+	case errors.Is(err, errSkipEvent):
+		return nil
+	case err != nil:
+		return fmt.Errorf("billing: update user: %w", err)
+	case user == nil:
+		logger.WarnContext(ctx, "unknown user", "type", ev.Type, "event", ev.ID, "user", userID)
+		return nil
+	}
+	logger.InfoContext(ctx, "subscription applied", "type", ev.Type, "event", ev.ID, "user", user.ID, "plan", user.Plan, "status", user.PlanStatus)
+	return nil
+}
+
+// stale reports whether ev predates the last event applied to user.
+// Providers do not guarantee delivery order, so an old event must not undo
+// a newer state.
+func stale(user *redis.User, ev *Event) bool {
+	if ev.Created < user.BillingEventAt {
+		logger.Info("event older than last applied event, skipped", "type", ev.Type, "event", ev.ID)
+		return true
+	}
+	return false
+}
+
+func paidPlan(name string) (entitlements.Plan, bool) {
+	plan := entitlements.ParsePlan(name)
+	return plan, plan != entitlements.PlanFree
+}
+
+// paid reports whether a checkout was paid for, or needed no payment (e.g. a
+// trial).
+func paid(obj *checkoutSession) bool {
+	return obj.PaymentStatus == "paid" || obj.PaymentStatus == "no_payment_required"
+}
+
+// mapStatus folds the provider's subscription statuses into ours. An empty
+// result means the status is unknown and carries no entitlement change.
+func mapStatus(status string) entitlements.Status {
+	switch status {
+	case "active":
+		return entitlements.StatusActive
+	case "trialing":
+		return entitlements.StatusTrialing
+	case "past_due":
+		return entitlements.StatusPastDue
+	case "incomplete":
+		return entitlements.StatusIncomplete
+	case "canceled", "unpaid", "incomplete_expired", "paused":
+		return entitlements.StatusCanceled
+	}
+	return ""
+}
@@ -0,0 +1,80 @@
+// Package billing processes subscription lifecycle webhooks from a
+// Stripe-compatible billing provider.
+package billing
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SignatureHeader is the header carrying the webhook signature.
+const SignatureHeader = "Stripe-Signature"
+
+var (
+	ErrMissingSignature = errors.New("billing: missing signature header")
+	ErrInvalidSignature = errors.New("billing: signature does not match payload")
+	ErrStaleSignature   = errors.New("billing: signature timestamp outside tolerance")
+)
+
+// Sign returns a signature header value for payload, in the
+// "t=<unix>,v1=<hex hmac>" format used by Stripe.
+func Sign(payload []byte, secret string, ts time.Time) string {
+	unix := strconv.FormatInt(ts.Unix(), 10)
+	return "t=" + unix + ",v1=" + computeSignature(unix, payload, secret)
+}
+
+// VerifySignature checks a signature header against payload. Any of the v1
+// signatures may match, which allows the secret to be rotated.
+func VerifySignature(payload []byte, header, secret string, tolerance time.Duration, now time.Time) error {
+	if header == "" {
+		return ErrMissingSignature
+	}
+
+	var ts string
+	var sigs []string
+	for _, part := range strings.Split(header, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch k {
+		case "t":
+			ts = v
+		case "v1":
+			sigs = append(sigs, v)
+		}
+	}
+	if ts == "" || len(sigs) == 0 {
+		return fmt.Errorf("%w: malformed header", ErrInvalidSignature)
+	}
+
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: bad timestamp", ErrInvalidSignature)
+	}
+	if age := now.Sub(time.Unix(unix, 0)); age > tolerance || age < -tolerance {
+		return ErrStaleSignature
+	}
+
+	expected := computeSignature(ts, payload, secret)
+	for _, sig := range sigs {
+		if hmac.Equal([]byte(sig), []byte(expected)) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
+
+func computeSignature(ts string, payload []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,42 @@
+package billing
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestVerifySignature(t *testing.T) {
+	const secret = "whsec_test"
+	payload := []byte(`{"id":"evt_1","type":"customer.subscription.updated"}`)
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	tolerance := 5 * time.Minute
+	ts := strconv.FormatInt(now.Unix(), 10)
+
+	tests := []struct {
+		name   string
+		header string
+		want   error
+	}{
+		{name: "valid", header: Sign(payload, secret, now)},
+		{name: "within tolerance", header: Sign(payload, secret, now.Add(-4*time.Minute))},
+		{name: "rotated secret", header: Sign(payload, "old", now) + ",v1=" + computeSignature(ts, payload, secret)},
+		{name: "missing", header: "", want: ErrMissingSignature},
+		{name: "wrong secret", header: Sign(payload, "other", now), want: ErrInvalidSignature},
+		{name: "other payload", header: Sign([]byte(`{"id":"evt_2"}`), secret, now), want: ErrInvalidSignature},
+		{name: "stale", header: Sign(payload, secret, now.Add(-6*time.Minute)), want: ErrStaleSignature},
+		{name: "future", header: Sign(payload, secret, now.Add(6*time.Minute)), want: ErrStaleSignature},
+		{name: "no timestamp", header: "v1=" + computeSignature(ts, payload, secret), want: ErrInvalidSignature},
+		{name: "no signature", header: "t=" + ts, want: ErrInvalidSignature},
+		{name: "bad timestamp", header: "t=yesterday,v1=00", want: ErrInvalidSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifySignature(payload, tt.header, secret, tolerance, now)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("VerifySignature() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
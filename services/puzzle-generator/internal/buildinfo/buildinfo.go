@@ -0,0 +1,64 @@
+// Package buildinfo reports the version and commit the binary was built
+// from. Release builds set them with -ldflags; otherwise they come from the
+// VCS stamp Go embeds when building inside a git checkout.
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+	"sync"
+)
+
+// Set at link time:
+//
+//	go build -ldflags "-X github.com/chess-puzzle-next/puzzle-generator/internal/buildinfo.version=v1.2.0 -X github.com/chess-puzzle-next/puzzle-generator/internal/buildinfo.commit=abc1234"
+var (
+	version string
+	commit  string
+)
+
+// Info describes the running binary.
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"buildTime,omitempty"`
+	Modified  bool   `json:"modified,omitempty"` // built from a dirty tree
+	GoVersion string `json:"goVersion"`
+}
+
+var (
+	once sync.Once
+	info Info
+)
+
+// Get returns the build information. Without a stamp the version reads "dev"
+// and the commit "unknown".
+func Get() Info {
+	once.Do(func() {
+		info = Info{Version: version, Commit: commit, GoVersion: runtime.Version()}
+		if bi, ok := debug.ReadBuildInfo(); ok {
+			if info.Version == "" && bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+				info.Version = bi.Main.Version
+			}
+			for _, s := range bi.Settings {
+				switch s.Key {
+				case "vcs.revision":
+					if info.Commit == "" {
+						info.Commit = s.Value
+					}
+				case "vcs.time":
+					info.BuildTime = s.Value
+				case "vcs.modified":
+					info.Modified = s.Value == "true"
+				}
+			}
+		}
+		if info.Version == "" {
+			info.Version = "dev"
+		}
+		if info.Commit == "" {
+			info.Commit = "unknown"
+		}
+	})
+	return info
+}
@@ -2,11 +2,16 @@ package config
 
 import (
 	"fmt"
+	"log/slog"
+	"net/netip"
 	"os"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/chess-puzzle-next/puzzle-generator/internal/logging"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/ratelimit"
+	"github.com/chess-puzzle-next/puzzle-generator/pkg/llm"
 	"github.com/joho/godotenv"
 )
 
@@ -15,8 +20,25 @@ type Config struct {
 	Server      ServerConfig
 	Redis       RedisConfig
 	Lichess     LichessConfig
-	NVIDIA      NVIDIAConfig
+	LLM         LLMConfig
 	HuggingFace HuggingFaceConfig
+	Challenge   ChallengeConfig
+	Auth        AuthConfig
+	Billing     BillingConfig
+	RateLimit   RateLimitConfig
+	Webhooks    WebhooksConfig
+	Tracing     TracingConfig
+	Logging     LoggingConfig
+	Health      HealthConfig
+	Upstream    UpstreamConfig
+	Sources     SourcesConfig
+	PuzzleCache PuzzleCacheConfig
+	Pools       PoolsConfig
+	Seen        SeenConfig
+	Explain     ExplainConfig
+	RAG         RAGConfig
+	Prompts     PromptsConfig
+	AIUsage     AIUsageConfig
 }
 
 // ServerConfig holds HTTP server settings.
@@ -24,6 +46,10 @@ type ServerConfig struct {
 	Port         string
 	ReadTimeout  time.Duration
 	WriteTimeout time.Duration
+	// TrustedProxies are the networks of the reverse proxies in front of the
+	// service. X-Forwarded-For is only read from them; without any, the
+	// client IP is the address of the TCP peer.
+	TrustedProxies []netip.Prefix
 }
 
 // LichessConfig holds Lichess API settings.
@@ -33,11 +59,23 @@ type LichessConfig struct {
 	Timeout  time.Duration
 }
 
-type NVIDIAConfig struct {
-	BaseURL string
-	APIKey  string
-	Model   string
-	Timeout time.Duration
+// LLMConfig holds the LLM providers, tried in the order of Providers.
+type LLMConfig struct {
+	Providers  []string // nvidia, openrouter, local, fake
+	NVIDIA     LLMProviderConfig
+	OpenRouter LLMProviderConfig
+	Local      LLMProviderConfig // any OpenAI-compatible endpoint, API key optional
+	FakeReply  string            // answer of the fake provider to every task
+	Prices     llm.Prices        // USD per million tokens by model, from LLM_PRICES
+}
+
+// LLMProviderConfig holds the settings of one OpenAI-compatible provider.
+type LLMProviderConfig struct {
+	BaseURL    string
+	APIKey     string
+	Model      string            // default model
+	TaskModels map[string]string // task → model, from <PREFIX>_MODEL_<TASK>
+	Timeout    time.Duration
 }
 
 type HuggingFaceConfig struct {
@@ -48,6 +86,140 @@ type HuggingFaceConfig struct {
 	Timeout time.Duration
 }
 
+// AuthConfig holds account and JWT settings.
+type AuthConfig struct {
+	JWTSecret       string // HS256 signing key; a random key is used when empty
+	Issuer          string
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	AdminUserIDs    []string // user IDs granted the admin role
+}
+
+// BillingConfig holds billing provider webhook settings.
+type BillingConfig struct {
+	WebhookSecret      string        // webhooks are rejected with 503 when empty
+	SignatureTolerance time.Duration // maximum age of a webhook signature
+	GracePeriod        time.Duration // how long a past-due subscription keeps its plan
+	ProPrices          []string      // provider price IDs that grant the pro plan
+	ElitePrices        []string      // provider price IDs that grant the elite plan
+}
+
+// RateLimitConfig holds request throttling budgets per route group.
+type RateLimitConfig struct {
+	Enabled bool
+	Budgets map[string]ratelimit.Budget
+}
+
+// WebhooksConfig holds outbound webhook delivery settings.
+type WebhooksConfig struct {
+	Workers        int
+	QueueSize      int
+	MaxAttempts    int
+	RetryBaseDelay time.Duration
+	Timeout        time.Duration
+	LogRetention   time.Duration
+	AllowPrivate   bool // allow http and private addresses as targets (local development)
+}
+
+// TracingConfig holds OpenTelemetry settings. The OTLP endpoint and sampler
+// use the standard OTEL_* variables read by the SDK.
+type TracingConfig struct {
+	Exporter    string // none, otlp or stdout
+	ServiceName string
+}
+
+// LoggingConfig holds structured logging settings.
+type LoggingConfig struct {
+	Format string // json or text
+	Level  slog.Level
+	Levels map[string]slog.Level // per package, e.g. services=debug
+}
+
+// HealthConfig holds readiness probe settings.
+type HealthConfig struct {
+	CacheTTL     time.Duration // how long a dependency probe result is reused
+	ProbeTimeout time.Duration
+}
+
+// UpstreamConfig holds retry and circuit breaker settings shared by the
+// upstream API clients. Each client's timeout is the budget for all retries.
+type UpstreamConfig struct {
+	MaxRetries         int // extra attempts for idempotent calls; 0 disables retries
+	RetryBaseDelay     time.Duration
+	RetryMaxDelay      time.Duration
+	BreakerFailures    int           // consecutive failures that open an upstream's circuit
+	BreakerOpenTimeout time.Duration // how long the circuit stays open before a probe
+}
+
+// SourcesConfig holds the puzzle source chains.
+type SourcesConfig struct {
+	Chains       map[string][]string // endpoint (random, id, daily, dataset) → sources; unset endpoints keep the default
+	StoreLimit   int                 // puzzles kept per difficulty for the store source
+	SyncInterval time.Duration       // how often replicas reload sources disabled by admins
+}
+
+// PuzzleCacheConfig holds the puzzle cache settings.
+type PuzzleCacheConfig struct {
+	Size int           // puzzles kept in memory by each replica
+	TTL  time.Duration // lifetime of a puzzle in Redis
+}
+
+// PoolsConfig holds the pre-fetched puzzle pool settings.
+type PoolsConfig struct {
+	Enabled        bool
+	Size           int           // ready puzzles per source and difficulty
+	RefillInterval time.Duration // minimum delay between two fetches of one pool
+	Sources        []string      // sources with pools (lichess, huggingface)
+}
+
+// SeenConfig holds the per-viewer de-duplication settings.
+type SeenConfig struct {
+	Limit         int           // most recent puzzles remembered per viewer
+	TTL           time.Duration // how long an idle viewer's set is kept in Redis
+	MemoryViewers int           // viewers kept in memory without Redis
+}
+
+// ExplainConfig holds the AI puzzle explanation settings.
+type ExplainConfig struct {
+	CacheTTL time.Duration // lifetime of an explanation in Redis
+}
+
+// RAGConfig holds the retrieval settings of the AI puzzle pipeline.
+type RAGConfig struct {
+	IndexPath  string // vector index built by cmd/build-index; empty uses random dataset rows
+	TopK       int    // puzzles retrieved for the model to choose from
+	Embeddings EmbeddingsConfig
+}
+
+// EmbeddingsConfig selects the embedder of the vector index. Queries must
+// use the embedder the index was built with.
+type EmbeddingsConfig struct {
+	Provider string // local (hashing, offline) or api (OpenAI-compatible /embeddings)
+	Dims     int    // vector size of the local embedder
+	BaseURL  string
+	APIKey   string
+	Model    string
+	Timeout  time.Duration
+}
+
+// PromptsConfig holds the AI prompt template settings.
+type PromptsConfig struct {
+	Dir            string        // templates overriding the built-in ones; empty uses the built-in ones
+	ReloadInterval time.Duration // how often Dir is checked for changed templates
+}
+
+// AIUsageConfig holds the accounting of AI calls.
+type AIUsageConfig struct {
+	DailySpendLimit float64       // USD per UTC day after which AI features pause; 0 for none
+	Retention       time.Duration // how long daily usage is kept
+}
+
+// ChallengeConfig holds weekly challenge settings.
+type ChallengeConfig struct {
+	ScheduleInterval time.Duration // how often the scheduler checks for a new week
+	Retention        time.Duration // how long closed weeks keep their results
+}
+
 // RedisConfig holds Redis connection settings.
 type RedisConfig struct {
 	URL            string
@@ -76,11 +248,30 @@ func Load() (*Config, error) {
 			APIToken: getEnvOrFile("LICHESS_API_TOKEN", ""),
 			Timeout:  parseDuration("LICHESS_TIMEOUT", 10*time.Second),
 		},
-		NVIDIA: NVIDIAConfig{
-			BaseURL: getEnv("NVIDIA_BASE_URL", "https://integrate.api.nvidia.com/v1"),
-			APIKey:  getEnvOrFile("NVIDIA_API_KEY", ""),
-			Model:   getEnv("NVIDIA_MODEL", "meta/llama-3.3-70b-instruct"),
-			Timeout: parseDuration("NVIDIA_TIMEOUT", 30*time.Second),
+		LLM: LLMConfig{
+			Providers: llmProviders(),
+			NVIDIA: LLMProviderConfig{
+				BaseURL:    getEnv("NVIDIA_BASE_URL", "https://integrate.api.nvidia.com/v1"),
+				APIKey:     getEnvOrFile("NVIDIA_API_KEY", ""),
+				Model:      getEnv("NVIDIA_MODEL", "meta/llama-3.3-70b-instruct"),
+				TaskModels: loadTaskModels("NVIDIA"),
+				Timeout:    parseDuration("NVIDIA_TIMEOUT", 30*time.Second),
+			},
+			OpenRouter: LLMProviderConfig{
+				BaseURL:    getEnv("OPENROUTER_BASE_URL", "https://openrouter.ai/api/v1"),
+				APIKey:     getEnvOrFile("OPENROUTER_API_KEY", ""),
+				Model:      getEnv("OPENROUTER_MODEL", "meta-llama/llama-3.3-70b-instruct"),
+				TaskModels: loadTaskModels("OPENROUTER"),
+				Timeout:    parseDuration("OPENROUTER_TIMEOUT", 20*time.Second),
+			},
+			Local: LLMProviderConfig{
+				BaseURL:    getEnv("LOCAL_LLM_BASE_URL", ""),
+				APIKey:     getEnvOrFile("LOCAL_LLM_API_KEY", ""),
+				Model:      getEnv("LOCAL_LLM_MODEL", ""),
+				TaskModels: loadTaskModels("LOCAL_LLM"),
+				Timeout:    parseDuration("LOCAL_LLM_TIMEOUT", 60*time.Second),
+			},
+			FakeReply: getEnv("LLM_FAKE_REPLY", `{}`),
 		},
 		HuggingFace: HuggingFaceConfig{
 			BaseURL: getEnv("HUGGINGFACE_BASE_URL", "https://datasets-server.huggingface.co"),
@@ -89,7 +280,137 @@ func Load() (*Config, error) {
 			Split:   getEnv("HUGGINGFACE_DATASET_SPLIT", "train"),
 			Timeout: parseDuration("HUGGINGFACE_TIMEOUT", 15*time.Second),
 		},
+		Auth: AuthConfig{
+			JWTSecret:       getEnvOrFile("JWT_SECRET", ""),
+			Issuer:          getEnv("JWT_ISSUER", "puzzle-generator"),
+			AccessTokenTTL:  parseDuration("JWT_ACCESS_TTL", 15*time.Minute),
+			RefreshTokenTTL: parseDuration("JWT_REFRESH_TTL", 30*24*time.Hour),
+			AdminUserIDs:    getEnvList("AUTH_ADMIN_USER_IDS"),
+		},
+		Billing: BillingConfig{
+			WebhookSecret:      getEnvOrFile("STRIPE_WEBHOOK_SECRET", ""),
+			SignatureTolerance: parseDuration("BILLING_SIGNATURE_TOLERANCE", 5*time.Minute),
+			GracePeriod:        parseDuration("BILLING_GRACE_PERIOD", 7*24*time.Hour),
+			ProPrices:          getEnvList("BILLING_PRICE_PRO"),
+			ElitePrices:        getEnvList("BILLING_PRICE_ELITE"),
+		},
+		RateLimit: RateLimitConfig{
+			Enabled: parseBool("RATE_LIMIT_ENABLED", true),
+		},
+		Webhooks: WebhooksConfig{
+			Workers:        parseInt("WEBHOOK_WORKERS", 4),
+			QueueSize:      parseInt("WEBHOOK_QUEUE_SIZE", 1000),
+			MaxAttempts:    parseInt("WEBHOOK_MAX_ATTEMPTS", 6),
+			RetryBaseDelay: parseDuration("WEBHOOK_RETRY_BASE_DELAY", 10*time.Second),
+			Timeout:        parseDuration("WEBHOOK_TIMEOUT", 10*time.Second),
+			LogRetention:   parseDuration("WEBHOOK_LOG_RETENTION", 7*24*time.Hour),
+			AllowPrivate:   parseBool("WEBHOOK_ALLOW_PRIVATE", false),
+		},
+		Tracing: TracingConfig{
+			Exporter:    getEnv("OTEL_TRACES_EXPORTER", "none"),
+			ServiceName: getEnv("OTEL_SERVICE_NAME", "puzzle-generator"),
+		},
+		Logging: LoggingConfig{
+			Format: getEnv("LOG_FORMAT", "json"),
+		},
+		Health: HealthConfig{
+			CacheTTL:     parseDuration("HEALTH_CACHE_TTL", 30*time.Second),
+			ProbeTimeout: parseDuration("HEALTH_PROBE_TIMEOUT", 3*time.Second),
+		},
+		Upstream: UpstreamConfig{
+			MaxRetries:         parseCount("UPSTREAM_MAX_RETRIES", 2),
+			RetryBaseDelay:     parseDuration("UPSTREAM_RETRY_BASE_DELAY", 200*time.Millisecond),
+			RetryMaxDelay:      parseDuration("UPSTREAM_RETRY_MAX_DELAY", 2*time.Second),
+			BreakerFailures:    parseInt("UPSTREAM_BREAKER_FAILURES", 5),
+			BreakerOpenTimeout: parseDuration("UPSTREAM_BREAKER_OPEN_TIMEOUT", 30*time.Second),
+		},
+		Sources: SourcesConfig{
+			Chains:       loadSourceChains(),
+			StoreLimit:   parseInt("PUZZLE_STORE_LIMIT", 5000),
+			SyncInterval: parseDuration("PUZZLE_SOURCES_SYNC_INTERVAL", 10*time.Second),
+		},
+		PuzzleCache: PuzzleCacheConfig{
+			Size: parseInt("PUZZLE_CACHE_SIZE", 10000),
+			TTL:  parseDuration("PUZZLE_CACHE_TTL", 30*24*time.Hour),
+		},
+		Pools: PoolsConfig{
+			Enabled:        parseBool("POOL_ENABLED", true),
+			Size:           parseInt("POOL_SIZE", 10),
+			RefillInterval: parseDuration("POOL_REFILL_INTERVAL", 500*time.Millisecond),
+			Sources:        poolSources(),
+		},
+		Seen: SeenConfig{
+			Limit:         parseInt("SEEN_LIMIT", 1000),
+			TTL:           parseDuration("SEEN_TTL", 90*24*time.Hour),
+			MemoryViewers: parseInt("SEEN_MEMORY_VIEWERS", 10000),
+		},
+		Explain: ExplainConfig{
+			CacheTTL: parseDuration("EXPLANATION_CACHE_TTL", 30*24*time.Hour),
+		},
+		RAG: RAGConfig{
+			IndexPath: getEnv("RAG_INDEX_PATH", ""),
+			TopK:      parseCount("RAG_TOP_K", 8),
+			Embeddings: EmbeddingsConfig{
+				Provider: getEnv("EMBEDDINGS_PROVIDER", "local"),
+				Dims:     parseCount("EMBEDDINGS_DIMS", 256),
+				BaseURL:  getEnv("EMBEDDINGS_BASE_URL", ""),
+				APIKey:   getEnvOrFile("EMBEDDINGS_API_KEY", ""),
+				Model:    getEnv("EMBEDDINGS_MODEL", ""),
+				Timeout:  parseDuration("EMBEDDINGS_TIMEOUT", 10*time.Second),
+			},
+		},
+		Prompts: PromptsConfig{
+			Dir:            getEnv("PROMPTS_DIR", ""),
+			ReloadInterval: parseDuration("PROMPTS_RELOAD_INTERVAL", 30*time.Second),
+		},
+		AIUsage: AIUsageConfig{
+			Retention: parseDuration("AI_USAGE_RETENTION", 90*24*time.Hour),
+		},
+		Challenge: ChallengeConfig{
+			ScheduleInterval: parseDuration("CHALLENGE_SCHEDULE_INTERVAL", time.Hour),
+			Retention:        parseDuration("CHALLENGE_RETENTION", 8*7*24*time.Hour),
+		},
+	}
+
+	prices, err := llm.ParsePrices(os.Getenv("LLM_PRICES"))
+	if err != nil {
+		return nil, fmt.Errorf("config: invalid LLM_PRICES: %w", err)
+	}
+	cfg.LLM.Prices = prices
+	if v := os.Getenv("AI_DAILY_SPEND_LIMIT"); v != "" {
+		limit, err := strconv.ParseFloat(v, 64)
+		if err != nil || limit < 0 {
+			return nil, fmt.Errorf("config: invalid AI_DAILY_SPEND_LIMIT %q; use USD, such as 5 or 12.50", v)
+		}
+		cfg.AIUsage.DailySpendLimit = limit
+	}
+
+	for _, v := range getEnvList("SERVER_TRUSTED_PROXIES") {
+		prefix, err := netip.ParsePrefix(v)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(v)
+			if addrErr != nil {
+				return nil, fmt.Errorf("config: invalid SERVER_TRUSTED_PROXIES entry %q; use CIDRs such as 10.0.0.0/8", v)
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		cfg.Server.TrustedProxies = append(cfg.Server.TrustedProxies, prefix.Masked())
+	}
+
+	budgets, err := loadRateLimitBudgets()
+	if err != nil {
+		return nil, err
+	}
+	cfg.RateLimit.Budgets = budgets
+
+	if err := cfg.Logging.Level.UnmarshalText([]byte(getEnv("LOG_LEVEL", "info"))); err != nil {
+		return nil, fmt.Errorf("config: invalid LOG_LEVEL: %w", err)
+	}
+	levels, err := logging.ParseLevels(os.Getenv("LOG_LEVELS"))
+	if err != nil {
+		return nil, fmt.Errorf("config: invalid LOG_LEVELS: %w", err)
 	}
+	cfg.Logging.Levels = levels
 
 	if err := cfg.validate(); err != nil {
 		return nil, err
@@ -103,9 +424,47 @@ func (c *Config) validate() error {
 	if err != nil || port < 1 || port > 65535 {
 		return fmt.Errorf("config: invalid SERVER_PORT %q", c.Server.Port)
 	}
+	if c.Auth.JWTSecret != "" && len(c.Auth.JWTSecret) < 32 {
+		return fmt.Errorf("config: JWT_SECRET must be at least 32 characters")
+	}
+	if c.RAG.TopK < 1 || c.RAG.Embeddings.Dims < 1 {
+		return fmt.Errorf("config: RAG_TOP_K and EMBEDDINGS_DIMS must be at least 1")
+	}
+	switch c.RAG.Embeddings.Provider {
+	case "local", "api":
+	default:
+		return fmt.Errorf("config: invalid EMBEDDINGS_PROVIDER %q; use local or api", c.RAG.Embeddings.Provider)
+	}
+	if c.Prompts.ReloadInterval <= 0 {
+		return fmt.Errorf("config: PROMPTS_RELOAD_INTERVAL must be positive")
+	}
+	if c.AIUsage.Retention < 24*time.Hour {
+		return fmt.Errorf("config: AI_USAGE_RETENTION must be at least 24h")
+	}
 	return nil
 }
 
+// defaultRateLimits are the budgets used when RATE_LIMIT_<GROUP> is unset.
+var defaultRateLimits = map[string]string{
+	ratelimit.GroupAI:      "anon=5/m,free=5/m,pro=20/m,elite=60/m",
+	ratelimit.GroupPuzzle:  "anon=30/m,free=60/m,pro=120/m,elite=240/m",
+	ratelimit.GroupAuth:    "anon=10/m,free=10/m,pro=10/m,elite=10/m",
+	ratelimit.GroupDefault: "anon=60/m,free=120/m,pro=300/m,elite=600/m",
+}
+
+func loadRateLimitBudgets() (map[string]ratelimit.Budget, error) {
+	budgets := make(map[string]ratelimit.Budget, len(defaultRateLimits))
+	for group, fallback := range defaultRateLimits {
+		key := "RATE_LIMIT_" + strings.ToUpper(group)
+		b, err := ratelimit.ParseBudget(getEnv(key, fallback))
+		if err != nil {
+			return nil, fmt.Errorf("config: invalid %s: %w", key, err)
+		}
+		budgets[group] = b
+	}
+	return budgets, nil
+}
+
 // ---------------------------------------------------------------------------
 // helpers
 // ---------------------------------------------------------------------------
@@ -117,6 +476,31 @@ func getEnv(key, fallback string) string {
 	return fallback
 }
 
+func parseInt(key string, fallback int) int {
+	v, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || v < 1 {
+		return fallback
+	}
+	return v
+}
+
+// parseCount is parseInt for settings where 0 is meaningful.
+func parseCount(key string, fallback int) int {
+	v, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || v < 0 {
+		return fallback
+	}
+	return v
+}
+
+func parseBool(key string, fallback bool) bool {
+	v, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
 func parseDuration(key string, fallback time.Duration) time.Duration {
 	v := os.Getenv(key)
 	if v == "" {
@@ -129,6 +513,59 @@ func parseDuration(key string, fallback time.Duration) time.Duration {
 	return d
 }
 
+// loadSourceChains reads PUZZLE_SOURCES_<ENDPOINT>, e.g.
+// PUZZLE_SOURCES_RANDOM=lichess,store,huggingface.
+func loadSourceChains() map[string][]string {
+	chains := make(map[string][]string)
+	for _, endpoint := range []string{"random", "id", "daily", "dataset"} {
+		if chain := getEnvList("PUZZLE_SOURCES_" + strings.ToUpper(endpoint)); len(chain) > 0 {
+			chains[endpoint] = chain
+		}
+	}
+	return chains
+}
+
+// poolSources reads POOL_SOURCES, defaulting to every source with pools.
+func poolSources() []string {
+	if sources := getEnvList("POOL_SOURCES"); len(sources) > 0 {
+		return sources
+	}
+	return []string{"lichess", "huggingface"}
+}
+
+// llmProviders reads LLM_PROVIDERS, defaulting to NVIDIA alone.
+func llmProviders() []string {
+	if providers := getEnvList("LLM_PROVIDERS"); len(providers) > 0 {
+		return providers
+	}
+	return []string{"nvidia"}
+}
+
+// loadTaskModels reads <prefix>_MODEL_<TASK> variables, e.g.
+// NVIDIA_MODEL_FILTER=meta/llama-3.1-8b-instruct, keyed by lower-case task.
+func loadTaskModels(prefix string) map[string]string {
+	models := make(map[string]string)
+	for _, kv := range os.Environ() {
+		key, value, _ := strings.Cut(kv, "=")
+		task, ok := strings.CutPrefix(key, prefix+"_MODEL_")
+		if ok && task != "" && strings.TrimSpace(value) != "" {
+			models[strings.ToLower(task)] = strings.TrimSpace(value)
+		}
+	}
+	return models
+}
+
+// getEnvList splits a comma-separated variable, dropping empty items.
+func getEnvList(key string) []string {
+	var out []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
 func getEnvOrFile(key, fallback string) string {
 	if value := strings.TrimSpace(os.Getenv(key)); value != "" {
 		return value
@@ -0,0 +1,69 @@
+package entitlements
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// memoryCounters is a process-local CounterStore. It forgets on restart and
+// is not shared between replicas, so it is only a stand-in without Redis.
+type memoryCounters struct {
+	mu       sync.Mutex
+	counters map[string]memoryCounter
+}
+
+type memoryCounter struct {
+	n         int64
+	expiresAt time.Time
+}
+
+func newMemoryCounters() *memoryCounters {
+	return &memoryCounters{counters: make(map[string]memoryCounter)}
+}
+
+func (m *memoryCounters) IncrUsage(_ context.Context, key string, ttl time.Duration) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	c, ok := m.counters[key]
+	if !ok || now.After(c.expiresAt) {
+		m.prune(now)
+		c = memoryCounter{expiresAt: now.Add(ttl)}
+	}
+	c.n++
+	m.counters[key] = c
+	return c.n, nil
+}
+
+func (m *memoryCounters) DecrUsage(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if c, ok := m.counters[key]; ok {
+		c.n--
+		m.counters[key] = c
+	}
+	return nil
+}
+
+func (m *memoryCounters) GetUsage(_ context.Context, keys ...string) ([]int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	out := make([]int64, len(keys))
+	for i, key := range keys {
+		if c, ok := m.counters[key]; ok && !now.After(c.expiresAt) {
+			out[i] = c.n
+		}
+	}
+	return out, nil
+}
+
+// prune drops the counters of past periods.
+func (m *memoryCounters) prune(now time.Time) {
+	for key, c := range m.counters {
+		if now.After(c.expiresAt) {
+			delete(m.counters, key)
+		}
+	}
+}
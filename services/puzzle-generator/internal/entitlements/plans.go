@@ -0,0 +1,95 @@
+// Package entitlements maps subscription plans to the features and usage
+// quotas they include. The plan names match the client's lib/subscription.tsx.
+package entitlements
+
+import "time"
+
+// Plan is a subscription plan identifier.
+type Plan string
+
+const (
+	PlanFree  Plan = "free"
+	PlanPro   Plan = "pro"
+	PlanElite Plan = "elite"
+)
+
+// ParsePlan returns the plan named s, defaulting to free for unknown values.
+func ParsePlan(s string) Plan {
+	switch Plan(s) {
+	case PlanPro, PlanElite:
+		return Plan(s)
+	default:
+		return PlanFree
+	}
+}
+
+// Feature is a gated capability of the service.
+type Feature string
+
+const (
+	FeatureAIGeneration Feature = "ai_generation"
+	FeatureExplanation  Feature = "explanation"
+	FeatureStorm        Feature = "storm"
+	FeatureExports      Feature = "exports"
+)
+
+// Features lists every gated feature in display order.
+var Features = []Feature{FeatureAIGeneration, FeatureExplanation, FeatureStorm, FeatureExports}
+
+// Period is the window a quota resets over.
+type Period string
+
+const (
+	PeriodDay   Period = "day"
+	PeriodMonth Period = "month"
+)
+
+// Quota limits how often a feature can be used per period. A zero Limit
+// means unlimited use.
+type Quota struct {
+	Limit  int
+	Period Period
+}
+
+// Unlimited reports whether the quota has no cap.
+func (q Quota) Unlimited() bool { return q.Limit == 0 }
+
+// planFeatures is the entitlement table. A feature missing from a plan is
+// not available on that plan at all.
+var planFeatures = map[Plan]map[Feature]Quota{
+	PlanFree: {
+		FeatureStorm: {Limit: 3, Period: PeriodDay},
+	},
+	PlanPro: {
+		FeatureAIGeneration: {Limit: 20, Period: PeriodDay},
+		FeatureExplanation:  {Limit: 30, Period: PeriodDay},
+		FeatureStorm:        {Period: PeriodDay},
+	},
+	PlanElite: {
+		FeatureAIGeneration: {Period: PeriodDay},
+		FeatureExplanation:  {Period: PeriodDay},
+		FeatureStorm:        {Period: PeriodDay},
+		FeatureExports:      {Limit: 50, Period: PeriodMonth},
+	},
+}
+
+// Lookup returns the quota of feature on plan and whether the plan includes
+// the feature at all.
+func Lookup(plan Plan, feature Feature) (Quota, bool) {
+	q, ok := planFeatures[plan][feature]
+	return q, ok
+}
+
+// periodWindow returns a stable key for the period containing t and the time
+// the period resets.
+func periodWindow(p Period, t time.Time) (key string, resetsAt time.Time) {
+	t = t.UTC()
+	switch p {
+	case PeriodMonth:
+		start := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return start.Format("2006-01"), start.AddDate(0, 1, 0)
+	default:
+		start := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		return start.Format("2006-01-02"), start.AddDate(0, 0, 1)
+	}
+}
@@ -0,0 +1,152 @@
+package entitlements
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/chess-puzzle-next/puzzle-generator/internal/models"
+	"github.com/chess-puzzle-next/puzzle-generator/pkg/redis"
+)
+
+// UserStore looks up the stored plan of a user.
+type UserStore interface {
+	GetUser(ctx context.Context, userID string) (*redis.User, error)
+}
+
+// CounterStore keeps per-period usage counters.
+type CounterStore interface {
+	IncrUsage(ctx context.Context, key string, ttl time.Duration) (int64, error)
+	DecrUsage(ctx context.Context, key string) error
+	GetUsage(ctx context.Context, keys ...string) ([]int64, error)
+}
+
+var (
+	ErrNotEntitled   = errors.New("entitlements: feature not included in plan")
+	ErrQuotaExceeded = errors.New("entitlements: quota exhausted for this period")
+)
+
+// Usage is a user's consumption of one feature in the current period.
+type Usage struct {
+	Feature  Feature
+	Quota    Quota
+	Used     int
+	ResetsAt time.Time
+}
+
+// Remaining returns how many uses are left, or -1 when unlimited.
+func (u *Usage) Remaining() int {
+	if u.Quota.Unlimited() {
+		return -1
+	}
+	if left := u.Quota.Limit - u.Used; left > 0 {
+		return left
+	}
+	return 0
+}
+
+// Service resolves plans and enforces quotas.
+type Service struct {
+	users    UserStore
+	counters CounterStore
+	now      func() time.Time
+}
+
+// New returns an entitlements Service. counters may be nil, in which case
+// usage is counted in process memory only: quotas are still enforced but
+// reset on restart and apply per replica.
+func New(users UserStore, counters CounterStore) *Service {
+	if counters == nil {
+		counters = newMemoryCounters()
+	}
+	return &Service{users: users, counters: counters, now: time.Now}
+}
+
+// PlanOf returns the current plan of a user. The stored account is the
+// source of truth because token claims go stale when a plan changes or a
+// subscription lapses; claimed is used when the account cannot be read.
+func (s *Service) PlanOf(ctx context.Context, userID, claimed string) Plan {
+	user, err := s.users.GetUser(ctx, userID)
+	if err != nil || user == nil {
+		return ParsePlan(claimed)
+	}
+	return EffectivePlan(user, s.now())
+}
+
+// Consume records one use of feature. It returns ErrNotEntitled when the
+// plan does not include the feature and ErrQuotaExceeded when the period's
+// quota is used up; in the latter case the returned Usage is still set.
+func (s *Service) Consume(ctx context.Context, userID string, plan Plan, feature Feature) (*Usage, error) {
+	quota, ok := Lookup(plan, feature)
+	if !ok {
+		return nil, ErrNotEntitled
+	}
+
+	window, resetsAt := periodWindow(quota.Period, s.now())
+	key := usageKey(userID, feature, window)
+	n, err := s.counters.IncrUsage(ctx, key, resetsAt.Sub(s.now())+time.Hour)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &Usage{Feature: feature, Quota: quota, Used: int(n), ResetsAt: resetsAt}
+	if !quota.Unlimited() && usage.Used > quota.Limit {
+		_ = s.counters.DecrUsage(ctx, key)
+		usage.Used = quota.Limit
+		return usage, ErrQuotaExceeded
+	}
+	return usage, nil
+}
+
+// Refund gives back one use of feature in the current period, for requests
+// that failed after their quota was consumed.
+func (s *Service) Refund(ctx context.Context, userID string, plan Plan, feature Feature) {
+	quota, ok := Lookup(plan, feature)
+	if !ok {
+		return
+	}
+	window, _ := periodWindow(quota.Period, s.now())
+	_ = s.counters.DecrUsage(ctx, usageKey(userID, feature, window))
+}
+
+// Summary lists every feature with its availability and current usage.
+func (s *Service) Summary(ctx context.Context, userID string, plan Plan) (*models.EntitlementsResponse, error) {
+	resp := &models.EntitlementsResponse{Plan: string(plan)}
+
+	var keys []string
+	var idx []int
+	for _, f := range Features {
+		item := models.FeatureEntitlement{Feature: string(f)}
+		if quota, ok := Lookup(plan, f); ok {
+			window, resetsAt := periodWindow(quota.Period, s.now())
+			item.Enabled = true
+			item.Unlimited = quota.Unlimited()
+			item.Limit = quota.Limit
+			item.Period = string(quota.Period)
+			item.ResetsAt = &resetsAt
+			keys = append(keys, usageKey(userID, f, window))
+			idx = append(idx, len(resp.Features))
+		}
+		resp.Features = append(resp.Features, item)
+	}
+
+	if len(keys) == 0 {
+		return resp, nil
+	}
+	counts, err := s.counters.GetUsage(ctx, keys...)
+	if err != nil {
+		return nil, err
+	}
+	for i, n := range counts {
+		item := &resp.Features[idx[i]]
+		item.Used = int(n)
+		if !item.Unlimited {
+			item.Remaining = max(item.Limit-item.Used, 0)
+		}
+	}
+	return resp, nil
+}
+
+func usageKey(userID string, feature Feature, window string) string {
+	return userID + ":" + string(feature) + ":" + window
+}
@@ -0,0 +1,37 @@
+package entitlements
+
+import (
+	"time"
+
+	"github.com/chess-puzzle-next/puzzle-generator/pkg/redis"
+)
+
+// Status is the billing state of a paid subscription.
+type Status string
+
+const (
+	StatusActive     Status = "active"
+	StatusTrialing   Status = "trialing"
+	StatusPastDue    Status = "past_due"
+	StatusIncomplete Status = "incomplete" // first payment not made yet
+	StatusCanceled   Status = "canceled"
+)
+
+// EffectivePlan returns the plan a user is entitled to at now. A canceled or
+// incomplete subscription falls back to free, and so does a past-due one once
+// its grace period has run out. Users without billing state keep their stored
+// plan.
+func EffectivePlan(u *redis.User, now time.Time) Plan {
+	if u == nil {
+		return PlanFree
+	}
+	switch Status(u.PlanStatus) {
+	case StatusCanceled, StatusIncomplete:
+		return PlanFree
+	case StatusPastDue:
+		if u.GraceUntil == nil || now.After(*u.GraceUntil) {
+			return PlanFree
+		}
+	}
+	return ParsePlan(u.Plan)
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/chess-puzzle-next/puzzle-generator/internal/apikeys"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/middleware"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/models"
+	"github.com/labstack/echo/v4"
+)
+
+// APIKeyHandler lets admins manage developer API keys.
+type APIKeyHandler struct {
+	svc *apikeys.Service
+}
+
+// NewAPIKeyHandler constructs an APIKeyHandler. svc may be nil when Redis is
+// unavailable, in which case every route answers 503.
+func NewAPIKeyHandler(svc *apikeys.Service) *APIKeyHandler {
+	return &APIKeyHandler{svc: svc}
+}
+
+// Register mounts admin key routes onto the given Echo group.
+func (h *APIKeyHandler) Register(g *echo.Group) {
+	admin := middleware.RequireAdmin()
+	g.POST("/admin/keys", h.CreateKey, admin)
+	g.GET("/admin/keys", h.ListKeys, admin)
+	g.GET("/admin/keys/:id", h.GetKey, admin)
+	g.DELETE("/admin/keys/:id", h.RevokeKey, admin)
+}
+
+// CreateKey handles POST /admin/keys
+// @Summary Issue API key
+// @Description Creates a developer API key. The key is only returned in this response; the server stores a hash. Scopes: puzzles:read, sessions:write, ai and webhooks (both require ownerId).
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.CreateAPIKeyRequest true "Key settings"
+// @Success 201 {object} models.APIKeyCreated
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /admin/keys [post]
+func (h *APIKeyHandler) CreateKey(c echo.Context) error {
+	if h.svc == nil {
+		return apiKeysUnavailable(c)
+	}
+	var req models.CreateAPIKeyRequest
+	if err := c.Bind(&req); err != nil {
+		return invalidJSON(c)
+	}
+	created, err := h.svc.Issue(c.Request().Context(), middleware.CurrentUser(c).Email, req)
+	if err != nil {
+		return h.handleError(c, err)
+	}
+	return c.JSON(http.StatusCreated, created)
+}
+
+// ListKeys handles GET /admin/keys
+// @Summary List API keys
+// @Description Lists every API key, newest first, with today's usage
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.APIKeyInfo
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /admin/keys [get]
+func (h *APIKeyHandler) ListKeys(c echo.Context) error {
+	if h.svc == nil {
+		return apiKeysUnavailable(c)
+	}
+	keys, err := h.svc.List(c.Request().Context())
+	if err != nil {
+		return h.handleError(c, err)
+	}
+	return c.JSON(http.StatusOK, keys)
+}
+
+// GetKey handles GET /admin/keys/:id
+// @Summary Get API key
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Key ID"
+// @Success 200 {object} models.APIKeyInfo
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /admin/keys/{id} [get]
+func (h *APIKeyHandler) GetKey(c echo.Context) error {
+	if h.svc == nil {
+		return apiKeysUnavailable(c)
+	}
+	key, err := h.svc.Get(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return h.handleError(c, err)
+	}
+	return c.JSON(http.StatusOK, key)
+}
+
+// RevokeKey handles DELETE /admin/keys/:id
+// @Summary Revoke API key
+// @Description Revokes a key immediately. The record is kept for auditing.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Key ID"
+// @Success 200 {object} models.APIKeyInfo
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /admin/keys/{id} [delete]
+func (h *APIKeyHandler) RevokeKey(c echo.Context) error {
+	if h.svc == nil {
+		return apiKeysUnavailable(c)
+	}
+	key, err := h.svc.Revoke(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return h.handleError(c, err)
+	}
+	return c.JSON(http.StatusOK, key)
+}
+
+func (h *APIKeyHandler) handleError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, apikeys.ErrInvalidRequest):
+		return c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "invalid request", Details: err.Error()})
+	case errors.Is(err, apikeys.ErrKeyNotFound):
+		return c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "API key not found"})
+	default:
+		logger.ErrorContext(c.Request().Context(), "api keys", "err", err)
+		return c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "internal error"})
+	}
+}
+
+func apiKeysUnavailable(c echo.Context) error {
+	return c.JSON(http.StatusServiceUnavailable, models.ErrorResponse{
+		Error:   "api keys unavailable",
+		Details: "API keys require Redis",
+	})
+}
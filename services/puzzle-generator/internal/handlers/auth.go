@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/chess-puzzle-next/puzzle-generator/internal/auth"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/middleware"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/models"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/services"
+	"github.com/labstack/echo/v4"
+)
+
+// AuthHandler serves account registration, login and token refresh.
+type AuthHandler struct {
+	svc *services.AuthService
+}
+
+// NewAuthHandler constructs an AuthHandler. svc may be nil when Redis is
+// unavailable, in which case every route answers 503.
+func NewAuthHandler(svc *services.AuthService) *AuthHandler {
+	return &AuthHandler{svc: svc}
+}
+
+// Register mounts auth routes onto the given Echo group.
+func (h *AuthHandler) Register(g *echo.Group) {
+	g.POST("/auth/register", h.RegisterUser)
+	g.POST("/auth/login", h.Login)
+	g.POST("/auth/refresh", h.Refresh)
+	g.POST("/auth/logout", h.Logout)
+	g.GET("/me", h.Me, middleware.RequireAuth())
+}
+
+// RegisterUser handles POST /auth/register
+// @Summary Register
+// @Description Creates an account on the free plan and returns an access/refresh token pair
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body models.RegisterRequest true "Credentials"
+// @Success 201 {object} models.AuthResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /auth/register [post]
+func (h *AuthHandler) RegisterUser(c echo.Context) error {
+	if h.svc == nil {
+		return authUnavailable(c)
+	}
+	var req models.RegisterRequest
+	if err := c.Bind(&req); err != nil {
+		return invalidJSON(c)
+	}
+	resp, err := h.svc.Register(c.Request().Context(), req)
+	if err != nil {
+		return h.handleError(c, err)
+	}
+	return c.JSON(http.StatusCreated, resp)
+}
+
+// Login handles POST /auth/login
+// @Summary Log in
+// @Description Verifies email and password and returns an access/refresh token pair
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body models.LoginRequest true "Credentials"
+// @Success 200 {object} models.AuthResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /auth/login [post]
+func (h *AuthHandler) Login(c echo.Context) error {
+	if h.svc == nil {
+		return authUnavailable(c)
+	}
+	var req models.LoginRequest
+	if err := c.Bind(&req); err != nil {
+		return invalidJSON(c)
+	}
+	resp, err := h.svc.Login(c.Request().Context(), req)
+	if err != nil {
+		return h.handleError(c, err)
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// Refresh handles POST /auth/refresh
+// @Summary Refresh tokens
+// @Description Exchanges a refresh token for a new token pair. Each refresh token can be used once.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body models.RefreshRequest true "Refresh token"
+// @Success 200 {object} models.AuthResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /auth/refresh [post]
+func (h *AuthHandler) Refresh(c echo.Context) error {
+	if h.svc == nil {
+		return authUnavailable(c)
+	}
+	var req models.RefreshRequest
+	if err := c.Bind(&req); err != nil {
+		return invalidJSON(c)
+	}
+	resp, err := h.svc.Refresh(c.Request().Context(), strings.TrimSpace(req.RefreshToken))
+	if err != nil {
+		return h.handleError(c, err)
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// Logout handles POST /auth/logout
+// @Summary Log out
+// @Description Revokes a refresh token
+// @Tags auth
+// @Accept json
+// @Param request body models.RefreshRequest true "Refresh token"
+// @Success 204
+// @Failure 503 {object} models.ErrorResponse
+// @Router /auth/logout [post]
+func (h *AuthHandler) Logout(c echo.Context) error {
+	if h.svc == nil {
+		return authUnavailable(c)
+	}
+	var req models.RefreshRequest
+	if err := c.Bind(&req); err != nil {
+		return invalidJSON(c)
+	}
+	if err := h.svc.Logout(c.Request().Context(), strings.TrimSpace(req.RefreshToken)); err != nil {
+		return h.handleError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// Me handles GET /me
+// @Summary Current user
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.UserProfile
+// @Failure 401 {object} models.ErrorResponse
+// @Router /me [get]
+func (h *AuthHandler) Me(c echo.Context) error {
+	if h.svc == nil {
+		return authUnavailable(c)
+	}
+	profile, err := h.svc.Profile(c.Request().Context(), middleware.CurrentUser(c).ID)
+	if err != nil {
+		return h.handleError(c, err)
+	}
+	return c.JSON(http.StatusOK, profile)
+}
+
+func (h *AuthHandler) handleError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, services.ErrEmailTaken):
+		return c.JSON(http.StatusConflict, models.ErrorResponse{Error: "email already registered"})
+	case errors.Is(err, services.ErrInvalidCredentials):
+		return c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "unauthorized", Details: "Invalid email or password"})
+	case errors.Is(err, auth.ErrInvalidToken), errors.Is(err, services.ErrUserNotFound):
+		return c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "unauthorized", Details: "Token is invalid or expired"})
+	case strings.Contains(err.Error(), "invalid email"),
+		strings.Contains(err.Error(), "password must be"):
+		return c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "invalid request", Details: err.Error()})
+	}
+
+	logger.ErrorContext(c.Request().Context(), "auth error", "err", err)
+	return c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "internal error"})
+}
+
+func invalidJSON(c echo.Context) error {
+	return c.JSON(http.StatusBadRequest, models.ErrorResponse{
+		Error:   "invalid request",
+		Details: "invalid JSON body",
+	})
+}
+
+func authUnavailable(c echo.Context) error {
+	return c.JSON(http.StatusServiceUnavailable, models.ErrorResponse{
+		Error:   "auth unavailable",
+		Details: "Accounts require Redis",
+	})
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/chess-puzzle-next/puzzle-generator/internal/billing"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/models"
+	"github.com/labstack/echo/v4"
+)
+
+// maxWebhookBody caps webhook payloads; provider events are a few KB.
+const maxWebhookBody = 1 << 20
+
+// BillingHandler receives subscription lifecycle webhooks.
+type BillingHandler struct {
+	svc *billing.Service
+}
+
+// NewBillingHandler constructs a BillingHandler. svc may be nil when Redis
+// or the webhook secret is missing, in which case deliveries answer 503 and
+// the provider retries them later.
+func NewBillingHandler(svc *billing.Service) *BillingHandler {
+	return &BillingHandler{svc: svc}
+}
+
+// Register mounts billing routes onto the given Echo group.
+func (h *BillingHandler) Register(g *echo.Group) {
+	g.POST("/billing/webhook", h.Webhook)
+}
+
+// Webhook handles POST /billing/webhook
+// @Summary Billing webhook
+// @Description Receives Stripe-compatible subscription events (checkout completed, subscription updated/deleted, invoice payment failed). The raw body must be signed in the Stripe-Signature header.
+// @Tags billing
+// @Accept json
+// @Produce json
+// @Param Stripe-Signature header string true "t=<unix>,v1=<hex HMAC-SHA256 of t.payload>"
+// @Success 200 {object} models.WebhookAck
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /billing/webhook [post]
+func (h *BillingHandler) Webhook(c echo.Context) error {
+	if h.svc == nil {
+		return c.JSON(http.StatusServiceUnavailable, models.ErrorResponse{Error: "billing is not configured"})
+	}
+
+	payload, err := io.ReadAll(io.LimitReader(c.Request().Body, maxWebhookBody+1))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "failed to read body"})
+	}
+	if len(payload) > maxWebhookBody {
+		return c.JSON(http.StatusRequestEntityTooLarge, models.ErrorResponse{Error: "payload too large"})
+	}
+
+	err = h.svc.HandleWebhook(c.Request().Context(), payload, c.Request().Header.Get(billing.SignatureHeader))
+	switch {
+	case err == nil:
+		return c.JSON(http.StatusOK, models.WebhookAck{Received: true})
+	case errors.Is(err, billing.ErrMissingSignature),
+		errors.Is(err, billing.ErrInvalidSignature),
+		errors.Is(err, billing.ErrStaleSignature):
+		logger.WarnContext(c.Request().Context(), "billing webhook rejected", "err", err)
+		return c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "invalid signature"})
+	case errors.Is(err, billing.ErrMalformedEvent):
+		return c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "malformed event"})
+	default:
+		logger.ErrorContext(c.Request().Context(), "billing webhook", "err", err)
+		return c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "failed to process event"})
+	}
+}
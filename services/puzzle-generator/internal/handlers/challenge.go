@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/chess-puzzle-next/puzzle-generator/internal/middleware"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/models"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/services"
+	"github.com/labstack/echo/v4"
+)
+
+// ChallengeHandler serves the weekly themed challenge.
+type ChallengeHandler struct {
+	svc *services.ChallengeService
+}
+
+// NewChallengeHandler constructs a ChallengeHandler. svc may be nil when
+// Redis is unavailable, in which case every route answers 503.
+func NewChallengeHandler(svc *services.ChallengeService) *ChallengeHandler {
+	return &ChallengeHandler{svc: svc}
+}
+
+// Register mounts challenge routes onto the given Echo group.
+func (h *ChallengeHandler) Register(g *echo.Group) {
+	g.GET("/challenge/weekly", h.GetCurrent)
+	g.GET("/challenge/weekly/progress", h.GetProgress)
+	g.POST("/challenge/weekly/attempts", h.SubmitAttempt)
+	g.GET("/challenge/weekly/:id", h.GetByWeek)
+	g.GET("/challenge/weekly/:id/results", h.GetResults)
+}
+
+// GetCurrent handles GET /challenge/weekly
+// @Summary Current weekly challenge
+// @Description Returns this week's themed challenge: 10 puzzles of one theme spread across rating bands
+// @Tags challenge
+// @Produce json
+// @Success 200 {object} models.WeeklyChallenge
+// @Failure 502 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /challenge/weekly [get]
+func (h *ChallengeHandler) GetCurrent(c echo.Context) error {
+	if h.svc == nil {
+		return challengeUnavailable(c)
+	}
+	ch, err := h.svc.Current(c.Request().Context())
+	if err != nil {
+		return h.handleError(c, err)
+	}
+	return c.JSON(http.StatusOK, ch)
+}
+
+// GetByWeek handles GET /challenge/weekly/:id
+// @Summary Weekly challenge by week
+// @Description Returns the challenge published for an ISO week, e.g. 2026-W42
+// @Tags challenge
+// @Produce json
+// @Param id path string true "ISO week"
+// @Success 200 {object} models.WeeklyChallenge
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /challenge/weekly/{id} [get]
+func (h *ChallengeHandler) GetByWeek(c echo.Context) error {
+	if h.svc == nil {
+		return challengeUnavailable(c)
+	}
+	ch, err := h.svc.Get(c.Request().Context(), strings.TrimSpace(c.Param("id")))
+	if err != nil {
+		return h.handleError(c, err)
+	}
+	return c.JSON(http.StatusOK, ch)
+}
+
+// GetProgress handles GET /challenge/weekly/progress?playerId=
+// @Summary Player progress in the weekly challenge
+// @Tags challenge
+// @Produce json
+// @Param playerId query string false "Player identifier (ignored when signed in)"
+// @Success 200 {object} models.ChallengeEntry
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /challenge/weekly/progress [get]
+func (h *ChallengeHandler) GetProgress(c echo.Context) error {
+	if h.svc == nil {
+		return challengeUnavailable(c)
+	}
+	entry, err := h.svc.Progress(c.Request().Context(), challengePlayer(c, c.QueryParam("playerId")))
+	if err != nil {
+		return h.handleError(c, err)
+	}
+	return c.JSON(http.StatusOK, entry)
+}
+
+// SubmitAttempt handles POST /challenge/weekly/attempts
+// @Summary Submit a weekly challenge attempt
+// @Description Records a solved or failed attempt at one challenge puzzle. Only the first attempt at each puzzle is scored, and a solve needs the player's moves, which must be the solution. Signed-in users are scored under their account; anonymous player IDs are kept apart from account IDs.
+// @Tags challenge
+// @Accept json
+// @Produce json
+// @Param request body models.ChallengeAttemptRequest true "Attempt"
+// @Success 200 {object} models.ChallengeEntry
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /challenge/weekly/attempts [post]
+func (h *ChallengeHandler) SubmitAttempt(c echo.Context) error {
+	if h.svc == nil {
+		return challengeUnavailable(c)
+	}
+	var req models.ChallengeAttemptRequest
+	if err := c.Bind(&req); err != nil {
+		return invalidJSON(c)
+	}
+	req.PlayerID = challengePlayer(c, req.PlayerID)
+	entry, err := h.svc.SubmitAttempt(c.Request().Context(), req)
+	if err != nil {
+		return h.handleError(c, err)
+	}
+	return c.JSON(http.StatusOK, entry)
+}
+
+// GetResults handles GET /challenge/weekly/:id/results
+// @Summary Weekly challenge results
+// @Description Returns the results table of a challenge once its week has closed
+// @Tags challenge
+// @Produce json
+// @Param id path string true "ISO week"
+// @Success 200 {object} models.ChallengeResults
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /challenge/weekly/{id}/results [get]
+func (h *ChallengeHandler) GetResults(c echo.Context) error {
+	if h.svc == nil {
+		return challengeUnavailable(c)
+	}
+	results, err := h.svc.Results(c.Request().Context(), strings.TrimSpace(c.Param("id")))
+	if err != nil {
+		return h.handleError(c, err)
+	}
+	return c.JSON(http.StatusOK, results)
+}
+
+func (h *ChallengeHandler) handleError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, services.ErrChallengeNotFound):
+		return c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "challenge not found"})
+	case errors.Is(err, services.ErrChallengeOpen):
+		return c.JSON(http.StatusConflict, models.ErrorResponse{Error: "challenge still open", Details: err.Error()})
+	case errors.Is(err, services.ErrChallengeAttempted):
+		return c.JSON(http.StatusConflict, models.ErrorResponse{Error: "already attempted", Details: err.Error()})
+	case errors.Is(err, services.ErrChallengePuzzleUnknown),
+		errors.Is(err, services.ErrChallengeMoves),
+		strings.Contains(err.Error(), "playerId is required"):
+		return c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "invalid request", Details: err.Error()})
+	}
+
+	logger.ErrorContext(c.Request().Context(), "challenge error", "err", err)
+	return c.JSON(http.StatusBadGateway, models.ErrorResponse{
+		Error:   "upstream error",
+		Details: err.Error(),
+	})
+}
+
+// challengePlayer returns the player ID a caller plays under: their account
+// when signed in, otherwise the ID they chose, namespaced so it can never
+// match an account.
+func challengePlayer(c echo.Context, chosen string) string {
+	if user := middleware.CurrentUser(c); user != nil {
+		return user.ID
+	}
+	return services.AnonymousPlayerID(chosen)
+}
+
+func challengeUnavailable(c echo.Context) error {
+	return c.JSON(http.StatusServiceUnavailable, models.ErrorResponse{
+		Error:   "challenge unavailable",
+		Details: "Weekly challenge requires Redis",
+	})
+}
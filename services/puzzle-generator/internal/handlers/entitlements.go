@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/chess-puzzle-next/puzzle-generator/internal/entitlements"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/middleware"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/models"
+	"github.com/labstack/echo/v4"
+)
+
+// EntitlementsHandler reports what the caller's plan includes.
+type EntitlementsHandler struct {
+	ent *entitlements.Service
+}
+
+// NewEntitlementsHandler constructs an EntitlementsHandler.
+func NewEntitlementsHandler(ent *entitlements.Service) *EntitlementsHandler {
+	return &EntitlementsHandler{ent: ent}
+}
+
+// Register mounts entitlement routes onto the given Echo group.
+func (h *EntitlementsHandler) Register(g *echo.Group) {
+	g.GET("/me/entitlements", h.GetEntitlements, middleware.RequireAuth())
+}
+
+// GetEntitlements handles GET /me/entitlements
+// @Summary Plan entitlements
+// @Description Lists the features of the caller's plan with their quotas and usage in the current period
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.EntitlementsResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /me/entitlements [get]
+func (h *EntitlementsHandler) GetEntitlements(c echo.Context) error {
+	user := middleware.CurrentUser(c)
+	ctx := c.Request().Context()
+
+	resp, err := h.ent.Summary(ctx, user.ID, h.ent.PlanOf(ctx, user.ID, user.Plan))
+	if err != nil {
+		logger.ErrorContext(ctx, "entitlements summary", "err", err)
+		return c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "failed to load entitlements"})
+	}
+	return c.JSON(http.StatusOK, resp)
+}
@@ -1,25 +1,86 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/chess-puzzle-next/puzzle-generator/internal/aiusage"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/logging"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/middleware"
 	"github.com/chess-puzzle-next/puzzle-generator/internal/models"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/services"
+	"github.com/chess-puzzle-next/puzzle-generator/pkg/llm"
+	"github.com/chess-puzzle-next/puzzle-generator/pkg/resilient"
 	"github.com/labstack/echo/v4"
 )
 
+var logger = logging.For("handlers")
+
 func (h *PuzzleHandler) handleServiceError(c echo.Context, err error) error {
-	c.Logger().Errorf("service error: %v", err)
+	logger.ErrorContext(c.Request().Context(), "service error", "err", err)
+	status, resp := serviceErrorResponse(err)
+	var noMatch *services.NoMatchError
+	if errors.As(err, &noMatch) {
+		// The user got no puzzle, so the AI generation is not charged.
+		middleware.FailFeature(c)
+	}
+	if errors.Is(err, aiusage.ErrSpendLimit) {
+		now := time.Now().UTC()
+		midnight := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+		c.Response().Header().Set("Retry-After", strconv.Itoa(int(midnight.Sub(now).Seconds())+1))
+	}
+	return c.JSON(status, resp)
+}
+
+// serviceErrorResponse maps a puzzle service error to an HTTP status and body.
+func serviceErrorResponse(err error) (int, models.ErrorResponse) {
 	if isValidationError(err) {
-		return c.JSON(http.StatusBadRequest, models.ErrorResponse{
+		return http.StatusBadRequest, models.ErrorResponse{
 			Error:   "invalid request",
 			Details: err.Error(),
-		})
+		}
+	}
+	var noMatch *services.NoMatchError
+	if errors.As(err, &noMatch) {
+		return http.StatusNotFound, models.ErrorResponse{
+			Error:   "no matching puzzle",
+			Details: "no puzzle matches the filter; loosen it and send it back as filter",
+			Filter:  noMatch.Filter,
+		}
 	}
-	return c.JSON(http.StatusBadGateway, models.ErrorResponse{
+	if errors.Is(err, services.ErrNoSource) {
+		return http.StatusServiceUnavailable, models.ErrorResponse{
+			Error:   "no puzzle source available",
+			Details: err.Error(),
+		}
+	}
+	if errors.Is(err, aiusage.ErrSpendLimit) {
+		return http.StatusServiceUnavailable, models.ErrorResponse{
+			Error:   "AI paused",
+			Details: "the daily AI spend limit is reached; AI features resume at 00:00 UTC",
+		}
+	}
+	if errors.Is(err, llm.ErrNotConfigured) {
+		return http.StatusServiceUnavailable, models.ErrorResponse{
+			Error:   "AI unavailable",
+			Details: "no LLM provider is configured",
+		}
+	}
+	if errors.Is(err, resilient.ErrCircuitOpen) {
+		// The upstream is failing and calls to it are paused; retrying
+		// immediately will not help.
+		return http.StatusServiceUnavailable, models.ErrorResponse{
+			Error:   "upstream unavailable",
+			Details: err.Error(),
+		}
+	}
+	return http.StatusBadGateway, models.ErrorResponse{
 		Error:   "upstream error",
 		Details: err.Error(),
-	})
+	}
 }
 
 func isValidationError(err error) bool {
@@ -27,5 +88,7 @@ func isValidationError(err error) bool {
 	return strings.Contains(msg, "unknown difficulty") ||
 		strings.Contains(msg, "invalid ID format") ||
 		strings.Contains(msg, "prompt is required") ||
-		strings.Contains(msg, "prompt must be")
+		strings.Contains(msg, "prompt must be") ||
+		strings.Contains(msg, "invalid filter") ||
+		strings.Contains(msg, "invalid language")
 }
@@ -2,7 +2,10 @@ package handlers
 
 import (
 	"net/http"
+	"time"
 
+	"github.com/chess-puzzle-next/puzzle-generator/internal/buildinfo"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/health"
 	"github.com/labstack/echo/v4"
 )
 
@@ -11,6 +14,7 @@ type HealthResponse struct {
 	Status  string `json:"status"`
 	Service string `json:"service"`
 	Version string `json:"version"`
+	Commit  string `json:"commit"`
 }
 
 // RootResponse is the response body for the root endpoint.
@@ -21,6 +25,76 @@ type RootResponse struct {
 	Hint    string `json:"hint"`
 }
 
+// LivenessResponse is the response body for GET /livez.
+type LivenessResponse struct {
+	Status        string         `json:"status"`
+	Service       string         `json:"service"`
+	Build         buildinfo.Info `json:"build"`
+	UptimeSeconds int64          `json:"uptimeSeconds"`
+}
+
+// ReadinessResponse is the response body for GET /readyz.
+type ReadinessResponse struct {
+	Status       string               `json:"status" example:"degraded"` // ok, degraded or unavailable
+	Service      string               `json:"service"`
+	Build        buildinfo.Info       `json:"build"`
+	Dependencies []health.CheckResult `json:"dependencies"`
+}
+
+const serviceName = "puzzle-generator"
+
+// HealthHandler serves the liveness and readiness probes.
+type HealthHandler struct {
+	checker *health.Checker
+	started time.Time
+}
+
+// NewHealthHandler creates a HealthHandler reporting the dependencies of
+// checker.
+func NewHealthHandler(checker *health.Checker) *HealthHandler {
+	return &HealthHandler{checker: checker, started: time.Now()}
+}
+
+// Livez handles GET /livez. It only reports that the process serves HTTP;
+// dependencies never fail it, so an upstream outage does not restart pods.
+// @Summary Liveness probe
+// @Description Reports that the process is up, with its build version and commit
+// @Tags health
+// @Produce json
+// @Success 200 {object} handlers.LivenessResponse
+// @Router /livez [get]
+func (h *HealthHandler) Livez(c echo.Context) error {
+	return c.JSON(http.StatusOK, LivenessResponse{
+		Status:        "ok",
+		Service:       serviceName,
+		Build:         buildinfo.Get(),
+		UptimeSeconds: int64(time.Since(h.started).Seconds()),
+	})
+}
+
+// Readyz handles GET /readyz. It answers 503 only when a dependency the
+// service cannot do without is down; a degraded service stays ready.
+// @Summary Readiness probe
+// @Description Checks Redis, Lichess, the HuggingFace dataset and the AI provider (results cached briefly). Each dependency reports whether the service degrades gracefully without it.
+// @Tags health
+// @Produce json
+// @Success 200 {object} handlers.ReadinessResponse
+// @Failure 503 {object} handlers.ReadinessResponse
+// @Router /readyz [get]
+func (h *HealthHandler) Readyz(c echo.Context) error {
+	status, deps := h.checker.Run(c.Request().Context())
+	code := http.StatusOK
+	if status == health.ReadyUnavailable {
+		code = http.StatusServiceUnavailable
+	}
+	return c.JSON(code, ReadinessResponse{
+		Status:       status,
+		Service:      serviceName,
+		Build:        buildinfo.Get(),
+		Dependencies: deps,
+	})
+}
+
 // Health handles GET /health – used by Docker and load balancers.
 // @Summary Service health
 // @Description Health probe endpoint
@@ -29,10 +103,12 @@ type RootResponse struct {
 // @Success 200 {object} handlers.HealthResponse
 // @Router /health [get]
 func Health(c echo.Context) error {
+	info := buildinfo.Get()
 	return c.JSON(http.StatusOK, HealthResponse{
 		Status:  "ok",
-		Service: "puzzle-generator",
-		Version: "0.1.0",
+		Service: serviceName,
+		Version: info.Version,
+		Commit:  info.Commit,
 	})
 }
 
@@ -40,8 +116,8 @@ func Health(c echo.Context) error {
 func Root(c echo.Context) error {
 	return c.JSON(http.StatusOK, RootResponse{
 		Status:  "ok",
-		Service: "puzzle-generator",
-		Version: "0.1.0",
+		Service: serviceName,
+		Version: buildinfo.Get().Version,
 		Hint:    "Use /api/v1 for API endpoints or /swagger/index.html for docs",
 	})
 }
@@ -2,9 +2,11 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 	"strings"
 
+	"github.com/chess-puzzle-next/puzzle-generator/internal/entitlements"
 	"github.com/chess-puzzle-next/puzzle-generator/internal/middleware"
 	"github.com/chess-puzzle-next/puzzle-generator/internal/models"
 	"github.com/labstack/echo/v4"
@@ -16,17 +18,21 @@ type puzzleProvider interface {
 	GetByID(ctx context.Context, id string) (*models.Puzzle, error)
 	GetDaily(ctx context.Context) (*models.Puzzle, error)
 	GenerateFromAI(ctx context.Context, req models.AIPuzzleRequest) (*models.Puzzle, error)
+	GenerateFromAIStream(ctx context.Context, req models.AIPuzzleRequest, progress func(models.AIProgressEvent)) (*models.Puzzle, error)
 	GenerateFromDataset(ctx context.Context, difficulty models.DifficultyLevel) (*models.Puzzle, error)
+	ExplainPuzzle(ctx context.Context, id, lang string) (*models.PuzzleExplanation, error)
+	ExportPGN(ctx context.Context, id string) (string, error)
 }
 
 // PuzzleHandler groups all puzzle-related HTTP handlers.
 type PuzzleHandler struct {
 	svc puzzleProvider
+	ent *entitlements.Service
 }
 
 // NewPuzzleHandler constructs a PuzzleHandler.
-func NewPuzzleHandler(svc puzzleProvider) *PuzzleHandler {
-	return &PuzzleHandler{svc: svc}
+func NewPuzzleHandler(svc puzzleProvider, ent *entitlements.Service) *PuzzleHandler {
+	return &PuzzleHandler{svc: svc, ent: ent}
 }
 
 // Register mounts all puzzle routes onto the given Echo group.
@@ -36,20 +42,26 @@ func (h *PuzzleHandler) Register(g *echo.Group) {
 	g.GET("/puzzle/:id", h.GetPuzzleByID)
 
 	// AI puzzle generation is a premium feature
-	g.POST("/puzzle/ai", h.GeneratePuzzleFromAI, middleware.PremiumCheck())
+	g.POST("/puzzle/ai", h.GeneratePuzzleFromAI, middleware.RequireFeature(h.ent, entitlements.FeatureAIGeneration))
+	g.GET("/puzzle/ai/stream", h.StreamPuzzleFromAI, middleware.RequireFeature(h.ent, entitlements.FeatureAIGeneration))
+	g.POST("/puzzle/:id/explain", h.ExplainPuzzle, middleware.RequireFeature(h.ent, entitlements.FeatureExplanation))
+	g.GET("/puzzle/:id/pgn", h.ExportPuzzle, middleware.RequireFeature(h.ent, entitlements.FeatureExports))
 
 	g.GET("/puzzle/dataset", h.GetPuzzleFromDataset)
 }
 
 // GetPuzzle handles GET /puzzle?difficulty=easy|medium|hard
 // @Summary Get puzzle by difficulty
-// @Description Returns a random puzzle (Lichess source) filtered by difficulty
+// @Description Returns a random puzzle filtered by difficulty from the first source of the random chain that answers (default lichess, store, huggingface). servedBy and the X-Puzzle-Source header name that source.
 // @Tags puzzle
 // @Produce json
 // @Param difficulty query string false "easy|medium|hard" Enums(easy,medium,hard)
 // @Success 200 {object} models.Puzzle
+// @Header 200 {string} X-Puzzle-Source "Source that served the puzzle"
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
 // @Failure 502 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
 // @Router /puzzle [get]
 func (h *PuzzleHandler) GetPuzzle(c echo.Context) error {
 	raw := strings.ToLower(strings.TrimSpace(c.QueryParam("difficulty")))
@@ -61,34 +73,41 @@ func (h *PuzzleHandler) GetPuzzle(c echo.Context) error {
 	if err != nil {
 		return h.handleServiceError(c, err)
 	}
-	return c.JSON(http.StatusOK, puzzle)
+	return servePuzzle(c, puzzle)
 }
 
 // GetDailyPuzzle handles GET /puzzle/daily
 // @Summary Get daily puzzle
-// @Description Returns Lichess daily puzzle
+// @Description Returns the Lichess daily puzzle, or the last one stored while Lichess is down
 // @Tags puzzle
 // @Produce json
 // @Success 200 {object} models.Puzzle
+// @Header 200 {string} X-Puzzle-Source "Source that served the puzzle"
+// @Failure 429 {object} models.ErrorResponse
 // @Failure 502 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
 // @Router /puzzle/daily [get]
 func (h *PuzzleHandler) GetDailyPuzzle(c echo.Context) error {
 	puzzle, err := h.svc.GetDaily(c.Request().Context())
 	if err != nil {
 		return h.handleServiceError(c, err)
 	}
-	return c.JSON(http.StatusOK, puzzle)
+	return servePuzzle(c, puzzle)
 }
 
 // GetPuzzleByID handles GET /puzzle/:id
 // @Summary Get puzzle by ID
-// @Description Returns a puzzle by Lichess puzzle identifier
+// @Description Returns a puzzle by Lichess puzzle identifier from the puzzle cache, Lichess or the puzzle store
 // @Tags puzzle
 // @Produce json
 // @Param id path string true "Puzzle ID"
 // @Success 200 {object} models.Puzzle
+// @Header 200 {string} X-Puzzle-Source "Source that served the puzzle"
+// @Header 200 {string} X-Cache "HIT when served from the puzzle cache, MISS otherwise"
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
 // @Failure 502 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
 // @Router /puzzle/{id} [get]
 func (h *PuzzleHandler) GetPuzzleByID(c echo.Context) error {
 	id := strings.TrimSpace(c.Param("id"))
@@ -96,19 +115,26 @@ func (h *PuzzleHandler) GetPuzzleByID(c echo.Context) error {
 	if err != nil {
 		return h.handleServiceError(c, err)
 	}
-	return c.JSON(http.StatusOK, puzzle)
+	return servePuzzle(c, puzzle)
 }
 
 // GeneratePuzzleFromAI handles POST /puzzle/ai
 // @Summary Generate puzzle from AI (RAG)
-// @Description Uses a RAG pipeline: fetches candidate puzzles from the Lichess dataset and asks the NVIDIA model to select the best match for the user's prompt
+// @Description Asks the first healthy LLM provider (LLM_PROVIDERS order) to turn the prompt into a validated filter (rating range, required and excluded themes, opening, player color, maximum solution length), then runs it against the vector index or the Lichess dataset. The puzzle carries the filter; sending it back as filter, edited or not, reruns the query without the model. When nothing matches, the 404 response carries the filter.
 // @Tags puzzle
 // @Accept json
 // @Produce json
 // @Param request body models.AIPuzzleRequest true "AI puzzle request"
+// @Security BearerAuth
+// @Security ApiKeyAuth
 // @Success 200 {object} models.Puzzle
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 402 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
 // @Failure 502 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
 // @Router /puzzle/ai [post]
 func (h *PuzzleHandler) GeneratePuzzleFromAI(c echo.Context) error {
 	var req models.AIPuzzleRequest
@@ -127,18 +153,151 @@ func (h *PuzzleHandler) GeneratePuzzleFromAI(c echo.Context) error {
 	if err != nil {
 		return h.handleServiceError(c, err)
 	}
-	return c.JSON(http.StatusOK, puzzle)
+	return servePuzzle(c, puzzle)
+}
+
+// StreamPuzzleFromAI handles GET /puzzle/ai/stream?prompt=&difficulty=&filter=
+// @Summary Stream AI puzzle generation (SSE)
+// @Description Runs the same pipeline as POST /puzzle/ai and streams its progress as Server-Sent Events: thinking, token (model output, when the provider streams), filter, candidates, selected and validated, each with a models.AIProgressEvent. The stream ends with a puzzle event carrying the models.Puzzle, or an error event carrying a models.ErrorResponse. Closing the connection cancels the pipeline. Errors raised before the first event are answered as plain JSON.
+// @Tags puzzle
+// @Produce text/event-stream
+// @Param prompt query string false "What the puzzle should be about; required without filter"
+// @Param difficulty query string false "easy|medium|hard" Enums(easy,medium,hard)
+// @Param filter query string false "models.PuzzleFilter as JSON, used instead of asking the model"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Success 200 {object} models.AIProgressEvent
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 402 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
+// @Failure 502 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /puzzle/ai/stream [get]
+func (h *PuzzleHandler) StreamPuzzleFromAI(c echo.Context) error {
+	req := models.AIPuzzleRequest{
+		Prompt:     c.QueryParam("prompt"),
+		Difficulty: models.DifficultyLevel(strings.ToLower(strings.TrimSpace(c.QueryParam("difficulty")))),
+	}
+	if req.Difficulty == "" {
+		req.Difficulty = models.DifficultyMedium
+	}
+	if raw := c.QueryParam("filter"); raw != "" {
+		req.Filter = &models.PuzzleFilter{}
+		if err := json.Unmarshal([]byte(raw), req.Filter); err != nil {
+			return c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "invalid request",
+				Details: "filter must be a JSON object",
+			})
+		}
+	}
+
+	stream := newSSEStream(c)
+	defer stream.Close()
+
+	// The request context is cancelled when the client disconnects, which
+	// stops the pipeline and any model call in flight.
+	ctx := c.Request().Context()
+	puzzle, err := h.svc.GenerateFromAIStream(ctx, req, func(ev models.AIProgressEvent) {
+		stream.Send(ev.Stage, ev)
+	})
+	if err != nil {
+		if !stream.Started() {
+			return h.handleServiceError(c, err)
+		}
+		middleware.FailFeature(c)
+		if ctx.Err() != nil {
+			return nil // nobody is listening anymore
+		}
+		logger.ErrorContext(ctx, "service error", "err", err)
+		_, resp := serviceErrorResponse(err)
+		stream.Send("error", resp)
+		return nil
+	}
+	stream.Send("puzzle", puzzle)
+	return nil
+}
+
+// ExplainPuzzle handles POST /puzzle/:id/explain
+// @Summary Explain a puzzle's solution (AI)
+// @Description Explains why the solution works: a short summary and one explanation per solution move. The model gets the position, the solution in SAN, the themes and what each move changes on the board; answers mentioning a move that is illegal in the line are rejected. Explanations are cached per puzzle and language. Cached explanations are served while the daily AI spend limit is reached; others get 503 with Retry-After.
+// @Tags puzzle
+// @Accept json
+// @Produce json
+// @Param id path string true "Puzzle ID"
+// @Param request body models.ExplainRequest false "Explanation language"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Success 200 {object} models.PuzzleExplanation
+// @Header 200 {string} X-Cache "HIT when served from the explanation cache, MISS otherwise"
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 402 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
+// @Failure 502 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /puzzle/{id}/explain [post]
+func (h *PuzzleHandler) ExplainPuzzle(c echo.Context) error {
+	var req models.ExplainRequest
+	if c.Request().ContentLength != 0 {
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "invalid request",
+				Details: "invalid JSON body",
+			})
+		}
+	}
+
+	id := strings.TrimSpace(c.Param("id"))
+	explanation, err := h.svc.ExplainPuzzle(c.Request().Context(), id, req.Language)
+	if err != nil {
+		return h.handleServiceError(c, err)
+	}
+	if explanation.CacheStatus != "" {
+		c.Response().Header().Set(CacheHeader, explanation.CacheStatus)
+	}
+	return c.JSON(http.StatusOK, explanation)
+}
+
+// ExportPuzzle handles GET /puzzle/:id/pgn
+// @Summary Export a puzzle as PGN
+// @Description Returns the puzzle as a PGN file: the position in the SetUp and FEN tags, then the opponent's setup move and the solution. Each export uses one unit of the exports quota; failed exports give it back.
+// @Tags puzzle
+// @Produce application/x-chess-pgn
+// @Param id path string true "Puzzle ID"
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Success 200 {string} string "PGN"
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 402 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
+// @Failure 502 {object} models.ErrorResponse
+// @Router /puzzle/{id}/pgn [get]
+func (h *PuzzleHandler) ExportPuzzle(c echo.Context) error {
+	id := strings.TrimSpace(c.Param("id"))
+	pgn, err := h.svc.ExportPGN(c.Request().Context(), id)
+	if err != nil {
+		middleware.FailFeature(c)
+		return h.handleServiceError(c, err)
+	}
+	c.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="puzzle-`+id+`.pgn"`)
+	return c.Blob(http.StatusOK, "application/x-chess-pgn", []byte(pgn))
 }
 
 // GetPuzzleFromDataset handles GET /puzzle/dataset
 // @Summary Get puzzle from dataset
-// @Description Returns one random puzzle from Hugging Face Lichess dataset
+// @Description Returns one random puzzle from Hugging Face Lichess dataset, or from the puzzle store while the dataset is down
 // @Tags puzzle
 // @Produce json
 // @Param difficulty query string false "easy|medium|hard" Enums(easy,medium,hard)
 // @Success 200 {object} models.Puzzle
+// @Header 200 {string} X-Puzzle-Source "Source that served the puzzle"
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
 // @Failure 502 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
 // @Router /puzzle/dataset [get]
 func (h *PuzzleHandler) GetPuzzleFromDataset(c echo.Context) error {
 	raw := strings.ToLower(strings.TrimSpace(c.QueryParam("difficulty")))
@@ -148,5 +307,21 @@ func (h *PuzzleHandler) GetPuzzleFromDataset(c echo.Context) error {
 	if err != nil {
 		return h.handleServiceError(c, err)
 	}
-	return c.JSON(http.StatusOK, puzzle)
+	return servePuzzle(c, puzzle)
+}
+
+// Response headers describing where a puzzle came from.
+const (
+	SourceHeader = "X-Puzzle-Source" // see Puzzle.ServedBy
+	CacheHeader  = "X-Cache"         // HIT or MISS, lookups by ID and explanations only
+)
+
+func servePuzzle(c echo.Context, p *models.Puzzle) error {
+	if p.ServedBy != "" {
+		c.Response().Header().Set(SourceHeader, p.ServedBy)
+	}
+	if p.CacheStatus != "" {
+		c.Response().Header().Set(CacheHeader, p.CacheStatus)
+	}
+	return c.JSON(http.StatusOK, p)
 }
@@ -1,9 +1,15 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"time"
 
+	"github.com/chess-puzzle-next/puzzle-generator/internal/metrics"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/middleware"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/models"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/services"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/webhooks"
 	"github.com/chess-puzzle-next/puzzle-generator/pkg/redis"
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
@@ -13,18 +19,25 @@ import (
 type SessionHandler struct {
 	redis      *redis.Client
 	sessionTTL time.Duration
+	events     services.EventPublisher
+	svc        *services.SessionService
 }
 
-// NewSessionHandler creates a SessionHandler.
-func NewSessionHandler(r *redis.Client, ttl time.Duration) *SessionHandler {
-	return &SessionHandler{redis: r, sessionTTL: ttl}
+// NewSessionHandler creates a SessionHandler. events may be nil, in which
+// case no webhook events are published. svc checks moves, reveals hints and
+// scores finished sessions; it is nil when r is.
+func NewSessionHandler(r *redis.Client, ttl time.Duration, events services.EventPublisher, svc *services.SessionService) *SessionHandler {
+	return &SessionHandler{redis: r, sessionTTL: ttl, events: events, svc: svc}
 }
 
 // Register mounts session routes.
 func (h *SessionHandler) Register(g *echo.Group) {
 	g.POST("/session", h.CreateSession)
+	g.GET("/session", h.ListSessions, middleware.RequireAuth())
 	g.GET("/session/:id", h.GetSession)
 	g.PUT("/session/:id", h.UpdateSession)
+	g.POST("/session/:id/move", h.Move)
+	g.POST("/session/:id/hint", h.Hint)
 	g.DELETE("/session/:id", h.DeleteSession)
 }
 
@@ -62,13 +75,42 @@ func (h *SessionHandler) CreateSession(c echo.Context) error {
 		UpdatedAt:  time.Now(),
 	}
 
+	if user := middleware.CurrentUser(c); user != nil {
+		session.UserID = user.ID
+	}
+	if h.svc != nil {
+		h.svc.Prepare(c.Request().Context(), session)
+	}
+
 	if err := h.redis.SaveSession(c.Request().Context(), session, h.sessionTTL); err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to create session"})
 	}
+	if session.UserID != "" {
+		if err := h.redis.AddUserSession(c.Request().Context(), session.UserID, session.ID, h.sessionTTL); err != nil {
+			logger.WarnContext(c.Request().Context(), "link session to user", "session", session.ID, "err", err)
+		}
+	}
+	metrics.SessionEvent(metrics.SessionCreated)
 
 	return c.JSON(http.StatusCreated, session)
 }
 
+// ListSessions handles GET /api/v1/session for the authenticated user.
+func (h *SessionHandler) ListSessions(c echo.Context) error {
+	if h.redis == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{
+			"error": "Session service unavailable",
+		})
+	}
+
+	sessions, err := h.redis.ListUserSessions(c.Request().Context(), middleware.CurrentUser(c).ID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to list sessions"})
+	}
+
+	return c.JSON(http.StatusOK, sessions)
+}
+
 // GetSession handles GET /api/v1/session/:id
 func (h *SessionHandler) GetSession(c echo.Context) error {
 	if h.redis == nil {
@@ -84,6 +126,9 @@ func (h *SessionHandler) GetSession(c echo.Context) error {
 	if session == nil {
 		return c.JSON(http.StatusNotFound, map[string]string{"error": "session not found"})
 	}
+	if !ownsSession(c, session) {
+		return forbiddenSession(c)
+	}
 
 	return c.JSON(http.StatusOK, session)
 }
@@ -96,7 +141,13 @@ type updateSessionRequest struct {
 	HintsUsed int  `json:"hints_used"`
 }
 
-// UpdateSession handles PUT /api/v1/session/:id
+// errSessionForbidden aborts a session update by a caller who does not own
+// the session.
+var errSessionForbidden = errors.New("session belongs to another user")
+
+// UpdateSession handles PUT /api/v1/session/:id. Verified sessions only take
+// hints_used and giving up (failed) from it; their progress is recorded by
+// POST /session/:id/move.
 func (h *SessionHandler) UpdateSession(c echo.Context) error {
 	if h.redis == nil {
 		return c.JSON(http.StatusServiceUnavailable, map[string]string{
@@ -104,29 +155,188 @@ func (h *SessionHandler) UpdateSession(c echo.Context) error {
 		})
 	}
 
-	session, err := h.redis.GetSession(c.Request().Context(), c.Param("id"))
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to get session"})
+	var req updateSessionRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request body"})
 	}
-	if session == nil {
+
+	var wasSolved, wasFailed, rate bool
+	puzzle := h.sessionPuzzle(c)
+	session, err := h.redis.UpdateSession(c.Request().Context(), c.Param("id"), h.sessionTTL, func(s *redis.Session) error {
+		if !ownsSession(c, s) {
+			return errSessionForbidden
+		}
+		wasSolved, wasFailed = s.Solved, s.Failed
+		if s.Verified {
+			s.Failed = s.Failed || req.Failed
+		} else {
+			s.MoveIndex = req.MoveIndex
+			s.Solved = req.Solved
+			s.Failed = req.Failed
+		}
+		// Hints are counted by the server; the client can add to them but never
+		// take them back.
+		s.HintsUsed = max(s.HintsUsed, req.HintsUsed)
+		if h.svc != nil {
+			rate = h.svc.Finish(s, puzzle)
+		}
+		return nil
+	})
+	switch {
+	case errors.Is(err, errSessionForbidden):
+		return forbiddenSession(c)
+	case errors.Is(err, redis.ErrSessionConflict):
+		return c.JSON(http.StatusConflict, map[string]string{"error": "session was changed by another request, try again"})
+	case err != nil:
+		logger.ErrorContext(c.Request().Context(), "update session", "session", c.Param("id"), "err", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to update session"})
+	case session == nil:
 		return c.JSON(http.StatusNotFound, map[string]string{"error": "session not found"})
 	}
 
-	var req updateSessionRequest
-	if err := c.Bind(&req); err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+	return c.JSON(http.StatusOK, h.finished(c, session, wasSolved, wasFailed, rate))
+}
+
+// moveRequest is the body for POST /session/:id/move.
+type moveRequest struct {
+	Move      string `json:"move"`       // the player's move, in UCI
+	HintsUsed int    `json:"hints_used"` // hints the client showed on its own so far
+}
+
+// Move handles POST /api/v1/session/:id/move. The server checks the player's
+// next move against the solution: a correct move advances move_index past it
+// (the client plays the opponent's reply), the last one solves the session,
+// and any other legal move fails it. Only sessions played this way change
+// ratings.
+func (h *SessionHandler) Move(c echo.Context) error {
+	if h.redis == nil || h.svc == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{
+			"error": "Session service unavailable",
+		})
 	}
 
-	session.MoveIndex = req.MoveIndex
-	session.Solved = req.Solved
-	session.Failed = req.Failed
-	session.HintsUsed = req.HintsUsed
+	var req moveRequest
+	if err := c.Bind(&req); err != nil || req.Move == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "move is required"})
+	}
 
-	if err := h.redis.SaveSession(c.Request().Context(), session, h.sessionTTL); err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to update session"})
+	var wasSolved, wasFailed, rate bool
+	puzzle := h.sessionPuzzle(c)
+	session, err := h.redis.UpdateSession(c.Request().Context(), c.Param("id"), h.sessionTTL, func(s *redis.Session) error {
+		if !ownsSession(c, s) {
+			return errSessionForbidden
+		}
+		wasSolved, wasFailed = s.Solved, s.Failed
+		s.HintsUsed = max(s.HintsUsed, req.HintsUsed)
+		if err := h.svc.Play(s, req.Move); err != nil {
+			return err
+		}
+		rate = h.svc.Finish(s, puzzle)
+		return nil
+	})
+	switch {
+	case errors.Is(err, errSessionForbidden):
+		return forbiddenSession(c)
+	case errors.Is(err, redis.ErrSessionConflict):
+		return c.JSON(http.StatusConflict, map[string]string{"error": "session was changed by another request, try again"})
+	case errors.Is(err, services.ErrSessionOver):
+		return c.JSON(http.StatusConflict, map[string]string{"error": "session is over"})
+	case errors.Is(err, services.ErrIllegalMove):
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	case errors.Is(err, services.ErrSessionUnplayable):
+		return c.JSON(http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+	case err != nil:
+		logger.ErrorContext(c.Request().Context(), "session move", "session", c.Param("id"), "err", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to record move"})
+	case session == nil:
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "session not found"})
 	}
 
-	return c.JSON(http.StatusOK, session)
+	return c.JSON(http.StatusOK, h.finished(c, session, wasSolved, wasFailed, rate))
+}
+
+// sessionPuzzle looks up the puzzle of the session being updated, if the
+// session still needs it for hints or scoring. The lookup may call the
+// puzzle sources, so it runs before UpdateSession, whose update function
+// can run more than once. Returns nil if the session cannot be read; the
+// update then reports why.
+func (h *SessionHandler) sessionPuzzle(c echo.Context) *models.Puzzle {
+	if h.svc == nil {
+		return nil
+	}
+	s, err := h.redis.GetSession(c.Request().Context(), c.Param("id"))
+	if err != nil || s == nil {
+		return nil
+	}
+	return h.svc.Puzzle(c.Request().Context(), s)
+}
+
+// finished runs what follows a saved session update: the owner's rating
+// when the update scored a rated session, then metrics and webhooks for a
+// session that has just been solved or failed. Returns the session to send.
+func (h *SessionHandler) finished(c echo.Context, session *redis.Session, wasSolved, wasFailed, rate bool) *redis.Session {
+	if rate {
+		session = h.svc.Rate(c.Request().Context(), session)
+	}
+
+	switch {
+	case session.Solved && !wasSolved:
+		metrics.SessionEvent(metrics.SessionSolved)
+	case session.Failed && !wasFailed:
+		metrics.SessionEvent(metrics.SessionFailed)
+	}
+
+	// Webhooks belong to accounts, so only owned sessions have a receiver.
+	if h.events != nil && session.UserID != "" {
+		switch {
+		case session.Solved && !wasSolved:
+			h.events.Publish(webhooks.Event{Type: webhooks.EventSessionSolved, UserID: session.UserID, Data: session})
+		case session.Failed && !wasFailed:
+			h.events.Publish(webhooks.Event{Type: webhooks.EventSessionFailed, UserID: session.UserID, Data: session})
+		}
+	}
+	return session
+}
+
+// Hint handles POST /api/v1/session/:id/hint. Each call reveals more about
+// the player's current move and is recorded on the session.
+func (h *SessionHandler) Hint(c echo.Context) error {
+	if h.redis == nil || h.svc == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{
+			"error": "Session service unavailable",
+		})
+	}
+
+	var hint *models.SessionHint
+	puzzle := h.sessionPuzzle(c)
+	session, err := h.redis.UpdateSession(c.Request().Context(), c.Param("id"), h.sessionTTL, func(s *redis.Session) error {
+		if !ownsSession(c, s) {
+			return errSessionForbidden
+		}
+		var err error
+		hint, err = h.svc.Hint(s, puzzle)
+		return err
+	})
+	switch {
+	case errors.Is(err, errSessionForbidden):
+		return forbiddenSession(c)
+	case errors.Is(err, redis.ErrSessionConflict):
+		return c.JSON(http.StatusConflict, map[string]string{"error": "session was changed by another request, try again"})
+	case errors.Is(err, services.ErrSessionOver):
+		return c.JSON(http.StatusConflict, map[string]string{"error": "session is over, no hint available"})
+	case errors.Is(err, services.ErrSessionUnplayable):
+		return c.JSON(http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+	case err != nil:
+		logger.ErrorContext(c.Request().Context(), "session hint", "session", c.Param("id"), "err", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to record hint"})
+	case session == nil:
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "session not found"})
+	}
+	if !hint.Repeated {
+		metrics.SessionEvent(metrics.SessionHint)
+	}
+
+	return c.JSON(http.StatusOK, hint)
 }
 
 // DeleteSession handles DELETE /api/v1/session/:id
@@ -137,9 +347,39 @@ func (h *SessionHandler) DeleteSession(c echo.Context) error {
 		})
 	}
 
-	if err := h.redis.DeleteSession(c.Request().Context(), c.Param("id")); err != nil {
+	session, err := h.redis.GetSession(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to get session"})
+	}
+	if session == nil {
+		return c.NoContent(http.StatusNoContent)
+	}
+	if !ownsSession(c, session) {
+		return forbiddenSession(c)
+	}
+
+	if err := h.redis.DeleteSession(c.Request().Context(), session.ID); err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to delete session"})
 	}
+	if session.UserID != "" {
+		_ = h.redis.RemoveUserSession(c.Request().Context(), session.UserID, session.ID)
+	}
+	metrics.SessionEvent(metrics.SessionDeleted)
 
 	return c.NoContent(http.StatusNoContent)
 }
+
+// ownsSession reports whether the caller may access the session. Anonymous
+// sessions stay open to anyone holding the ID; sessions created by a signed-in
+// user are only visible to that user.
+func ownsSession(c echo.Context, s *redis.Session) bool {
+	if s.UserID == "" {
+		return true
+	}
+	user := middleware.CurrentUser(c)
+	return user != nil && user.ID == s.UserID
+}
+
+func forbiddenSession(c echo.Context) error {
+	return c.JSON(http.StatusForbidden, map[string]string{"error": errSessionForbidden.Error()})
+}
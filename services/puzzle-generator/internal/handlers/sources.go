@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/chess-puzzle-next/puzzle-generator/internal/middleware"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/models"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/services"
+	"github.com/labstack/echo/v4"
+)
+
+// sourceAdmin is the dependency SourceHandler needs from the service layer.
+type sourceAdmin interface {
+	Sources() *models.PuzzleSources
+	SetSourceEnabled(ctx context.Context, name string, enabled bool) (*models.SourceStatus, error)
+}
+
+// SourceHandler lets admins inspect the puzzle source chains and switch
+// sources off at runtime.
+type SourceHandler struct {
+	svc sourceAdmin
+}
+
+// NewSourceHandler constructs a SourceHandler.
+func NewSourceHandler(svc sourceAdmin) *SourceHandler {
+	return &SourceHandler{svc: svc}
+}
+
+// Register mounts admin source routes onto the given Echo group.
+func (h *SourceHandler) Register(g *echo.Group) {
+	admin := middleware.RequireAdmin()
+	g.GET("/admin/sources", h.ListSources, admin)
+	g.PUT("/admin/sources/:name", h.UpdateSource, admin)
+}
+
+// ListSources handles GET /admin/sources
+// @Summary List puzzle sources
+// @Description Lists the puzzle sources (lichess, store, huggingface), whether each is enabled and configured, and the priority chain of each endpoint
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.PuzzleSources
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Router /admin/sources [get]
+func (h *SourceHandler) ListSources(c echo.Context) error {
+	return c.JSON(http.StatusOK, h.svc.Sources())
+}
+
+// UpdateSource handles PUT /admin/sources/:name
+// @Summary Enable or disable a puzzle source
+// @Description Switches a source on or off for every endpoint chain. Disabled sources are skipped and the next source of the chain answers. With Redis the switch applies to every replica.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param name path string true "Source" Enums(lichess,store,huggingface)
+// @Param request body models.UpdateSourceRequest true "New state"
+// @Success 200 {object} models.SourceStatus
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /admin/sources/{name} [put]
+func (h *SourceHandler) UpdateSource(c echo.Context) error {
+	var req models.UpdateSourceRequest
+	if err := c.Bind(&req); err != nil {
+		return invalidJSON(c)
+	}
+	if req.Enabled == nil {
+		return c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid request",
+			Details: "enabled is required",
+		})
+	}
+	status, err := h.svc.SetSourceEnabled(c.Request().Context(), c.Param("name"), *req.Enabled)
+	if errors.Is(err, services.ErrUnknownSource) {
+		return c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "source not found", Details: err.Error()})
+	}
+	if err != nil {
+		logger.ErrorContext(c.Request().Context(), "update puzzle source", "err", err)
+		return c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "internal error"})
+	}
+	return c.JSON(http.StatusOK, status)
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// sseKeepAlive is how often an idle event stream gets a comment line, so
+// proxies do not close it while the model is thinking.
+const sseKeepAlive = 15 * time.Second
+
+// sseStream writes Server-Sent Events. The response headers are sent with the
+// first event, so a handler can still answer with a plain JSON error before
+// that. It is safe for concurrent use.
+type sseStream struct {
+	c echo.Context
+
+	mu      sync.Mutex
+	started bool
+	closed  bool
+	stop    chan struct{}
+}
+
+func newSSEStream(c echo.Context) *sseStream {
+	return &sseStream{c: c, stop: make(chan struct{})}
+}
+
+// Started reports whether an event was sent.
+func (s *sseStream) Started() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.started
+}
+
+// Send writes one event with data encoded as JSON.
+func (s *sseStream) Send(event string, data any) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		logger.ErrorContext(s.c.Request().Context(), "encode event", "event", event, "err", err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.started {
+		s.started = true
+		h := s.c.Response().Header()
+		h.Set(echo.HeaderContentType, "text/event-stream")
+		h.Set(echo.HeaderCacheControl, "no-cache")
+		h.Set("X-Accel-Buffering", "no") // nginx must not buffer the stream
+		// A stream outlives SERVER_WRITE_TIMEOUT when the model is slow; it
+		// ends when the pipeline does or the client goes away.
+		_ = http.NewResponseController(s.c.Response()).SetWriteDeadline(time.Time{})
+		s.c.Response().WriteHeader(http.StatusOK)
+		go s.keepAlive()
+	}
+	fmt.Fprintf(s.c.Response(), "event: %s\ndata: %s\n\n", event, payload)
+	s.c.Response().Flush()
+}
+
+// Close stops the keep-alive comments. No event may be sent afterwards.
+func (s *sseStream) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	close(s.stop)
+}
+
+func (s *sseStream) keepAlive() {
+	t := time.NewTicker(sseKeepAlive)
+	defer t.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-s.c.Request().Context().Done():
+			return
+		case <-t.C:
+			s.mu.Lock()
+			if !s.closed {
+				fmt.Fprint(s.c.Response(), ": keep-alive\n\n")
+				s.c.Response().Flush()
+			}
+			s.mu.Unlock()
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/chess-puzzle-next/puzzle-generator/internal/entitlements"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/middleware"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/models"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/services"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/webhooks"
+	"github.com/chess-puzzle-next/puzzle-generator/pkg/redis"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// stormRunTTL is how long a started storm run can be reported. Runs last a
+// few minutes; the rest is slack for slow clients.
+const stormRunTTL = 15 * time.Minute
+
+// stormPuzzles fetches the puzzles of a storm run.
+type stormPuzzles interface {
+	StormBatch(ctx context.Context) ([]*models.Puzzle, error)
+}
+
+// StormHandler starts Puzzle Storm runs and receives their results. Runs are
+// played entirely in the client; starting one takes a unit of the storm
+// quota, and only started runs can be reported.
+type StormHandler struct {
+	puzzles stormPuzzles
+	redis   *redis.Client
+	events  services.EventPublisher
+	ent     *entitlements.Service
+}
+
+// NewStormHandler constructs a StormHandler. Runs are tracked in r; without
+// Redis every route answers 503. events may be nil, in which case no webhook
+// events are published.
+func NewStormHandler(puzzles stormPuzzles, r *redis.Client, events services.EventPublisher, ent *entitlements.Service) *StormHandler {
+	return &StormHandler{puzzles: puzzles, redis: r, events: events, ent: ent}
+}
+
+// Register mounts storm routes onto the given Echo group.
+func (h *StormHandler) Register(g *echo.Group) {
+	g.POST("/storm/start", h.StartRun, middleware.RequireFeature(h.ent, entitlements.FeatureStorm))
+	g.POST("/storm/runs", h.FinishRun, middleware.RequireAuth())
+}
+
+// StartRun handles POST /storm/start
+// @Summary Start storm run
+// @Description Starts a Puzzle Storm run and returns its puzzles, easiest first. Each run takes one unit of the storm quota; report the result with POST /storm/runs before expiresAt
+// @Tags storm
+// @Produce json
+// @Security BearerAuth
+// @Success 201 {object} models.StormStart
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 402 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
+// @Failure 502 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /storm/start [post]
+func (h *StormHandler) StartRun(c echo.Context) error {
+	if h.redis == nil {
+		return stormUnavailable(c)
+	}
+	ctx := c.Request().Context()
+	puzzles, err := h.puzzles.StormBatch(ctx)
+	if err != nil {
+		logger.ErrorContext(ctx, "storm batch", "err", err)
+		status, resp := serviceErrorResponse(err)
+		return c.JSON(status, resp)
+	}
+
+	start := &models.StormStart{
+		RunID:     uuid.New().String(),
+		Puzzles:   puzzles,
+		ExpiresAt: time.Now().UTC().Add(stormRunTTL),
+	}
+	if err := h.redis.StartStormRun(ctx, start.RunID, middleware.CurrentUser(c).ID, stormRunTTL); err != nil {
+		logger.ErrorContext(ctx, "start storm run", "err", err)
+		return c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "failed to start storm run"})
+	}
+	return c.JSON(http.StatusCreated, start)
+}
+
+// FinishRun handles POST /storm/runs
+// @Summary Report storm run
+// @Description Reports the result of a run started with POST /storm/start and publishes the storm.finished webhook event. Each run is reported once
+// @Tags storm
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.StormRunReport true "Run result"
+// @Success 202 {object} models.StormRun
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /storm/runs [post]
+func (h *StormHandler) FinishRun(c echo.Context) error {
+	if h.redis == nil {
+		return stormUnavailable(c)
+	}
+	var req models.StormRunReport
+	if err := c.Bind(&req); err != nil {
+		return invalidJSON(c)
+	}
+	if req.Score < 0 || req.Solved < 0 || req.Failed < 0 || req.DurationMs < 0 {
+		return c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid request",
+			Details: "score, solved, failed and durationMs must not be negative",
+		})
+	}
+
+	user := middleware.CurrentUser(c)
+	ctx := c.Request().Context()
+	// Only the player who started the run can report it, and only once.
+	owner, err := h.redis.FinishStormRun(ctx, req.RunID)
+	if err != nil {
+		logger.ErrorContext(ctx, "finish storm run", "run", req.RunID, "err", err)
+		return c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "failed to record storm run"})
+	}
+	if owner == "" || owner != user.ID {
+		return c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Error:   "storm run not found",
+			Details: "Start a run with POST /storm/start and report it once before it expires",
+		})
+	}
+	run := &models.StormRun{
+		ID:         req.RunID,
+		UserID:     user.ID,
+		Score:      req.Score,
+		Solved:     req.Solved,
+		Failed:     req.Failed,
+		DurationMs: req.DurationMs,
+		FinishedAt: time.Now().UTC(),
+	}
+	if h.events != nil {
+		h.events.Publish(webhooks.Event{Type: webhooks.EventStormFinished, UserID: user.ID, Data: run})
+	}
+	return c.JSON(http.StatusAccepted, run)
+}
+
+func stormUnavailable(c echo.Context) error {
+	return c.JSON(http.StatusServiceUnavailable, models.ErrorResponse{
+		Error:   "storm unavailable",
+		Details: "Puzzle Storm requires Redis",
+	})
+}
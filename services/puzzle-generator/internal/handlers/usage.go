@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/chess-puzzle-next/puzzle-generator/internal/aiusage"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/middleware"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/models"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	defaultUsageDays = 7
+	maxUsageDays     = 90
+	defaultTopUsers  = 10
+	maxTopUsers      = 100
+)
+
+// UsageHandler reports the tokens and cost of AI calls.
+type UsageHandler struct {
+	usage *aiusage.Tracker
+}
+
+// NewUsageHandler constructs a UsageHandler.
+func NewUsageHandler(usage *aiusage.Tracker) *UsageHandler {
+	return &UsageHandler{usage: usage}
+}
+
+// Register mounts usage routes onto the given Echo group.
+func (h *UsageHandler) Register(g *echo.Group) {
+	g.GET("/me/usage", h.GetUsage, middleware.RequireAuth())
+	g.GET("/admin/usage", h.GetReport, middleware.RequireAdmin())
+}
+
+// GetUsage handles GET /me/usage
+// @Summary AI usage
+// @Description Lists the caller's AI calls, tokens and cost per UTC day, newest first. aiPaused is set while the service's daily AI spend limit is reached; AI puzzles then use the prompt's keywords and explanations are unavailable until resumesAt.
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Param days query int false "Days to list, today included (1-90)" default(7)
+// @Success 200 {object} models.AIUsageResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /me/usage [get]
+func (h *UsageHandler) GetUsage(c echo.Context) error {
+	days, err := queryCount(c, "days", defaultUsageDays, maxUsageDays)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "invalid request", Details: err.Error()})
+	}
+	ctx := c.Request().Context()
+	resp, err := h.usage.UserUsage(ctx, middleware.CurrentUser(c).ID, days)
+	if err != nil {
+		logger.ErrorContext(ctx, "AI usage", "err", err)
+		return c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "failed to load usage"})
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// GetReport handles GET /admin/usage
+// @Summary AI usage report
+// @Description Reports everyone's AI calls, tokens and cost per UTC day, newest first, with the cost of each model and the users who cost the most. Costs come from the LLM_PRICES table.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param days query int false "Days to report, today included (1-90)" default(7)
+// @Param top query int false "Top users listed per day (1-100)" default(10)
+// @Success 200 {object} models.AIUsageReport
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/usage [get]
+func (h *UsageHandler) GetReport(c echo.Context) error {
+	days, err := queryCount(c, "days", defaultUsageDays, maxUsageDays)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "invalid request", Details: err.Error()})
+	}
+	top, err := queryCount(c, "top", defaultTopUsers, maxTopUsers)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "invalid request", Details: err.Error()})
+	}
+	ctx := c.Request().Context()
+	report, err := h.usage.Report(ctx, days, top)
+	if err != nil {
+		logger.ErrorContext(ctx, "AI usage report", "err", err)
+		return c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "failed to load usage"})
+	}
+	return c.JSON(http.StatusOK, report)
+}
+
+// queryCount reads the query parameter name as a number from 1 to limit,
+// fallback when it is absent.
+func queryCount(c echo.Context, name string, fallback, limit int) (int, error) {
+	raw := c.QueryParam(name)
+	if raw == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 1 || n > limit {
+		return 0, fmt.Errorf("%s must be a number from 1 to %d", name, limit)
+	}
+	return n, nil
+}
@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/chess-puzzle-next/puzzle-generator/internal/middleware"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/models"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/webhooks"
+	"github.com/labstack/echo/v4"
+)
+
+// WebhookHandler lets users register URLs for outbound events.
+type WebhookHandler struct {
+	svc *webhooks.Service
+}
+
+// NewWebhookHandler constructs a WebhookHandler. svc may be nil when Redis is
+// unavailable, in which case every route answers 503.
+func NewWebhookHandler(svc *webhooks.Service) *WebhookHandler {
+	return &WebhookHandler{svc: svc}
+}
+
+// Register mounts webhook routes onto the given Echo group.
+func (h *WebhookHandler) Register(g *echo.Group) {
+	auth := middleware.RequireAuth()
+	g.POST("/webhooks", h.CreateWebhook, auth)
+	g.GET("/webhooks", h.ListWebhooks, auth)
+	g.DELETE("/webhooks/:id", h.DeleteWebhook, auth)
+	g.GET("/webhooks/:id/deliveries", h.ListDeliveries, auth)
+	g.POST("/webhooks/:id/deliveries/:deliveryId/replay", h.ReplayDelivery, auth)
+}
+
+// CreateWebhook handles POST /webhooks
+// @Summary Register webhook
+// @Description Registers a URL for outbound events: session.solved, session.failed, puzzle.daily_published, storm.finished. Deliveries are signed in X-Webhook-Signature (t=<unix>,v1=<hex HMAC-SHA256 of t.body>) with the returned secret.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param request body models.CreateWebhookRequest true "Endpoint"
+// @Success 201 {object} models.WebhookEndpointCreated
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /webhooks [post]
+func (h *WebhookHandler) CreateWebhook(c echo.Context) error {
+	if h.svc == nil {
+		return webhooksUnavailable(c)
+	}
+	var req models.CreateWebhookRequest
+	if err := c.Bind(&req); err != nil {
+		return invalidJSON(c)
+	}
+	created, err := h.svc.Register(c.Request().Context(), middleware.CurrentUser(c).ID, req)
+	if err != nil {
+		return h.handleError(c, err)
+	}
+	return c.JSON(http.StatusCreated, created)
+}
+
+// ListWebhooks handles GET /webhooks
+// @Summary List webhooks
+// @Tags webhooks
+// @Produce json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Success 200 {array} models.WebhookEndpointInfo
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /webhooks [get]
+func (h *WebhookHandler) ListWebhooks(c echo.Context) error {
+	if h.svc == nil {
+		return webhooksUnavailable(c)
+	}
+	eps, err := h.svc.List(c.Request().Context(), middleware.CurrentUser(c).ID)
+	if err != nil {
+		return h.handleError(c, err)
+	}
+	return c.JSON(http.StatusOK, eps)
+}
+
+// DeleteWebhook handles DELETE /webhooks/:id
+// @Summary Delete webhook
+// @Tags webhooks
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param id path string true "Endpoint ID"
+// @Success 204
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /webhooks/{id} [delete]
+func (h *WebhookHandler) DeleteWebhook(c echo.Context) error {
+	if h.svc == nil {
+		return webhooksUnavailable(c)
+	}
+	if err := h.svc.Delete(c.Request().Context(), middleware.CurrentUser(c).ID, c.Param("id")); err != nil {
+		return h.handleError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// ListDeliveries handles GET /webhooks/:id/deliveries
+// @Summary Webhook delivery log
+// @Description Lists the latest deliveries to an endpoint, newest first, with their payload and outcome
+// @Tags webhooks
+// @Produce json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param id path string true "Endpoint ID"
+// @Success 200 {array} models.WebhookDeliveryInfo
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /webhooks/{id}/deliveries [get]
+func (h *WebhookHandler) ListDeliveries(c echo.Context) error {
+	if h.svc == nil {
+		return webhooksUnavailable(c)
+	}
+	ds, err := h.svc.Deliveries(c.Request().Context(), middleware.CurrentUser(c).ID, c.Param("id"))
+	if err != nil {
+		return h.handleError(c, err)
+	}
+	return c.JSON(http.StatusOK, ds)
+}
+
+// ReplayDelivery handles POST /webhooks/:id/deliveries/:deliveryId/replay
+// @Summary Replay webhook delivery
+// @Description Sends a logged delivery again with the same payload and event ID
+// @Tags webhooks
+// @Produce json
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Param id path string true "Endpoint ID"
+// @Param deliveryId path string true "Delivery ID"
+// @Success 202 {object} models.WebhookDeliveryInfo
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 503 {object} models.ErrorResponse
+// @Router /webhooks/{id}/deliveries/{deliveryId}/replay [post]
+func (h *WebhookHandler) ReplayDelivery(c echo.Context) error {
+	if h.svc == nil {
+		return webhooksUnavailable(c)
+	}
+	d, err := h.svc.Replay(c.Request().Context(), middleware.CurrentUser(c).ID, c.Param("id"), c.Param("deliveryId"))
+	if err != nil {
+		return h.handleError(c, err)
+	}
+	return c.JSON(http.StatusAccepted, d)
+}
+
+func (h *WebhookHandler) handleError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, webhooks.ErrInvalidRequest):
+		return c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "invalid request", Details: err.Error()})
+	case errors.Is(err, webhooks.ErrNotFound):
+		return c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "webhook not found"})
+	case errors.Is(err, webhooks.ErrQueueFull):
+		return c.JSON(http.StatusServiceUnavailable, models.ErrorResponse{Error: "webhooks busy", Details: "Try again shortly"})
+	default:
+		logger.ErrorContext(c.Request().Context(), "webhooks", "err", err)
+		return c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "internal error"})
+	}
+}
+
+func webhooksUnavailable(c echo.Context) error {
+	return c.JSON(http.StatusServiceUnavailable, models.ErrorResponse{
+		Error:   "webhooks unavailable",
+		Details: "Webhooks require Redis",
+	})
+}
@@ -0,0 +1,140 @@
+// Package health probes the service's dependencies for the readiness
+// endpoint. Probe results are cached so frequent probes from the orchestrator
+// and load balancers do not turn into a request storm on upstream APIs.
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Status of a single dependency.
+type Status string
+
+const (
+	StatusUp       Status = "up"
+	StatusDown     Status = "down"
+	StatusDisabled Status = "disabled" // not configured, so never probed
+)
+
+// Overall readiness.
+const (
+	ReadyOK          = "ok"
+	ReadyDegraded    = "degraded"    // a dependency the service can do without is down
+	ReadyUnavailable = "unavailable" // a required dependency is down
+)
+
+// Check describes one dependency.
+type Check struct {
+	Name string
+	// DegradesGracefully is true when the service keeps serving without the
+	// dependency, with the features listed in Impact switched off.
+	DegradesGracefully bool
+	Impact             string
+	// Probe returns nil when the dependency is usable. A nil Probe marks the
+	// dependency as disabled.
+	Probe func(ctx context.Context) error
+	// Circuit, when set, reports the state of the dependency's circuit
+	// breaker. It is read on every run, never cached.
+	Circuit func() string
+}
+
+// CheckResult is the last known state of a dependency.
+type CheckResult struct {
+	Name               string    `json:"name"`
+	Status             Status    `json:"status"`
+	DegradesGracefully bool      `json:"degradesGracefully"`
+	Impact             string    `json:"impact,omitempty"`
+	Error              string    `json:"error,omitempty"`
+	Circuit            string    `json:"circuit,omitempty" example:"closed"` // closed, half-open or open
+	LatencyMs          int64     `json:"latencyMs"`
+	CheckedAt          time.Time `json:"checkedAt"`
+}
+
+// Checker runs the checks and caches each result for a TTL.
+type Checker struct {
+	ttl     time.Duration
+	timeout time.Duration
+	entries []*entry
+}
+
+type entry struct {
+	check Check
+
+	mu     sync.Mutex
+	result CheckResult
+	valid  bool
+}
+
+// NewChecker returns a Checker that re-probes a dependency once its result
+// is older than ttl, giving each probe at most timeout.
+func NewChecker(ttl, timeout time.Duration, checks ...Check) *Checker {
+	c := &Checker{ttl: ttl, timeout: timeout}
+	for _, ch := range checks {
+		c.entries = append(c.entries, &entry{check: ch})
+	}
+	return c
+}
+
+// Run returns the state of every dependency, probing stale ones in parallel,
+// and the overall readiness.
+func (c *Checker) Run(ctx context.Context) (string, []CheckResult) {
+	results := make([]CheckResult, len(c.entries))
+	var wg sync.WaitGroup
+	for i, e := range c.entries {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = c.resolve(ctx, e)
+			if e.check.Circuit != nil {
+				results[i].Circuit = e.check.Circuit()
+			}
+		}()
+	}
+	wg.Wait()
+
+	status := ReadyOK
+	for _, r := range results {
+		if r.Status != StatusDown {
+			continue
+		}
+		if !r.DegradesGracefully {
+			return ReadyUnavailable, results
+		}
+		status = ReadyDegraded
+	}
+	return status, results
+}
+
+// resolve returns the cached result of e or probes it again. Concurrent
+// callers wait for the probe in flight instead of starting their own.
+func (c *Checker) resolve(ctx context.Context, e *entry) CheckResult {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.valid && time.Since(e.result.CheckedAt) < c.ttl {
+		return e.result
+	}
+
+	r := CheckResult{
+		Name:               e.check.Name,
+		Status:             StatusDisabled,
+		DegradesGracefully: e.check.DegradesGracefully,
+		Impact:             e.check.Impact,
+		CheckedAt:          time.Now(),
+	}
+	if e.check.Probe != nil {
+		// The result is shared, so the caller going away must not fail it.
+		pctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.timeout)
+		err := e.check.Probe(pctx)
+		cancel()
+		r.LatencyMs = time.Since(r.CheckedAt).Milliseconds()
+		r.Status = StatusUp
+		if err != nil {
+			r.Status = StatusDown
+			r.Error = err.Error()
+		}
+	}
+	e.result, e.valid = r, true
+	return r
+}
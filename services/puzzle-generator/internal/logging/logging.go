@@ -0,0 +1,165 @@
+// Package logging provides the service's structured logger: JSON lines on
+// stdout through log/slog, a minimum level per package, the request ID and
+// trace ID of the current request on every line, and redaction of secrets.
+//
+// Packages get their logger once with For and log with the *Context methods
+// so request correlation works:
+//
+//	var logger = logging.For("billing")
+//	logger.InfoContext(ctx, "plan updated", "user", id)
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"github.com/chess-puzzle-next/puzzle-generator/internal/tracing"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Formats accepted by Setup.
+const (
+	FormatJSON = "json"
+	FormatText = "text" // easier to read in a local terminal
+)
+
+// Config selects the log output.
+type Config struct {
+	Format string
+	Level  slog.Level
+	Levels map[string]slog.Level // per package, overriding Level
+}
+
+// state is the active output. Loggers returned by For read it on every call,
+// so they can be created before Setup runs.
+type state struct {
+	base   slog.Handler
+	level  slog.Level
+	levels map[string]slog.Level
+}
+
+var current atomic.Pointer[state]
+
+func init() {
+	current.Store(&state{base: newBaseHandler(os.Stdout, FormatJSON), level: slog.LevelInfo})
+}
+
+// Setup replaces the output of every logger and installs it as the slog and
+// log package default.
+func Setup(cfg Config, w io.Writer) error {
+	if cfg.Format == "" {
+		cfg.Format = FormatJSON
+	}
+	if cfg.Format != FormatJSON && cfg.Format != FormatText {
+		return fmt.Errorf("logging: unknown format %q", cfg.Format)
+	}
+	current.Store(&state{base: newBaseHandler(w, cfg.Format), level: cfg.Level, levels: cfg.Levels})
+	slog.SetDefault(For("default"))
+	return nil
+}
+
+// For returns the logger of the named package. Its lines carry
+// "component": pkg and are filtered by that package's level.
+func For(pkg string) *slog.Logger {
+	return slog.New(&handler{component: pkg})
+}
+
+// ParseLevels parses per-package levels such as "services=debug,ratelimit=warn".
+func ParseLevels(s string) (map[string]slog.Level, error) {
+	levels := make(map[string]slog.Level)
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		pkg, lvl, ok := strings.Cut(item, "=")
+		if !ok || strings.TrimSpace(pkg) == "" {
+			return nil, fmt.Errorf("logging: invalid package level %q (want pkg=level)", item)
+		}
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(strings.TrimSpace(lvl))); err != nil {
+			return nil, fmt.Errorf("logging: invalid level for %s: %w", pkg, err)
+		}
+		levels[strings.TrimSpace(pkg)] = level
+	}
+	return levels, nil
+}
+
+func newBaseHandler(w io.Writer, format string) slog.Handler {
+	opts := &slog.HandlerOptions{
+		// Filtering happens per component in handler.Enabled.
+		Level:       slog.LevelDebug,
+		ReplaceAttr: redactAttr,
+	}
+	if format == FormatText {
+		return slog.NewTextHandler(w, opts)
+	}
+	return slog.NewJSONHandler(w, opts)
+}
+
+// handler resolves the active state on each record. WithAttrs and WithGroup
+// calls are replayed on top of the base handler in the order they were made.
+type handler struct {
+	component string
+	ops       []func(slog.Handler) slog.Handler
+}
+
+func (h *handler) Enabled(_ context.Context, level slog.Level) bool {
+	st := current.Load()
+	minLevel, ok := st.levels[h.component]
+	if !ok {
+		minLevel = st.level
+	}
+	return level >= minLevel
+}
+
+func (h *handler) Handle(ctx context.Context, r slog.Record) error {
+	var next slog.Handler = current.Load().base.WithAttrs([]slog.Attr{slog.String("component", h.component)})
+	for _, op := range h.ops {
+		next = op(next)
+	}
+	if ctx != nil {
+		if id := tracing.RequestID(ctx); id != "" {
+			r.AddAttrs(slog.String("request_id", id))
+		}
+		if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+			r.AddAttrs(slog.String("trace_id", sc.TraceID().String()), slog.String("span_id", sc.SpanID().String()))
+		}
+	}
+	return next.Handle(ctx, r)
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(func(next slog.Handler) slog.Handler { return next.WithAttrs(attrs) })
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	return h.with(func(next slog.Handler) slog.Handler { return next.WithGroup(name) })
+}
+
+func (h *handler) with(op func(slog.Handler) slog.Handler) *handler {
+	ops := make([]func(slog.Handler) slog.Handler, len(h.ops), len(h.ops)+1)
+	copy(ops, h.ops)
+	return &handler{component: h.component, ops: append(ops, op)}
+}
+
+// Writer returns an io.Writer that logs each write as one message on l at
+// level. It adapts libraries that only accept a writer, such as Echo's logger.
+func Writer(l *slog.Logger, level slog.Level) io.Writer {
+	return lineWriter{logger: l, level: level}
+}
+
+type lineWriter struct {
+	logger *slog.Logger
+	level  slog.Level
+}
+
+func (w lineWriter) Write(p []byte) (int, error) {
+	w.logger.Log(context.Background(), w.level, strings.TrimRight(string(p), "\n"))
+	return len(p), nil
+}
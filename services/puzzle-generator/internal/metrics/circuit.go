@@ -0,0 +1,47 @@
+package metrics
+
+import (
+	"github.com/chess-puzzle-next/puzzle-generator/pkg/resilient"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	upstreamRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upstream_retries_total",
+		Help:      "Retried outbound calls by upstream client and reason (timeout, transport, http_<status>).",
+	}, []string{"upstream", "reason"})
+
+	circuitTransitions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upstream_circuit_transitions_total",
+		Help:      "Circuit breaker state changes by upstream client and new state.",
+	}, []string{"upstream", "state"})
+)
+
+func init() {
+	Registry.MustRegister(upstreamRetries, circuitTransitions)
+}
+
+// UpstreamRetry counts a retried call to upstream.
+func UpstreamRetry(upstream, reason string) {
+	upstreamRetries.WithLabelValues(upstream, reason).Inc()
+}
+
+// CircuitTransition counts a breaker of upstream moving to state.
+func CircuitTransition(upstream string, state resilient.State) {
+	circuitTransitions.WithLabelValues(upstream, state.String()).Inc()
+}
+
+// RegisterCircuit exports the breaker state of upstream as
+// upstream_circuit_state: 0 closed, 1 half-open, 2 open.
+func RegisterCircuit(upstream string, t *resilient.Transport) {
+	Registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace:   namespace,
+		Name:        "upstream_circuit_state",
+		Help:        "Circuit breaker state by upstream client: 0 closed, 1 half-open, 2 open.",
+		ConstLabels: prometheus.Labels{"upstream": upstream},
+	}, func() float64 {
+		return float64(t.State())
+	}))
+}
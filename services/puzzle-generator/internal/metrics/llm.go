@@ -0,0 +1,71 @@
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var llmFailovers = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "llm_failovers_total",
+	Help:      "LLM requests handed to the next provider, by the provider that failed.",
+}, []string{"provider"})
+
+var aiExplanations = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "ai_explanations_total",
+	Help:      "Puzzle explanation outcomes: cached, generated, rejected (a model answer failed the checks) or failed.",
+}, []string{"result"})
+
+var ragRetrievals = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "rag_retrievals_total",
+	Help:      "RAG candidate retrievals by retriever: index (vector search) or dataset (random rows).",
+}, []string{"retriever"})
+
+var llmTokens = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "llm_tokens_total",
+	Help:      "Tokens of LLM completions by provider, model and kind (prompt or completion).",
+}, []string{"provider", "model", "kind"})
+
+var llmCost = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "llm_cost_usd_total",
+	Help:      "Cost of LLM completions in USD by provider and model, from the LLM_PRICES table.",
+}, []string{"provider", "model"})
+
+var aiSpendLimited = prometheus.NewCounter(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "ai_spend_limited_total",
+	Help:      "LLM calls refused because the daily AI spend limit was reached.",
+})
+
+func init() {
+	Registry.MustRegister(llmFailovers, aiExplanations, ragRetrievals, llmTokens, llmCost, aiSpendLimited)
+}
+
+// LLMUsage counts the tokens and cost of a completion.
+func LLMUsage(provider, model string, promptTokens, completionTokens int, cost float64) {
+	llmTokens.WithLabelValues(provider, model, "prompt").Add(float64(promptTokens))
+	llmTokens.WithLabelValues(provider, model, "completion").Add(float64(completionTokens))
+	llmCost.WithLabelValues(provider, model).Add(cost)
+}
+
+// AISpendLimited counts an LLM call refused by the daily spend limit.
+func AISpendLimited() {
+	aiSpendLimited.Inc()
+}
+
+// LLMFailover counts a request that provider failed and the next provider
+// was asked instead.
+func LLMFailover(provider string) {
+	llmFailovers.WithLabelValues(provider).Inc()
+}
+
+// RAGRetrieval counts a retrieval of RAG candidates by retriever.
+func RAGRetrieval(retriever string) {
+	ragRetrievals.WithLabelValues(retriever).Inc()
+}
+
+// Explanation counts a puzzle explanation outcome.
+func Explanation(result string) {
+	aiExplanations.WithLabelValues(result).Inc()
+}
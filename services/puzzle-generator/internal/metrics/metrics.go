@@ -0,0 +1,146 @@
+// Package metrics defines the Prometheus collectors of the puzzle generator
+// and the handler that serves them on /metrics.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "puzzle_generator"
+
+// Registry holds every collector of the service. It is separate from the
+// default registry so only the metrics defined here are exported.
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of HTTP requests by route pattern.",
+		Buckets:   []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+	}, []string{"method", "route", "status"})
+
+	upstreamDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "upstream_request_duration_seconds",
+		Help:      "Latency of outbound calls by upstream client.",
+		Buckets:   []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 20, 30},
+	}, []string{"upstream", "code"})
+
+	upstreamErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upstream_errors_total",
+		Help:      "Failed outbound calls by upstream client and reason.",
+	}, []string{"upstream", "reason"})
+
+	aiSelections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ai_selections_total",
+		Help:      "AI puzzles served by source (ai-rag or ai-rag-fallback).",
+	}, []string{"source"})
+
+	puzzlesServed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "puzzles_served_total",
+		Help:      "Puzzles served by endpoint chain (random, id, daily, dataset) and the source that answered.",
+	}, []string{"endpoint", "source"})
+
+	puzzleCacheLookups = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "puzzle_cache_lookups_total",
+		Help:      "Puzzle lookups by ID by result (memory, redis or miss).",
+	}, []string{"result"})
+
+	dedupRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "dedup_retries_total",
+		Help:      "Refetches because the viewer was already served the puzzle, by source and difficulty.",
+	}, []string{"source", "difficulty"})
+
+	dedupExhausted = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "dedup_exhausted_total",
+		Help:      "Requests that ran out of attempts and served the viewer a puzzle again, by source and difficulty.",
+	}, []string{"source", "difficulty"})
+
+	sessionEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "sessions_total",
+		Help:      "Puzzle session lifecycle events (created, solved, failed, deleted).",
+	}, []string{"event"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpDuration,
+		upstreamDuration,
+		upstreamErrors,
+		aiSelections,
+		puzzlesServed,
+		puzzleCacheLookups,
+		dedupRetries,
+		dedupExhausted,
+		sessionEvents,
+	)
+}
+
+// Handler serves the registry in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// ObserveRequest records a served HTTP request. route is the matched route
+// pattern, not the raw path, to keep label cardinality bounded.
+func ObserveRequest(method, route string, status int, d time.Duration) {
+	httpDuration.WithLabelValues(method, route, strconv.Itoa(status)).Observe(d.Seconds())
+}
+
+// AISelection counts an AI puzzle served with the given source.
+func AISelection(source string) {
+	aiSelections.WithLabelValues(source).Inc()
+}
+
+// PuzzleServed counts a puzzle served for endpoint by source.
+func PuzzleServed(endpoint, source string) {
+	puzzlesServed.WithLabelValues(endpoint, source).Inc()
+}
+
+// PuzzleCacheLookup counts a lookup by ID answered by the given cache tier,
+// or "miss".
+func PuzzleCacheLookup(result string) {
+	puzzleCacheLookups.WithLabelValues(result).Inc()
+}
+
+// DedupRetry counts a refetch from source caused by a puzzle the viewer was
+// already served.
+func DedupRetry(source, difficulty string) {
+	dedupRetries.WithLabelValues(source, difficulty).Inc()
+}
+
+// DedupExhausted counts a request that served a puzzle again after using up
+// its refetch attempts.
+func DedupExhausted(source, difficulty string) {
+	dedupExhausted.WithLabelValues(source, difficulty).Inc()
+}
+
+// Session lifecycle events.
+const (
+	SessionCreated = "created"
+	SessionSolved  = "solved"
+	SessionFailed  = "failed"
+	SessionDeleted = "deleted"
+	SessionHint    = "hint"
+)
+
+// SessionEvent counts a session lifecycle event.
+func SessionEvent(event string) {
+	sessionEvents.WithLabelValues(event).Inc()
+}
@@ -0,0 +1,34 @@
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var poolTakes = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "pool_takes_total",
+	Help:      "Requests that tried a pre-fetched puzzle pool, by source, difficulty and result (hit, empty).",
+}, []string{"source", "difficulty", "result"})
+
+func init() {
+	Registry.MustRegister(poolTakes)
+}
+
+// PoolTake counts a request served from the pool of source and difficulty,
+// or falling back to an on-demand fetch when it was empty.
+func PoolTake(source, difficulty string, hit bool) {
+	result := "empty"
+	if hit {
+		result = "hit"
+	}
+	poolTakes.WithLabelValues(source, difficulty, result).Inc()
+}
+
+// RegisterPool exports the number of ready puzzles in the pool of source and
+// difficulty as pool_depth.
+func RegisterPool(source, difficulty string, depth func() float64) {
+	Registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace:   namespace,
+		Name:        "pool_depth",
+		Help:        "Ready puzzles in the pre-fetched pool by source and difficulty.",
+		ConstLabels: prometheus.Labels{"source": source, "difficulty": difficulty},
+	}, depth))
+}
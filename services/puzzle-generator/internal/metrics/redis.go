@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	redisProbeTimeout = time.Second
+	// sessionCountTTL bounds how often the session count SCANs the keyspace;
+	// scrapes in between report the last value.
+	sessionCountTTL = 30 * time.Second
+)
+
+// RedisProbe is the subset of redis.Client read by the Redis gauges.
+type RedisProbe interface {
+	Healthy(ctx context.Context) bool
+	CountSessions(ctx context.Context) (int64, error)
+}
+
+// RegisterRedis exports Redis availability and the number of live puzzle
+// sessions. Both are read from r when Prometheus scrapes.
+func RegisterRedis(r RedisProbe) {
+	sessions := &sessionCounter{redis: r}
+	Registry.MustRegister(
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "redis_up",
+			Help:      "Whether Redis answered a ping (1) or not (0).",
+		}, func() float64 {
+			ctx, cancel := context.WithTimeout(context.Background(), redisProbeTimeout)
+			defer cancel()
+			if r.Healthy(ctx) {
+				return 1
+			}
+			return 0
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "sessions_active",
+			Help:      "Puzzle sessions currently stored in Redis.",
+		}, sessions.value),
+	)
+}
+
+// sessionCounter caches the session count between scrapes.
+type sessionCounter struct {
+	redis RedisProbe
+
+	mu        sync.Mutex
+	count     float64
+	refreshed time.Time
+}
+
+func (s *sessionCounter) value() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if time.Since(s.refreshed) < sessionCountTTL {
+		return s.count
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), redisProbeTimeout)
+	defer cancel()
+	n, err := s.redis.CountSessions(ctx)
+	if err != nil {
+		return s.count
+	}
+	s.count, s.refreshed = float64(n), time.Now()
+	return s.count
+}
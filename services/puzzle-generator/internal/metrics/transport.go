@@ -0,0 +1,80 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Upstream client names used as the upstream label.
+const (
+	UpstreamLichess     = "lichess"
+	UpstreamHuggingFace = "huggingface"
+	UpstreamNVIDIA      = "nvidia"
+	UpstreamOpenRouter  = "openrouter"
+	UpstreamLocalLLM    = "local-llm"
+	UpstreamEmbeddings  = "embeddings"
+)
+
+// Transport wraps next (http.DefaultTransport when nil) so every call records
+// its latency under upstream and counts transport failures and 4xx/5xx
+// answers as errors.
+func Transport(upstream string, next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &instrumentedTransport{upstream: upstream, next: next}
+}
+
+type instrumentedTransport struct {
+	upstream string
+	next     http.RoundTripper
+}
+
+func (t *instrumentedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	elapsed := time.Since(start).Seconds()
+
+	if err != nil {
+		upstreamDuration.WithLabelValues(t.upstream, "error").Observe(elapsed)
+		upstreamErrors.WithLabelValues(t.upstream, errorReason(err)).Inc()
+		return nil, err
+	}
+
+	code := statusClass(resp.StatusCode)
+	upstreamDuration.WithLabelValues(t.upstream, code).Observe(elapsed)
+	if resp.StatusCode >= 400 {
+		upstreamErrors.WithLabelValues(t.upstream, "http_"+code).Inc()
+	}
+	return resp, nil
+}
+
+// errorReason classifies a transport error as timeout, canceled (the caller
+// went away) or transport.
+func errorReason(err error) string {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	default:
+		return "transport"
+	}
+}
+
+func statusClass(code int) string {
+	switch {
+	case code >= 500:
+		return "5xx"
+	case code >= 400:
+		return "4xx"
+	case code >= 300:
+		return "3xx"
+	default:
+		return "2xx"
+	}
+}
@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"github.com/chess-puzzle-next/puzzle-generator/internal/aiusage"
+	"github.com/labstack/echo/v4"
+)
+
+// AIUsageUser stores the caller in the request context so the model calls
+// made for the request are accounted to them: the signed-in user, or the
+// owner of the API key. It must run after Authenticate and APIKey.
+func AIUsageUser() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if user := CurrentUser(c); user != nil {
+				req := c.Request()
+				c.SetRequest(req.WithContext(aiusage.WithUser(req.Context(), user.ID)))
+			}
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/chess-puzzle-next/puzzle-generator/internal/apikeys"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/models"
+	"github.com/labstack/echo/v4"
+)
+
+// APIKeyHeader carries a developer API key.
+const APIKeyHeader = "X-API-Key"
+
+const apiKeyContextKey = "auth.apikey"
+
+// CurrentAPIKey returns the API key the request was authenticated with, or
+// nil when none was sent.
+func CurrentAPIKey(c echo.Context) *apikeys.Principal {
+	p, _ := c.Get(apiKeyContextKey).(*apikeys.Principal)
+	return p
+}
+
+// APIKey authenticates requests carrying an X-API-Key header. It runs next
+// to Authenticate: a request may use an access token or an API key, not
+// both. The key must hold the scope of the route and have quota left for
+// the day. Keys owned by an account act as that account, so plan
+// entitlements apply to them as well. svc may be nil, in which case API keys
+// are refused with 503.
+func APIKey(svc *apikeys.Service) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			raw := c.Request().Header.Get(APIKeyHeader)
+			if raw == "" {
+				return next(c)
+			}
+			if svc == nil {
+				return c.JSON(http.StatusServiceUnavailable, models.ErrorResponse{Error: "API keys are unavailable"})
+			}
+			if CurrentUser(c) != nil {
+				return c.JSON(http.StatusBadRequest, models.ErrorResponse{
+					Error:   "invalid request",
+					Details: "Send either an access token or an API key, not both",
+				})
+			}
+
+			ctx := c.Request().Context()
+			key, err := svc.Authenticate(ctx, raw)
+			switch {
+			case errors.Is(err, apikeys.ErrInvalidKey), errors.Is(err, apikeys.ErrKeyRevoked):
+				return c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+					Error:   "unauthorized",
+					Details: "API key is invalid or revoked",
+				})
+			case err != nil:
+				logger.ErrorContext(c.Request().Context(), "api key auth", "err", err)
+				return c.JSON(http.StatusServiceUnavailable, models.ErrorResponse{Error: "API keys are unavailable"})
+			}
+
+			scope, ok := apikeys.ScopeFor(c.Request().Method, c.Path())
+			if !ok || !key.Has(scope) {
+				details := "API keys cannot access this endpoint"
+				if ok {
+					details = fmt.Sprintf("API key lacks the %q scope", scope)
+				}
+				return c.JSON(http.StatusForbidden, models.ErrorResponse{Error: "forbidden", Details: details})
+			}
+
+			quota, err := svc.Consume(ctx, key)
+			switch {
+			case errors.Is(err, apikeys.ErrQuotaExceeded):
+				setKeyQuotaHeaders(c, quota)
+				c.Response().Header().Set("Retry-After", strconv.Itoa(int(time.Until(quota.ResetsAt).Seconds())+1))
+				return c.JSON(http.StatusTooManyRequests, models.ErrorResponse{
+					Error:   "quota exceeded",
+					Details: fmt.Sprintf("This API key is limited to %d requests per day", quota.Limit),
+				})
+			case err != nil:
+				// Counters unavailable: the key is valid, serve the request.
+				logger.WarnContext(c.Request().Context(), "api key quota", "key", key.KeyID, "err", err)
+			}
+			setKeyQuotaHeaders(c, quota)
+
+			c.Set(apiKeyContextKey, key)
+			if key.OwnerID != "" {
+				c.Set(userContextKey, &AuthUser{
+					ID:    key.OwnerID,
+					Email: key.OwnerEmail,
+					Plan:  string(key.OwnerPlan),
+				})
+			}
+
+			err = next(c)
+			if quota != nil && (err != nil || c.Response().Status >= http.StatusInternalServerError) {
+				svc.Refund(ctx, key)
+			}
+			return err
+		}
+	}
+}
+
+func setKeyQuotaHeaders(c echo.Context, q *apikeys.Quota) {
+	if q == nil {
+		return
+	}
+	h := c.Response().Header()
+	h.Set("X-Quota-Limit", strconv.Itoa(q.Limit))
+	h.Set("X-Quota-Remaining", strconv.Itoa(max(q.Limit-q.Used, 0)))
+	h.Set("X-Quota-Reset", strconv.FormatInt(q.ResetsAt.Unix(), 10))
+}
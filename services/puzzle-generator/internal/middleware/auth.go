@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/chess-puzzle-next/puzzle-generator/internal/auth"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/models"
+	"github.com/labstack/echo/v4"
+)
+
+const userContextKey = "auth.user"
+
+// AuthUser is the authenticated caller stored in echo.Context.
+type AuthUser struct {
+	ID    string
+	Email string
+	Plan  string
+	Role  string
+}
+
+// CurrentUser returns the authenticated user, or nil for anonymous requests.
+func CurrentUser(c echo.Context) *AuthUser {
+	u, _ := c.Get(userContextKey).(*AuthUser)
+	return u
+}
+
+// Authenticate parses a "Bearer" access token when one is present and stores
+// the user in the context. Requests without a token pass through as
+// anonymous; requests with an invalid token are rejected.
+func Authenticate(tokens *auth.Tokens) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			header := c.Request().Header.Get(echo.HeaderAuthorization)
+			if header == "" {
+				return next(c)
+			}
+
+			raw, ok := strings.CutPrefix(header, "Bearer ")
+			if !ok || strings.TrimSpace(raw) == "" {
+				return unauthorized(c, "Authorization header must use the Bearer scheme")
+			}
+
+			claims, err := tokens.Parse(strings.TrimSpace(raw), auth.TokenTypeAccess)
+			if err != nil {
+				return unauthorized(c, "Access token is invalid or expired")
+			}
+
+			c.Set(userContextKey, &AuthUser{
+				ID:    claims.Subject,
+				Email: claims.Email,
+				Plan:  claims.Plan,
+				Role:  claims.Role,
+			})
+			return next(c)
+		}
+	}
+}
+
+// RequireAuth rejects anonymous requests.
+func RequireAuth() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if CurrentUser(c) == nil {
+				return unauthorized(c, "Sign in to use this endpoint")
+			}
+			return next(c)
+		}
+	}
+}
+
+// RequireAdmin rejects callers without the admin role.
+func RequireAdmin() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			user := CurrentUser(c)
+			if user == nil {
+				return unauthorized(c, "Sign in to use this endpoint")
+			}
+			if user.Role != auth.RoleAdmin {
+				return c.JSON(http.StatusForbidden, models.ErrorResponse{
+					Error:   "forbidden",
+					Details: "Admin role required",
+				})
+			}
+			return next(c)
+		}
+	}
+}
+
+func unauthorized(c echo.Context, details string) error {
+	c.Response().Header().Set(echo.HeaderWWWAuthenticate, `Bearer realm="puzzle-generator"`)
+	return c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+		Error:   "unauthorized",
+		Details: details,
+	})
+}
@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/chess-puzzle-next/puzzle-generator/internal/entitlements"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/models"
+	"github.com/labstack/echo/v4"
+)
+
+const featureFailedKey = "entitlements.failed"
+
+// FailFeature marks a request as failed after its response status was sent,
+// such as an event stream ending with an error, so RequireFeature refunds
+// the usage it consumed.
+func FailFeature(c echo.Context) {
+	c.Set(featureFailedKey, true)
+}
+
+// RequireFeature gates a route behind a plan feature and consumes one unit of
+// its quota. It must run after Authenticate. Anonymous callers get 401, plans
+// without the feature get 402 and exhausted quotas get 429. The unit is given
+// back when the handler fails with a 5xx so upstream outages do not eat into
+// the user's quota.
+func RequireFeature(ent *entitlements.Service, feature entitlements.Feature) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			user := CurrentUser(c)
+			if user == nil {
+				return unauthorized(c, "Sign in to use this feature")
+			}
+
+			ctx := c.Request().Context()
+			plan := ent.PlanOf(ctx, user.ID, user.Plan)
+			usage, err := ent.Consume(ctx, user.ID, plan, feature)
+			switch {
+			case errors.Is(err, entitlements.ErrNotEntitled):
+				return c.JSON(http.StatusPaymentRequired, models.ErrorResponse{
+					Error:   "premium required",
+					Details: fmt.Sprintf("The %s plan does not include %s", plan, feature),
+				})
+			case errors.Is(err, entitlements.ErrQuotaExceeded):
+				setQuotaHeaders(c, usage)
+				retry := int(time.Until(usage.ResetsAt).Seconds()) + 1
+				c.Response().Header().Set("Retry-After", strconv.Itoa(retry))
+				return c.JSON(http.StatusTooManyRequests, models.ErrorResponse{
+					Error:   "quota exceeded",
+					Details: fmt.Sprintf("%s is limited to %d per %s on the %s plan", feature, usage.Quota.Limit, usage.Quota.Period, plan),
+				})
+			case err != nil:
+				// Counters unavailable: the plan check already passed, so
+				// serve the request rather than fail it.
+				logger.WarnContext(c.Request().Context(), "consume feature usage", "feature", feature, "user", user.ID, "err", err)
+				return next(c)
+			}
+
+			setQuotaHeaders(c, usage)
+			err = next(c)
+			if err != nil || c.Response().Status >= http.StatusInternalServerError || c.Get(featureFailedKey) != nil {
+				ent.Refund(ctx, user.ID, plan, feature)
+			}
+			return err
+		}
+	}
+}
+
+func setQuotaHeaders(c echo.Context, u *entitlements.Usage) {
+	if u == nil || u.Quota.Unlimited() {
+		return
+	}
+	h := c.Response().Header()
+	h.Set("X-Quota-Limit", strconv.Itoa(u.Quota.Limit))
+	h.Set("X-Quota-Remaining", strconv.Itoa(u.Remaining()))
+	h.Set("X-Quota-Reset", strconv.FormatInt(u.ResetsAt.Unix(), 10))
+}
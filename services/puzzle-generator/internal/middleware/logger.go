@@ -1,13 +1,18 @@
 package middleware
 
 import (
+	"log/slog"
 	"time"
 
+	"github.com/chess-puzzle-next/puzzle-generator/internal/logging"
 	"github.com/labstack/echo/v4"
 )
 
+var logger = logging.For("middleware")
+
 // RequestLogger returns an Echo middleware that logs each request with
-// method, path, status, latency, and remote IP.
+// method, path, route, status, latency, and remote IP. Server errors are
+// logged at error level, client errors at warn.
 func RequestLogger() echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
@@ -21,16 +26,27 @@ func RequestLogger() echo.MiddlewareFunc {
 
 			req := c.Request()
 			res := c.Response()
-			latency := time.Since(start)
-
-			c.Logger().Infof(
-				"method=%s path=%s status=%d latency=%s ip=%s",
-				req.Method,
-				req.URL.Path,
-				res.Status,
-				latency.Round(time.Millisecond),
-				c.RealIP(),
-			)
+			level := slog.LevelInfo
+			switch {
+			case res.Status >= 500:
+				level = slog.LevelError
+			case res.Status >= 400:
+				level = slog.LevelWarn
+			}
+
+			attrs := []slog.Attr{
+				slog.String("method", req.Method),
+				slog.String("path", req.URL.Path),
+				slog.String("route", c.Path()),
+				slog.Int("status", res.Status),
+				slog.Int64("latency_ms", time.Since(start).Milliseconds()),
+				slog.Int64("bytes", res.Size),
+				slog.String("ip", c.RealIP()),
+			}
+			if err != nil {
+				attrs = append(attrs, slog.Any("err", err))
+			}
+			logger.LogAttrs(req.Context(), level, "request", attrs...)
 
 			return nil
 		}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/chess-puzzle-next/puzzle-generator/internal/metrics"
+	"github.com/labstack/echo/v4"
+)
+
+// Metrics returns an Echo middleware that records request latency per route
+// pattern. Requests that match no route are grouped under "unmatched".
+func Metrics() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+
+			err := next(c)
+			if err != nil {
+				// Let Echo central error handler set the proper status/body first.
+				c.Error(err)
+			}
+
+			route := c.Path()
+			if route == "" {
+				route = "unmatched"
+			}
+			metrics.ObserveRequest(c.Request().Method, route, c.Response().Status, time.Since(start))
+
+			return nil
+		}
+	}
+}
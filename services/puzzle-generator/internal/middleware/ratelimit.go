@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/chess-puzzle-next/puzzle-generator/internal/entitlements"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/models"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/ratelimit"
+	"github.com/labstack/echo/v4"
+)
+
+// RateLimit throttles requests with the limiter's token buckets. It must run
+// after Authenticate and APIKey: signed-in callers are limited per user with
+// their plan's budget, API keys per key, anonymous callers per client IP. Every limited response
+// carries RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset; throttled
+// requests get 429 with Retry-After.
+func RateLimit(limiter *ratelimit.Limiter) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			group, ok := ratelimit.Classify(c.Request().Method, c.Path())
+			if !ok {
+				return next(c)
+			}
+
+			tier, subject := ratelimit.TierAnonymous, "ip:"+c.RealIP()
+			if user := CurrentUser(c); user != nil {
+				// The token's plan claim may lag a plan change by one access
+				// token lifetime, which is fine for throttling.
+				tier, subject = string(entitlements.ParsePlan(user.Plan)), "user:"+user.ID
+			}
+			if key := CurrentAPIKey(c); key != nil {
+				subject = "key:" + key.KeyID
+			}
+
+			res, limited := limiter.Allow(c.Request().Context(), group, tier, subject)
+			if !limited {
+				return next(c)
+			}
+
+			h := c.Response().Header()
+			h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+			h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.ResetAfter)))
+			if res.Allowed {
+				return next(c)
+			}
+
+			h.Set("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter)))
+			return c.JSON(http.StatusTooManyRequests, models.ErrorResponse{
+				Error:   "rate limit exceeded",
+				Details: fmt.Sprintf("Too many %s requests; retry in %d seconds", group, ceilSeconds(res.RetryAfter)),
+			})
+		}
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/chess-puzzle-next/puzzle-generator/internal/tracing"
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestID takes the X-Request-ID header (or generates one when missing or
+// malformed), echoes it in the response and stores it in the request context
+// for spans, logs and upstream calls.
+func RequestID() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			id := tracing.NormalizeRequestID(req.Header.Get(tracing.RequestIDHeader))
+			c.Response().Header().Set(tracing.RequestIDHeader, id)
+			c.SetRequest(req.WithContext(tracing.WithRequestID(req.Context(), id)))
+			return next(c)
+		}
+	}
+}
+
+// Tracing returns an Echo middleware that continues the incoming W3C trace
+// context and wraps each request in a server span named after its route
+// pattern. It must run after RequestID.
+func Tracing() echo.MiddlewareFunc {
+	tracer := tracing.Tracer()
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			ctx := otel.GetTextMapPropagator().Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+
+			route := c.Path()
+			if route == "" {
+				route = "unmatched"
+			}
+			ctx, span := tracer.Start(ctx, req.Method+" "+route,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					semconv.HTTPRequestMethodKey.String(req.Method),
+					semconv.HTTPRoute(route),
+					semconv.URLPath(req.URL.Path),
+					semconv.ClientAddress(c.RealIP()),
+					attribute.String("http.request.id", tracing.RequestID(ctx)),
+				),
+			)
+			defer span.End()
+			c.SetRequest(req.WithContext(ctx))
+
+			err := next(c)
+			if err != nil {
+				// Let Echo central error handler set the proper status/body first.
+				c.Error(err)
+			}
+
+			status := c.Response().Status
+			span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+			if status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(status))
+			}
+			if err != nil {
+				span.RecordError(err)
+			}
+
+			return nil
+		}
+	}
+}
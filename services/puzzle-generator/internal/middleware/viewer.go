@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/chess-puzzle-next/puzzle-generator/internal/seen"
+	"github.com/labstack/echo/v4"
+)
+
+// Viewer stores who is being served puzzles in the request context, for
+// per-viewer de-duplication. It must run after Authenticate and APIKey:
+// signed-in callers are tracked per user, anonymous callers per X-Device-ID
+// and, without a valid one, per client IP.
+func Viewer() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			viewer := "ip:" + c.RealIP()
+			if user := CurrentUser(c); user != nil {
+				viewer = "user:" + user.ID
+			} else if id := strings.TrimSpace(c.Request().Header.Get(seen.DeviceIDHeader)); seen.ValidDeviceID(id) {
+				viewer = "device:" + id
+			}
+			req := c.Request()
+			c.SetRequest(req.WithContext(seen.WithViewer(req.Context(), viewer)))
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package models
+
+// Stages of the AI puzzle pipeline, sent as SSE event names by
+// GET /puzzle/ai/stream. The stream ends with a "puzzle" or "error" event.
+const (
+	AIStageThinking   = "thinking"   // the model is turning the prompt into a filter
+	AIStageToken      = "token"      // a piece of the model's output
+	AIStageFilter     = "filter"     // the filter to run
+	AIStageCandidates = "candidates" // puzzles matching the filter
+	AIStageSelected   = "selected"   // a candidate was selected
+	AIStageValidated  = "validated"  // the selected puzzle's moves are legal
+)
+
+// AIProgressEvent is the data of a progress event of GET /puzzle/ai/stream.
+type AIProgressEvent struct {
+	Stage      string `json:"stage"`
+	ElapsedMs  int64  `json:"elapsedMs"`
+	Candidates int    `json:"candidates,omitempty"`
+	Retriever  string `json:"retriever,omitempty"` // index (vector search) or dataset (random batches)
+	Attempt    int    `json:"attempt,omitempty"`   // 1 for the first model call
+	Token      string `json:"token,omitempty"`
+	Provider   string `json:"provider,omitempty"`
+	Model      string `json:"model,omitempty"`
+	Index      *int   `json:"index,omitempty"` // selected candidate
+	PuzzleID   string `json:"puzzleId,omitempty"`
+	// Filter is the query of the filter stage. Fallback marks a filter
+	// taken from the prompt's keywords because the model gave no valid one.
+	// PromptVersion is the prompt template the model was given.
+	Filter        *PuzzleFilter `json:"filter,omitempty"`
+	Fallback      bool          `json:"fallback,omitempty"`
+	PromptVersion string        `json:"promptVersion,omitempty"`
+}
@@ -0,0 +1,58 @@
+package models
+
+import "time"
+
+// AIUsage counts model calls, their tokens and their cost.
+type AIUsage struct {
+	Calls            int64   `json:"calls"`
+	PromptTokens     int64   `json:"promptTokens"`
+	CompletionTokens int64   `json:"completionTokens"`
+	CostUSD          float64 `json:"costUsd"`
+}
+
+// AIUsageDay is the AI usage of one UTC day.
+type AIUsageDay struct {
+	Date string `json:"date" example:"2026-10-18"`
+	AIUsage
+}
+
+// AIUsageResponse is the body of GET /me/usage.
+type AIUsageResponse struct {
+	Days  []AIUsageDay `json:"days"` // newest first
+	Total AIUsage      `json:"total"`
+	// AIPaused is set while the service's daily AI spend limit is reached.
+	// AI features resume at ResumesAt.
+	AIPaused  bool       `json:"aiPaused"`
+	ResumesAt *time.Time `json:"resumesAt,omitempty"`
+}
+
+// ModelAIUsage is the AI usage of one model.
+type ModelAIUsage struct {
+	Model string `json:"model" example:"meta/llama-3.3-70b-instruct"`
+	AIUsage
+}
+
+// UserAIUsage is the AI usage of one user.
+type UserAIUsage struct {
+	UserID string `json:"userId"`
+	AIUsage
+}
+
+// AIUsageReportDay is the admin view of one UTC day.
+type AIUsageReportDay struct {
+	Date string `json:"date" example:"2026-10-18"`
+	AIUsage
+	Models   []ModelAIUsage `json:"models"`   // most expensive first
+	TopUsers []UserAIUsage  `json:"topUsers"` // most expensive first
+}
+
+// AIUsageReport is the body of GET /admin/usage.
+type AIUsageReport struct {
+	Days  []AIUsageReportDay `json:"days"` // newest first
+	Total AIUsage            `json:"total"`
+	// DailySpendLimitUSD is the spend after which AI features are paused
+	// until the next UTC day; 0 means no limit.
+	DailySpendLimitUSD float64    `json:"dailySpendLimitUsd"`
+	AIPaused           bool       `json:"aiPaused"`
+	ResumesAt          *time.Time `json:"resumesAt,omitempty"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// CreateAPIKeyRequest is the body for POST /admin/keys.
+type CreateAPIKeyRequest struct {
+	Name       string   `json:"name"`
+	OwnerID    string   `json:"ownerId,omitempty"` // account the key acts for; required for the "ai" scope
+	Scopes     []string `json:"scopes"`
+	DailyQuota int      `json:"dailyQuota"` // requests per UTC day, 0 = unlimited
+}
+
+// APIKeyInfo is the admin view of an API key. The secret is never returned
+// after creation.
+type APIKeyInfo struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"` // identifies the key without revealing it
+	OwnerID    string     `json:"ownerId,omitempty"`
+	Scopes     []string   `json:"scopes"`
+	DailyQuota int        `json:"dailyQuota"`
+	UsedToday  int        `json:"usedToday"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	RevokedAt  *time.Time `json:"revokedAt,omitempty"`
+}
+
+// APIKeyCreated is returned once, when a key is issued.
+type APIKeyCreated struct {
+	APIKeyInfo
+	Key string `json:"key"` // send as the X-API-Key header; store it now, it cannot be shown again
+}
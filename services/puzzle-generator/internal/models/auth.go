@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+// RegisterRequest is the body for POST /auth/register.
+type RegisterRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// LoginRequest is the body for POST /auth/login.
+type LoginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// RefreshRequest is the body for POST /auth/refresh and POST /auth/logout.
+type RefreshRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+// UserProfile is the public view of an account.
+type UserProfile struct {
+	ID        string    `json:"id"`
+	Email     string    `json:"email"`
+	Plan      string    `json:"plan"`
+	CreatedAt time.Time `json:"createdAt"`
+
+	// Subscription state, present once the user has been through checkout.
+	PlanStatus  string     `json:"planStatus,omitempty"`
+	TrialEndsAt *time.Time `json:"trialEndsAt,omitempty"`
+	GraceUntil  *time.Time `json:"graceUntil,omitempty"`
+
+	// Puzzle rating, updated by finished sessions.
+	Rating        int `json:"rating"`
+	RatedSessions int `json:"ratedSessions"`
+}
+
+// TokenPair is an access token and the refresh token used to renew it.
+type TokenPair struct {
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken"`
+	TokenType    string `json:"tokenType"`
+	ExpiresIn    int    `json:"expiresIn"` // access token lifetime in seconds
+}
+
+// AuthResponse is returned by register, login and refresh.
+type AuthResponse struct {
+	User   UserProfile `json:"user"`
+	Tokens TokenPair   `json:"tokens"`
+}
@@ -0,0 +1,6 @@
+package models
+
+// WebhookAck is returned to the billing provider for accepted deliveries.
+type WebhookAck struct {
+	Received bool `json:"received"`
+}
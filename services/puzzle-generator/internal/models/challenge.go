@@ -0,0 +1,101 @@
+package models
+
+import "time"
+
+// RatingBand is a rating range used to spread challenge puzzles from easy to
+// hard, and the points awarded for solving a puzzle from that band.
+type RatingBand struct {
+	Name      string `json:"name"`
+	MinRating int    `json:"minRating"`
+	MaxRating int    `json:"maxRating"`
+	Points    int    `json:"points"`
+}
+
+// ChallengeRatingBands are the bands every weekly challenge draws from.
+var ChallengeRatingBands = []RatingBand{
+	{Name: "beginner", MinRating: 0, MaxRating: 1199, Points: 10},
+	{Name: "intermediate", MinRating: 1200, MaxRating: 1599, Points: 20},
+	{Name: "advanced", MinRating: 1600, MaxRating: 1999, Points: 30},
+	{Name: "expert", MinRating: 2000, MaxRating: 2399, Points: 40},
+	{Name: "master", MinRating: 2400, MaxRating: 9999, Points: 50},
+}
+
+// RatingToBand returns the challenge band containing rating.
+func RatingToBand(rating int) RatingBand {
+	for _, b := range ChallengeRatingBands {
+		if rating >= b.MinRating && rating <= b.MaxRating {
+			return b
+		}
+	}
+	return ChallengeRatingBands[len(ChallengeRatingBands)-1]
+}
+
+// ChallengePuzzle is one puzzle of a weekly challenge.
+type ChallengePuzzle struct {
+	Band   string  `json:"band"`
+	Points int     `json:"points"`
+	Puzzle *Puzzle `json:"puzzle"`
+}
+
+// WeeklyChallenge is the themed puzzle set published for one ISO week.
+type WeeklyChallenge struct {
+	ID        string            `json:"id"` // ISO week, e.g. "2026-W42"
+	Theme     string            `json:"theme"`
+	ThemeName string            `json:"themeName"`
+	StartsAt  time.Time         `json:"startsAt"`
+	EndsAt    time.Time         `json:"endsAt"`
+	Puzzles   []ChallengePuzzle `json:"puzzles"`
+	CreatedAt time.Time         `json:"createdAt"`
+}
+
+// Closed reports whether the challenge week is over at the given time.
+func (c *WeeklyChallenge) Closed(now time.Time) bool {
+	return !now.Before(c.EndsAt)
+}
+
+// ChallengeAttempt records a player's single scored attempt at a puzzle.
+type ChallengeAttempt struct {
+	PuzzleID    string    `json:"puzzleId"`
+	Solved      bool      `json:"solved"`
+	Points      int       `json:"points"`
+	AttemptedAt time.Time `json:"attemptedAt"`
+}
+
+// ChallengeEntry is a player's progress in a weekly challenge.
+type ChallengeEntry struct {
+	PlayerID  string             `json:"playerId"`
+	Name      string             `json:"name"`
+	Score     int                `json:"score"`
+	Solved    int                `json:"solved"`
+	Attempted int                `json:"attempted"`
+	Completed bool               `json:"completed"`
+	Attempts  []ChallengeAttempt `json:"attempts"`
+	UpdatedAt time.Time          `json:"updatedAt"`
+}
+
+// ChallengeAttemptRequest is the body for submitting a challenge attempt.
+type ChallengeAttemptRequest struct {
+	PlayerID string   `json:"playerId"`
+	Name     string   `json:"name"`
+	PuzzleID string   `json:"puzzleId"`
+	Solved   bool     `json:"solved"`
+	Moves    []string `json:"moves"` // the player's moves in UCI; required when solved
+}
+
+// ChallengeResultRow is one line of a closed challenge's results table.
+type ChallengeResultRow struct {
+	Rank      int    `json:"rank"`
+	Player    string `json:"player"` // short hash of the player ID
+	Name      string `json:"name"`
+	Score     int    `json:"score"`
+	Solved    int    `json:"solved"`
+	Attempted int    `json:"attempted"`
+}
+
+// ChallengeResults is the final standings of a closed weekly challenge.
+type ChallengeResults struct {
+	ChallengeID string               `json:"challengeId"`
+	Theme       string               `json:"theme"`
+	ClosedAt    time.Time            `json:"closedAt"`
+	Rows        []ChallengeResultRow `json:"rows"`
+}
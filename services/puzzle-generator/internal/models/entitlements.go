@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// FeatureEntitlement describes one gated feature for the caller's plan.
+type FeatureEntitlement struct {
+	Feature   string     `json:"feature"`
+	Enabled   bool       `json:"enabled"`
+	Unlimited bool       `json:"unlimited"`
+	Limit     int        `json:"limit,omitempty"`
+	Period    string     `json:"period,omitempty"`
+	Used      int        `json:"used"`
+	Remaining int        `json:"remaining"`
+	ResetsAt  *time.Time `json:"resetsAt,omitempty"`
+}
+
+// EntitlementsResponse is the body of GET /me/entitlements.
+type EntitlementsResponse struct {
+	Plan     string               `json:"plan"`
+	Features []FeatureEntitlement `json:"features"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// ExplainRequest is the body of POST /puzzle/:id/explain.
+type ExplainRequest struct {
+	// Language of the explanation as an ISO 639-1 code, optionally with a
+	// region (fr, pt-BR). Defaults to en.
+	Language string `json:"language" example:"en"`
+}
+
+// PuzzleExplanation explains a puzzle's solution move by move.
+type PuzzleExplanation struct {
+	PuzzleID  string            `json:"puzzleId"`
+	Language  string            `json:"language"`
+	Summary   string            `json:"summary"`
+	Moves     []MoveExplanation `json:"moves"`
+	Provider  string            `json:"provider"`
+	Model     string            `json:"model"`
+	CreatedAt time.Time         `json:"createdAt"`
+
+	CacheStatus string `json:"-"` // HIT or MISS, sent as X-Cache
+}
+
+// MoveExplanation explains one move of a puzzle's solution.
+type MoveExplanation struct {
+	Ply         int    `json:"ply"`  // 1 for the player's first move
+	Side        string `json:"side"` // player or opponent
+	SAN         string `json:"san"`
+	UCI         string `json:"uci"`
+	Explanation string `json:"explanation"`
+}
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"slices"
+	"strings"
+)
+
+// PuzzleFilter is a structured puzzle query. The AI pipeline derives it from
+// the prompt and returns it with the puzzle; sending it back in
+// AIPuzzleRequest.Filter reruns the query without the model, so users can
+// refine it. Zero values do not filter.
+type PuzzleFilter struct {
+	MinRating     int      `json:"minRating,omitempty"`
+	MaxRating     int      `json:"maxRating,omitempty"`
+	Themes        []string `json:"themes,omitempty"`        // theme keys the puzzle must all have
+	ExcludeThemes []string `json:"excludeThemes,omitempty"` // theme keys the puzzle must not have
+	Opening       string   `json:"opening,omitempty"`       // opening family or variation, such as "Sicilian Defense"
+	Color         string   `json:"color,omitempty"`         // side the player solves for: white or black
+	MaxMoves      int      `json:"maxMoves,omitempty"`      // most player moves in the solution
+}
+
+// Matches reports whether p satisfies every condition of f.
+func (f *PuzzleFilter) Matches(p *Puzzle) bool {
+	switch {
+	case f.MinRating > 0 && p.Rating < f.MinRating,
+		f.MaxRating > 0 && p.Rating > f.MaxRating,
+		f.MaxMoves > 0 && len(p.Moves)/2 > f.MaxMoves,
+		f.Color != "" && playerColor(p) != f.Color:
+		return false
+	}
+	for _, k := range f.Themes {
+		if !slices.Contains(p.Themes, k) {
+			return false
+		}
+	}
+	for _, k := range f.ExcludeThemes {
+		if slices.Contains(p.Themes, k) {
+			return false
+		}
+	}
+	if f.Opening != "" {
+		tag := openingTag(f.Opening)
+		return slices.ContainsFunc(p.OpeningTags, func(t string) bool {
+			return strings.HasPrefix(strings.ToLower(t), tag)
+		})
+	}
+	return true
+}
+
+// openingTag turns an opening name into the lowercased prefix of its Lichess
+// opening tags: "Queen's Gambit" becomes "queens_gambit".
+func openingTag(name string) string {
+	name = strings.ToLower(strings.ReplaceAll(name, "'", ""))
+	return strings.ReplaceAll(name, " ", "_")
+}
+
+// playerColor returns the side the player solves for. The FEN is the
+// position before the opponent's setup move, so it is the other side.
+func playerColor(p *Puzzle) string {
+	if fields := strings.Fields(p.FEN); len(fields) > 1 && fields[1] == "w" {
+		return "black"
+	}
+	return "white"
+}
@@ -3,4 +3,7 @@ package models
 type AIPuzzleRequest struct {
 	Prompt     string          `json:"prompt"`
 	Difficulty DifficultyLevel `json:"difficulty"`
+	// Filter, when set, is used as is instead of asking the model to derive
+	// one from the prompt. The prompt is then optional.
+	Filter *PuzzleFilter `json:"filter,omitempty"`
 }
@@ -0,0 +1,24 @@
+package models
+
+// Hint kinds, from the vaguest to the full answer.
+const (
+	HintTheme  = "theme"  // the puzzle's main tactical theme
+	HintPiece  = "piece"  // the kind of piece to move
+	HintSquare = "square" // the square the piece moves from
+	HintMove   = "move"   // the move itself
+)
+
+// SessionHint is the body returned by POST /session/:id/hint.
+type SessionHint struct {
+	Level     int    `json:"level"` // 1 theme, 2 piece, 3 square, 4 move
+	Kind      string `json:"kind"`
+	MoveIndex int    `json:"move_index"` // index in the session's moves of the move the hint is about
+	Theme     string `json:"theme,omitempty"`
+	ThemeName string `json:"theme_name,omitempty"`
+	Piece     string `json:"piece,omitempty"`  // king, queen, rook, bishop, knight or pawn
+	Square    string `json:"square,omitempty"` // source square, e.g. f3
+	Move      string `json:"move,omitempty"`   // UCI
+	SAN       string `json:"san,omitempty"`
+	HintsUsed int    `json:"hints_used"`         // hints counted in the session so far
+	Repeated  bool   `json:"repeated,omitempty"` // the move was already revealed; not counted again
+}
@@ -11,9 +11,14 @@ type Puzzle struct {
 	Popularity      int             `json:"popularity"`
 	NbPlays         int             `json:"nbPlays"`
 	Themes          []string        `json:"themes"`
+	OpeningTags     []string        `json:"openingTags,omitempty"` // Lichess opening names, such as Sicilian_Defense_Najdorf_Variation
 	GameURL         string          `json:"gameUrl,omitempty"`
 	Difficulty      DifficultyLevel `json:"difficulty"`
 	Source          string          `json:"source"`
+	ServedBy        string          `json:"servedBy,omitempty"`      // chain source that answered: lichess, store or huggingface
+	Filter          *PuzzleFilter   `json:"filter,omitempty"`        // query the AI pipeline ran to find the puzzle
+	PromptVersion   string          `json:"promptVersion,omitempty"` // prompt template the model derived the filter with, such as filter/v1
+	CacheStatus     string          `json:"-"`                       // HIT or MISS for lookups by ID, sent as X-Cache
 }
 
 // LichessPuzzleResponse is the raw Lichess API puzzle response shape.
@@ -68,6 +73,7 @@ func (r *LichessPuzzleResponse) ToCanonical() *Puzzle {
 
 // ErrorResponse is the standard API error envelope.
 type ErrorResponse struct {
-	Error   string `json:"error"`
-	Details string `json:"details,omitempty"`
+	Error   string        `json:"error"`
+	Details string        `json:"details,omitempty"`
+	Filter  *PuzzleFilter `json:"filter,omitempty"` // AI query that matched no puzzle, to refine
 }
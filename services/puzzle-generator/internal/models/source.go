@@ -0,0 +1,21 @@
+package models
+
+// SourceStatus is the admin view of a puzzle source.
+type SourceStatus struct {
+	Name      string   `json:"name" example:"lichess"`
+	Enabled   bool     `json:"enabled"`   // switched on by an admin
+	Available bool     `json:"available"` // configured in this deployment
+	Endpoints []string `json:"endpoints"` // chains the source is part of
+}
+
+// PuzzleSources lists the puzzle sources and the priority chain of each
+// endpoint.
+type PuzzleSources struct {
+	Sources []SourceStatus      `json:"sources"`
+	Chains  map[string][]string `json:"chains"` // endpoint (random, id, daily, dataset) → sources in order
+}
+
+// UpdateSourceRequest is the body for PUT /admin/sources/{name}.
+type UpdateSourceRequest struct {
+	Enabled *bool `json:"enabled"`
+}
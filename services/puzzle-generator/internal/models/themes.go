@@ -0,0 +1,112 @@
+package models
+
+// ThemeCategory groups Lichess puzzle themes by what they describe.
+type ThemeCategory string
+
+const (
+	ThemeCategoryTactic ThemeCategory = "tactic"
+	ThemeCategoryMate   ThemeCategory = "mate"
+	ThemeCategoryPhase  ThemeCategory = "phase"
+	ThemeCategoryLength ThemeCategory = "length"
+	ThemeCategoryGoal   ThemeCategory = "goal"
+)
+
+// Theme describes one entry of the Lichess puzzle theme taxonomy.
+type Theme struct {
+	Key      string        `json:"key"`
+	Name     string        `json:"name"`
+	Category ThemeCategory `json:"category"`
+	// Weekly marks themes common enough in the dataset to fill a weekly
+	// challenge across every rating band.
+	Weekly bool `json:"weekly"`
+}
+
+// ThemeTaxonomy is the list of Lichess puzzle themes the service knows about.
+// The order of weekly themes defines the weekly challenge rotation, so new
+// entries should be appended rather than inserted.
+var ThemeTaxonomy = []Theme{
+	{Key: "fork", Name: "Fork", Category: ThemeCategoryTactic, Weekly: true},
+	{Key: "pin", Name: "Pin", Category: ThemeCategoryTactic, Weekly: true},
+	{Key: "backRankMate", Name: "Back-rank mate", Category: ThemeCategoryMate, Weekly: true},
+	{Key: "skewer", Name: "Skewer", Category: ThemeCategoryTactic, Weekly: true},
+	{Key: "discoveredAttack", Name: "Discovered attack", Category: ThemeCategoryTactic, Weekly: true},
+	{Key: "hangingPiece", Name: "Hanging piece", Category: ThemeCategoryTactic, Weekly: true},
+	{Key: "deflection", Name: "Deflection", Category: ThemeCategoryTactic, Weekly: true},
+	{Key: "mateIn2", Name: "Mate in 2", Category: ThemeCategoryMate, Weekly: true},
+	{Key: "attraction", Name: "Attraction", Category: ThemeCategoryTactic, Weekly: true},
+	{Key: "sacrifice", Name: "Sacrifice", Category: ThemeCategoryTactic, Weekly: true},
+	{Key: "trappedPiece", Name: "Trapped piece", Category: ThemeCategoryTactic, Weekly: true},
+	{Key: "promotion", Name: "Promotion", Category: ThemeCategoryTactic, Weekly: true},
+	{Key: "kingsideAttack", Name: "Kingside attack", Category: ThemeCategoryTactic, Weekly: true},
+	{Key: "clearance", Name: "Clearance", Category: ThemeCategoryTactic, Weekly: true},
+	{Key: "quietMove", Name: "Quiet move", Category: ThemeCategoryTactic, Weekly: true},
+	{Key: "exposedKing", Name: "Exposed king", Category: ThemeCategoryTactic, Weekly: true},
+	{Key: "intermezzo", Name: "Intermezzo", Category: ThemeCategoryTactic, Weekly: true},
+	{Key: "defensiveMove", Name: "Defensive move", Category: ThemeCategoryTactic, Weekly: true},
+
+	{Key: "doubleCheck", Name: "Double check", Category: ThemeCategoryTactic},
+	{Key: "interference", Name: "Interference", Category: ThemeCategoryTactic},
+	{Key: "xRayAttack", Name: "X-ray attack", Category: ThemeCategoryTactic},
+	{Key: "zugzwang", Name: "Zugzwang", Category: ThemeCategoryTactic},
+	{Key: "capturingDefender", Name: "Capture the defender", Category: ThemeCategoryTactic},
+	{Key: "underPromotion", Name: "Underpromotion", Category: ThemeCategoryTactic},
+	{Key: "enPassant", Name: "En passant", Category: ThemeCategoryTactic},
+	{Key: "castling", Name: "Castling", Category: ThemeCategoryTactic},
+	{Key: "attackingF2F7", Name: "Attacking f2 or f7", Category: ThemeCategoryTactic},
+	{Key: "queensideAttack", Name: "Queenside attack", Category: ThemeCategoryTactic},
+	{Key: "advancedPawn", Name: "Advanced pawn", Category: ThemeCategoryTactic},
+
+	{Key: "mate", Name: "Checkmate", Category: ThemeCategoryMate},
+	{Key: "mateIn1", Name: "Mate in 1", Category: ThemeCategoryMate},
+	{Key: "mateIn3", Name: "Mate in 3", Category: ThemeCategoryMate},
+	{Key: "mateIn4", Name: "Mate in 4", Category: ThemeCategoryMate},
+	{Key: "mateIn5", Name: "Mate in 5 or more", Category: ThemeCategoryMate},
+	{Key: "smotheredMate", Name: "Smothered mate", Category: ThemeCategoryMate},
+	{Key: "anastasiaMate", Name: "Anastasia's mate", Category: ThemeCategoryMate},
+	{Key: "arabianMate", Name: "Arabian mate", Category: ThemeCategoryMate},
+	{Key: "bodenMate", Name: "Boden's mate", Category: ThemeCategoryMate},
+	{Key: "doubleBishopMate", Name: "Double bishop mate", Category: ThemeCategoryMate},
+	{Key: "dovetailMate", Name: "Dovetail mate", Category: ThemeCategoryMate},
+	{Key: "hookMate", Name: "Hook mate", Category: ThemeCategoryMate},
+
+	{Key: "opening", Name: "Opening", Category: ThemeCategoryPhase},
+	{Key: "middlegame", Name: "Middlegame", Category: ThemeCategoryPhase},
+	{Key: "endgame", Name: "Endgame", Category: ThemeCategoryPhase},
+	{Key: "rookEndgame", Name: "Rook endgame", Category: ThemeCategoryPhase},
+	{Key: "bishopEndgame", Name: "Bishop endgame", Category: ThemeCategoryPhase},
+	{Key: "pawnEndgame", Name: "Pawn endgame", Category: ThemeCategoryPhase},
+	{Key: "knightEndgame", Name: "Knight endgame", Category: ThemeCategoryPhase},
+	{Key: "queenEndgame", Name: "Queen endgame", Category: ThemeCategoryPhase},
+	{Key: "queenRookEndgame", Name: "Queen and rook endgame", Category: ThemeCategoryPhase},
+
+	{Key: "oneMove", Name: "One-move puzzle", Category: ThemeCategoryLength},
+	{Key: "short", Name: "Short puzzle", Category: ThemeCategoryLength},
+	{Key: "long", Name: "Long puzzle", Category: ThemeCategoryLength},
+	{Key: "veryLong", Name: "Very long puzzle", Category: ThemeCategoryLength},
+
+	{Key: "advantage", Name: "Advantage", Category: ThemeCategoryGoal},
+	{Key: "crushing", Name: "Crushing", Category: ThemeCategoryGoal},
+	{Key: "equality", Name: "Equality", Category: ThemeCategoryGoal},
+}
+
+// LookupTheme returns the taxonomy entry for key, if any.
+func LookupTheme(key string) (Theme, bool) {
+	for _, t := range ThemeTaxonomy {
+		if t.Key == key {
+			return t, true
+		}
+	}
+	return Theme{}, false
+}
+
+// WeeklyThemes returns the themes eligible for the weekly challenge, in
+// rotation order.
+func WeeklyThemes() []Theme {
+	out := make([]Theme, 0, len(ThemeTaxonomy))
+	for _, t := range ThemeTaxonomy {
+		if t.Weekly {
+			out = append(out, t)
+		}
+	}
+	return out
+}
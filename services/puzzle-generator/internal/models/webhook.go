@@ -0,0 +1,69 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// CreateWebhookRequest is the body for POST /webhooks.
+type CreateWebhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+}
+
+// WebhookEndpointInfo is a registered webhook endpoint.
+type WebhookEndpointInfo struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// WebhookEndpointCreated is returned once, when an endpoint is registered.
+type WebhookEndpointCreated struct {
+	WebhookEndpointInfo
+	Secret string `json:"secret"` // verifies X-Webhook-Signature; store it now, it cannot be shown again
+}
+
+// WebhookDeliveryInfo is an entry of an endpoint's delivery log.
+type WebhookDeliveryInfo struct {
+	ID            string          `json:"id"`
+	EventID       string          `json:"eventId"`
+	EventType     string          `json:"eventType"`
+	Status        string          `json:"status"` // pending, succeeded, failed
+	Attempts      int             `json:"attempts"`
+	ResponseCode  int             `json:"responseCode,omitempty"`
+	LastError     string          `json:"lastError,omitempty"`
+	CreatedAt     time.Time       `json:"createdAt"`
+	LastAttemptAt *time.Time      `json:"lastAttemptAt,omitempty"`
+	ReplayOf      string          `json:"replayOf,omitempty"`
+	Payload       json.RawMessage `json:"payload" swaggertype:"object"`
+}
+
+// StormStart is returned by POST /storm/start: the run to report when it
+// ends and its puzzles, easiest first.
+type StormStart struct {
+	RunID     string    `json:"runId"`
+	Puzzles   []*Puzzle `json:"puzzles"`
+	ExpiresAt time.Time `json:"expiresAt"` // the run can no longer be reported after this
+}
+
+// StormRunReport is the body for POST /storm/runs.
+type StormRunReport struct {
+	RunID      string `json:"runId"` // from POST /storm/start
+	Score      int    `json:"score"`
+	Solved     int    `json:"solved"`
+	Failed     int    `json:"failed"`
+	DurationMs int64  `json:"durationMs"`
+}
+
+// StormRun is a finished Puzzle Storm run.
+type StormRun struct {
+	ID         string    `json:"id"`
+	UserID     string    `json:"userId"`
+	Score      int       `json:"score"`
+	Solved     int       `json:"solved"`
+	Failed     int       `json:"failed"`
+	DurationMs int64     `json:"durationMs"`
+	FinishedAt time.Time `json:"finishedAt"`
+}
@@ -0,0 +1,304 @@
+// Package prompts loads the LLM prompt templates. Each version of a prompt
+// is a text/template file, <name>/<version>.tmpl, defining "system", "user"
+// and optionally "retry". prompts.json picks the version each provider and
+// model gets, first matching rule first:
+//
+//	{"filter": {"default": "v1", "rules": [
+//		{"provider": "local", "version": "v2"},
+//		{"model": "meta/llama-3.1-8b-*", "version": "v2"}
+//	]}}
+//
+// The templates built into the binary are used unless a directory with the
+// same layout overrides them: its files replace or add versions, and its
+// prompts.json replaces the selection of the prompts it lists. The directory
+// is reloaded when its files change.
+package prompts
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/template"
+	"time"
+
+	"github.com/chess-puzzle-next/puzzle-generator/internal/logging"
+)
+
+var logger = logging.For("prompts")
+
+//go:embed templates
+var builtin embed.FS
+
+const manifestFile = "prompts.json"
+
+// Selection picks the version of one prompt.
+type Selection struct {
+	Default string `json:"default"`
+	Rules   []Rule `json:"rules,omitempty"`
+}
+
+// Rule selects Version for a provider, a model or both. Model is a
+// path.Match pattern, such as "meta/llama-3.1-*".
+type Rule struct {
+	Provider string `json:"provider,omitempty"`
+	Model    string `json:"model,omitempty"`
+	Version  string `json:"version"`
+}
+
+func (r Rule) matches(provider, model string) bool {
+	if r.Provider != "" && r.Provider != provider {
+		return false
+	}
+	if r.Model != "" {
+		ok, _ := path.Match(r.Model, model) // patterns are checked on load
+		return ok
+	}
+	return true
+}
+
+// Rendered is a prompt rendered for one model call.
+type Rendered struct {
+	Version string // <name>/<version>, such as filter/v1
+	System  string
+	User    string
+	Retry   string // empty when the version defines no retry
+}
+
+// set is one loaded generation of templates.
+type set struct {
+	templates  map[string]*template.Template // by <name>/<version>
+	selections map[string]Selection          // by name
+}
+
+// Store holds the active templates. It is safe for concurrent use.
+type Store struct {
+	dir     string
+	current atomic.Pointer[set]
+
+	mu          sync.Mutex // serializes reloads
+	fingerprint uint64
+}
+
+// Builtin returns a Store of the templates built into the binary.
+var Builtin = sync.OnceValue(func() *Store {
+	s, err := Load("")
+	if err != nil {
+		panic(err) // the built-in templates ship with the binary; an error is a bug
+	}
+	return s
+})
+
+// Load returns a Store of the built-in templates overridden by those of
+// dir, when set.
+func Load(dir string) (*Store, error) {
+	s := &Store{dir: dir}
+	if _, err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Reload reads the templates again when the files of the directory changed
+// and reports whether it did. On error the previous templates stay active
+// until the files change again.
+func (s *Store) Reload() (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.dir != "" {
+		fingerprint, err := dirFingerprint(s.dir)
+		if err != nil {
+			return false, err
+		}
+		if s.current.Load() != nil && fingerprint == s.fingerprint {
+			return false, nil
+		}
+		s.fingerprint = fingerprint
+	}
+
+	next := &set{templates: make(map[string]*template.Template), selections: make(map[string]Selection)}
+	if err := next.add(builtin, "templates"); err != nil {
+		return false, fmt.Errorf("prompts: built-in templates: %w", err)
+	}
+	if s.dir != "" {
+		if err := next.add(os.DirFS(s.dir), "."); err != nil {
+			return false, fmt.Errorf("prompts: %s: %w", s.dir, err)
+		}
+	}
+	if err := next.check(); err != nil {
+		return false, fmt.Errorf("prompts: %w", err)
+	}
+	s.current.Store(next)
+	return true, nil
+}
+
+// Run reloads the directory's templates every interval until ctx is
+// cancelled. It returns at once without a directory.
+func (s *Store) Run(ctx context.Context, interval time.Duration) {
+	if s.dir == "" {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		reloaded, err := s.Reload()
+		switch {
+		case err != nil:
+			logger.ErrorContext(ctx, "prompt templates not reloaded, keeping the previous ones", "dir", s.dir, "err", err)
+		case reloaded:
+			logger.InfoContext(ctx, "prompt templates reloaded", "dir", s.dir, "versions", s.Versions())
+		}
+	}
+}
+
+// Versions returns the loaded prompt versions, sorted.
+func (s *Store) Versions() []string {
+	cur := s.current.Load()
+	versions := make([]string, 0, len(cur.templates))
+	for v := range cur.templates {
+		versions = append(versions, v)
+	}
+	slices.Sort(versions)
+	return versions
+}
+
+// Render renders the version of prompt name selected for provider and model
+// with data.
+func (s *Store) Render(name, provider, model string, data any) (*Rendered, error) {
+	cur := s.current.Load()
+	sel, ok := cur.selections[name]
+	if !ok {
+		return nil, fmt.Errorf("prompts: unknown prompt %q", name)
+	}
+	version := sel.Default
+	for _, r := range sel.Rules {
+		if r.matches(provider, model) {
+			version = r.Version
+			break
+		}
+	}
+	key := name + "/" + version
+	t := cur.templates[key]
+
+	out := &Rendered{Version: key}
+	for _, part := range []struct {
+		name string
+		dst  *string
+	}{{"system", &out.System}, {"user", &out.User}, {"retry", &out.Retry}} {
+		if t.Lookup(part.name) == nil {
+			continue
+		}
+		var buf bytes.Buffer
+		if err := t.ExecuteTemplate(&buf, part.name, data); err != nil {
+			return nil, fmt.Errorf("prompts: render %s: %w", key, err)
+		}
+		*part.dst = strings.TrimSpace(buf.String())
+	}
+	return out, nil
+}
+
+// add parses the templates and the manifest under root of fsys.
+func (st *set) add(fsys fs.FS, root string) error {
+	err := fs.WalkDir(fsys, root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(p, ".tmpl") {
+			return err
+		}
+		rel := strings.TrimPrefix(p, root+"/")
+		name, version, ok := strings.Cut(strings.TrimSuffix(rel, ".tmpl"), "/")
+		if !ok || strings.Contains(version, "/") {
+			return fmt.Errorf("%s: templates go in <prompt>/<version>.tmpl", p)
+		}
+		data, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		t, err := template.New(name + "/" + version).Parse(string(data))
+		if err != nil {
+			return err
+		}
+		for _, required := range []string{"system", "user"} {
+			if t.Lookup(required) == nil {
+				return fmt.Errorf("%s: no %q template", p, required)
+			}
+		}
+		st.templates[name+"/"+version] = t
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	data, err := fs.ReadFile(fsys, path.Join(root, manifestFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var selections map[string]Selection
+	if err := json.Unmarshal(data, &selections); err != nil {
+		return fmt.Errorf("%s: %w", manifestFile, err)
+	}
+	for name, sel := range selections {
+		st.selections[name] = sel
+	}
+	return nil
+}
+
+// check verifies that every selected version exists and every rule can match.
+func (st *set) check() error {
+	for name, sel := range st.selections {
+		if _, ok := st.templates[name+"/"+sel.Default]; !ok {
+			return fmt.Errorf("%s: default version %q has no template %s/%s.tmpl", name, sel.Default, name, sel.Default)
+		}
+		for i, r := range sel.Rules {
+			if r.Provider == "" && r.Model == "" {
+				return fmt.Errorf("%s: rule %d names no provider or model", name, i)
+			}
+			if _, err := path.Match(r.Model, ""); err != nil {
+				return fmt.Errorf("%s: rule %d: model pattern %q: %w", name, i, r.Model, err)
+			}
+			if _, ok := st.templates[name+"/"+r.Version]; !ok {
+				return fmt.Errorf("%s: rule %d selects version %q, which has no template", name, i, r.Version)
+			}
+		}
+	}
+	return nil
+}
+
+// dirFingerprint hashes the names, sizes and modification times of the
+// files under dir, so a reload is only done when one of them changed.
+func dirFingerprint(dir string) (uint64, error) {
+	h := fnv.New64a()
+	err := fs.WalkDir(os.DirFS(dir), ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(h, "%s %d %d\n", p, info.Size(), info.ModTime().UnixNano())
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("prompts: %w", err)
+	}
+	return h.Sum64(), nil
+}
@@ -0,0 +1,144 @@
+// Package puzzlecache caches enriched puzzles by source and ID. Puzzles never
+// change once published, so entries are never invalidated: an in-process LRU
+// answers hot lookups and Redis shares entries between replicas and restarts.
+package puzzlecache
+
+import (
+	"container/list"
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/chess-puzzle-next/puzzle-generator/internal/logging"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/models"
+)
+
+var logger = logging.For("puzzlecache")
+
+// Tiers reported by Get.
+const (
+	TierMemory = "memory"
+	TierRedis  = "redis"
+)
+
+// Store is the shared tier.
+type Store interface {
+	GetCachedPuzzle(ctx context.Context, source, id string) (*models.Puzzle, error)
+	CachePuzzle(ctx context.Context, source string, p *models.Puzzle, ttl time.Duration) error
+}
+
+// Cache is a two-tier puzzle cache. A nil *Cache caches nothing.
+type Cache struct {
+	store Store // optional
+	ttl   time.Duration
+	size  int
+
+	mu      sync.Mutex
+	entries map[key]*list.Element
+	order   *list.List // front is most recently used
+}
+
+type key struct{ source, id string }
+
+type item struct {
+	key    key
+	puzzle *models.Puzzle
+}
+
+// New returns a cache keeping up to size puzzles in memory and, when store
+// is set, every puzzle in store for ttl.
+func New(size int, store Store, ttl time.Duration) *Cache {
+	return &Cache{
+		store:   store,
+		ttl:     ttl,
+		size:    max(size, 1),
+		entries: make(map[key]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// Get looks id up under each source in turn and returns a copy of the first
+// puzzle found with the tier that held it. Redis hits are promoted to memory.
+func (c *Cache) Get(ctx context.Context, id string, sources ...string) (*models.Puzzle, string) {
+	if c == nil {
+		return nil, ""
+	}
+	for _, source := range sources {
+		if p := c.getMemory(key{source, id}); p != nil {
+			return p, TierMemory
+		}
+	}
+	if c.store == nil {
+		return nil, ""
+	}
+	for _, source := range sources {
+		p, err := c.store.GetCachedPuzzle(ctx, source, id)
+		if err != nil {
+			logger.WarnContext(ctx, "read puzzle cache", "source", source, "id", id, "err", err)
+			return nil, ""
+		}
+		if p != nil {
+			c.addMemory(key{source, id}, p)
+			return Clone(p), TierRedis
+		}
+	}
+	return nil, ""
+}
+
+// Add caches a copy of p, served by source, in both tiers.
+func (c *Cache) Add(ctx context.Context, source string, p *models.Puzzle) {
+	if c == nil || p == nil || p.ID == "" {
+		return
+	}
+	p = Clone(p)
+	c.addMemory(key{source, p.ID}, p)
+	if c.store != nil {
+		if err := c.store.CachePuzzle(ctx, source, p, c.ttl); err != nil {
+			logger.WarnContext(ctx, "write puzzle cache", "source", source, "id", p.ID, "err", err)
+		}
+	}
+}
+
+func (c *Cache) getMemory(k key) *models.Puzzle {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[k]
+	if !ok {
+		return nil
+	}
+	c.order.MoveToFront(el)
+	return Clone(el.Value.(*item).puzzle)
+}
+
+func (c *Cache) addMemory(k key, p *models.Puzzle) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[k]; ok {
+		el.Value.(*item).puzzle = p
+		c.order.MoveToFront(el)
+		return
+	}
+	c.entries[k] = c.order.PushFront(&item{key: k, puzzle: p})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*item).key)
+	}
+}
+
+// Clone returns a deep copy of p, so cached values are never shared with
+// callers that modify the puzzle they get.
+func Clone(p *models.Puzzle) *models.Puzzle {
+	cp := *p
+	cp.Moves = slices.Clone(p.Moves)
+	cp.Themes = slices.Clone(p.Themes)
+	cp.OpeningTags = slices.Clone(p.OpeningTags)
+	if p.Filter != nil {
+		f := *p.Filter
+		f.Themes = slices.Clone(p.Filter.Themes)
+		f.ExcludeThemes = slices.Clone(p.Filter.ExcludeThemes)
+		cp.Filter = &f
+	}
+	return &cp
+}
@@ -0,0 +1,112 @@
+// Package ratelimit throttles API callers with token buckets. Buckets live in
+// Redis so every replica shares them, with an in-process fallback while Redis
+// is unreachable.
+package ratelimit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Route groups with their own budgets.
+const (
+	GroupAI      = "ai"      // AI generation, its stream and explanations — one LLM call per request
+	GroupPuzzle  = "puzzle"  // puzzle fetches — Lichess / dataset calls
+	GroupAuth    = "auth"    // login and registration
+	GroupDefault = "default" // everything else
+)
+
+// TierAnonymous is the budget tier of callers without an access token. The
+// other tiers are plan names.
+const TierAnonymous = "anon"
+
+// Limit allows Rate requests per Per, with bursts of up to Rate.
+type Limit struct {
+	Rate int
+	Per  time.Duration
+}
+
+// refillPerSecond returns how many tokens the bucket regains per second.
+func (l Limit) refillPerSecond() float64 {
+	return float64(l.Rate) / l.Per.Seconds()
+}
+
+func (l Limit) String() string {
+	return fmt.Sprintf("%d/%s", l.Rate, l.Per)
+}
+
+// Budget maps a tier (plan name or TierAnonymous) to its limit. Tiers
+// missing from a budget are not limited.
+type Budget map[string]Limit
+
+// ParseBudget parses a budget such as "anon=30/m,free=60/m,pro=120/m".
+// Periods are s, m or h, or any Go duration ("10/30s").
+func ParseBudget(s string) (Budget, error) {
+	b := Budget{}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		tier, spec, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("ratelimit: %q: expected tier=rate/period", part)
+		}
+		lim, err := parseLimit(strings.TrimSpace(spec))
+		if err != nil {
+			return nil, fmt.Errorf("ratelimit: %q: %w", part, err)
+		}
+		b[strings.TrimSpace(tier)] = lim
+	}
+	return b, nil
+}
+
+func parseLimit(spec string) (Limit, error) {
+	rate, period, ok := strings.Cut(spec, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("expected rate/period")
+	}
+	n, err := strconv.Atoi(rate)
+	if err != nil || n < 1 {
+		return Limit{}, fmt.Errorf("invalid rate %q", rate)
+	}
+
+	var per time.Duration
+	switch period {
+	case "s":
+		per = time.Second
+	case "m":
+		per = time.Minute
+	case "h":
+		per = time.Hour
+	default:
+		per, err = time.ParseDuration(period)
+		if err != nil || per <= 0 {
+			return Limit{}, fmt.Errorf("invalid period %q", period)
+		}
+	}
+	return Limit{Rate: n, Per: per}, nil
+}
+
+// Classify returns the route group of a request. path is the matched route
+// pattern (echo.Context.Path), so parameterised routes classify alike. The
+// second result is false for routes that are never limited.
+func Classify(method, path string) (string, bool) {
+	path = strings.TrimPrefix(path, "/api/v1")
+	switch {
+	case strings.HasPrefix(path, "/billing/"):
+		// Signed provider callbacks; throttling them only causes retries.
+		return "", false
+	case method == "POST" && path == "/puzzle/ai",
+		method == "GET" && path == "/puzzle/ai/stream",
+		method == "POST" && path == "/puzzle/:id/explain":
+		return GroupAI, true
+	case strings.HasPrefix(path, "/puzzle"):
+		return GroupPuzzle, true
+	case strings.HasPrefix(path, "/auth/"):
+		return GroupAuth, true
+	}
+	return GroupDefault, true
+}
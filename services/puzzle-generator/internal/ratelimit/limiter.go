@@ -0,0 +1,164 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/chess-puzzle-next/puzzle-generator/internal/logging"
+)
+
+var logger = logging.For("ratelimit")
+
+// BucketStore takes one token from a bucket holding up to capacity tokens
+// that refills at refill tokens per second. It reports whether a token was
+// taken and how many are left.
+type BucketStore interface {
+	TakeToken(ctx context.Context, key string, capacity int, refill float64) (bool, float64, error)
+}
+
+// Result describes the state of a caller's bucket after a request.
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	ResetAfter time.Duration // until the bucket is full again
+	RetryAfter time.Duration // until the next token, when not allowed
+}
+
+// Limiter applies per-group, per-tier budgets.
+type Limiter struct {
+	budgets  map[string]Budget
+	store    BucketStore
+	fallback *memoryStore
+	degraded atomic.Bool
+	retryAt  atomic.Int64 // unix nanos before which the store is not retried
+}
+
+// storeRetryInterval is how long the limiter stays on in-memory buckets
+// after the store fails, so an outage does not add a dial timeout to every
+// request.
+const storeRetryInterval = 30 * time.Second
+
+// New returns a Limiter. store may be nil, in which case buckets are kept in
+// process memory only.
+func New(budgets map[string]Budget, store BucketStore) *Limiter {
+	return &Limiter{budgets: budgets, store: store, fallback: newMemoryStore()}
+}
+
+// Allow takes a token for subject from the bucket of group and tier. Groups
+// without a budget share the default group's buckets. The second result is
+// false when no limit applies.
+func (l *Limiter) Allow(ctx context.Context, group, tier, subject string) (Result, bool) {
+	budget, ok := l.budgets[group]
+	if !ok {
+		group, budget = GroupDefault, l.budgets[GroupDefault]
+	}
+	lim, ok := budget[tier]
+	if !ok {
+		return Result{}, false
+	}
+
+	key := "ratelimit:" + group + ":" + subject
+	refill := lim.refillPerSecond()
+	allowed, tokens, err := l.take(ctx, key, lim.Rate, refill)
+	if err != nil {
+		// Neither store answered; serve the request.
+		return Result{}, false
+	}
+
+	res := Result{
+		Allowed:    allowed,
+		Limit:      lim.Rate,
+		Remaining:  int(math.Floor(tokens)),
+		ResetAfter: secondsToDuration((float64(lim.Rate) - tokens) / refill),
+	}
+	if !allowed {
+		res.RetryAfter = secondsToDuration((1 - tokens) / refill)
+	}
+	return res, true
+}
+
+func (l *Limiter) take(ctx context.Context, key string, capacity int, refill float64) (bool, float64, error) {
+	if l.store != nil && time.Now().UnixNano() >= l.retryAt.Load() {
+		allowed, tokens, err := l.store.TakeToken(ctx, key, capacity, refill)
+		if err == nil {
+			if l.degraded.CompareAndSwap(true, false) {
+				logger.InfoContext(ctx, "redis buckets available again")
+			}
+			return allowed, tokens, nil
+		}
+		if ctx.Err() != nil {
+			return false, 0, err
+		}
+		l.retryAt.Store(time.Now().Add(storeRetryInterval).UnixNano())
+		if l.degraded.CompareAndSwap(false, true) {
+			logger.WarnContext(ctx, "redis unavailable, using in-memory buckets", "err", err)
+		}
+	}
+	return l.fallback.TakeToken(ctx, key, capacity, refill)
+}
+
+func secondsToDuration(s float64) time.Duration {
+	if s <= 0 {
+		return 0
+	}
+	return time.Duration(math.Ceil(s * float64(time.Second)))
+}
+
+// memoryStore is a process-local BucketStore. Each replica enforces the full
+// budget on its own, so it is only a stand-in while Redis is down.
+type memoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type bucket struct {
+	tokens float64
+	at     time.Time
+	full   time.Time // when the bucket is full again and can be dropped
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{buckets: make(map[string]*bucket), now: time.Now}
+}
+
+func (m *memoryStore) TakeToken(_ context.Context, key string, capacity int, refill float64) (bool, float64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	m.sweep(now)
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(capacity), at: now}
+		m.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(capacity), b.tokens+now.Sub(b.at).Seconds()*refill)
+	b.at = now
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	b.full = now.Add(secondsToDuration((float64(capacity) - b.tokens) / refill))
+	return allowed, b.tokens, nil
+}
+
+// sweep drops buckets that have refilled completely, at most once a minute.
+func (m *memoryStore) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < time.Minute {
+		return
+	}
+	m.lastSweep = now
+	for key, b := range m.buckets {
+		if now.After(b.full) {
+			delete(m.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreTakeToken(t *testing.T) {
+	start := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	const capacity, refill = 3, 1.0 // 3 tokens, one more per second
+
+	// Each step takes one token at start+at.
+	type step struct {
+		at      time.Duration
+		allowed bool
+		left    float64
+	}
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "burst up to the limit",
+			steps: []step{
+				{0, true, 2},
+				{0, true, 1},
+				{0, true, 0},
+				{0, false, 0},
+			},
+		},
+		{
+			name: "refill after the limit",
+			steps: []step{
+				{0, true, 2},
+				{0, true, 1},
+				{0, true, 0},
+				{500 * time.Millisecond, false, 0.5},
+				{time.Second, true, 0},
+			},
+		},
+		{
+			name: "refill stops at capacity",
+			steps: []step{
+				{0, true, 2},
+				{time.Minute, true, 2},
+				{time.Minute, true, 1},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newMemoryStore()
+			for i, s := range tt.steps {
+				m.now = func() time.Time { return start.Add(s.at) }
+				allowed, left, err := m.TakeToken(context.Background(), "k", capacity, refill)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if allowed != s.allowed || left != s.left {
+					t.Fatalf("step %d: TakeToken() = %v, %v; want %v, %v", i, allowed, left, s.allowed, s.left)
+				}
+			}
+		})
+	}
+}
+
+type failingStore struct{ calls int }
+
+func (f *failingStore) TakeToken(context.Context, string, int, float64) (bool, float64, error) {
+	f.calls++
+	return false, 0, errors.New("connection refused")
+}
+
+func TestLimiterAllow(t *testing.T) {
+	budgets := map[string]Budget{
+		GroupDefault: {TierAnonymous: {Rate: 2, Per: time.Minute}},
+		GroupAI:      {TierAnonymous: {Rate: 1, Per: time.Minute}},
+	}
+
+	tests := []struct {
+		name    string
+		group   string
+		tier    string
+		takes   int
+		want    Result
+		limited bool
+	}{
+		{name: "first request", group: GroupDefault, tier: TierAnonymous, takes: 1,
+			want: Result{Allowed: true, Limit: 2, Remaining: 1, ResetAfter: 30 * time.Second}, limited: true},
+		{name: "over the limit", group: GroupDefault, tier: TierAnonymous, takes: 3,
+			want: Result{Allowed: false, Limit: 2, Remaining: 0, ResetAfter: time.Minute, RetryAfter: 30 * time.Second}, limited: true},
+		{name: "own group budget", group: GroupAI, tier: TierAnonymous, takes: 2,
+			want: Result{Allowed: false, Limit: 1, Remaining: 0, ResetAfter: time.Minute, RetryAfter: time.Minute}, limited: true},
+		{name: "group without budget uses default", group: GroupPuzzle, tier: TierAnonymous, takes: 2,
+			want: Result{Allowed: true, Limit: 2, Remaining: 0, ResetAfter: time.Minute}, limited: true},
+		{name: "tier without limit", group: GroupDefault, tier: "pro", takes: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &failingStore{}
+			l := New(budgets, store)
+			now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+			l.fallback.now = func() time.Time { return now }
+
+			var got Result
+			var limited bool
+			for range tt.takes {
+				got, limited = l.Allow(context.Background(), tt.group, tt.tier, "1.2.3.4")
+			}
+			if limited != tt.limited || got != tt.want {
+				t.Fatalf("Allow() = %+v, %v; want %+v, %v", got, limited, tt.want, tt.limited)
+			}
+			// The store is not retried for a while after it fails.
+			if tt.limited && store.calls != 1 {
+				t.Fatalf("store called %d times, want 1", store.calls)
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package retrieval
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/chess-puzzle-next/puzzle-generator/internal/models"
+	"github.com/notnil/chess"
+)
+
+var pieceNames = map[chess.PieceType]string{
+	chess.King:   "king",
+	chess.Queen:  "queen",
+	chess.Rook:   "rook",
+	chess.Bishop: "bishop",
+	chess.Knight: "knight",
+	chess.Pawn:   "pawn",
+}
+
+var pieceValues = map[chess.PieceType]int{
+	chess.Queen:  9,
+	chess.Rook:   5,
+	chess.Bishop: 3,
+	chess.Knight: 3,
+	chess.Pawn:   1,
+}
+
+// Describe writes a puzzle as the text that is embedded: its themes, opening,
+// phase, the first move of the solution, the material on the board and its
+// difficulty. For example:
+//
+//	Themes: Endgame, Checkmate, Mate in 2, Short puzzle. Phase: endgame.
+//	Black to play; the solution starts with a rook move, with check.
+//	Material: white 2 rooks, 6 pawns; black rook, bishop, 4 pawns. Black is
+//	down 4 points of material. Rating 1403, medium, intermediate.
+func Describe(p *models.Puzzle) string {
+	var sb strings.Builder
+
+	if names := themeNames(p.Themes); len(names) > 0 {
+		fmt.Fprintf(&sb, "Themes: %s. ", strings.Join(names, ", "))
+	}
+	if openings := openingNames(p.OpeningTags); len(openings) > 0 {
+		fmt.Fprintf(&sb, "Opening: %s. ", strings.Join(openings, "; "))
+	}
+
+	pos, first := solutionStart(p)
+	if pos != nil {
+		fmt.Fprintf(&sb, "Phase: %s. ", phase(p.Themes, pos))
+		player := pos.Turn()
+		fmt.Fprintf(&sb, "%s to play", player.Name())
+		if first != nil {
+			fmt.Fprintf(&sb, "; the solution starts with a %s move", pieceNames[pos.Board().Piece(first.S1()).Type()])
+			if first.HasTag(chess.Capture) || first.HasTag(chess.EnPassant) {
+				sb.WriteString(", a capture")
+			}
+			if first.HasTag(chess.Check) {
+				sb.WriteString(", with check")
+			}
+			if first.Promo() != chess.NoPieceType {
+				sb.WriteString(", a promotion")
+			}
+		}
+		sb.WriteString(". ")
+		sb.WriteString(describeMaterial(pos, player))
+	}
+
+	difficulty := p.Difficulty
+	if difficulty == "" {
+		difficulty = models.RatingToDifficulty(p.Rating)
+	}
+	fmt.Fprintf(&sb, "Rating %d, %s, %s.", p.Rating, difficulty, models.RatingToBand(p.Rating).Name)
+	return sb.String()
+}
+
+// themeNames returns the display names of theme keys, keeping unknown keys.
+func themeNames(keys []string) []string {
+	names := make([]string, 0, len(keys))
+	for _, k := range keys {
+		if t, ok := models.LookupTheme(k); ok {
+			names = append(names, t.Name)
+		} else {
+			names = append(names, k)
+		}
+	}
+	return names
+}
+
+// openingNames turns Lichess opening tags into words and drops tags that a
+// more precise tag repeats, such as Sicilian_Defense next to
+// Sicilian_Defense_Najdorf_Variation.
+func openingNames(tags []string) []string {
+	var out []string
+	for i, tag := range tags {
+		covered := false
+		for j, other := range tags {
+			if i != j && len(other) > len(tag) && strings.HasPrefix(other, tag) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			out = append(out, strings.ReplaceAll(tag, "_", " "))
+		}
+	}
+	return out
+}
+
+// solutionStart returns the position the player solves from, after the
+// opponent's setup move, and the first move of the solution. Both are nil
+// when the moves cannot be played.
+func solutionStart(p *models.Puzzle) (*chess.Position, *chess.Move) {
+	fen, err := chess.FEN(p.FEN)
+	if err != nil || len(p.Moves) == 0 {
+		return nil, nil
+	}
+	game := chess.NewGame(fen, chess.UseNotation(chess.UCINotation{}))
+	if err := game.MoveStr(p.Moves[0]); err != nil {
+		return nil, nil
+	}
+	pos := game.Position()
+	if len(p.Moves) < 2 {
+		return pos, nil
+	}
+	if err := game.MoveStr(p.Moves[1]); err != nil {
+		return pos, nil
+	}
+	moves := game.Moves()
+	return pos, moves[len(moves)-1]
+}
+
+// phase names the game phase from the puzzle's phase themes, or from the
+// material left when it has none.
+func phase(themes []string, pos *chess.Position) string {
+	for _, k := range themes {
+		if t, ok := models.LookupTheme(k); ok && t.Category == models.ThemeCategoryPhase {
+			return strings.ToLower(t.Name)
+		}
+	}
+	pieces := 0
+	for _, piece := range pos.Board().SquareMap() {
+		if piece.Type() != chess.Pawn && piece.Type() != chess.King {
+			pieces += pieceValues[piece.Type()]
+		}
+	}
+	switch {
+	case pieces <= 26:
+		return "endgame"
+	case pos.Board().Piece(chess.E1) == chess.WhiteKing && pos.Board().Piece(chess.E8) == chess.BlackKing && pieces >= 60:
+		return "opening"
+	default:
+		return "middlegame"
+	}
+}
+
+// describeMaterial lists each side's pieces and says how far the player is
+// ahead or behind.
+func describeMaterial(pos *chess.Position, player chess.Color) string {
+	counts := map[chess.Color]map[chess.PieceType]int{chess.White: {}, chess.Black: {}}
+	points := map[chess.Color]int{}
+	for _, piece := range pos.Board().SquareMap() {
+		counts[piece.Color()][piece.Type()]++
+		points[piece.Color()] += pieceValues[piece.Type()]
+	}
+
+	sides := make([]string, 0, 2)
+	for _, color := range []chess.Color{chess.White, chess.Black} {
+		var parts []string
+		for _, t := range []chess.PieceType{chess.Queen, chess.Rook, chess.Bishop, chess.Knight, chess.Pawn} {
+			switch n := counts[color][t]; {
+			case n == 1:
+				parts = append(parts, pieceNames[t])
+			case n > 1:
+				parts = append(parts, fmt.Sprintf("%d %ss", n, pieceNames[t]))
+			}
+		}
+		if len(parts) == 0 {
+			parts = append(parts, "bare king")
+		}
+		sides = append(sides, strings.ToLower(color.Name())+" "+strings.Join(parts, ", "))
+	}
+
+	balance := "Material is even."
+	if diff := points[player] - points[player.Other()]; diff != 0 {
+		direction, unit := "up", "points"
+		if diff < 0 {
+			direction, diff = "down", -diff
+		}
+		if diff == 1 {
+			unit = "point"
+		}
+		balance = fmt.Sprintf("%s is %s %d %s of material.", player.Name(), direction, diff, unit)
+	}
+	return fmt.Sprintf("Material: %s. %s ", strings.Join(sides, "; "), balance)
+}
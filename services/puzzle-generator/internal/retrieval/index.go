@@ -0,0 +1,141 @@
+// Package retrieval finds the puzzles that match a prompt in a vector index.
+// Each puzzle is described as text (themes, opening, phase, material) and
+// embedded offline by cmd/build-index; prompts are embedded with the same
+// embedder at query time and compared by cosine similarity.
+package retrieval
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/gob"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/chess-puzzle-next/puzzle-generator/internal/models"
+	"github.com/chess-puzzle-next/puzzle-generator/pkg/embed"
+)
+
+// Index holds puzzles with the embedding of their description.
+type Index struct {
+	Embedder string // name of the embedder that built the vectors
+	BuiltAt  time.Time
+	Entries  []Entry
+}
+
+// Entry is one indexed puzzle.
+type Entry struct {
+	Puzzle models.Puzzle
+	Text   string // the description that was embedded
+	Vector []float32
+}
+
+// Match is a puzzle found by Search and its cosine similarity to the query.
+type Match struct {
+	Puzzle *models.Puzzle
+	Score  float32
+}
+
+// Build describes and embeds puzzles, batch texts per embedder call.
+// progress, when set, is called with the number of puzzles embedded so far.
+func Build(ctx context.Context, emb embed.Embedder, puzzles []*models.Puzzle, batch int, progress func(done int)) (*Index, error) {
+	idx := &Index{Embedder: emb.Name(), BuiltAt: time.Now().UTC(), Entries: make([]Entry, 0, len(puzzles))}
+	for start := 0; start < len(puzzles); start += batch {
+		chunk := puzzles[start:min(start+batch, len(puzzles))]
+		texts := make([]string, len(chunk))
+		for i, p := range chunk {
+			texts[i] = Describe(p)
+		}
+		vectors, err := emb.Embed(ctx, texts)
+		if err != nil {
+			return nil, fmt.Errorf("retrieval: embed puzzles %d-%d: %w", start, start+len(chunk)-1, err)
+		}
+		for i, p := range chunk {
+			idx.Entries = append(idx.Entries, Entry{Puzzle: *p, Text: texts[i], Vector: vectors[i]})
+		}
+		if progress != nil {
+			progress(len(idx.Entries))
+		}
+	}
+	return idx, nil
+}
+
+// Search returns the k entries most similar to query among those accepted
+// by keep (all when nil), best first. Puzzles are copies.
+func (idx *Index) Search(query []float32, k int, keep func(*models.Puzzle) bool) []Match {
+	var matches []Match
+	for i := range idx.Entries {
+		e := &idx.Entries[i]
+		if keep != nil && !keep(&e.Puzzle) {
+			continue
+		}
+		matches = append(matches, Match{Puzzle: &e.Puzzle, Score: embed.Dot(query, e.Vector)})
+	}
+	slices.SortStableFunc(matches, func(a, b Match) int {
+		switch {
+		case a.Score > b.Score:
+			return -1
+		case a.Score < b.Score:
+			return 1
+		}
+		return 0
+	})
+	matches = matches[:min(k, len(matches))]
+	for i := range matches {
+		p := *matches[i].Puzzle
+		matches[i].Puzzle = &p
+	}
+	return matches
+}
+
+// Save writes the index to path as gzipped gob, through a temporary file so
+// a running server never reads a partial index.
+func (idx *Index) Save(path string) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("retrieval: create index file: %w", err)
+	}
+	defer os.Remove(f.Name())
+	if err := f.Chmod(0o644); err != nil {
+		f.Close()
+		return fmt.Errorf("retrieval: create index file: %w", err)
+	}
+
+	zw := gzip.NewWriter(f)
+	if err := gob.NewEncoder(zw).Encode(idx); err != nil {
+		f.Close()
+		return fmt.Errorf("retrieval: encode index: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		f.Close()
+		return fmt.Errorf("retrieval: compress index: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("retrieval: write index: %w", err)
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return fmt.Errorf("retrieval: move index into place: %w", err)
+	}
+	return nil
+}
+
+// Load reads an index written by Save.
+func Load(path string) (*Index, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("retrieval: open index: %w", err)
+	}
+	defer f.Close()
+
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("retrieval: read index %s: %w", path, err)
+	}
+	var idx Index
+	if err := gob.NewDecoder(zr).Decode(&idx); err != nil {
+		return nil, fmt.Errorf("retrieval: decode index %s: %w", path, err)
+	}
+	return &idx, nil
+}
@@ -0,0 +1,65 @@
+package retrieval
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/chess-puzzle-next/puzzle-generator/internal/config"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/models"
+	"github.com/chess-puzzle-next/puzzle-generator/pkg/embed"
+)
+
+// NewEmbedder returns the embedder selected by cfg. rt may be nil.
+func NewEmbedder(cfg config.EmbeddingsConfig, rt http.RoundTripper) (embed.Embedder, error) {
+	switch cfg.Provider {
+	case "local":
+		return embed.NewHashing(cfg.Dims), nil
+	case "api":
+		if cfg.BaseURL == "" || cfg.Model == "" {
+			return nil, fmt.Errorf("retrieval: EMBEDDINGS_BASE_URL and EMBEDDINGS_MODEL are required with the api embedder")
+		}
+		opts := []embed.Option{embed.WithAPIKey(cfg.APIKey), embed.WithTimeout(cfg.Timeout)}
+		if rt != nil {
+			opts = append(opts, embed.WithTransport(rt))
+		}
+		return embed.NewOpenAI(cfg.BaseURL, cfg.Model, opts...), nil
+	default:
+		return nil, fmt.Errorf("retrieval: unknown embedder %q", cfg.Provider)
+	}
+}
+
+// Retriever finds the indexed puzzles closest to a prompt.
+type Retriever struct {
+	index    *Index
+	embedder embed.Embedder
+}
+
+// NewRetriever returns a Retriever searching idx. emb must be the embedder
+// the index was built with.
+func NewRetriever(idx *Index, emb embed.Embedder) (*Retriever, error) {
+	if idx.Embedder != emb.Name() {
+		return nil, fmt.Errorf("retrieval: index was built with embedder %q, queries would use %q", idx.Embedder, emb.Name())
+	}
+	return &Retriever{index: idx, embedder: emb}, nil
+}
+
+// Len returns the number of indexed puzzles.
+func (r *Retriever) Len() int {
+	return len(r.index.Entries)
+}
+
+// Retrieve returns up to k puzzles accepted by keep (all when nil) whose
+// description is closest to query, best first.
+func (r *Retriever) Retrieve(ctx context.Context, query string, k int, keep func(*models.Puzzle) bool) ([]*models.Puzzle, error) {
+	vectors, err := r.embedder.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("retrieval: embed query: %w", err)
+	}
+	matches := r.index.Search(vectors[0], k, keep)
+	puzzles := make([]*models.Puzzle, len(matches))
+	for i, m := range matches {
+		puzzles[i] = m.Puzzle
+	}
+	return puzzles, nil
+}
@@ -0,0 +1,173 @@
+// Package seen remembers which puzzles each viewer was served, so no source
+// serves a viewer the same puzzle twice. Sets live in Redis so every replica
+// and restart shares them, with an in-process fallback while Redis is
+// unreachable.
+package seen
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/chess-puzzle-next/puzzle-generator/internal/logging"
+)
+
+var logger = logging.For("seen")
+
+// Store keeps a capped set of puzzle IDs per viewer.
+type Store interface {
+	// SeenPuzzles reports, for each of ids, whether viewer was served it.
+	SeenPuzzles(ctx context.Context, viewer string, ids ...string) ([]bool, error)
+	// MarkPuzzleSeen adds id to the viewer's set, keeping the limit most
+	// recent IDs and dropping the set after ttl without activity.
+	MarkPuzzleSeen(ctx context.Context, viewer, id string, limit int, ttl time.Duration) error
+}
+
+// Tracker records served puzzles per viewer. A nil *Tracker, or a context
+// without a viewer, tracks nothing.
+type Tracker struct {
+	store    Store
+	limit    int
+	ttl      time.Duration
+	fallback *memoryStore
+	degraded atomic.Bool
+	retryAt  atomic.Int64 // unix nanos before which the store is not retried
+}
+
+// storeRetryInterval is how long the tracker stays on in-memory sets after
+// the store fails, so an outage does not add a dial timeout to every request.
+const storeRetryInterval = 30 * time.Second
+
+// New returns a Tracker remembering the limit most recent puzzles of each
+// viewer for ttl. store may be nil, in which case sets are kept in process
+// memory only, for up to viewers viewers.
+func New(store Store, limit int, ttl time.Duration, viewers int) *Tracker {
+	return &Tracker{
+		store:    store,
+		limit:    max(limit, 1),
+		ttl:      ttl,
+		fallback: newMemoryStore(max(viewers, 1)),
+	}
+}
+
+// Seen reports whether the viewer of ctx was already served id. Lookup
+// errors count as not seen.
+func (t *Tracker) Seen(ctx context.Context, id string) bool {
+	viewer := Viewer(ctx)
+	if t == nil || viewer == "" || id == "" {
+		return false
+	}
+	var seen []bool
+	err := t.use(ctx, func(s Store) (err error) {
+		seen, err = s.SeenPuzzles(ctx, viewer, id)
+		return err
+	})
+	return err == nil && len(seen) == 1 && seen[0]
+}
+
+// Mark records that the viewer of ctx was served id.
+func (t *Tracker) Mark(ctx context.Context, id string) {
+	viewer := Viewer(ctx)
+	if t == nil || viewer == "" || id == "" {
+		return
+	}
+	err := t.use(ctx, func(s Store) error {
+		return s.MarkPuzzleSeen(ctx, viewer, id, t.limit, t.ttl)
+	})
+	if err != nil {
+		logger.WarnContext(ctx, "mark puzzle seen", "id", id, "err", err)
+	}
+}
+
+// use runs op against the store, or against the in-memory sets while the
+// store is failing.
+func (t *Tracker) use(ctx context.Context, op func(Store) error) error {
+	if t.store != nil && time.Now().UnixNano() >= t.retryAt.Load() {
+		err := op(t.store)
+		if err == nil {
+			if t.degraded.CompareAndSwap(true, false) {
+				logger.InfoContext(ctx, "redis seen sets available again")
+			}
+			return nil
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+		t.retryAt.Store(time.Now().Add(storeRetryInterval).UnixNano())
+		if t.degraded.CompareAndSwap(false, true) {
+			logger.WarnContext(ctx, "redis unavailable, using in-memory seen sets", "err", err)
+		}
+	}
+	return op(t.fallback)
+}
+
+// memoryStore is a process-local Store. It forgets on restart and is not
+// shared between replicas, so it is only a stand-in without Redis. Sets
+// never expire; the least recently active viewers are dropped instead.
+type memoryStore struct {
+	maxViewers int
+
+	mu      sync.Mutex
+	viewers map[string]*list.Element
+	order   *list.List // front is most recently active
+}
+
+type viewerSet struct {
+	viewer string
+	ids    map[string]bool
+	order  []string // oldest first
+}
+
+func newMemoryStore(maxViewers int) *memoryStore {
+	return &memoryStore{
+		maxViewers: maxViewers,
+		viewers:    make(map[string]*list.Element),
+		order:      list.New(),
+	}
+}
+
+func (m *memoryStore) SeenPuzzles(_ context.Context, viewer string, ids ...string) ([]bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	seen := make([]bool, len(ids))
+	if el, ok := m.viewers[viewer]; ok {
+		set := el.Value.(*viewerSet)
+		for i, id := range ids {
+			seen[i] = set.ids[id]
+		}
+	}
+	return seen, nil
+}
+
+func (m *memoryStore) MarkPuzzleSeen(_ context.Context, viewer, id string, limit int, _ time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	el, ok := m.viewers[viewer]
+	if ok {
+		m.order.MoveToFront(el)
+	} else {
+		el = m.order.PushFront(&viewerSet{viewer: viewer, ids: make(map[string]bool)})
+		m.viewers[viewer] = el
+		for m.order.Len() > m.maxViewers {
+			oldest := m.order.Back()
+			m.order.Remove(oldest)
+			delete(m.viewers, oldest.Value.(*viewerSet).viewer)
+		}
+	}
+
+	set := el.Value.(*viewerSet)
+	if set.ids[id] {
+		return nil
+	}
+	set.ids[id] = true
+	set.order = append(set.order, id)
+	for len(set.order) > limit {
+		delete(set.ids, set.order[0])
+		set.order = set.order[1:]
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package seen
+
+import "context"
+
+// DeviceIDHeader identifies an anonymous client across requests. The web
+// client stores a random ID and sends it with every request.
+const DeviceIDHeader = "X-Device-ID"
+
+// Device IDs outside these bounds are ignored.
+const (
+	minDeviceIDLen = 8
+	maxDeviceIDLen = 64
+)
+
+type viewerKey struct{}
+
+// WithViewer returns a copy of ctx carrying the viewer, such as "user:<id>",
+// "device:<id>" or "ip:<addr>".
+func WithViewer(ctx context.Context, viewer string) context.Context {
+	return context.WithValue(ctx, viewerKey{}, viewer)
+}
+
+// Viewer returns the viewer stored in ctx, or "".
+func Viewer(ctx context.Context) string {
+	v, _ := ctx.Value(viewerKey{}).(string)
+	return v
+}
+
+// ValidDeviceID reports whether id is a usable device ID: 8 to 64 letters,
+// digits, '-' or '_'.
+func ValidDeviceID(id string) bool {
+	if len(id) < minDeviceIDLen || len(id) > maxDeviceIDLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,158 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/chess-puzzle-next/puzzle-generator/internal/models"
+)
+
+// Bounds of a puzzle filter.
+const (
+	filterMaxRating = 3500
+	filterMaxMoves  = 15
+)
+
+// NoMatchError is returned when no puzzle matches the filter of an AI
+// request. It carries the filter so the user can refine it, and where the
+// filter came from: ai-rag, or ai-rag-fallback when the model gave no valid
+// filter and the prompt's keywords were used.
+type NoMatchError struct {
+	Filter *models.PuzzleFilter
+	Source string
+}
+
+func (e *NoMatchError) Error() string {
+	return "puzzle: no puzzle matches the filter"
+}
+
+var openingRe = regexp.MustCompile(`^[A-Za-z][A-Za-z' -]{1,59}$`)
+
+// normalizeFilter checks f and puts it in canonical form: theme names become
+// keys, lists are deduplicated, the color is lowercased and the opening
+// trimmed. A filter without a rating range gets the range of difficulty.
+func normalizeFilter(f *models.PuzzleFilter, difficulty models.DifficultyLevel) error {
+	var err error
+	if f.Themes, err = themeKeys(f.Themes); err != nil {
+		return err
+	}
+	if f.ExcludeThemes, err = themeKeys(f.ExcludeThemes); err != nil {
+		return err
+	}
+	for _, k := range f.Themes {
+		if slices.Contains(f.ExcludeThemes, k) {
+			return fmt.Errorf("theme %q is both required and excluded", k)
+		}
+	}
+
+	switch {
+	case f.MinRating < 0 || f.MaxRating < 0:
+		return fmt.Errorf("ratings cannot be negative")
+	case f.MinRating > filterMaxRating || f.MaxRating > filterMaxRating:
+		return fmt.Errorf("ratings go up to %d", filterMaxRating)
+	case f.MaxRating > 0 && f.MinRating > f.MaxRating:
+		return fmt.Errorf("minRating %d is above maxRating %d", f.MinRating, f.MaxRating)
+	}
+	if f.MinRating == 0 && f.MaxRating == 0 {
+		if bounds, ok := models.DifficultyRatingBounds[difficulty]; ok {
+			f.MinRating = bounds[0]
+			if bounds[1] < filterMaxRating {
+				f.MaxRating = bounds[1]
+			}
+		}
+	}
+
+	f.Color = strings.ToLower(strings.TrimSpace(f.Color))
+	if f.Color != "" && f.Color != "white" && f.Color != "black" {
+		return fmt.Errorf("color must be white or black, got %q", f.Color)
+	}
+
+	f.Opening = strings.Join(strings.Fields(strings.ReplaceAll(f.Opening, "_", " ")), " ")
+	if f.Opening != "" && !openingRe.MatchString(f.Opening) {
+		return fmt.Errorf("opening %q is not an opening name", f.Opening)
+	}
+
+	if f.MaxMoves < 0 || f.MaxMoves > filterMaxMoves {
+		return fmt.Errorf("maxMoves must be between 1 and %d", filterMaxMoves)
+	}
+	return nil
+}
+
+// themeKeys maps theme keys or names, in any case, to taxonomy keys without
+// duplicates. Unknown themes are an error.
+func themeKeys(themes []string) ([]string, error) {
+	var keys, unknown []string
+	for _, t := range themes {
+		t = strings.TrimSpace(t)
+		key := ""
+		for _, theme := range models.ThemeTaxonomy {
+			if strings.EqualFold(t, theme.Key) || strings.EqualFold(t, theme.Name) {
+				key = theme.Key
+				break
+			}
+		}
+		switch {
+		case key == "":
+			unknown = append(unknown, t)
+		case !slices.Contains(keys, key):
+			keys = append(keys, key)
+		}
+	}
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("unknown themes: %s", strings.Join(unknown, ", "))
+	}
+	return keys, nil
+}
+
+var colorRe = regexp.MustCompile(`(?i)\b(?:as|i'm|i am|playing|play|for)\s+(white|black)\b`)
+
+// themeKeywordRes find the tactic and mate themes named in a prompt, by key
+// or name, as whole words.
+var themeKeywordRes = func() map[string]*regexp.Regexp {
+	res := make(map[string]*regexp.Regexp)
+	for _, t := range models.ThemeTaxonomy {
+		if t.Category == models.ThemeCategoryTactic || t.Category == models.ThemeCategoryMate {
+			res[t.Key] = regexp.MustCompile(`(?i)\b(?:` + regexp.QuoteMeta(t.Key) + `|` + regexp.QuoteMeta(t.Name) + `)s?\b`)
+		}
+	}
+	return res
+}()
+
+// keywordFilter builds a filter from the themes and color named in the
+// prompt. It is the fallback when the model gives no valid filter.
+func keywordFilter(prompt string, difficulty models.DifficultyLevel) *models.PuzzleFilter {
+	f := &models.PuzzleFilter{}
+	for _, t := range models.ThemeTaxonomy {
+		if re := themeKeywordRes[t.Key]; re != nil && re.MatchString(prompt) {
+			f.Themes = append(f.Themes, t.Key)
+		}
+	}
+	if m := colorRe.FindStringSubmatch(prompt); m != nil {
+		f.Color = strings.ToLower(m[1])
+	}
+	_ = normalizeFilter(f, difficulty) // keys and color are valid by construction
+	return f
+}
+
+// filterQuery writes a filter as text for the vector index, used when the
+// request has a filter but no prompt.
+func filterQuery(f *models.PuzzleFilter) string {
+	var parts []string
+	for _, k := range f.Themes {
+		if t, ok := models.LookupTheme(k); ok {
+			parts = append(parts, t.Name)
+		}
+	}
+	if f.Opening != "" {
+		parts = append(parts, f.Opening)
+	}
+	if f.Color != "" {
+		parts = append(parts, f.Color+" to play")
+	}
+	if len(parts) == 0 {
+		return "chess puzzle"
+	}
+	return strings.Join(parts, ", ")
+}
@@ -3,60 +3,38 @@ package services
 import (
 	"encoding/json"
 	"fmt"
-	"regexp"
-	"strconv"
 	"strings"
 
 	"github.com/chess-puzzle-next/puzzle-generator/internal/models"
+	"github.com/chess-puzzle-next/puzzle-generator/pkg/llm"
 )
 
 // ---------------------------------------------------------------------------
-// RAG selection parsing – the AI returns {"selected_index": N}
+// Filter parsing – the AI returns a models.PuzzleFilter as JSON
 // ---------------------------------------------------------------------------
 
-// ragSelection is the expected JSON shape from the RAG prompt.
-type ragSelection struct {
-	SelectedIndex int `json:"selected_index"`
-}
-
-// parseRAGSelectionResponse extracts the selected puzzle from the AI's reply.
-// It handles JSON objects, plain numbers, and noisy outputs gracefully.
-func parseRAGSelectionResponse(raw string, candidates []*models.Puzzle) (*models.Puzzle, error) {
-	if len(candidates) == 0 {
-		return nil, fmt.Errorf("no candidate puzzles available")
+// parseFilterResponse extracts the filter from the AI's reply and validates
+// it. Unknown fields are rejected so that a misspelt field is retried rather
+// than silently ignored.
+func parseFilterResponse(raw string, difficulty models.DifficultyLevel) (*models.PuzzleFilter, error) {
+	payload := extractJSONObject(llm.StripThinkingTags(raw))
+	if payload == "" {
+		return nil, fmt.Errorf("no JSON object found")
 	}
-
-	content := strings.TrimSpace(raw)
-
-	// 1) Try to extract a JSON object with selected_index.
-	jsonPayload := extractJSONObject(content)
-	if jsonPayload != "" {
-		var sel ragSelection
-		if err := json.Unmarshal([]byte(jsonPayload), &sel); err == nil {
-			if sel.SelectedIndex >= 0 && sel.SelectedIndex < len(candidates) {
-				return candidates[sel.SelectedIndex], nil
-			}
-		}
+	dec := json.NewDecoder(strings.NewReader(payload))
+	dec.DisallowUnknownFields()
+	var f models.PuzzleFilter
+	if err := dec.Decode(&f); err != nil {
+		return nil, fmt.Errorf("decode filter: %w", err)
 	}
-
-	// 2) Try to find a bare integer in the response.
-	re := regexp.MustCompile(`\b(\d+)\b`)
-	if m := re.FindStringSubmatch(content); len(m) > 1 {
-		if idx, err := strconv.Atoi(m[1]); err == nil {
-			if idx >= 0 && idx < len(candidates) {
-				return candidates[idx], nil
-			}
-		}
+	if err := normalizeFilter(&f, difficulty); err != nil {
+		return nil, err
 	}
-
-	// 3) Fallback — return the first candidate.
-	return candidates[0], nil
+	return &f, nil
 }
 
-// ---------------------------------------------------------------------------
-// Legacy helpers (kept for potential future use)
-// ---------------------------------------------------------------------------
-
+// extractJSONObject returns the first balanced JSON object in raw, which may
+// be wrapped in a Markdown code fence or surrounded by prose.
 func extractJSONObject(raw string) string {
 	trimmed := strings.TrimSpace(raw)
 	if strings.HasPrefix(trimmed, "```") {
@@ -83,5 +61,3 @@ func extractJSONObject(raw string) string {
 	}
 	return ""
 }
-
-var _ = regexp.MustCompile(`^[a-h][1-8][a-h][1-8][qrbn]?$`)
@@ -1,53 +1,63 @@
 package services
 
 import (
-	"fmt"
 	"strings"
 
 	"github.com/chess-puzzle-next/puzzle-generator/internal/models"
-	"github.com/chess-puzzle-next/puzzle-generator/pkg/nvidia"
+	"github.com/chess-puzzle-next/puzzle-generator/pkg/llm"
 )
 
-// buildRAGSelectionPrompt creates the messages for the RAG puzzle-selection
-// pipeline. The AI receives a list of real candidate puzzles from the dataset
-// and must pick the one that best matches the user's intent.
-func buildRAGSelectionPrompt(req models.AIPuzzleRequest, candidates []*models.Puzzle) []nvidia.Message {
-	difficulty := req.Difficulty
-	if difficulty == "" {
-		difficulty = models.DifficultyMedium
-	}
-
-	systemPrompt := `You are a chess puzzle selector. Given a user request and a numbered list of puzzles, pick the best match.
-Return ONLY: {"selected_index": N}
-No extra text.`
-
-	var sb strings.Builder
-	sb.WriteString(fmt.Sprintf("User wants: %s (%s difficulty)\n\nPuzzles:\n",
-		strings.TrimSpace(req.Prompt), difficulty))
+// filterPromptName is the prompt template that turns a request into a
+// puzzle filter (internal/prompts/templates/filter).
+const filterPromptName = "filter"
+
+// filterPromptData is the data of the filter prompt templates.
+type filterPromptData struct {
+	Prompt        string
+	Difficulty    models.DifficultyLevel
+	Themes        []models.Theme
+	MaxRating     int
+	MaxMoves      int
+	PreviousError string // why the previous output was rejected, in the retry
+}
 
-	for i, p := range candidates {
-		sb.WriteString(fmt.Sprintf(
-			"[%d] Rating:%d Themes:%s\n",
-			i, p.Rating, strings.Join(p.Themes, ","),
-		))
+// filterPrompt returns the llm.Request.Render func that builds the messages
+// turning the user's prompt into a puzzle filter, with the template version
+// selected for the provider and model that answer. The model only translates
+// the request; the puzzle itself is found by running the filter. When
+// previousOutput is set, it and the retry template's correction are
+// appended.
+func (s *PuzzleService) filterPrompt(req models.AIPuzzleRequest, previousOutput string, previousErr error) func(provider, model string) ([]llm.Message, string, error) {
+	data := filterPromptData{
+		Prompt:     strings.TrimSpace(req.Prompt),
+		Difficulty: req.Difficulty,
+		Themes:     models.ThemeTaxonomy,
+		MaxRating:  filterMaxRating,
+		MaxMoves:   filterMaxMoves,
 	}
-
-	return []nvidia.Message{
-		{Role: "system", Content: systemPrompt},
-		{Role: "user", Content: sb.String()},
+	if data.Difficulty == "" {
+		data.Difficulty = models.DifficultyMedium
+	}
+	if previousErr != nil {
+		data.PreviousError = previousErr.Error()
+	}
+	previousOutput = strings.TrimSpace(previousOutput)
+
+	return func(provider, model string) ([]llm.Message, string, error) {
+		p, err := s.prompts.Render(filterPromptName, provider, model, data)
+		if err != nil {
+			return nil, "", err
+		}
+		messages := []llm.Message{
+			{Role: llm.RoleSystem, Content: p.System},
+			{Role: llm.RoleUser, Content: p.User},
+		}
+		if previousOutput != "" && p.Retry != "" {
+			messages = append(messages,
+				llm.Message{Role: llm.RoleAssistant, Content: previousOutput},
+				llm.Message{Role: llm.RoleUser, Content: p.Retry},
+			)
+		}
+		return messages, p.Version, nil
 	}
-}
-
-// buildRAGRetryPrompt appends a correction turn when the first response
-// could not be parsed.
-func buildRAGRetryPrompt(req models.AIPuzzleRequest, candidates []*models.Puzzle, previousOutput string, previousErr error) []nvidia.Message {
-	base := buildRAGSelectionPrompt(req, candidates)
-	correction := fmt.Sprintf(
-		"Your previous output was invalid (%v). Reply with ONLY the JSON object {\"selected_index\": N} and nothing else.",
-		previousErr,
-	)
-	return append(base,
-		nvidia.Message{Role: "assistant", Content: strings.TrimSpace(previousOutput)},
-		nvidia.Message{Role: "user", Content: correction},
-	)
 }
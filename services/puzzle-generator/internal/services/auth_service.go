@@ -0,0 +1,220 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/chess-puzzle-next/puzzle-generator/internal/auth"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/entitlements"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/models"
+	"github.com/chess-puzzle-next/puzzle-generator/pkg/redis"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// UserStore persists accounts and refresh tokens.
+type UserStore interface {
+	CreateUser(ctx context.Context, u *redis.User) error
+	GetUser(ctx context.Context, userID string) (*redis.User, error)
+	GetUserByEmail(ctx context.Context, email string) (*redis.User, error)
+	SaveRefreshToken(ctx context.Context, tokenID, userID string, ttl time.Duration) error
+	ConsumeRefreshToken(ctx context.Context, tokenID string) (string, error)
+	GetPlayerRating(ctx context.Context, userID string) (*redis.PlayerRating, error)
+}
+
+var (
+	ErrEmailTaken         = errors.New("auth: email already registered")
+	ErrInvalidCredentials = errors.New("auth: invalid email or password")
+	ErrUserNotFound       = errors.New("auth: user not found")
+)
+
+const (
+	passwordMinLen = 8
+	passwordMaxLen = 72 // bcrypt ignores anything past 72 bytes
+)
+
+// AuthService registers users and issues their tokens.
+type AuthService struct {
+	users  UserStore
+	tokens *auth.Tokens
+	admins []string // user IDs granted the admin role
+}
+
+// NewAuthService returns an AuthService. Users whose ID is in adminIDs get
+// the admin role in their tokens. Admins are matched by ID rather than email
+// because registering does not prove the caller owns the address.
+func NewAuthService(users UserStore, tokens *auth.Tokens, adminIDs []string) *AuthService {
+	return &AuthService{users: users, tokens: tokens, admins: adminIDs}
+}
+
+// Register creates an account on the free plan and signs the user in.
+func (s *AuthService) Register(ctx context.Context, req models.RegisterRequest) (*models.AuthResponse, error) {
+	email, err := normalizeEmail(req.Email)
+	if err != nil {
+		return nil, err
+	}
+	if len(req.Password) < passwordMinLen || len(req.Password) > passwordMaxLen {
+		return nil, fmt.Errorf("auth: password must be between %d and %d characters", passwordMinLen, passwordMaxLen)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("auth: hash password: %w", err)
+	}
+
+	user := &redis.User{
+		ID:           uuid.New().String(),
+		Email:        email,
+		PasswordHash: string(hash),
+		Plan:         string(entitlements.PlanFree),
+		CreatedAt:    time.Now(),
+	}
+	if err := s.users.CreateUser(ctx, user); err != nil {
+		if errors.Is(err, redis.ErrEmailTaken) {
+			return nil, ErrEmailTaken
+		}
+		return nil, err
+	}
+
+	return s.signIn(ctx, user)
+}
+
+// Login checks the user's password and issues a fresh token pair.
+func (s *AuthService) Login(ctx context.Context, req models.LoginRequest) (*models.AuthResponse, error) {
+	email, err := normalizeEmail(req.Email)
+	if err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	user, err := s.users.GetUserByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		// Spend the same time as a real comparison so response timing does
+		// not reveal which emails are registered.
+		_ = bcrypt.CompareHashAndPassword(dummyHash, []byte(req.Password))
+		return nil, ErrInvalidCredentials
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	return s.signIn(ctx, user)
+}
+
+// Refresh rotates a refresh token: the old one is consumed and a new pair is
+// issued. A refresh token can therefore only be used once.
+func (s *AuthService) Refresh(ctx context.Context, refreshToken string) (*models.AuthResponse, error) {
+	claims, err := s.tokens.Parse(refreshToken, auth.TokenTypeRefresh)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, err := s.users.ConsumeRefreshToken(ctx, claims.ID)
+	if err != nil {
+		return nil, err
+	}
+	if userID == "" || userID != claims.Subject {
+		return nil, auth.ErrInvalidToken
+	}
+
+	user, err := s.users.GetUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, auth.ErrInvalidToken
+	}
+
+	return s.signIn(ctx, user)
+}
+
+// Logout revokes a refresh token. Unknown or expired tokens are ignored.
+func (s *AuthService) Logout(ctx context.Context, refreshToken string) error {
+	claims, err := s.tokens.Parse(refreshToken, auth.TokenTypeRefresh)
+	if err != nil {
+		return nil
+	}
+	_, err = s.users.ConsumeRefreshToken(ctx, claims.ID)
+	return err
+}
+
+// Profile returns the account of an authenticated user.
+func (s *AuthService) Profile(ctx context.Context, userID string) (*models.UserProfile, error) {
+	user, err := s.users.GetUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	profile := toProfile(user)
+	profile.Rating = initialPlayerRating
+	rating, err := s.users.GetPlayerRating(ctx, userID)
+	if err != nil {
+		logger.WarnContext(ctx, "read player rating", "user", userID, "err", err)
+	}
+	if rating != nil {
+		profile.Rating, profile.RatedSessions = rating.Rating, rating.Games
+	}
+	return &profile, nil
+}
+
+func (s *AuthService) signIn(ctx context.Context, user *redis.User) (*models.AuthResponse, error) {
+	plan := entitlements.EffectivePlan(user, time.Now())
+	sub := auth.Subject{UserID: user.ID, Email: user.Email, Plan: string(plan)}
+	if slices.Contains(s.admins, user.ID) {
+		sub.Role = auth.RoleAdmin
+	}
+
+	access, err := s.tokens.IssueAccess(sub)
+	if err != nil {
+		return nil, err
+	}
+	refresh, refreshID, err := s.tokens.IssueRefresh(sub)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.users.SaveRefreshToken(ctx, refreshID, user.ID, s.tokens.RefreshTTL()); err != nil {
+		return nil, err
+	}
+
+	return &models.AuthResponse{
+		User: toProfile(user),
+		Tokens: models.TokenPair{
+			AccessToken:  access,
+			RefreshToken: refresh,
+			TokenType:    "Bearer",
+			ExpiresIn:    int(s.tokens.AccessTTL().Seconds()),
+		},
+	}, nil
+}
+
+// dummyHash is compared against when a login email is unknown.
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("chess-puzzle-next"), bcrypt.DefaultCost)
+
+func normalizeEmail(raw string) (string, error) {
+	addr, err := mail.ParseAddress(strings.TrimSpace(raw))
+	if err != nil || addr.Name != "" {
+		return "", fmt.Errorf("auth: invalid email address")
+	}
+	return strings.ToLower(addr.Address), nil
+}
+
+func toProfile(u *redis.User) models.UserProfile {
+	return models.UserProfile{
+		ID:          u.ID,
+		Email:       u.Email,
+		Plan:        string(entitlements.EffectivePlan(u, time.Now())),
+		CreatedAt:   u.CreatedAt,
+		PlanStatus:  u.PlanStatus,
+		TrialEndsAt: u.TrialEndsAt,
+		GraceUntil:  u.GraceUntil,
+	}
+}
@@ -0,0 +1,392 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chess-puzzle-next/puzzle-generator/internal/models"
+)
+
+// ChallengeDatasetAPI is the dataset access the weekly challenge needs.
+type ChallengeDatasetAPI interface {
+	FindPuzzles(ctx context.Context, match func(*models.Puzzle) bool, count, maxBatches int) ([]*models.Puzzle, error)
+}
+
+// ChallengeStore persists weekly challenges and player progress.
+type ChallengeStore interface {
+	SaveWeeklyChallenge(ctx context.Context, ch *models.WeeklyChallenge, ttl time.Duration) (bool, error)
+	GetWeeklyChallenge(ctx context.Context, id string) (*models.WeeklyChallenge, error)
+	RecordChallengeAttempt(ctx context.Context, challengeID, playerID, name string, attempt models.ChallengeAttempt, puzzles int, ttl time.Duration) (*models.ChallengeEntry, error)
+	GetChallengeEntry(ctx context.Context, challengeID, playerID string) (*models.ChallengeEntry, error)
+	ListChallengeEntries(ctx context.Context, challengeID string) ([]*models.ChallengeEntry, error)
+}
+
+var (
+	ErrChallengeNotFound      = errors.New("challenge: not found")
+	ErrChallengeOpen          = errors.New("challenge: results are published when the week closes")
+	ErrChallengeAttempted     = errors.New("challenge: puzzle already attempted")
+	ErrChallengePuzzleUnknown = errors.New("challenge: puzzle is not part of this week's challenge")
+	ErrChallengeMoves         = errors.New("challenge: moves do not solve the puzzle")
+)
+
+const (
+	challengePuzzleCount = 10
+	challengeScanBatches = 40
+	challengeNameMaxLen  = 32
+	challengeIDMaxLen    = 64
+)
+
+// anonPlayerPrefix namespaces the IDs anonymous players choose, so they can
+// never collide with account IDs.
+const anonPlayerPrefix = "anon:"
+
+// AnonymousPlayerID returns the player ID an anonymous caller who chose id
+// plays under, or "" if id is empty.
+func AnonymousPlayerID(id string) string {
+	if id = strings.TrimSpace(id); id == "" {
+		return ""
+	}
+	return anonPlayerPrefix + id
+}
+
+// rotationEpoch is the Monday the weekly theme rotation counts from.
+var rotationEpoch = time.Date(1970, time.January, 5, 0, 0, 0, 0, time.UTC)
+
+// ChallengeService schedules weekly themed challenges and scores attempts.
+type ChallengeService struct {
+	dataset   ChallengeDatasetAPI
+	store     ChallengeStore
+	retention time.Duration
+	now       func() time.Time
+
+	mu sync.Mutex // serialises challenge builds within this replica
+}
+
+// NewChallengeService returns a ChallengeService. Challenges and player
+// entries are kept in the store for `retention` so closed weeks keep their
+// results table.
+func NewChallengeService(dataset ChallengeDatasetAPI, store ChallengeStore, retention time.Duration) *ChallengeService {
+	return &ChallengeService{
+		dataset:   dataset,
+		store:     store,
+		retention: retention,
+		now:       time.Now,
+	}
+}
+
+// Current returns this week's challenge, building it if it does not exist yet.
+func (s *ChallengeService) Current(ctx context.Context) (*models.WeeklyChallenge, error) {
+	return s.ensure(ctx, s.now())
+}
+
+// Get returns the challenge for the given ISO week (e.g. "2026-W42").
+func (s *ChallengeService) Get(ctx context.Context, id string) (*models.WeeklyChallenge, error) {
+	if currentID, _, _ := challengeWeek(s.now()); id == currentID {
+		return s.Current(ctx)
+	}
+	ch, err := s.store.GetWeeklyChallenge(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if ch == nil {
+		return nil, ErrChallengeNotFound
+	}
+	return ch, nil
+}
+
+// SubmitAttempt records a player's result for one puzzle of the current
+// challenge. Only the first attempt at each puzzle counts, and a solve only
+// counts if the player's moves are the solution.
+func (s *ChallengeService) SubmitAttempt(ctx context.Context, req models.ChallengeAttemptRequest) (*models.ChallengeEntry, error) {
+	playerID := strings.TrimSpace(req.PlayerID)
+	if playerID == "" || len(strings.TrimPrefix(playerID, anonPlayerPrefix)) > challengeIDMaxLen {
+		return nil, fmt.Errorf("challenge: playerId is required (max %d characters)", challengeIDMaxLen)
+	}
+
+	ch, err := s.Current(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var target *models.ChallengePuzzle
+	for i := range ch.Puzzles {
+		if ch.Puzzles[i].Puzzle.ID == req.PuzzleID {
+			target = &ch.Puzzles[i]
+			break
+		}
+	}
+	if target == nil {
+		return nil, ErrChallengePuzzleUnknown
+	}
+	if req.Solved && !solvesPuzzle(target.Puzzle, req.Moves) {
+		return nil, ErrChallengeMoves
+	}
+
+	attempt := models.ChallengeAttempt{
+		PuzzleID:    target.Puzzle.ID,
+		Solved:      req.Solved,
+		AttemptedAt: s.now().UTC(),
+	}
+	if req.Solved {
+		attempt.Points = target.Points
+	}
+	entry, err := s.store.RecordChallengeAttempt(ctx, ch.ID, playerID, displayName(req.Name), attempt, len(ch.Puzzles), s.retention)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, ErrChallengeAttempted
+	}
+	return entry, nil
+}
+
+// solvesPuzzle reports whether moves, the player's moves in UCI, are the
+// player's side of the puzzle's solution: every other move, starting after
+// the opponent's setup move.
+func solvesPuzzle(p *models.Puzzle, moves []string) bool {
+	if len(moves) != len(p.Moves)/2 {
+		return false
+	}
+	for i, m := range moves {
+		if strings.ToLower(strings.TrimSpace(m)) != p.Moves[2*i+1] {
+			return false
+		}
+	}
+	return true
+}
+
+// Progress returns a player's entry in the current challenge. Players who
+// have not attempted any puzzle get an empty entry.
+func (s *ChallengeService) Progress(ctx context.Context, playerID string) (*models.ChallengeEntry, error) {
+	playerID = strings.TrimSpace(playerID)
+	if playerID == "" {
+		return nil, fmt.Errorf("challenge: playerId is required")
+	}
+	id, _, _ := challengeWeek(s.now())
+	entry, err := s.store.GetChallengeEntry(ctx, id, playerID)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		entry = &models.ChallengeEntry{PlayerID: playerID, Attempts: []models.ChallengeAttempt{}}
+	}
+	return entry, nil
+}
+
+// Results returns the final standings of a challenge once its week has closed.
+func (s *ChallengeService) Results(ctx context.Context, id string) (*models.ChallengeResults, error) {
+	ch, err := s.store.GetWeeklyChallenge(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if ch == nil {
+		return nil, ErrChallengeNotFound
+	}
+	if !ch.Closed(s.now()) {
+		return nil, ErrChallengeOpen
+	}
+
+	entries, err := s.store.ListChallengeEntries(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Solved != b.Solved {
+			return a.Solved > b.Solved
+		}
+		return a.UpdatedAt.Before(b.UpdatedAt)
+	})
+
+	rows := make([]models.ChallengeResultRow, 0, len(entries))
+	for i, e := range entries {
+		rank := i + 1
+		if i > 0 && e.Score == entries[i-1].Score && e.Solved == entries[i-1].Solved {
+			rank = rows[i-1].Rank
+		}
+		rows = append(rows, models.ChallengeResultRow{
+			Rank:      rank,
+			Player:    playerTag(e.PlayerID),
+			Name:      e.Name,
+			Score:     e.Score,
+			Solved:    e.Solved,
+			Attempted: e.Attempted,
+		})
+	}
+
+	return &models.ChallengeResults{
+		ChallengeID: ch.ID,
+		Theme:       ch.Theme,
+		ClosedAt:    ch.EndsAt,
+		Rows:        rows,
+	}, nil
+}
+
+// RunScheduler makes sure the current week's challenge exists, checking every
+// interval until ctx is cancelled. A new challenge is therefore published
+// shortly after each week starts without waiting for the first request.
+func (s *ChallengeService) RunScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if ch, err := s.ensure(ctx, s.now()); err != nil {
+			logger.ErrorContext(ctx, "challenge schedule failed", "err", err)
+		} else {
+			logger.InfoContext(ctx, "challenge scheduled", "week", ch.ID, "theme", ch.Theme, "puzzles", len(ch.Puzzles))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *ChallengeService) ensure(ctx context.Context, t time.Time) (*models.WeeklyChallenge, error) {
+	id, start, end := challengeWeek(t)
+
+	ch, err := s.store.GetWeeklyChallenge(ctx, id)
+	if err != nil || ch != nil {
+		return ch, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Another request may have built it while we were waiting for the lock.
+	if ch, err := s.store.GetWeeklyChallenge(ctx, id); err != nil || ch != nil {
+		return ch, err
+	}
+
+	ch, err = s.build(ctx, id, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := s.store.SaveWeeklyChallenge(ctx, ch, end.Sub(t)+s.retention)
+	if err != nil {
+		return nil, err
+	}
+	if !stored {
+		// Another replica won the race; serve its puzzle set instead.
+		return s.store.GetWeeklyChallenge(ctx, id)
+	}
+	return ch, nil
+}
+
+// build selects the challenge puzzles: the week's theme, spread evenly across
+// the rating bands, ordered from easiest to hardest.
+func (s *ChallengeService) build(ctx context.Context, id string, start, end time.Time) (*models.WeeklyChallenge, error) {
+	theme := themeForWeek(start)
+	perBand := challengePuzzleCount / len(models.ChallengeRatingBands)
+
+	counts := make(map[string]int)
+	var spare []*models.Puzzle
+	// Scan batches can repeat a puzzle; each one is either chosen or kept
+	// as a spare once, so the top-up never adds a puzzle twice.
+	seen := make(map[string]bool)
+	match := func(p *models.Puzzle) bool {
+		if !hasTheme(p, theme.Key) || seen[p.ID] {
+			return false
+		}
+		seen[p.ID] = true
+		band := models.RatingToBand(p.Rating)
+		if counts[band.Name] < perBand {
+			counts[band.Name]++
+			return true
+		}
+		// Keep a few extras to top up bands the scan could not fill.
+		if len(spare) < challengePuzzleCount {
+			spare = append(spare, p)
+		}
+		return false
+	}
+
+	puzzles, err := s.dataset.FindPuzzles(ctx, match, challengePuzzleCount, challengeScanBatches)
+	if err != nil {
+		return nil, fmt.Errorf("challenge: fetch %s puzzles: %w", theme.Key, err)
+	}
+	for _, p := range spare {
+		if len(puzzles) >= challengePuzzleCount {
+			break
+		}
+		puzzles = append(puzzles, p)
+	}
+	if len(puzzles) == 0 {
+		return nil, fmt.Errorf("challenge: no %s puzzles found in dataset", theme.Key)
+	}
+	if len(puzzles) < challengePuzzleCount {
+		logger.WarnContext(ctx, "challenge puzzle set incomplete", "week", id, "found", len(puzzles), "want", challengePuzzleCount, "theme", theme.Key)
+	}
+
+	sort.SliceStable(puzzles, func(i, j int) bool { return puzzles[i].Rating < puzzles[j].Rating })
+
+	items := make([]models.ChallengePuzzle, 0, len(puzzles))
+	for _, p := range puzzles {
+		band := models.RatingToBand(p.Rating)
+		items = append(items, models.ChallengePuzzle{Band: band.Name, Points: band.Points, Puzzle: p})
+	}
+
+	return &models.WeeklyChallenge{
+		ID:        id,
+		Theme:     theme.Key,
+		ThemeName: theme.Name,
+		StartsAt:  start,
+		EndsAt:    end,
+		Puzzles:   items,
+		CreatedAt: s.now(),
+	}, nil
+}
+
+// challengeWeek returns the ISO week identifier containing t and the week's
+// UTC boundaries (Monday 00:00 to the following Monday 00:00).
+func challengeWeek(t time.Time) (id string, start, end time.Time) {
+	t = t.UTC()
+	year, week := t.ISOWeek()
+	daysSinceMonday := (int(t.Weekday()) + 6) % 7
+	start = time.Date(t.Year(), t.Month(), t.Day()-daysSinceMonday, 0, 0, 0, 0, time.UTC)
+	return fmt.Sprintf("%d-W%02d", year, week), start, start.AddDate(0, 0, 7)
+}
+
+// themeForWeek rotates through the weekly themes of the taxonomy.
+func themeForWeek(start time.Time) models.Theme {
+	themes := models.WeeklyThemes()
+	weeks := int(start.Sub(rotationEpoch).Hours() / (24 * 7))
+	return themes[weeks%len(themes)]
+}
+
+func hasTheme(p *models.Puzzle, theme string) bool {
+	for _, t := range p.Themes {
+		if t == theme {
+			return true
+		}
+	}
+	return false
+}
+
+func displayName(raw string) string {
+	name := []rune(strings.TrimSpace(raw))
+	if len(name) > challengeNameMaxLen {
+		name = name[:challengeNameMaxLen]
+	}
+	return string(name)
+}
+
+// playerTag is a short, stable stand-in for a player ID, so the results table
+// can tell players apart without publishing their IDs.
+func playerTag(playerID string) string {
+	sum := sha256.Sum256([]byte(playerID))
+	return hex.EncodeToString(sum[:4])
+}
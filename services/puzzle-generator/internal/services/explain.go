@@ -0,0 +1,231 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/chess-puzzle-next/puzzle-generator/internal/metrics"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/models"
+	"github.com/chess-puzzle-next/puzzle-generator/pkg/llm"
+)
+
+// ExplanationStore caches puzzle explanations per puzzle and language.
+type ExplanationStore interface {
+	GetCachedExplanation(ctx context.Context, puzzleID, lang string) (*models.PuzzleExplanation, error)
+	CacheExplanation(ctx context.Context, e *models.PuzzleExplanation, ttl time.Duration) error
+}
+
+// WithExplanations caches explanations in store for ttl. Without it every
+// request asks the model.
+func WithExplanations(store ExplanationStore, ttl time.Duration) Option {
+	return func(s *PuzzleService) {
+		s.explanations = store
+		s.explanationTTL = ttl
+	}
+}
+
+var languageRe = regexp.MustCompile(`^[a-z]{2,3}(-[a-z0-9]{2,8})?$`)
+
+// normalizeLanguage lowercases a language tag and defaults it to en.
+func normalizeLanguage(lang string) (string, error) {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if lang == "" {
+		return "en", nil
+	}
+	if !languageRe.MatchString(lang) {
+		return "", fmt.Errorf("puzzle: invalid language %q; use an ISO 639-1 code such as en or pt-br", lang)
+	}
+	return lang, nil
+}
+
+// ExplainPuzzle explains the solution of a puzzle move by move in lang.
+// Explanations are checked so that every move they mention is legal in the
+// line, and cached per puzzle and language.
+func (s *PuzzleService) ExplainPuzzle(ctx context.Context, id, lang string) (*models.PuzzleExplanation, error) {
+	lang, err := normalizeLanguage(lang)
+	if err != nil {
+		return nil, err
+	}
+	if s.ai == nil || !s.ai.Configured() {
+		return nil, fmt.Errorf("puzzle: AI provider: %w", llm.ErrNotConfigured)
+	}
+	p, err := s.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.explanations != nil {
+		cached, err := s.explanations.GetCachedExplanation(ctx, p.ID, lang)
+		if err != nil {
+			logger.WarnContext(ctx, "read cached explanation", "puzzle", p.ID, "lang", lang, "err", err)
+		}
+		if cached != nil {
+			metrics.Explanation("cached")
+			cached.CacheStatus = "HIT"
+			return cached, nil
+		}
+	}
+
+	// Concurrent requests for the same explanation share one model call.
+	v, err, _ := s.lookups.Do("explain:"+p.ID+":"+lang, func() (any, error) {
+		return s.explain(context.WithoutCancel(ctx), p, lang)
+	})
+	if err != nil {
+		return nil, err
+	}
+	e := *v.(*models.PuzzleExplanation)
+	e.CacheStatus = "MISS"
+	return &e, nil
+}
+
+// explain asks the model for an explanation, retrying once with the
+// problems of the first answer.
+func (s *PuzzleService) explain(ctx context.Context, p *models.Puzzle, lang string) (*models.PuzzleExplanation, error) {
+	line, err := replaySolution(p)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := buildExplainPrompt(p, line, lang)
+	const maxAttempts = 2
+	var lastErr error
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		resp, err := s.ai.Chat(ctx, llm.Request{Task: TaskExplain, Messages: messages, MaxTokens: 1024})
+		if err != nil {
+			metrics.Explanation("failed")
+			return nil, fmt.Errorf("puzzle: explain %s: llm completion: %w", p.ID, err)
+		}
+		e, err := parseExplanation(resp.Content, line)
+		if err == nil {
+			e.PuzzleID = p.ID
+			e.Language = lang
+			e.Provider = resp.Provider
+			e.Model = resp.Model
+			e.CreatedAt = time.Now().UTC()
+			s.cacheExplanation(ctx, e)
+			metrics.Explanation("generated")
+			logger.InfoContext(ctx, "puzzle explained", "puzzle", p.ID, "lang", lang, "attempt", attempt, "provider", resp.Provider)
+			return e, nil
+		}
+		logger.DebugContext(ctx, "explanation rejected", "puzzle", p.ID, "attempt", attempt, "err", err, "content", truncateStr(resp.Content, 200))
+		metrics.Explanation("rejected")
+		lastErr = err
+		messages = buildExplainRetryPrompt(p, line, lang, resp.Content, err)
+	}
+	metrics.Explanation("failed")
+	return nil, fmt.Errorf("puzzle: explain %s: no valid explanation: %w", p.ID, lastErr)
+}
+
+func (s *PuzzleService) cacheExplanation(ctx context.Context, e *models.PuzzleExplanation) {
+	if s.explanations == nil {
+		return
+	}
+	if err := s.explanations.CacheExplanation(ctx, e, s.explanationTTL); err != nil {
+		logger.WarnContext(ctx, "cache explanation", "puzzle", e.PuzzleID, "lang", e.Language, "err", err)
+	}
+}
+
+// buildExplainPrompt creates the messages asking for a move-by-move
+// explanation. The model gets the position, the solution in SAN, the themes
+// and what each move changes on the board, so it does not have to read the
+// FEN on its own.
+func buildExplainPrompt(p *models.Puzzle, line *solutionLine, lang string) []llm.Message {
+	systemPrompt := fmt.Sprintf(`You are a chess coach explaining why the solution of a tactics puzzle works, to a club player who just solved it.
+Write the explanations in the language with code %q. Always write moves in English SAN (Nxe5, O-O, exd8=Q), whatever the language.
+Only mention moves that are legal in the position where they could be played.
+Return ONLY JSON: {"summary": "<1-2 sentences>", "moves": [{"move": "<SAN>", "explanation": "<1-2 sentences>"}]}
+with exactly one entry per solution move, in order. No extra text.`, lang)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Position (FEN): %s\n", line.fen)
+	fmt.Fprintf(&sb, "The player is %s.\n", line.player)
+	fmt.Fprintf(&sb, "The opponent just played %s (%s).\n", line.setup, line.setupDesc)
+	if p.Rating > 0 {
+		fmt.Fprintf(&sb, "Rating: %d\n", p.Rating)
+	}
+	if len(p.Themes) > 0 {
+		fmt.Fprintf(&sb, "Themes: %s\n", strings.Join(p.Themes, ", "))
+	}
+	sb.WriteString("\nSolution:\n")
+	for i, m := range line.moves {
+		fmt.Fprintf(&sb, "%d. %s by the %s: %s\n", i+1, m.san, m.side, m.change)
+	}
+
+	return []llm.Message{
+		{Role: llm.RoleSystem, Content: systemPrompt},
+		{Role: llm.RoleUser, Content: sb.String()},
+	}
+}
+
+// buildExplainRetryPrompt appends a correction turn when the first
+// explanation was rejected.
+func buildExplainRetryPrompt(p *models.Puzzle, line *solutionLine, lang, previousOutput string, previousErr error) []llm.Message {
+	base := buildExplainPrompt(p, line, lang)
+	correction := fmt.Sprintf(
+		"Your previous output was rejected (%v). Reply again with ONLY the JSON object, one entry per solution move in order, and mention only legal moves.",
+		previousErr,
+	)
+	return append(base,
+		llm.Message{Role: llm.RoleAssistant, Content: strings.TrimSpace(previousOutput)},
+		llm.Message{Role: llm.RoleUser, Content: correction},
+	)
+}
+
+// explanationReply is the expected JSON shape from the explain prompt.
+type explanationReply struct {
+	Summary string `json:"summary"`
+	Moves   []struct {
+		Move        string `json:"move"`
+		Explanation string `json:"explanation"`
+	} `json:"moves"`
+}
+
+// parseExplanation decodes the model's reply and checks it against the
+// solution: one entry per move, in order, and no illegal move mentioned.
+func parseExplanation(raw string, line *solutionLine) (*models.PuzzleExplanation, error) {
+	payload := extractJSONObject(llm.StripThinkingTags(raw))
+	if payload == "" {
+		return nil, fmt.Errorf("no JSON object in reply")
+	}
+	var reply explanationReply
+	if err := json.Unmarshal([]byte(payload), &reply); err != nil {
+		return nil, fmt.Errorf("decode reply: %w", err)
+	}
+	reply.Summary = strings.TrimSpace(reply.Summary)
+	if reply.Summary == "" {
+		return nil, fmt.Errorf("summary is empty")
+	}
+	if len(reply.Moves) != len(line.moves) {
+		return nil, fmt.Errorf("got %d move entries, the solution has %d moves", len(reply.Moves), len(line.moves))
+	}
+
+	e := &models.PuzzleExplanation{Summary: reply.Summary}
+	var illegal []string
+	illegal = append(illegal, line.illegalMentions(reply.Summary)...)
+	for i, m := range line.moves {
+		got := reply.Moves[i]
+		if strings.TrimRight(strings.TrimSpace(got.Move), "+#!?") != strings.TrimRight(m.san, "+#") {
+			return nil, fmt.Errorf("entry %d is %q, expected %s", i+1, got.Move, m.san)
+		}
+		text := strings.TrimSpace(got.Explanation)
+		if text == "" {
+			return nil, fmt.Errorf("explanation of %s is empty", m.san)
+		}
+		illegal = append(illegal, line.illegalMentions(text)...)
+		e.Moves = append(e.Moves, models.MoveExplanation{
+			Ply:         i + 1,
+			Side:        m.side,
+			SAN:         m.san,
+			UCI:         m.uci,
+			Explanation: text,
+		})
+	}
+	if len(illegal) > 0 {
+		return nil, fmt.Errorf("mentions moves that are not legal in the line: %s", strings.Join(illegal, ", "))
+	}
+	return e, nil
+}
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/chess-puzzle-next/puzzle-generator/internal/models"
+	"github.com/notnil/chess"
+)
+
+// solutionLine is a puzzle's solution replayed from its position.
+type solutionLine struct {
+	fen       string // position the player solves from, after the setup move
+	setup     string // the opponent's setup move in SAN
+	setupDesc string
+	player    string // White or Black
+	moves     []lineMove
+	// positions holds every position of the line, from the one before the
+	// setup move to the final one.
+	positions []*chess.Position
+}
+
+// lineMove is one move of a solution.
+type lineMove struct {
+	san, uci string
+	side     string // player or opponent
+	change   string // what the move does to the board
+}
+
+// replaySolution plays a puzzle's moves from its FEN. The first move is the
+// opponent's setup move; the solution starts after it.
+func replaySolution(p *models.Puzzle) (*solutionLine, error) {
+	if err := checkPlayable(p); err != nil {
+		return nil, err
+	}
+	fen, _ := chess.FEN(p.FEN)
+	game := chess.NewGame(fen, chess.UseNotation(chess.UCINotation{}))
+	line := &solutionLine{positions: []*chess.Position{game.Position()}}
+	for i, uci := range p.Moves {
+		before := game.Position()
+		_ = game.MoveStr(uci) // checkPlayable replayed the same moves
+		moves := game.Moves()
+		m := moves[len(moves)-1]
+		san := chess.AlgebraicNotation{}.Encode(before, m)
+		change := describeMove(before, m, san)
+		line.positions = append(line.positions, game.Position())
+
+		if i == 0 {
+			line.setup, line.setupDesc = san, change
+			line.fen = game.Position().String()
+			line.player = game.Position().Turn().Name()
+			continue
+		}
+		side := "player"
+		if i%2 == 0 {
+			side = "opponent"
+		}
+		line.moves = append(line.moves, lineMove{san: san, uci: uci, side: side, change: change})
+	}
+	return line, nil
+}
+
+var pieceNames = map[chess.PieceType]string{
+	chess.King:   "king",
+	chess.Queen:  "queen",
+	chess.Rook:   "rook",
+	chess.Bishop: "bishop",
+	chess.Knight: "knight",
+	chess.Pawn:   "pawn",
+}
+
+// describeMove says in plain English what m does to the board, such as
+// "White knight f3-e5, captures the black pawn on e5, check".
+func describeMove(pos *chess.Position, m *chess.Move, san string) string {
+	piece := pos.Board().Piece(m.S1())
+	color := piece.Color().Name()
+
+	var parts []string
+	switch {
+	case m.HasTag(chess.KingSideCastle):
+		parts = append(parts, color+" castles kingside")
+	case m.HasTag(chess.QueenSideCastle):
+		parts = append(parts, color+" castles queenside")
+	default:
+		parts = append(parts, fmt.Sprintf("%s %s %s-%s", color, pieceNames[piece.Type()], m.S1(), m.S2()))
+	}
+
+	if m.HasTag(chess.EnPassant) {
+		parts = append(parts, "captures the pawn en passant")
+	} else if captured := pos.Board().Piece(m.S2()); captured != chess.NoPiece {
+		parts = append(parts, fmt.Sprintf("captures the %s %s on %s",
+			strings.ToLower(captured.Color().Name()), pieceNames[captured.Type()], m.S2()))
+	}
+	if m.Promo() != chess.NoPieceType {
+		parts = append(parts, "promotes to a "+pieceNames[m.Promo()])
+	}
+	switch {
+	case strings.HasSuffix(san, "#"):
+		parts = append(parts, "checkmate")
+	case strings.HasSuffix(san, "+"):
+		parts = append(parts, "check")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// sanMentionRe finds moves written in SAN inside prose. Plain pawn pushes
+// ("e4") are left out because they read the same as squares.
+var sanMentionRe = regexp.MustCompile(`(O-O-O|O-O|0-0-0|0-0|[KQRBN][a-h]?[1-8]?x?[a-h][1-8](?:=?[QRBN])?|[a-h]x[a-h][1-8](?:=?[QRBN])?|[a-h][1-8]=[QRBN])[+#]?`)
+
+// mentionedMoves returns the SAN moves mentioned in text, without check
+// marks. Matches glued to other letters or digits are not moves.
+func mentionedMoves(text string) []string {
+	var out []string
+	for _, loc := range sanMentionRe.FindAllStringSubmatchIndex(text, -1) {
+		start, end := loc[0], loc[1]
+		if start > 0 && isAlnum(text[start-1]) || end < len(text) && isAlnum(text[end]) {
+			continue
+		}
+		out = append(out, strings.ReplaceAll(text[loc[2]:loc[3]], "0", "O"))
+	}
+	return out
+}
+
+func isAlnum(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9'
+}
+
+var sanPartsRe = regexp.MustCompile(`^(?:(O-O-O|O-O)|([KQRBN])?([a-h])?([1-8])?(x)?([a-h][1-8])=?([QRBN])?)$`)
+
+var sanPieces = map[string]chess.PieceType{
+	"": chess.Pawn, "K": chess.King, "Q": chess.Queen, "R": chess.Rook, "B": chess.Bishop, "N": chess.Knight,
+}
+
+// legalSAN reports whether san, without check marks, names a legal move in
+// pos. Extra disambiguation and a missing capture mark are tolerated.
+func legalSAN(pos *chess.Position, san string) bool {
+	p := sanPartsRe.FindStringSubmatch(san)
+	if p == nil {
+		return false
+	}
+	castle, piece, file, rank, capture, dest, promo := p[1], p[2], p[3], p[4], p[5], p[6], p[7]
+	for _, m := range pos.ValidMoves() {
+		if castle != "" {
+			if castle == "O-O" && m.HasTag(chess.KingSideCastle) || castle == "O-O-O" && m.HasTag(chess.QueenSideCastle) {
+				return true
+			}
+			continue
+		}
+		switch {
+		case pos.Board().Piece(m.S1()).Type() != sanPieces[piece],
+			m.S2().String() != dest,
+			file != "" && m.S1().File().String() != file,
+			rank != "" && m.S1().Rank().String() != rank,
+			capture != "" && !m.HasTag(chess.Capture) && !m.HasTag(chess.EnPassant),
+			promo != "" && m.Promo() != sanPieces[promo]:
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// illegalMentions returns the moves mentioned in text that are legal in none
+// of the line's positions.
+func (l *solutionLine) illegalMentions(text string) []string {
+	var out []string
+	for _, san := range mentionedMoves(text) {
+		legal := false
+		for _, pos := range l.positions {
+			if legalSAN(pos, san) {
+				legal = true
+				break
+			}
+		}
+		if !legal {
+			out = append(out, san)
+		}
+	}
+	return out
+}
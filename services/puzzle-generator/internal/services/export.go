@@ -0,0 +1,64 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/chess-puzzle-next/puzzle-generator/internal/models"
+	"github.com/notnil/chess"
+)
+
+// ExportPGN returns the puzzle with the given ID as PGN: its position as the
+// SetUp and FEN tags, followed by the setup move and the solution. Exporting
+// a puzzle does not mark it as seen.
+func (s *PuzzleService) ExportPGN(ctx context.Context, id string) (string, error) {
+	p, err := s.LookupByID(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	return puzzlePGN(p)
+}
+
+func puzzlePGN(p *models.Puzzle) (string, error) {
+	if err := checkPlayable(p); err != nil {
+		return "", err
+	}
+	fen, _ := chess.FEN(p.FEN)
+	game := chess.NewGame(fen, chess.UseNotation(chess.UCINotation{}))
+	for _, m := range p.Moves {
+		_ = game.MoveStr(m) // checkPlayable replayed the same moves
+	}
+
+	var b strings.Builder
+	tag := func(k, v string) { fmt.Fprintf(&b, "[%s %q]\n", k, v) }
+	tag("Event", "Puzzle "+p.ID)
+	tag("Site", cmp.Or(p.GameURL, "?"))
+	tag("Result", "*")
+	tag("SetUp", "1")
+	tag("FEN", p.FEN)
+	if p.Rating > 0 {
+		tag("PuzzleRating", strconv.Itoa(p.Rating))
+	}
+	if len(p.Themes) > 0 {
+		tag("PuzzleThemes", strings.Join(p.Themes, " "))
+	}
+	b.WriteByte('\n')
+
+	positions := game.Positions()
+	for i, m := range game.Moves() {
+		before := positions[i]
+		num := before.String()[strings.LastIndexByte(before.String(), ' ')+1:]
+		switch {
+		case before.Turn() == chess.White:
+			b.WriteString(num + ". ")
+		case i == 0:
+			b.WriteString(num + "... ")
+		}
+		b.WriteString(chess.AlgebraicNotation{}.Encode(before, m) + " ")
+	}
+	b.WriteString("*\n")
+	return b.String(), nil
+}
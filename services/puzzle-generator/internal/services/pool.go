@@ -0,0 +1,226 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/chess-puzzle-next/puzzle-generator/internal/metrics"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/models"
+	"github.com/notnil/chess"
+)
+
+// PoolConfig sizes the pre-fetched puzzle pools.
+type PoolConfig struct {
+	Size     int           // ready puzzles kept per source and difficulty
+	Interval time.Duration // minimum time between two fetches of one worker
+	Sources  []string      // sources with pools: lichess and/or huggingface
+}
+
+// maxPoolBackoff caps the wait of a worker whose source keeps failing.
+const maxPoolBackoff = time.Minute
+
+var errDuplicate = errors.New("puzzle: already pooled")
+
+// puzzlePool holds ready puzzles of one source and difficulty. A worker keeps
+// it full; requests take from it without waiting.
+type puzzlePool struct {
+	source     string
+	difficulty models.DifficultyLevel
+	ready      chan *models.Puzzle
+
+	mu  sync.Mutex
+	ids map[string]bool // puzzles in ready, to keep duplicates out
+}
+
+// WithPools keeps cfg.Size enriched, validated puzzles ready per source and
+// difficulty. Pools only fill while RunPools runs.
+func WithPools(cfg PoolConfig) Option {
+	return func(s *PuzzleService) {
+		s.pools = make(map[string]*puzzlePool)
+		for _, source := range cfg.Sources {
+			if source != SourceLichess && source != SourceHuggingFace {
+				logger.Warn("ignoring pool of unsupported source", "source", source)
+				continue
+			}
+			for _, d := range []models.DifficultyLevel{models.DifficultyEasy, models.DifficultyMedium, models.DifficultyHard} {
+				pl := &puzzlePool{
+					source:     source,
+					difficulty: d,
+					ready:      make(chan *models.Puzzle, max(cfg.Size, 1)),
+					ids:        make(map[string]bool),
+				}
+				s.pools[poolKey(source, d)] = pl
+				metrics.RegisterPool(source, string(d), func() float64 { return float64(len(pl.ready)) })
+			}
+		}
+		s.poolInterval = cfg.Interval
+	}
+}
+
+func (s *PuzzleService) anyPool() *puzzlePool {
+	for _, pl := range s.pools {
+		return pl
+	}
+	return nil
+}
+
+func poolKey(source string, d models.DifficultyLevel) string {
+	return source + ":" + string(d)
+}
+
+// RunPools refills every pool until ctx is cancelled. It returns at once
+// without pools.
+func (s *PuzzleService) RunPools(ctx context.Context) {
+	if len(s.pools) == 0 {
+		return
+	}
+	logger.InfoContext(ctx, "puzzle pools started", "pools", len(s.pools), "size", cap(s.anyPool().ready))
+	var wg sync.WaitGroup
+	for _, pl := range s.pools {
+		if !s.available(pl.source) {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.fillPool(ctx, pl)
+		}()
+	}
+	wg.Wait()
+}
+
+// fillPool fetches puzzles for pl, blocking while it is full. A failing or
+// disabled source is retried with a growing delay.
+func (s *PuzzleService) fillPool(ctx context.Context, pl *puzzlePool) {
+	backoff := s.poolInterval
+	for {
+		wait := s.poolInterval
+		if s.enabled(pl.source) {
+			p, err := s.fetchForPool(ctx, pl)
+			switch {
+			case err == nil:
+				backoff = s.poolInterval
+				pl.mu.Lock()
+				pl.ids[p.ID] = true
+				pl.mu.Unlock()
+				select {
+				case pl.ready <- p:
+				case <-ctx.Done():
+					return
+				}
+			case errors.Is(err, errDuplicate):
+			case ctx.Err() != nil:
+				return
+			default:
+				if backoff == s.poolInterval {
+					logger.WarnContext(ctx, "pool refill failing", "source", pl.source, "difficulty", pl.difficulty, "err", err)
+				}
+				backoff = min(max(backoff*2, time.Second), maxPoolBackoff)
+				wait = backoff
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// fetchForPool fetches one new puzzle for pl and checks it is playable.
+func (s *PuzzleService) fetchForPool(ctx context.Context, pl *puzzlePool) (*models.Puzzle, error) {
+	var p *models.Puzzle
+	switch pl.source {
+	case SourceLichess:
+		raw, err := s.lichess.GetNextPuzzle(ctx, models.LichessDifficultyParam[pl.difficulty])
+		if err != nil {
+			return nil, fmt.Errorf("puzzle: fetch from Lichess: %w", err)
+		}
+		if pl.has(raw.Puzzle.ID) {
+			return nil, errDuplicate
+		}
+		p = s.enrich(ctx, raw)
+	case SourceHuggingFace:
+		var err error
+		if p, err = s.dataset.GetRandomPuzzle(ctx, pl.difficulty); err != nil {
+			return nil, fmt.Errorf("puzzle: fetch from dataset: %w", err)
+		}
+		if pl.has(p.ID) {
+			return nil, errDuplicate
+		}
+	}
+	if err := checkPlayable(p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// has reports whether the pool holds id, or id is empty.
+func (pl *puzzlePool) has(id string) bool {
+	if id == "" {
+		return true
+	}
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	return pl.ids[id]
+}
+
+// takePooled returns a ready puzzle of source and difficulty that the viewer
+// of ctx was not served, or nil when the pool has none or is absent. Puzzles
+// the viewer has seen go back to the pool for other viewers.
+func (s *PuzzleService) takePooled(ctx context.Context, source string, d models.DifficultyLevel) *models.Puzzle {
+	pl := s.pools[poolKey(source, d)]
+	if pl == nil {
+		return nil
+	}
+	for range len(pl.ready) {
+		var p *models.Puzzle
+		select {
+		case p = <-pl.ready:
+		default:
+		}
+		if p == nil {
+			break
+		}
+		if !s.seen.Seen(ctx, p.ID) {
+			pl.forget(p.ID)
+			metrics.PoolTake(source, string(d), true)
+			return p
+		}
+		select {
+		case pl.ready <- p:
+		default:
+			// Refilled meanwhile.
+			pl.forget(p.ID)
+		}
+	}
+	metrics.PoolTake(source, string(d), false)
+	return nil
+}
+
+func (pl *puzzlePool) forget(id string) {
+	pl.mu.Lock()
+	delete(pl.ids, id)
+	pl.mu.Unlock()
+}
+
+// checkPlayable verifies that the puzzle's moves are legal from its FEN.
+func checkPlayable(p *models.Puzzle) error {
+	if p.FEN == "" || len(p.Moves) < 2 {
+		return fmt.Errorf("puzzle: %s has no position or solution", p.ID)
+	}
+	fen, err := chess.FEN(p.FEN)
+	if err != nil {
+		return fmt.Errorf("puzzle: %s has an invalid FEN: %w", p.ID, err)
+	}
+	game := chess.NewGame(fen, chess.UseNotation(chess.UCINotation{}))
+	for i, m := range p.Moves {
+		if err := game.MoveStr(m); err != nil {
+			return fmt.Errorf("puzzle: %s move %d (%s) is illegal: %w", p.ID, i, m, err)
+		}
+	}
+	return nil
+}
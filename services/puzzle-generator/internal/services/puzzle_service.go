@@ -3,14 +3,28 @@ package services
 import (
 	"context"
 	"fmt"
-	"log"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/chess-puzzle-next/puzzle-generator/internal/logging"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/metrics"
 	"github.com/chess-puzzle-next/puzzle-generator/internal/models"
-	"github.com/chess-puzzle-next/puzzle-generator/pkg/nvidia"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/prompts"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/puzzlecache"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/seen"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/tracing"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/webhooks"
+	"github.com/chess-puzzle-next/puzzle-generator/pkg/llm"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/singleflight"
 )
 
+var logger = logging.For("services")
+
 // LichessAPI defines the subset of lichess.Client used by PuzzleService.
 type LichessAPI interface {
 	HasToken() bool
@@ -19,16 +33,32 @@ type LichessAPI interface {
 	GetNextPuzzle(ctx context.Context, difficulty string) (*models.LichessPuzzleResponse, error)
 }
 
-// AIAPI abstracts the LLM inference provider (NVIDIA).
+// AIAPI abstracts the LLM providers (usually an llm.Failover).
 type AIAPI interface {
-	IsConfigured() bool
-	CreateCompletion(ctx context.Context, messages []nvidia.Message) (string, error)
+	Configured() bool
+	Chat(ctx context.Context, req llm.Request) (*llm.Response, error)
 }
 
+// LLM tasks. Each can use its own model.
+const (
+	TaskFilter  = "filter"  // turn a prompt into a puzzle filter
+	TaskExplain = "explain" // explain a puzzle's solution move by move
+)
+
 // DatasetAPI abstracts access to the HuggingFace puzzle dataset.
 type DatasetAPI interface {
 	GetRandomPuzzle(ctx context.Context, difficulty models.DifficultyLevel) (*models.Puzzle, error)
-	GetCandidatePuzzles(ctx context.Context, difficulty models.DifficultyLevel, count int) ([]*models.Puzzle, error)
+	FindPuzzles(ctx context.Context, match func(*models.Puzzle) bool, count, maxBatches int) ([]*models.Puzzle, error)
+}
+
+// Retriever finds the puzzles closest to a prompt in a vector index.
+type Retriever interface {
+	Retrieve(ctx context.Context, query string, k int, keep func(*models.Puzzle) bool) ([]*models.Puzzle, error)
+}
+
+// EventPublisher queues outbound webhook events.
+type EventPublisher interface {
+	Publish(ev webhooks.Event)
 }
 
 // PuzzleService orchestrates puzzle retrieval and enrichment.
@@ -36,149 +66,435 @@ type PuzzleService struct {
 	lichess LichessAPI
 	ai      AIAPI
 	dataset DatasetAPI
+	events  EventPublisher
+
+	retriever Retriever
+	ragTopK   int
+	prompts   *prompts.Store
+
+	store      PuzzleStore
+	storeLimit int64
+	chains     map[string][]string // endpoint → sources in priority order
+	cache      *puzzlecache.Cache
+	lookups    singleflight.Group // concurrent cache misses of the same ID or explanation
+
+	pools        map[string]*puzzlePool // by poolKey
+	poolInterval time.Duration
+
+	seen *seen.Tracker
+
+	explanations   ExplanationStore
+	explanationTTL time.Duration
 
 	mu        sync.Mutex
-	recentIDs map[models.DifficultyLevel][]string
+	lastDaily string          // ID of the last daily puzzle announced to webhooks
+	disabled  map[string]bool // sources switched off by an admin
+}
+
+// Option configures optional PuzzleService dependencies.
+type Option func(*PuzzleService)
+
+// WithEvents publishes puzzle events (such as a new daily puzzle) to pub.
+func WithEvents(pub EventPublisher) Option {
+	return func(s *PuzzleService) { s.events = pub }
+}
+
+// WithCache caches every puzzle served by an upstream source and answers
+// lookups by ID from it.
+func WithCache(c *puzzlecache.Cache) Option {
+	return func(s *PuzzleService) { s.cache = c }
+}
+
+// WithSeen skips puzzles the viewer was already served, in every source of
+// the random and dataset chains, and records every puzzle served.
+func WithSeen(t *seen.Tracker) Option {
+	return func(s *PuzzleService) { s.seen = t }
+}
+
+// WithRetriever runs AI filters against the puzzles of r, ranked by
+// similarity to the prompt, instead of random dataset batches. The dataset
+// is still scanned when r finds nothing.
+func WithRetriever(r Retriever) Option {
+	return func(s *PuzzleService) { s.retriever = r }
+}
+
+// WithRAGTopK sets how many puzzles matching an AI filter are collected.
+func WithRAGTopK(k int) Option {
+	return func(s *PuzzleService) { s.ragTopK = k }
+}
+
+// WithPrompts renders the AI prompts from store instead of the built-in
+// templates.
+func WithPrompts(store *prompts.Store) Option {
+	return func(s *PuzzleService) { s.prompts = store }
 }
 
 // New returns a PuzzleService backed by the given clients.
-func New(lc LichessAPI, ai AIAPI, dataset DatasetAPI) *PuzzleService {
-	return &PuzzleService{
-		lichess: lc,
-		ai:      ai,
-		dataset: dataset,
-		recentIDs: map[models.DifficultyLevel][]string{
-			models.DifficultyEasy:   {},
-			models.DifficultyMedium: {},
-			models.DifficultyHard:   {},
-		},
+func New(lc LichessAPI, ai AIAPI, dataset DatasetAPI, opts ...Option) *PuzzleService {
+	s := &PuzzleService{
+		lichess:  lc,
+		ai:       ai,
+		dataset:  dataset,
+		chains:   make(map[string][]string, len(supportedSources)),
+		disabled: make(map[string]bool),
+	}
+	for endpoint, chain := range supportedSources {
+		s.chains[endpoint] = slices.Clone(chain)
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+	if s.prompts == nil {
+		s.prompts = prompts.Builtin()
+	}
+	return s
 }
 
-// GetByDifficulty returns a puzzle filtered by difficulty.
+// GetByDifficulty returns a puzzle filtered by difficulty from the first
+// source of the random chain that has one. Sources with a pool answer from it
+// while it has ready puzzles.
 func (s *PuzzleService) GetByDifficulty(ctx context.Context, difficulty models.DifficultyLevel) (*models.Puzzle, error) {
 	if err := validateDifficulty(difficulty); err != nil {
 		return nil, err
 	}
+	return s.serve(ctx, EndpointRandom, map[string]sourceFunc{
+		SourceLichess: func(ctx context.Context) (*models.Puzzle, error) {
+			if p := s.takePooled(ctx, SourceLichess, difficulty); p != nil {
+				return p, nil
+			}
+			return s.lichessByDifficulty(ctx, difficulty)
+		},
+		SourceStore: fromStore(func(ctx context.Context) (*models.Puzzle, error) {
+			return s.storedPuzzle(ctx, difficulty)
+		}),
+		SourceHuggingFace: func(ctx context.Context) (*models.Puzzle, error) {
+			return s.datasetPuzzle(ctx, difficulty)
+		},
+	})
+}
 
+// lichessByDifficulty fetches Lichess puzzles until one was not served to
+// the viewer.
+func (s *PuzzleService) lichessByDifficulty(ctx context.Context, difficulty models.DifficultyLevel) (*models.Puzzle, error) {
 	lichessDiff := models.LichessDifficultyParam[difficulty]
-	const maxAttempts = 4
-
-	var last *models.LichessPuzzleResponse
-	for i := 0; i < maxAttempts; i++ {
+	raw, err := firstUnseen(ctx, s, SourceLichess, difficulty, func(ctx context.Context) (*models.LichessPuzzleResponse, string, error) {
 		raw, err := s.lichess.GetNextPuzzle(ctx, lichessDiff)
 		if err != nil {
-			return nil, fmt.Errorf("puzzle: fetch from Lichess: %w", err)
-		}
-
-		last = raw
-		id := raw.Puzzle.ID
-		if id == "" {
-			break
+			return nil, "", fmt.Errorf("puzzle: fetch from Lichess: %w", err)
 		}
-
-		if !s.seenRecently(difficulty, id) {
-			s.remember(difficulty, id)
-			return s.enrich(raw), nil
-		}
-	}
-
-	if last != nil && last.Puzzle.ID != "" {
-		s.remember(difficulty, last.Puzzle.ID)
+		return raw, raw.Puzzle.ID, nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	if last == nil {
+	if raw == nil {
 		return nil, fmt.Errorf("puzzle: empty response from Lichess")
 	}
-
-	return s.enrich(last), nil
+	return s.enrich(ctx, raw), nil
 }
 
-// GetByID returns a specific puzzle by its Lichess puzzle ID.
+// GetByID returns a specific puzzle by its Lichess puzzle ID and marks it as
+// seen by the viewer.
 func (s *PuzzleService) GetByID(ctx context.Context, id string) (*models.Puzzle, error) {
+	p, err := s.LookupByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	s.seen.Mark(ctx, p.ID)
+	return p, nil
+}
+
+// LookupByID returns a specific puzzle by its Lichess puzzle ID without
+// marking it as seen, for callers that read a puzzle rather than serve it.
+// Puzzles never change, so cached ones are served without calling any
+// source; concurrent misses for the same ID share one trip through the id
+// chain.
+func (s *PuzzleService) LookupByID(ctx context.Context, id string) (*models.Puzzle, error) {
 	if !validPuzzleID(id) {
 		return nil, fmt.Errorf("puzzle: invalid ID format %q", id)
 	}
 
-	raw, err := s.lichess.GetPuzzleByID(ctx, id)
+	// Dataset puzzles carry Lichess IDs, so either source's entry will do.
+	if p, tier := s.cache.Get(ctx, id, SourceLichess, SourceHuggingFace); p != nil {
+		metrics.PuzzleCacheLookup(tier)
+		p.CacheStatus = "HIT"
+		return p, nil
+	}
+	metrics.PuzzleCacheLookup("miss")
+
+	// The shared fetch must not fail for the others when its first caller
+	// goes away; the client timeouts still bound it.
+	v, err, _ := s.lookups.Do(id, func() (any, error) {
+		return s.serve(context.WithoutCancel(ctx), EndpointByID, map[string]sourceFunc{
+			SourceLichess: func(ctx context.Context) (*models.Puzzle, error) {
+				raw, err := s.lichess.GetPuzzleByID(ctx, id)
+				if err != nil {
+					return nil, fmt.Errorf("puzzle: fetch by id %q: %w", id, err)
+				}
+				return s.enrich(ctx, raw), nil
+			},
+			SourceStore: fromStore(func(ctx context.Context) (*models.Puzzle, error) {
+				return s.store.GetStoredPuzzle(ctx, id)
+			}),
+		})
+	})
 	if err != nil {
-		return nil, fmt.Errorf("puzzle: fetch by id %q: %w", id, err)
+		return nil, err
 	}
-
-	return s.enrich(raw), nil
+	p := puzzlecache.Clone(v.(*models.Puzzle))
+	p.CacheStatus = "MISS"
+	return p, nil
 }
 
-// GetDaily returns the Lichess puzzle of the day.
+// GetDaily returns the Lichess puzzle of the day. While Lichess is down the
+// store serves the last daily puzzle it saw.
 func (s *PuzzleService) GetDaily(ctx context.Context) (*models.Puzzle, error) {
-	raw, err := s.lichess.GetDailyPuzzle(ctx)
+	p, err := s.serve(ctx, EndpointDaily, map[string]sourceFunc{
+		SourceLichess: func(ctx context.Context) (*models.Puzzle, error) {
+			raw, err := s.lichess.GetDailyPuzzle(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("puzzle: fetch daily: %w", err)
+			}
+			return s.enrich(ctx, raw), nil
+		},
+		SourceStore: fromStore(func(ctx context.Context) (*models.Puzzle, error) {
+			return s.store.GetStoredDailyPuzzle(ctx)
+		}),
+	})
 	if err != nil {
-		return nil, fmt.Errorf("puzzle: fetch daily: %w", err)
+		return nil, err
 	}
-	return s.enrich(raw), nil
+	if p.ServedBy == SourceLichess {
+		s.announceDaily(p)
+	}
+	return p, nil
 }
 
-// GenerateFromAI uses a RAG (Retrieval-Augmented Generation) pipeline:
-//  1. Fetch candidate puzzles from the HuggingFace dataset.
-//  2. Send the candidates + user prompt to the NVIDIA model.
-//  3. The model selects the best-matching puzzle.
-func (s *PuzzleService) GenerateFromAI(ctx context.Context, req models.AIPuzzleRequest) (*models.Puzzle, error) {
-	if s.ai == nil || !s.ai.IsConfigured() {
-		return nil, fmt.Errorf("puzzle: AI provider (NVIDIA) is not configured")
+// announceDaily publishes puzzle.daily_published the first time a daily
+// puzzle is seen. Replicas each announce it once; the dispatcher dedupes
+// across them by puzzle ID.
+func (s *PuzzleService) announceDaily(p *models.Puzzle) {
+	if s.events == nil || p.ID == "" {
+		return
 	}
-	if s.dataset == nil {
-		return nil, fmt.Errorf("puzzle: dataset provider is not configured (needed for RAG)")
+	s.mu.Lock()
+	seen := s.lastDaily == p.ID
+	s.lastDaily = p.ID
+	s.mu.Unlock()
+	if seen {
+		return
 	}
+	s.events.Publish(webhooks.Event{Type: webhooks.EventDailyPuzzlePublished, Data: p, Once: p.ID})
+}
+
+// GenerateFromAI turns the prompt into a puzzle filter and runs it:
+//  1. The first healthy LLM provider translates the prompt into a
+//     models.PuzzleFilter (rating range, themes, opening, color, solution
+//     length), which is validated. A filter in the request skips the model.
+//  2. The filter is run against the vector index, whose matches are ranked
+//     by similarity to the prompt, or against random dataset batches.
+//  3. The first match whose moves are legal is returned with the filter, so
+//     the user can refine it.
+func (s *PuzzleService) GenerateFromAI(ctx context.Context, req models.AIPuzzleRequest) (*models.Puzzle, error) {
+	return s.GenerateFromAIStream(ctx, req, nil)
+}
+
+// GenerateFromAIStream runs GenerateFromAI and reports each stage to
+// progress, streaming the model's output as token events when the provider
+// supports it. A nil progress reports nothing. Cancelling ctx stops the
+// pipeline.
+func (s *PuzzleService) GenerateFromAIStream(ctx context.Context, req models.AIPuzzleRequest, progress func(models.AIProgressEvent)) (*models.Puzzle, error) {
 	if err := validateAIPuzzleRequest(req); err != nil {
 		return nil, err
 	}
+	if s.dataset == nil && s.retriever == nil {
+		return nil, fmt.Errorf("puzzle: dataset provider is not configured (needed for RAG)")
+	}
 
-	// --- Step 1: Retrieve candidate puzzles from the dataset ---
-	const candidateCount = 8
 	t0 := time.Now()
-	candidates, err := s.dataset.GetCandidatePuzzles(ctx, req.Difficulty, candidateCount)
+	report := func(ev models.AIProgressEvent) {
+		if progress != nil {
+			ev.ElapsedMs = time.Since(t0).Milliseconds()
+			progress(ev)
+		}
+	}
+
+	// --- Step 1: Translate the prompt into a filter ---
+	filter, source, promptVersion, err := s.promptFilter(ctx, req, progress != nil, report)
+	if err != nil {
+		return nil, err
+	}
+
+	// --- Step 2: Run the filter ---
+	query := strings.TrimSpace(req.Prompt)
+	if query == "" {
+		query = filterQuery(filter)
+	}
+	retrieveCtx, span := tracing.Tracer().Start(ctx, "rag.retrieve_candidates")
+	candidates, retriever, err := s.filterCandidates(retrieveCtx, query, filter)
+	span.SetAttributes(attribute.Int("rag.candidates", len(candidates)), attribute.String("rag.retriever", retriever))
+	endSpan(span, err)
 	if err != nil {
-		return nil, fmt.Errorf("puzzle: fetch RAG candidates: %w", err)
+		return nil, fmt.Errorf("puzzle: run filter: %w", err)
 	}
+	metrics.RAGRetrieval(retriever)
+	logger.DebugContext(ctx, "rag candidates fetched", "count", len(candidates), "retriever", retriever, "elapsed", time.Since(t0))
+	report(models.AIProgressEvent{Stage: models.AIStageCandidates, Candidates: len(candidates), Retriever: retriever})
 	if len(candidates) == 0 {
-		return nil, fmt.Errorf("puzzle: no candidate puzzles found for RAG")
+		return nil, &NoMatchError{Filter: filter, Source: source}
 	}
-	log.Printf("[RAG] fetched %d candidates in %s", len(candidates), time.Since(t0))
 
-	// --- Step 2 & 3: Ask the AI to select the best match ---
-	messages := buildRAGSelectionPrompt(req, candidates)
+	// --- Step 3: Return the first playable match ---
+	for i, p := range candidates {
+		if checkPlayable(p) != nil {
+			continue
+		}
+		report(models.AIProgressEvent{Stage: models.AIStageSelected, Index: &i, PuzzleID: p.ID})
+		report(models.AIProgressEvent{Stage: models.AIStageValidated, PuzzleID: p.ID})
+		p.Source = source
+		p.Filter = filter
+		p.PromptVersion = promptVersion
+		s.seen.Mark(ctx, p.ID)
+		metrics.AISelection(source)
+		logger.InfoContext(ctx, "rag puzzle selected", "total", time.Since(t0), "source", source, "prompt_version", promptVersion)
+		return p, nil
+	}
+	return nil, fmt.Errorf("puzzle: none of the %d matching puzzles is playable", len(candidates))
+}
 
+// promptFilter returns the filter of req, the source of the puzzles it
+// finds and the version of the prompt template the model was given. A filter
+// in the request is validated and used as is; otherwise the model derives
+// one from the prompt, with one corrected retry, and the prompt's keywords
+// are used when it gives no valid filter.
+func (s *PuzzleService) promptFilter(ctx context.Context, req models.AIPuzzleRequest, stream bool, report func(models.AIProgressEvent)) (*models.PuzzleFilter, string, string, error) {
+	if req.Filter != nil {
+		filter := *req.Filter
+		if err := normalizeFilter(&filter, req.Difficulty); err != nil {
+			return nil, "", "", fmt.Errorf("puzzle: invalid filter: %w", err)
+		}
+		report(models.AIProgressEvent{Stage: models.AIStageFilter, Filter: &filter})
+		return &filter, "ai-rag", "", nil
+	}
+	if s.ai == nil || !s.ai.Configured() {
+		return nil, "", "", fmt.Errorf("puzzle: AI provider: %w", llm.ErrNotConfigured)
+	}
+
+	render := s.filterPrompt(req, "", nil)
 	const maxAttempts = 2
 	var lastErr error
+	var promptVersion string
 	for attempt := 0; attempt < maxAttempts; attempt++ {
 		t1 := time.Now()
-		content, err := s.ai.CreateCompletion(ctx, messages)
-		log.Printf("[RAG] NVIDIA attempt=%d elapsed=%s err=%v content=%q", attempt, time.Since(t1), err, truncateStr(content, 200))
-
+		report(models.AIProgressEvent{Stage: models.AIStageThinking, Attempt: attempt + 1})
+		filterCtx, span := tracing.Tracer().Start(ctx, "rag.filter", trace.WithAttributes(attribute.Int("rag.attempt", attempt)))
+		resp, err := s.complete(filterCtx, llm.Request{Task: TaskFilter, Render: render, MaxTokens: 256}, stream, func(token string) {
+			report(models.AIProgressEvent{Stage: models.AIStageToken, Attempt: attempt + 1, Token: token})
+		})
 		if err != nil {
-			lastErr = fmt.Errorf("nvidia completion: %w", err)
-			break // API error — no point retrying the same request
+			logger.DebugContext(ctx, "rag completion", "attempt", attempt, "elapsed", time.Since(t1), "err", err)
+			endSpan(span, err)
+			lastErr = fmt.Errorf("llm completion: %w", err)
+			break // every provider failed — no point retrying the same request
 		}
-
-		puzzle, err := parseRAGSelectionResponse(content, candidates)
+		content := resp.Content
+		promptVersion = resp.PromptVersion
+		span.SetAttributes(attribute.String("llm.provider", resp.Provider), attribute.String("llm.model", resp.Model), attribute.String("llm.prompt_version", promptVersion))
+		logger.DebugContext(ctx, "rag completion", "attempt", attempt, "elapsed", time.Since(t1), "provider", resp.Provider, "model", resp.Model, "prompt_version", promptVersion, "content", truncateStr(content, 200))
+
+		filter, err := parseFilterResponse(content, req.Difficulty)
+		span.SetAttributes(attribute.Bool("rag.parsed", err == nil))
+		span.End()
 		if err == nil {
-			puzzle.Source = "ai-rag"
-			log.Printf("[RAG] selected puzzle index from AI, total=%s", time.Since(t0))
-			return puzzle, nil
+			report(models.AIProgressEvent{Stage: models.AIStageFilter, Provider: resp.Provider, Model: resp.Model, Filter: filter, PromptVersion: promptVersion})
+			return filter, "ai-rag", promptVersion, nil
 		}
 
 		// Build correction prompt and retry.
 		lastErr = err
-		messages = buildRAGRetryPrompt(req, candidates, content, err)
+		render = s.filterPrompt(req, content, err)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, "", "", fmt.Errorf("puzzle: RAG pipeline: %w", err)
 	}
 
-	// Fallback: if AI selection failed, return the first candidate.
-	if len(candidates) > 0 {
-		log.Printf("[RAG] falling back to first candidate, lastErr=%v, total=%s", lastErr, time.Since(t0))
-		p := candidates[0]
-		p.Source = "ai-rag-fallback"
-		return p, nil
+	filter := keywordFilter(req.Prompt, req.Difficulty)
+	logger.WarnContext(ctx, "rag falling back to a keyword filter", "err", lastErr, "prompt_version", promptVersion)
+	report(models.AIProgressEvent{Stage: models.AIStageFilter, Filter: filter, Fallback: true, PromptVersion: promptVersion})
+	return filter, "ai-rag-fallback", promptVersion, nil
+}
+
+// RAG retrievers, reported in metrics and progress events.
+const (
+	retrieverIndex   = "index"
+	retrieverDataset = "dataset"
+)
+
+// defaultRAGCandidates is the number of matching puzzles collected unless
+// WithRAGTopK sets it.
+const defaultRAGCandidates = 8
+
+// filterScanBatches bounds the dataset batches scanned for puzzles matching
+// a filter.
+const filterScanBatches = 10
+
+// filterCandidates returns puzzles matching filter and the retriever that
+// found them. The vector index ranks its matches by similarity to query and
+// is searched for a few times more puzzles than needed so that those the
+// viewer has seen can be dropped; when it fails or has no match, random
+// dataset batches are scanned.
+func (s *PuzzleService) filterCandidates(ctx context.Context, query string, filter *models.PuzzleFilter) ([]*models.Puzzle, string, error) {
+	count := s.ragTopK
+	if count == 0 {
+		count = defaultRAGCandidates
+	}
+	match := func(p *models.Puzzle) bool { return filter.Matches(p) }
+
+	var found []*models.Puzzle
+	var err error
+	retriever := retrieverIndex
+	if s.retriever != nil {
+		found, err = s.retriever.Retrieve(ctx, query, count*4, match)
+		if len(found) == 0 && s.dataset != nil {
+			logger.WarnContext(ctx, "vector retrieval found nothing, scanning the dataset", "err", err)
+		}
+	}
+	if len(found) == 0 && s.dataset != nil {
+		retriever = retrieverDataset
+		found, err = s.dataset.FindPuzzles(ctx, match, count*2, filterScanBatches)
+	}
+	if err != nil {
+		return nil, retriever, err
 	}
 
-	return nil, fmt.Errorf("puzzle: RAG pipeline failed: %w", lastErr)
+	unseen := make([]*models.Puzzle, 0, count)
+	for _, p := range found {
+		if s.seen == nil || !s.seen.Seen(ctx, p.ID) {
+			unseen = append(unseen, p)
+		}
+	}
+	if len(unseen) == 0 {
+		unseen = found // the viewer has seen them all; a repeat beats nothing
+	}
+	return unseen[:min(count, len(unseen))], retriever, nil
+}
+
+// complete asks the AI provider for a completion, streaming its tokens to
+// onToken when stream is set and the provider can stream.
+func (s *PuzzleService) complete(ctx context.Context, req llm.Request, stream bool, onToken func(string)) (*llm.Response, error) {
+	if streamer, ok := s.ai.(llm.Streamer); ok && stream {
+		return streamer.ChatStream(ctx, req, onToken)
+	}
+	return s.ai.Chat(ctx, req)
+}
+
+// endSpan marks span as failed when err is set and ends it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
 }
 
 func truncateStr(s string, max int) string {
@@ -188,27 +504,65 @@ func truncateStr(s string, max int) string {
 	return s[:max] + "..."
 }
 
+// GenerateFromDataset returns a random puzzle from the first source of the
+// dataset chain that has one.
 func (s *PuzzleService) GenerateFromDataset(ctx context.Context, difficulty models.DifficultyLevel) (*models.Puzzle, error) {
 	if err := validateDifficulty(difficulty); err != nil {
 		return nil, err
 	}
-	if s.dataset == nil {
+	if s.dataset == nil && s.store == nil {
 		return nil, fmt.Errorf("puzzle: dataset provider is not configured")
 	}
+	return s.serve(ctx, EndpointDataset, map[string]sourceFunc{
+		SourceHuggingFace: func(ctx context.Context) (*models.Puzzle, error) {
+			return s.datasetPuzzle(ctx, difficulty)
+		},
+		SourceStore: fromStore(func(ctx context.Context) (*models.Puzzle, error) {
+			return s.storedPuzzle(ctx, difficulty)
+		}),
+	})
+}
 
-	puzzle, err := s.dataset.GetRandomPuzzle(ctx, difficulty)
-	if err != nil {
-		return nil, fmt.Errorf("puzzle: fetch from dataset: %w", err)
+// datasetPuzzle returns a dataset puzzle not served to the viewer, from the
+// pool when it has one.
+func (s *PuzzleService) datasetPuzzle(ctx context.Context, difficulty models.DifficultyLevel) (*models.Puzzle, error) {
+	if difficulty != "" {
+		if p := s.takePooled(ctx, SourceHuggingFace, difficulty); p != nil {
+			return p, nil
+		}
 	}
+	return firstUnseen(ctx, s, SourceHuggingFace, difficulty, func(ctx context.Context) (*models.Puzzle, string, error) {
+		p, err := s.dataset.GetRandomPuzzle(ctx, difficulty)
+		if err != nil {
+			return nil, "", fmt.Errorf("puzzle: fetch from dataset: %w", err)
+		}
+		return p, p.ID, nil
+	})
+}
 
-	return puzzle, nil
+// storedPuzzle returns a random stored puzzle not served to the viewer.
+func (s *PuzzleService) storedPuzzle(ctx context.Context, difficulty models.DifficultyLevel) (*models.Puzzle, error) {
+	return firstUnseen(ctx, s, SourceStore, difficulty, func(ctx context.Context) (*models.Puzzle, string, error) {
+		p, err := s.store.RandomStoredPuzzle(ctx, difficulty)
+		if err != nil || p == nil {
+			return nil, "", err
+		}
+		return p, p.ID, nil
+	})
 }
 
-func (s *PuzzleService) enrich(raw *models.LichessPuzzleResponse) *models.Puzzle {
+func (s *PuzzleService) enrich(ctx context.Context, raw *models.LichessPuzzleResponse) *models.Puzzle {
 	p := raw.ToCanonical()
 
 	if raw.Game.Pgn != "" {
+		_, span := tracing.Tracer().Start(ctx, "puzzle.extract_position", trace.WithAttributes(
+			attribute.String("puzzle.id", raw.Puzzle.ID),
+			attribute.Int("puzzle.initial_ply", raw.Puzzle.InitialPly),
+			attribute.Int("puzzle.pgn_bytes", len(raw.Game.Pgn)),
+		))
 		fen, setupMove := extractPuzzlePosition(raw.Game.Pgn, raw.Puzzle.InitialPly)
+		span.SetAttributes(attribute.Bool("puzzle.setup_move_found", setupMove != ""))
+		span.End()
 		if fen != "" {
 			p.FEN = fen
 		}
@@ -223,28 +577,35 @@ func (s *PuzzleService) enrich(raw *models.LichessPuzzleResponse) *models.Puzzle
 	return p
 }
 
-const recentWindowSize = 30
-
-func (s *PuzzleService) seenRecently(difficulty models.DifficultyLevel, id string) bool {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	ids := s.recentIDs[difficulty]
-	for _, seen := range ids {
-		if seen == id {
-			return true
-		}
+// maxDedupAttempts bounds the fetches spent looking for a puzzle the viewer
+// was not served yet.
+const maxDedupAttempts = 4
+
+// firstUnseen calls fetch until it returns a puzzle, identified by its ID,
+// that the viewer of ctx was not served. Once attempts run out it returns the
+// last one anyway; a failed refetch also settles for the previous puzzle.
+func firstUnseen[T any](ctx context.Context, s *PuzzleService, source string, difficulty models.DifficultyLevel, fetch func(context.Context) (T, string, error)) (T, error) {
+	label := string(difficulty)
+	if label == "" {
+		label = "any"
 	}
-	return false
-}
-
-func (s *PuzzleService) remember(difficulty models.DifficultyLevel, id string) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	ids := append(s.recentIDs[difficulty], id)
-	if len(ids) > recentWindowSize {
-		ids = ids[len(ids)-recentWindowSize:]
+	var last T
+	for i := 0; i < maxDedupAttempts; i++ {
+		p, id, err := fetch(ctx)
+		if err != nil {
+			if i > 0 {
+				return last, nil
+			}
+			return last, err
+		}
+		last = p
+		if id == "" || !s.seen.Seen(ctx, id) {
+			return p, nil
+		}
+		if i < maxDedupAttempts-1 {
+			metrics.DedupRetry(source, label)
+		}
 	}
-	s.recentIDs[difficulty] = ids
+	metrics.DedupExhausted(source, label)
+	return last, nil
 }
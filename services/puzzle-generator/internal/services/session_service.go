@@ -0,0 +1,377 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/chess-puzzle-next/puzzle-generator/internal/models"
+	"github.com/chess-puzzle-next/puzzle-generator/pkg/redis"
+	"github.com/notnil/chess"
+)
+
+// SessionStore persists puzzle sessions and player ratings.
+type SessionStore interface {
+	UpdateSession(ctx context.Context, id string, ttl time.Duration, fn func(*redis.Session) error) (*redis.Session, error)
+	GetPlayerRating(ctx context.Context, userID string) (*redis.PlayerRating, error)
+	AdjustPlayerRating(ctx context.Context, userID, puzzleID string, initial, delta int) (*redis.PlayerRating, error)
+}
+
+// PuzzleLookup finds a puzzle by ID without marking it as seen.
+type PuzzleLookup interface {
+	LookupByID(ctx context.Context, id string) (*models.Puzzle, error)
+}
+
+var (
+	ErrSessionOver       = errors.New("session: puzzle already solved or failed")
+	ErrSessionUnplayable = errors.New("session: moves are not playable from the position")
+	ErrIllegalMove       = errors.New("session: illegal move")
+)
+
+// Hint levels, from the vaguest to the full answer.
+const (
+	hintLevelTheme = iota + 1
+	hintLevelPiece
+	hintLevelSquare
+	hintLevelMove
+)
+
+var hintKinds = map[int]string{
+	hintLevelTheme:  models.HintTheme,
+	hintLevelPiece:  models.HintPiece,
+	hintLevelSquare: models.HintSquare,
+	hintLevelMove:   models.HintMove,
+}
+
+// hintCost is the share of a puzzle's points, in percent, that each hint
+// level costs. Revealing every level of one move costs the whole puzzle.
+var hintCost = map[int]int{
+	hintLevelTheme:  10,
+	hintLevelPiece:  15,
+	hintLevelSquare: 25,
+	hintLevelMove:   50,
+}
+
+// Puzzle rating of players without rated sessions, and how far one session
+// moves it.
+const (
+	initialPlayerRating = 1500
+	ratingK             = 32
+)
+
+// SessionService checks moves, reveals hints and scores finished sessions.
+// Hints are recorded on the session, so the score and the owner's rating
+// account for every hint taken. Only sessions played against a stored
+// puzzle's solution, move by move, change ratings.
+type SessionService struct {
+	store   SessionStore
+	puzzles PuzzleLookup
+	ttl     time.Duration
+}
+
+// NewSessionService returns a SessionService saving sessions with ttl.
+// puzzles may be nil; sessions then have no theme hint and are never rated.
+func NewSessionService(store SessionStore, puzzles PuzzleLookup, ttl time.Duration) *SessionService {
+	return &SessionService{store: store, puzzles: puzzles, ttl: ttl}
+}
+
+// Prepare fills in a new session from the puzzle it names. The position and
+// solution come from the puzzle store rather than the client, and the
+// session is marked verified. Sessions whose puzzle cannot be found keep the
+// client's position and are never rated.
+func (s *SessionService) Prepare(ctx context.Context, sess *redis.Session) {
+	if s.puzzles == nil || sess.PuzzleID == "" {
+		return
+	}
+	p, err := s.puzzles.LookupByID(ctx, sess.PuzzleID)
+	if err == nil {
+		err = checkPlayable(p)
+	}
+	if err != nil {
+		logger.WarnContext(ctx, "look up session puzzle", "puzzle", sess.PuzzleID, "err", err)
+		return
+	}
+	sess.FEN = p.FEN
+	sess.Moves = p.Moves
+	sess.Themes = p.Themes
+	sess.PuzzleRating = p.Rating
+	sess.Verified = true
+}
+
+// Play checks the player's next move, in UCI, against the solution. A correct
+// move is recorded by advancing MoveIndex past it; the opponent's reply is
+// taken as played, and reaching the end of the solution solves the session.
+// Any other legal move fails it. The caller saves the session.
+func (s *SessionService) Play(sess *redis.Session, move string) error {
+	idx, err := nextPlayerMove(sess)
+	if err != nil {
+		return err
+	}
+	pos, want, err := sessionMove(sess, idx)
+	if err != nil {
+		return err
+	}
+	got, err := chess.UCINotation{}.Decode(pos, move)
+	if err != nil || !slices.ContainsFunc(pos.ValidMoves(), func(m *chess.Move) bool { return m.String() == got.String() }) {
+		return fmt.Errorf("%w: %s", ErrIllegalMove, move)
+	}
+	if got.String() != want.String() {
+		sess.Failed = true
+		return nil
+	}
+	sess.MoveIndex = idx + 1
+	if sess.MoveIndex+1 >= len(sess.Moves) {
+		sess.MoveIndex = len(sess.Moves)
+		sess.Solved = true
+	}
+	return nil
+}
+
+// nextPlayerMove returns the index in Moves of the player's next move. Even
+// indices are the opponent's moves, starting with the setup move.
+func nextPlayerMove(sess *redis.Session) (int, error) {
+	if sess.Solved || sess.Failed {
+		return 0, ErrSessionOver
+	}
+	idx := sess.MoveIndex
+	if idx%2 == 0 {
+		idx++
+	}
+	if idx < 0 || idx >= len(sess.Moves) {
+		return 0, ErrSessionOver
+	}
+	return idx, nil
+}
+
+// Hint reveals the next hint for the player's current move and records it on
+// the session. Each call goes one level further: the theme (once per
+// session), the piece to move, its square, then the move. Asking again once
+// the move is revealed repeats it without counting another hint. p is the
+// session's puzzle from Puzzle, if any, and gives the theme of sessions that
+// do not know it yet. Hint has no side effects, so it can run inside
+// UpdateSession. The caller saves the session.
+func (s *SessionService) Hint(sess *redis.Session, p *models.Puzzle) (*models.SessionHint, error) {
+	idx, err := nextPlayerMove(sess)
+	if err != nil {
+		return nil, err
+	}
+
+	pos, move, err := sessionMove(sess, idx)
+	if err != nil {
+		return nil, err
+	}
+	fillPuzzle(sess, p)
+
+	level, themeShown := hintLevelPiece, false
+	for _, h := range sess.Hints {
+		if h.Level == hintLevelTheme {
+			themeShown = true
+		}
+		if h.MoveIndex == idx {
+			level = h.Level + 1
+		}
+	}
+	theme, hasTheme := mainTheme(sess.Themes)
+	if level == hintLevelPiece && !themeShown && hasTheme {
+		level = hintLevelTheme
+	}
+
+	repeated := level > hintLevelMove
+	if repeated {
+		level = hintLevelMove
+	} else {
+		sess.Hints = append(sess.Hints, redis.SessionHint{Level: level, MoveIndex: idx, At: time.Now().UTC()})
+		sess.HintsUsed = max(sess.HintsUsed, len(sess.Hints))
+	}
+
+	hint := &models.SessionHint{
+		Level:     level,
+		Kind:      hintKinds[level],
+		MoveIndex: idx,
+		HintsUsed: sess.HintsUsed,
+		Repeated:  repeated,
+	}
+	piece := pos.Board().Piece(move.S1())
+	switch level {
+	case hintLevelTheme:
+		hint.Theme, hint.ThemeName = theme.Key, theme.Name
+	case hintLevelPiece:
+		hint.Piece = pieceNames[piece.Type()]
+	case hintLevelSquare:
+		hint.Piece = pieceNames[piece.Type()]
+		hint.Square = move.S1().String()
+	case hintLevelMove:
+		hint.Piece = pieceNames[piece.Type()]
+		hint.Square = move.S1().String()
+		hint.Move = sess.Moves[idx]
+		hint.SAN = chess.AlgebraicNotation{}.Encode(pos, move)
+	}
+	return hint, nil
+}
+
+// sessionMove replays a session's moves up to idx and returns the position
+// before that move and the move itself.
+func sessionMove(sess *redis.Session, idx int) (*chess.Position, *chess.Move, error) {
+	fen, err := chess.FEN(sess.FEN)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrSessionUnplayable, err)
+	}
+	game := chess.NewGame(fen, chess.UseNotation(chess.UCINotation{}))
+	for i := 0; i <= idx; i++ {
+		if err := game.MoveStr(sess.Moves[i]); err != nil {
+			return nil, nil, fmt.Errorf("%w: move %d (%s): %v", ErrSessionUnplayable, i, sess.Moves[i], err)
+		}
+	}
+	positions, moves := game.Positions(), game.Moves()
+	return positions[idx], moves[idx], nil
+}
+
+// mainTheme picks the most telling theme of a puzzle: the first tactic or
+// mate pattern in taxonomy order. Phases, lengths and goals give nothing away.
+func mainTheme(keys []string) (models.Theme, bool) {
+	for _, t := range models.ThemeTaxonomy {
+		if t.Category != models.ThemeCategoryTactic && t.Category != models.ThemeCategoryMate {
+			continue
+		}
+		for _, k := range keys {
+			if k == t.Key {
+				return t, true
+			}
+		}
+	}
+	return models.Theme{}, false
+}
+
+// Puzzle looks up the puzzle of a session that does not know its themes and
+// rating yet, for Hint and Finish. It may call the puzzle sources, so call it
+// before UpdateSession rather than inside it. Returns nil when the session
+// needs no lookup or its puzzle cannot be found.
+func (s *SessionService) Puzzle(ctx context.Context, sess *redis.Session) *models.Puzzle {
+	if s.puzzles == nil || sess.PuzzleRating != 0 || sess.PuzzleID == "" {
+		return nil
+	}
+	p, err := s.puzzles.LookupByID(ctx, sess.PuzzleID)
+	if err != nil {
+		logger.WarnContext(ctx, "look up session puzzle", "session", sess.ID, "puzzle", sess.PuzzleID, "err", err)
+		return nil
+	}
+	return p
+}
+
+// fillPuzzle fills in the themes and rating of the session's puzzle from p
+// the first time they are needed.
+func fillPuzzle(sess *redis.Session, p *models.Puzzle) {
+	if p == nil || sess.PuzzleRating != 0 || p.ID != sess.PuzzleID {
+		return
+	}
+	sess.Themes = p.Themes
+	sess.PuzzleRating = p.Rating
+}
+
+// Finish scores a session the first time it is solved or failed. Solving
+// earns the points of the puzzle's rating band less the cost of the hints
+// taken; p is the session's puzzle from Puzzle, if any, and gives the rating
+// of sessions that do not know it yet. Finish has no side effects, so it can
+// run inside UpdateSession. It reports whether the owner's rating should now
+// be updated with Rate, which is only the case for verified sessions of a
+// signed-in user. The caller saves the session.
+func (s *SessionService) Finish(sess *redis.Session, p *models.Puzzle) bool {
+	if sess.ScoredAt != nil || !sess.Solved && !sess.Failed {
+		return false
+	}
+	fillPuzzle(sess, p)
+	if sess.Solved {
+		sess.Score = models.RatingToBand(sessionRating(sess)).Points * (100 - hintPenalty(sess)) / 100
+	}
+	now := time.Now().UTC()
+	sess.ScoredAt = &now
+	// A solve only counts if the server checked every move of it.
+	return sess.UserID != "" && sess.Verified && (sess.Failed || sess.MoveIndex >= len(sess.Moves))
+}
+
+// Rate updates the owner's rating for a session Finish has just scored and
+// records the change on the session. Call it once, after the scored session
+// is saved. Hints lower the result the update is based on. Each puzzle is
+// rated once per user, so playing it again leaves the rating alone. Returns
+// the session as saved.
+func (s *SessionService) Rate(ctx context.Context, sess *redis.Session) *redis.Session {
+	player := initialPlayerRating
+	current, err := s.store.GetPlayerRating(ctx, sess.UserID)
+	if err != nil {
+		logger.WarnContext(ctx, "read player rating", "user", sess.UserID, "err", err)
+		return sess
+	}
+	if current != nil {
+		player = current.Rating
+	}
+	result := 0.0
+	if sess.Solved {
+		result = float64(100-hintPenalty(sess)) / 100
+	}
+	delta := ratingDelta(player, sessionRating(sess), result)
+	updated, err := s.store.AdjustPlayerRating(ctx, sess.UserID, sess.PuzzleID, initialPlayerRating, delta)
+	if err != nil {
+		logger.WarnContext(ctx, "update player rating", "user", sess.UserID, "err", err)
+		return sess
+	}
+	if updated == nil {
+		return sess
+	}
+	saved, err := s.store.UpdateSession(ctx, sess.ID, s.ttl, func(cur *redis.Session) error {
+		cur.RatingChange = &delta
+		return nil
+	})
+	if err != nil || saved == nil {
+		logger.WarnContext(ctx, "record rating change", "session", sess.ID, "err", err)
+		sess.RatingChange = &delta
+		return sess
+	}
+	return saved
+}
+
+// sessionRating is the rating of a session's puzzle, or of its difficulty if
+// the puzzle is unknown.
+func sessionRating(sess *redis.Session) int {
+	if sess.PuzzleRating != 0 {
+		return sess.PuzzleRating
+	}
+	return difficultyRating(models.DifficultyLevel(sess.Difficulty))
+}
+
+// hintPenalty is the share of a session's points, in percent, its hints
+// cost.
+func hintPenalty(sess *redis.Session) int {
+	cost := 0
+	for _, h := range sess.Hints {
+		cost += hintCost[h.Level]
+	}
+	// Hints the client reports without asking the server show the source
+	// square, and cost as much.
+	if extra := sess.HintsUsed - len(sess.Hints); extra > 0 {
+		cost += extra * hintCost[hintLevelSquare]
+	}
+	return min(cost, 100)
+}
+
+// ratingDelta is the Elo change of a player rated player after scoring
+// result (0 to 1) against a puzzle rated puzzle.
+func ratingDelta(player, puzzle int, result float64) int {
+	expected := 1 / (1 + math.Pow(10, float64(puzzle-player)/400))
+	return int(math.Round(ratingK * (result - expected)))
+}
+
+// difficultyRating is the rating assumed for a puzzle of unknown rating: the
+// middle of its difficulty's range.
+func difficultyRating(d models.DifficultyLevel) int {
+	switch d {
+	case models.DifficultyEasy:
+		return 1000
+	case models.DifficultyHard:
+		return 2000
+	default:
+		return 1550
+	}
+}
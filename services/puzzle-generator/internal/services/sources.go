@@ -0,0 +1,296 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/chess-puzzle-next/puzzle-generator/internal/metrics"
+	"github.com/chess-puzzle-next/puzzle-generator/internal/models"
+)
+
+// Puzzle sources an endpoint chain can list.
+const (
+	SourceLichess     = "lichess"
+	SourceStore       = "store" // puzzles previously served by another source, kept in Redis
+	SourceHuggingFace = "huggingface"
+)
+
+// Endpoints with a source chain.
+const (
+	EndpointRandom  = "random"  // GET /puzzle
+	EndpointByID    = "id"      // GET /puzzle/{id}
+	EndpointDaily   = "daily"   // GET /puzzle/daily
+	EndpointDataset = "dataset" // GET /puzzle/dataset
+)
+
+var allSources = []string{SourceLichess, SourceStore, SourceHuggingFace}
+
+// supportedSources lists, in default priority order, the sources able to
+// serve each endpoint. The dataset cannot look puzzles up by ID and has no
+// daily puzzle; the store serves the last daily puzzle it saw.
+var supportedSources = map[string][]string{
+	EndpointRandom:  {SourceLichess, SourceStore, SourceHuggingFace},
+	EndpointByID:    {SourceLichess, SourceStore},
+	EndpointDaily:   {SourceLichess, SourceStore},
+	EndpointDataset: {SourceHuggingFace, SourceStore},
+}
+
+var (
+	// ErrNoSource is returned when every source of an endpoint's chain is
+	// disabled or not configured.
+	ErrNoSource = errors.New("puzzle: no puzzle source available")
+	// ErrUnknownSource is returned for a source name that does not exist.
+	ErrUnknownSource = errors.New("puzzle: unknown source")
+
+	errNotStored = errors.New("puzzle: not in the puzzle store")
+)
+
+// PuzzleStore backs the store source and shares the sources switched off by
+// admins between replicas.
+type PuzzleStore interface {
+	StorePuzzle(ctx context.Context, p *models.Puzzle, limit int64) error
+	GetStoredPuzzle(ctx context.Context, id string) (*models.Puzzle, error)
+	RandomStoredPuzzle(ctx context.Context, d models.DifficultyLevel) (*models.Puzzle, error)
+	StoreDailyPuzzle(ctx context.Context, p *models.Puzzle) error
+	GetStoredDailyPuzzle(ctx context.Context) (*models.Puzzle, error)
+	SetSourceDisabled(ctx context.Context, source string, disabled bool) error
+	DisabledSources(ctx context.Context) ([]string, error)
+}
+
+// WithStore enables the store source. Puzzles served by other sources are
+// kept in store, up to limit per difficulty.
+func WithStore(store PuzzleStore, limit int64) Option {
+	return func(s *PuzzleService) {
+		s.store = store
+		s.storeLimit = limit
+	}
+}
+
+// WithSourceChains overrides the source priority of the given endpoints.
+// Unknown endpoints and sources an endpoint cannot use are dropped.
+func WithSourceChains(chains map[string][]string) Option {
+	return func(s *PuzzleService) {
+		for endpoint, chain := range chains {
+			supported, ok := supportedSources[endpoint]
+			if !ok {
+				logger.Warn("ignoring source chain of unknown endpoint", "endpoint", endpoint)
+				continue
+			}
+			var valid []string
+			for _, name := range chain {
+				if !slices.Contains(supported, name) {
+					logger.Warn("ignoring source unsupported by endpoint", "endpoint", endpoint, "source", name, "supported", strings.Join(supported, ","))
+					continue
+				}
+				if !slices.Contains(valid, name) {
+					valid = append(valid, name)
+				}
+			}
+			if len(valid) > 0 {
+				s.chains[endpoint] = valid
+			}
+		}
+	}
+}
+
+// sourceFunc fetches a puzzle from one source of a chain.
+type sourceFunc func(ctx context.Context) (*models.Puzzle, error)
+
+// serve walks the chain of endpoint and returns the first puzzle a source
+// produces. Disabled or unconfigured sources are skipped; a failing source
+// falls through to the next one.
+func (s *PuzzleService) serve(ctx context.Context, endpoint string, fetch map[string]sourceFunc) (*models.Puzzle, error) {
+	var errs sourceErrors
+	for _, name := range s.chains[endpoint] {
+		fn := fetch[name]
+		if fn == nil || !s.available(name) || !s.enabled(name) {
+			continue
+		}
+		p, err := fn(ctx)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		p.ServedBy = name
+		metrics.PuzzleServed(endpoint, name)
+		if endpoint != EndpointByID {
+			// Lookups by ID are shared between callers and do not always
+			// serve the puzzle; GetByID marks them.
+			s.seen.Mark(ctx, p.ID)
+		}
+		if len(errs) > 0 {
+			logger.WarnContext(ctx, "puzzle served by fallback source", "endpoint", endpoint, "source", name, "err", errs)
+		}
+		if name != SourceStore {
+			s.keep(ctx, endpoint, p)
+			s.cache.Add(ctx, name, p)
+		}
+		return p, nil
+	}
+	switch len(errs) {
+	case 0:
+		return nil, fmt.Errorf("%w for %s", ErrNoSource, endpoint)
+	case 1:
+		return nil, errs[0]
+	default:
+		return nil, fmt.Errorf("puzzle: all sources failed: %w", errs)
+	}
+}
+
+// keep saves a puzzle served by an upstream so the store can serve it later.
+func (s *PuzzleService) keep(ctx context.Context, endpoint string, p *models.Puzzle) {
+	if s.store == nil {
+		return
+	}
+	var err error
+	if endpoint == EndpointDaily {
+		err = s.store.StoreDailyPuzzle(ctx, p)
+	} else {
+		err = s.store.StorePuzzle(ctx, p, s.storeLimit)
+	}
+	if err != nil {
+		logger.WarnContext(ctx, "keep puzzle in store", "id", p.ID, "err", err)
+	}
+}
+
+// fromStore adapts a store lookup to a sourceFunc, turning a miss into an
+// error so the chain moves on.
+func fromStore(lookup func(ctx context.Context) (*models.Puzzle, error)) sourceFunc {
+	return func(ctx context.Context) (*models.Puzzle, error) {
+		p, err := lookup(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("puzzle: read store: %w", err)
+		}
+		if p == nil {
+			return nil, errNotStored
+		}
+		return p, nil
+	}
+}
+
+func (s *PuzzleService) available(name string) bool {
+	switch name {
+	case SourceStore:
+		return s.store != nil
+	case SourceHuggingFace:
+		return s.dataset != nil
+	default:
+		return s.lichess != nil
+	}
+}
+
+func (s *PuzzleService) enabled(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return !s.disabled[name]
+}
+
+// Sources returns every source with its state and the chain of each
+// endpoint.
+func (s *PuzzleService) Sources() *models.PuzzleSources {
+	out := &models.PuzzleSources{Chains: make(map[string][]string, len(s.chains))}
+	for endpoint, chain := range s.chains {
+		out.Chains[endpoint] = slices.Clone(chain)
+	}
+	for _, name := range allSources {
+		out.Sources = append(out.Sources, s.sourceStatus(name))
+	}
+	return out
+}
+
+func (s *PuzzleService) sourceStatus(name string) models.SourceStatus {
+	st := models.SourceStatus{
+		Name:      name,
+		Enabled:   s.enabled(name),
+		Available: s.available(name),
+		Endpoints: []string{},
+	}
+	for _, endpoint := range []string{EndpointRandom, EndpointByID, EndpointDaily, EndpointDataset} {
+		if slices.Contains(s.chains[endpoint], name) {
+			st.Endpoints = append(st.Endpoints, endpoint)
+		}
+	}
+	return st
+}
+
+// SetSourceEnabled switches a source on or off for every endpoint. With a
+// store the switch is shared with the other replicas.
+func (s *PuzzleService) SetSourceEnabled(ctx context.Context, name string, enabled bool) (*models.SourceStatus, error) {
+	if !slices.Contains(allSources, name) {
+		return nil, fmt.Errorf("%w %q", ErrUnknownSource, name)
+	}
+	if s.store != nil {
+		if err := s.store.SetSourceDisabled(ctx, name, !enabled); err != nil {
+			return nil, err
+		}
+	}
+	s.mu.Lock()
+	s.disabled[name] = !enabled
+	s.mu.Unlock()
+	logger.InfoContext(ctx, "puzzle source switched", "source", name, "enabled", enabled)
+
+	st := s.sourceStatus(name)
+	return &st, nil
+}
+
+// RunSourceSync reloads the sources switched off on other replicas every
+// interval until ctx is cancelled. It returns at once without a store.
+func (s *PuzzleService) RunSourceSync(ctx context.Context, interval time.Duration) {
+	if s.store == nil {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		disabled, err := s.store.DisabledSources(ctx)
+		if err != nil {
+			logger.WarnContext(ctx, "load disabled sources", "err", err)
+		} else {
+			m := make(map[string]bool, len(disabled))
+			for _, name := range disabled {
+				m[name] = true
+			}
+			s.mu.Lock()
+			s.disabled = m
+			s.mu.Unlock()
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// HasFallback reports whether every chain that uses name has another
+// configured source, so the service keeps serving without it.
+func (s *PuzzleService) HasFallback(name string) bool {
+	for _, chain := range s.chains {
+		if !slices.Contains(chain, name) {
+			continue
+		}
+		if !slices.ContainsFunc(chain, func(other string) bool { return other != name && s.available(other) }) {
+			return false
+		}
+	}
+	return true
+}
+
+// sourceErrors collects the failure of each source tried by a chain.
+type sourceErrors []error
+
+func (e sourceErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (e sourceErrors) Unwrap() []error {
+	return e
+}
@@ -0,0 +1,51 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/chess-puzzle-next/puzzle-generator/internal/models"
+)
+
+// stormPerDifficulty is how many puzzles of each difficulty a Puzzle Storm
+// batch holds.
+const stormPerDifficulty = 4
+
+// StormBatch returns the puzzles of one Puzzle Storm run, easiest first, from
+// the dataset chain. Puzzles that cannot be fetched are left out, so a batch
+// may be short; it is an error only when nothing could be fetched.
+func (s *PuzzleService) StormBatch(ctx context.Context) ([]*models.Puzzle, error) {
+	levels := []models.DifficultyLevel{models.DifficultyEasy, models.DifficultyMedium, models.DifficultyHard}
+	slots := make([]*models.Puzzle, len(levels)*stormPerDifficulty)
+	errs := make([]error, len(slots))
+	var wg sync.WaitGroup
+	for i := range slots {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots[i], errs[i] = s.GenerateFromDataset(ctx, levels[i/stormPerDifficulty])
+		}()
+	}
+	wg.Wait()
+
+	// Concurrent fetches can pick the same puzzle before it is marked seen.
+	batch := make([]*models.Puzzle, 0, len(slots))
+	ids := make(map[string]bool, len(slots))
+	for _, p := range slots {
+		if p == nil || ids[p.ID] {
+			continue
+		}
+		ids[p.ID] = true
+		batch = append(batch, p)
+	}
+	if len(batch) == 0 {
+		for _, err := range errs {
+			if err != nil {
+				return nil, fmt.Errorf("puzzle: storm batch: %w", err)
+			}
+		}
+		return nil, fmt.Errorf("puzzle: storm batch is empty")
+	}
+	return batch, nil
+}
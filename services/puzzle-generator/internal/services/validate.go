@@ -25,10 +25,11 @@ func validateDifficulty(d models.DifficultyLevel) error {
 }
 
 func validateAIPuzzleRequest(req models.AIPuzzleRequest) error {
-	if strings.TrimSpace(req.Prompt) == "" {
+	prompt := strings.TrimSpace(req.Prompt)
+	if prompt == "" && req.Filter == nil {
 		return fmt.Errorf("puzzle: prompt is required")
 	}
-	if len(strings.TrimSpace(req.Prompt)) < 8 {
+	if prompt != "" && len(prompt) < 8 {
 		return fmt.Errorf("puzzle: prompt must be at least 8 characters")
 	}
 	if err := validateDifficulty(req.Difficulty); err != nil {
@@ -0,0 +1,355 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/chess-puzzle-next/puzzle-generator/internal/logging"
+	"github.com/chess-puzzle-next/puzzle-generator/pkg/redis"
+	"github.com/google/uuid"
+)
+
+var logger = logging.For("webhooks")
+
+// Delivery statuses.
+const (
+	StatusPending   = "pending"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// onceTTL is how long a once-only event key is remembered.
+const onceTTL = 48 * time.Hour
+
+// task is a unit of work for the delivery workers: either an event to fan
+// out to its endpoints or one attempt of a delivery.
+type task struct {
+	event      *Event
+	deliveryID string
+}
+
+// laneTask is one attempt of a delivery, queued on its endpoint's lane.
+type laneTask struct {
+	ep *redis.WebhookEndpoint
+	d  *redis.WebhookDelivery
+}
+
+const (
+	// laneSize bounds the attempts queued for one endpoint. Past it, new
+	// attempts are put off as if they had failed.
+	laneSize = 100
+	// laneIdle is how long a lane waits for work before it stops.
+	laneIdle = time.Minute
+)
+
+// Publish queues an event for delivery and returns immediately. Events are
+// dropped (and logged) when the queue is full, so a slow receiver can never
+// hold up a request. Publish is a no-op on a nil Service.
+func (s *Service) Publish(ev Event) {
+	if s == nil {
+		return
+	}
+	if !s.enqueue(task{event: &ev}) {
+		logger.Warn("queue full, event dropped", "type", ev.Type)
+	}
+}
+
+func (s *Service) enqueue(t task) bool {
+	select {
+	case <-s.done:
+		return false
+	default:
+	}
+	select {
+	case s.queue <- t:
+		return true
+	default:
+		return false
+	}
+}
+
+// Run starts the delivery workers and blocks until ctx is cancelled. The
+// workers fan events out and load retries; the requests themselves are made
+// by one lane per endpoint, so a slow receiver only delays its own
+// deliveries. Retries scheduled for later are dropped on shutdown; their
+// deliveries stay pending in the log and can be replayed.
+func (s *Service) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for range max(s.cfg.Workers, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case t := <-s.queue:
+					s.handle(ctx, t)
+				}
+			}
+		}()
+	}
+	<-ctx.Done()
+	close(s.done)
+	wg.Wait()
+	s.lanesWG.Wait()
+}
+
+func (s *Service) handle(ctx context.Context, t task) {
+	if t.event != nil {
+		if err := s.fanOut(ctx, t.event); err != nil {
+			logger.ErrorContext(ctx, "fan out", "type", t.event.Type, "err", err)
+		}
+		return
+	}
+	if err := s.attempt(ctx, t.deliveryID); err != nil {
+		logger.ErrorContext(ctx, "delivery", "delivery", t.deliveryID, "err", err)
+	}
+}
+
+// fanOut creates one delivery per subscribed endpoint and tries each once.
+func (s *Service) fanOut(ctx context.Context, ev *Event) error {
+	if ev.Once != "" {
+		ok, err := s.store.ClaimWebhookEvent(ctx, ev.Type+":"+ev.Once, onceTTL)
+		if err != nil || !ok {
+			return err
+		}
+	}
+
+	var eps []*redis.WebhookEndpoint
+	var err error
+	switch {
+	case broadcast[ev.Type]:
+		eps, err = s.store.ListEventWebhookEndpoints(ctx, ev.Type)
+	case ev.UserID != "":
+		eps, err = s.store.ListUserWebhookEndpoints(ctx, ev.UserID)
+	}
+	if err != nil {
+		return err
+	}
+	eps = slices.DeleteFunc(eps, func(ep *redis.WebhookEndpoint) bool {
+		return !slices.Contains(ep.Events, ev.Type)
+	})
+	if len(eps) == 0 {
+		return nil
+	}
+
+	now := s.now().UTC()
+	eventID := "evt_" + uuid.New().String()
+	payload, err := json.Marshal(envelope{ID: eventID, Type: ev.Type, CreatedAt: now, Data: ev.Data})
+	if err != nil {
+		return fmt.Errorf("marshal payload: %w", err)
+	}
+
+	for _, ep := range eps {
+		d := &redis.WebhookDelivery{
+			ID:         uuid.New().String(),
+			EndpointID: ep.ID,
+			EventID:    eventID,
+			EventType:  ev.Type,
+			Payload:    payload,
+			Status:     StatusPending,
+			CreatedAt:  now,
+		}
+		if err := s.store.SaveWebhookDelivery(ctx, d, s.cfg.LogRetention); err != nil {
+			return err
+		}
+		s.dispatch(ctx, ep, d)
+	}
+	return nil
+}
+
+// attempt makes the next attempt of a logged delivery.
+func (s *Service) attempt(ctx context.Context, deliveryID string) error {
+	d, err := s.store.GetWebhookDelivery(ctx, deliveryID)
+	if err != nil || d == nil || d.Status != StatusPending {
+		return err
+	}
+	ep, err := s.store.GetWebhookEndpoint(ctx, d.EndpointID)
+	if err != nil {
+		return err
+	}
+	if ep == nil {
+		// Endpoint deleted while a retry was scheduled.
+		return nil
+	}
+	s.dispatch(ctx, ep, d)
+	return nil
+}
+
+// dispatch queues an attempt of d on the lane of ep, starting the lane when
+// it is not running. When the lane is full the attempt is put off like a
+// failed one, without counting against MaxAttempts.
+func (s *Service) dispatch(ctx context.Context, ep *redis.WebhookEndpoint, d *redis.WebhookDelivery) {
+	s.lanesMu.Lock()
+	lane, ok := s.lanes[ep.ID]
+	if !ok {
+		lane = make(chan laneTask, laneSize)
+		s.lanes[ep.ID] = lane
+		s.lanesWG.Add(1)
+		go s.runLane(ctx, ep.ID, lane)
+	}
+	select {
+	case lane <- laneTask{ep: ep, d: d}:
+		s.lanesMu.Unlock()
+		return
+	default:
+	}
+	s.lanesMu.Unlock()
+	logger.WarnContext(ctx, "endpoint backlog full, delivery put off", "url", ep.URL, "delivery", d.ID)
+	s.retryLater(d.ID, s.backoff(max(d.Attempts, 1)))
+}
+
+// runLane makes the attempts queued for one endpoint, one at a time, and
+// stops once the lane stayed idle for laneIdle.
+func (s *Service) runLane(ctx context.Context, endpointID string, lane chan laneTask) {
+	defer s.lanesWG.Done()
+	idle := time.NewTimer(laneIdle)
+	defer idle.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case t := <-lane:
+			s.deliver(ctx, t.ep, t.d)
+			idle.Reset(laneIdle)
+		case <-idle.C:
+			s.lanesMu.Lock()
+			if len(lane) == 0 {
+				delete(s.lanes, endpointID)
+				s.lanesMu.Unlock()
+				return
+			}
+			s.lanesMu.Unlock()
+			idle.Reset(laneIdle)
+		}
+	}
+}
+
+// deliver posts d to ep once and records the outcome. Failed attempts are
+// retried after RetryBaseDelay·2^n (with jitter) until MaxAttempts.
+func (s *Service) deliver(ctx context.Context, ep *redis.WebhookEndpoint, d *redis.WebhookDelivery) {
+	now := s.now().UTC()
+	d.Attempts++
+	d.LastAttemptAt = &now
+
+	code, err := s.post(ctx, ep, d)
+	d.ResponseCode = code
+	d.LastError = ""
+	switch {
+	case err == nil:
+		d.Status = StatusSucceeded
+	case d.Attempts >= s.cfg.MaxAttempts:
+		d.Status = StatusFailed
+		d.LastError = err.Error()
+		logger.WarnContext(ctx, "delivery failed", "type", d.EventType, "url", ep.URL, "attempts", d.Attempts, "err", err)
+	default:
+		d.LastError = err.Error()
+		s.retryLater(d.ID, s.backoff(d.Attempts))
+	}
+
+	if err := s.store.SaveWebhookDelivery(ctx, d, s.cfg.LogRetention); err != nil {
+		logger.ErrorContext(ctx, "save delivery", "delivery", d.ID, "err", err)
+	}
+}
+
+// retryLater queues another attempt of a delivery after delay.
+func (s *Service) retryLater(deliveryID string, delay time.Duration) {
+	time.AfterFunc(delay, func() {
+		if !s.enqueue(task{deliveryID: deliveryID}) {
+			logger.Warn("retry dropped", "delivery", deliveryID)
+		}
+	})
+}
+
+func (s *Service) post(ctx context.Context, ep *redis.WebhookEndpoint, d *redis.WebhookDelivery) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ep.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "chess-puzzle-next-webhooks/1.0")
+	req.Header.Set(HeaderEvent, d.EventType)
+	req.Header.Set(HeaderDelivery, d.ID)
+	req.Header.Set(HeaderSignature, Sign(d.Payload, ep.Secret, s.now()))
+
+	resp, err := s.http.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint answered %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff returns the delay before the attempt following attempt n, with
+// ±20% jitter so retries to one receiver do not arrive in lockstep.
+func (s *Service) backoff(n int) time.Duration {
+	d := s.cfg.RetryBaseDelay << (n - 1)
+	jitter := 0.8 + 0.4*rand.Float64()
+	return time.Duration(float64(d) * jitter)
+}
+
+var errPrivateAddress = errors.New("webhooks: refusing to connect to a private address")
+
+// newHTTPClient returns the client deliveries are posted with. Unless
+// private targets are allowed, it refuses to connect to loopback, private
+// and link-local addresses, checked on the resolved IP so DNS cannot be used
+// to reach internal services.
+func newHTTPClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivate {
+		dialer.Control = func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !publicIP(ip) {
+				return errPrivateAddress
+			}
+			return nil
+		}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	transport.Proxy = nil
+	return &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+		// Redirects could point anywhere; receivers must answer directly.
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// sharedAddressSpace is carrier-grade NAT (RFC 6598). It is not private in
+// the net package's sense but is internal to providers' networks.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+func publicIP(ip net.IP) bool {
+	if addr, ok := netip.AddrFromSlice(ip); ok && sharedAddressSpace.Contains(addr.Unmap()) {
+		return false
+	}
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified())
+}
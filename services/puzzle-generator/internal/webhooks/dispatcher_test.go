@@ -0,0 +1,97 @@
+package webhooks
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPublicIP(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"8.8.8.8", true},
+		{"1.1.1.1", true},
+		{"2606:4700:4700::1111", true},
+		{"100.63.255.255", true},
+		{"100.128.0.0", true},
+		{"127.0.0.1", false},
+		{"127.5.6.7", false},
+		{"::1", false},
+		{"10.0.0.1", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"fd00::1", false},
+		{"100.64.0.1", false},
+		{"100.127.255.254", false},
+		{"::ffff:100.64.0.1", false},
+		{"::ffff:127.0.0.1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"224.0.0.1", false},
+		{"0.0.0.0", false},
+		{"::", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			if got := publicIP(net.ParseIP(tt.ip)); got != tt.want {
+				t.Fatalf("publicIP(%s) = %v, want %v", tt.ip, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateURL(t *testing.T) {
+	tests := []struct {
+		url          string
+		allowPrivate bool
+		ok           bool
+	}{
+		{url: "https://hooks.example.com/puzzles", ok: true},
+		{url: "https://8.8.8.8/hook", ok: true},
+		{url: "http://hooks.example.com/puzzles"},
+		{url: "https://localhost/hook"},
+		{url: "https://api.localhost/hook"},
+		{url: "https://127.0.0.1/hook"},
+		{url: "https://[::1]/hook"},
+		{url: "https://10.1.2.3/hook"},
+		{url: "https://192.168.0.10:8443/hook"},
+		{url: "https://100.64.12.34/hook"},
+		{url: "https://169.254.169.254/latest/meta-data"},
+		{url: "ftp://hooks.example.com/"},
+		{url: "/relative"},
+		{url: "http://localhost:9000/hook", allowPrivate: true, ok: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			s := &Service{cfg: Config{AllowPrivate: tt.allowPrivate}}
+			_, err := s.validateURL(tt.url)
+			if tt.ok && err != nil {
+				t.Fatalf("validateURL() error = %v", err)
+			}
+			if !tt.ok && !errors.Is(err, ErrInvalidRequest) {
+				t.Fatalf("validateURL() error = %v, want ErrInvalidRequest", err)
+			}
+		})
+	}
+}
+
+func TestHTTPClientRefusesPrivateAddresses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	if _, err := newHTTPClient(time.Second, false).Get(srv.URL); !errors.Is(err, errPrivateAddress) {
+		t.Fatalf("Get(%s) error = %v, want errPrivateAddress", srv.URL, err)
+	}
+	resp, err := newHTTPClient(time.Second, true).Get(srv.URL)
+	if err != nil {
+		t.Fatalf("Get(%s) with private targets allowed: %v", srv.URL, err)
+	}
+	resp.Body.Close()
+}
@@ -0,0 +1,65 @@
+// Package webhooks delivers puzzle and session events to URLs registered by
+// users and integrators. Deliveries are signed, retried with exponential
+// backoff and logged so they can be inspected and replayed.
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"time"
+)
+
+// Event types that endpoints can subscribe to.
+const (
+	EventSessionSolved        = "session.solved"
+	EventSessionFailed        = "session.failed"
+	EventDailyPuzzlePublished = "puzzle.daily_published"
+	EventStormFinished        = "storm.finished"
+)
+
+// EventTypes lists every event type.
+var EventTypes = []string{EventSessionSolved, EventSessionFailed, EventDailyPuzzlePublished, EventStormFinished}
+
+// broadcast events go to every subscribed endpoint rather than only to the
+// endpoints of the user the event belongs to.
+var broadcast = map[string]bool{EventDailyPuzzlePublished: true}
+
+// Delivery headers.
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// Event is something that happened, to be delivered to subscribed endpoints.
+type Event struct {
+	Type   string
+	UserID string // owner of the event; ignored for broadcast events
+	Data   any
+
+	// Once, when set, makes the event fire at most once across replicas,
+	// e.g. "2026-10-18" for the daily puzzle.
+	Once string
+}
+
+// envelope is the JSON body of a delivery.
+type envelope struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"createdAt"`
+	Data      any       `json:"data"`
+}
+
+// Sign returns the signature header for a delivery body, in the
+// "t=<unix>,v1=<hex hmac-sha256 of t.body>" form used by Stripe, so
+// receivers can verify it with existing libraries.
+func Sign(body []byte, secret string, ts time.Time) string {
+	unix := strconv.FormatInt(ts.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unix))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "t=" + unix + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
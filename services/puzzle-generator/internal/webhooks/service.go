@@ -0,0 +1,253 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chess-puzzle-next/puzzle-generator/internal/models"
+	"github.com/chess-puzzle-next/puzzle-generator/pkg/redis"
+	"github.com/google/uuid"
+)
+
+// Store persists endpoints and the delivery log.
+type Store interface {
+	CreateWebhookEndpoint(ctx context.Context, ep *redis.WebhookEndpoint, limit int) error
+	GetWebhookEndpoint(ctx context.Context, id string) (*redis.WebhookEndpoint, error)
+	DeleteWebhookEndpoint(ctx context.Context, ep *redis.WebhookEndpoint) error
+	ListUserWebhookEndpoints(ctx context.Context, userID string) ([]*redis.WebhookEndpoint, error)
+	ListEventWebhookEndpoints(ctx context.Context, event string) ([]*redis.WebhookEndpoint, error)
+	SaveWebhookDelivery(ctx context.Context, d *redis.WebhookDelivery, ttl time.Duration) error
+	GetWebhookDelivery(ctx context.Context, id string) (*redis.WebhookDelivery, error)
+	ListWebhookDeliveries(ctx context.Context, endpointID string) ([]*redis.WebhookDelivery, error)
+	ClaimWebhookEvent(ctx context.Context, key string, ttl time.Duration) (bool, error)
+}
+
+var (
+	ErrNotFound       = errors.New("webhooks: not found")
+	ErrInvalidRequest = errors.New("webhooks: invalid request")
+	ErrQueueFull      = errors.New("webhooks: delivery queue is full")
+)
+
+// maxEndpointsPerUser bounds how many URLs one account can register.
+const maxEndpointsPerUser = 10
+
+// Config holds delivery settings.
+type Config struct {
+	Workers        int
+	QueueSize      int
+	MaxAttempts    int           // including the first try
+	RetryBaseDelay time.Duration // doubled after every failed attempt
+	Timeout        time.Duration // per attempt
+	LogRetention   time.Duration // how long deliveries stay in the log
+	AllowPrivate   bool          // allow plain http and private/loopback targets (development only)
+}
+
+// Service registers endpoints and delivers events to them.
+type Service struct {
+	store Store
+	cfg   Config
+	queue chan task
+	http  *http.Client
+	now   func() time.Time
+
+	// done is closed when the workers stop; later retries are dropped.
+	done chan struct{}
+
+	lanesMu sync.Mutex
+	lanes   map[string]chan laneTask // endpoint ID → queued attempts
+	lanesWG sync.WaitGroup
+}
+
+// New returns a webhook Service. Call Run to start delivering.
+func New(store Store, cfg Config) *Service {
+	return &Service{
+		store: store,
+		cfg:   cfg,
+		queue: make(chan task, cfg.QueueSize),
+		http:  newHTTPClient(cfg.Timeout, cfg.AllowPrivate),
+		now:   time.Now,
+		done:  make(chan struct{}),
+		lanes: make(map[string]chan laneTask),
+	}
+}
+
+// Register adds an endpoint for a user. The returned secret signs every
+// delivery and is only shown here.
+func (s *Service) Register(ctx context.Context, userID string, req models.CreateWebhookRequest) (*models.WebhookEndpointCreated, error) {
+	target, err := s.validateURL(req.URL)
+	if err != nil {
+		return nil, err
+	}
+	if len(req.Events) == 0 {
+		return nil, fmt.Errorf("%w: at least one event is required", ErrInvalidRequest)
+	}
+	var events []string
+	for _, ev := range req.Events {
+		if !slices.Contains(EventTypes, ev) {
+			return nil, fmt.Errorf("%w: unknown event %q", ErrInvalidRequest, ev)
+		}
+		if !slices.Contains(events, ev) {
+			events = append(events, ev)
+		}
+	}
+
+	secret := make([]byte, 24)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("webhooks: generate secret: %w", err)
+	}
+	ep := &redis.WebhookEndpoint{
+		ID:        uuid.New().String(),
+		OwnerID:   userID,
+		URL:       target,
+		Events:    events,
+		Secret:    "whsec_" + base64.RawURLEncoding.EncodeToString(secret),
+		CreatedAt: s.now().UTC(),
+	}
+	if err := s.store.CreateWebhookEndpoint(ctx, ep, maxEndpointsPerUser); err != nil {
+		if errors.Is(err, redis.ErrWebhookLimit) {
+			return nil, fmt.Errorf("%w: at most %d endpoints per account", ErrInvalidRequest, maxEndpointsPerUser)
+		}
+		return nil, err
+	}
+	return &models.WebhookEndpointCreated{WebhookEndpointInfo: toEndpointInfo(ep), Secret: ep.Secret}, nil
+}
+
+// List returns a user's endpoints.
+func (s *Service) List(ctx context.Context, userID string) ([]models.WebhookEndpointInfo, error) {
+	eps, err := s.store.ListUserWebhookEndpoints(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(eps, func(a, b *redis.WebhookEndpoint) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	out := make([]models.WebhookEndpointInfo, len(eps))
+	for i, ep := range eps {
+		out[i] = toEndpointInfo(ep)
+	}
+	return out, nil
+}
+
+// Delete removes one of a user's endpoints.
+func (s *Service) Delete(ctx context.Context, userID, id string) error {
+	ep, err := s.endpoint(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	return s.store.DeleteWebhookEndpoint(ctx, ep)
+}
+
+// Deliveries returns the delivery log of one of a user's endpoints.
+func (s *Service) Deliveries(ctx context.Context, userID, id string) ([]models.WebhookDeliveryInfo, error) {
+	if _, err := s.endpoint(ctx, userID, id); err != nil {
+		return nil, err
+	}
+	ds, err := s.store.ListWebhookDeliveries(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]models.WebhookDeliveryInfo, len(ds))
+	for i, d := range ds {
+		out[i] = toDeliveryInfo(d)
+	}
+	return out, nil
+}
+
+// Replay sends a logged delivery again as a new delivery with the same
+// payload, so receivers see the original event ID.
+func (s *Service) Replay(ctx context.Context, userID, id, deliveryID string) (*models.WebhookDeliveryInfo, error) {
+	if _, err := s.endpoint(ctx, userID, id); err != nil {
+		return nil, err
+	}
+	orig, err := s.store.GetWebhookDelivery(ctx, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	if orig == nil || orig.EndpointID != id {
+		return nil, ErrNotFound
+	}
+
+	d := &redis.WebhookDelivery{
+		ID:         uuid.New().String(),
+		EndpointID: id,
+		EventID:    orig.EventID,
+		EventType:  orig.EventType,
+		Payload:    orig.Payload,
+		Status:     StatusPending,
+		CreatedAt:  s.now().UTC(),
+		ReplayOf:   orig.ID,
+	}
+	if err := s.store.SaveWebhookDelivery(ctx, d, s.cfg.LogRetention); err != nil {
+		return nil, err
+	}
+	if !s.enqueue(task{deliveryID: d.ID}) {
+		return nil, ErrQueueFull
+	}
+	info := toDeliveryInfo(d)
+	return &info, nil
+}
+
+// endpoint loads an endpoint and checks that userID owns it. Endpoints of
+// other users are reported as not found.
+func (s *Service) endpoint(ctx context.Context, userID, id string) (*redis.WebhookEndpoint, error) {
+	ep, err := s.store.GetWebhookEndpoint(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if ep == nil || ep.OwnerID != userID {
+		return nil, ErrNotFound
+	}
+	return ep, nil
+}
+
+func (s *Service) validateURL(raw string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") {
+		return "", fmt.Errorf("%w: url must be an absolute http(s) URL", ErrInvalidRequest)
+	}
+	if s.cfg.AllowPrivate {
+		return u.String(), nil
+	}
+	if u.Scheme != "https" {
+		return "", fmt.Errorf("%w: url must use https", ErrInvalidRequest)
+	}
+	if host := u.Hostname(); host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return "", fmt.Errorf("%w: url must not point to a private address", ErrInvalidRequest)
+	} else if ip := net.ParseIP(host); ip != nil && !publicIP(ip) {
+		return "", fmt.Errorf("%w: url must not point to a private address", ErrInvalidRequest)
+	}
+	return u.String(), nil
+}
+
+func toEndpointInfo(ep *redis.WebhookEndpoint) models.WebhookEndpointInfo {
+	return models.WebhookEndpointInfo{
+		ID:        ep.ID,
+		URL:       ep.URL,
+		Events:    ep.Events,
+		CreatedAt: ep.CreatedAt,
+	}
+}
+
+func toDeliveryInfo(d *redis.WebhookDelivery) models.WebhookDeliveryInfo {
+	return models.WebhookDeliveryInfo{
+		ID:            d.ID,
+		EventID:       d.EventID,
+		EventType:     d.EventType,
+		Status:        d.Status,
+		Attempts:      d.Attempts,
+		ResponseCode:  d.ResponseCode,
+		LastError:     d.LastError,
+		CreatedAt:     d.CreatedAt,
+		LastAttemptAt: d.LastAttemptAt,
+		ReplayOf:      d.ReplayOf,
+		Payload:       d.Payload,
+	}
+}
@@ -0,0 +1,46 @@
+// Package embed turns text into vectors for semantic search. Hashing is a
+// local embedder that needs no model or network; OpenAI talks to any
+// OpenAI-compatible /embeddings endpoint, such as NVIDIA or a local Ollama.
+package embed
+
+import (
+	"context"
+	"math"
+)
+
+// Embedder turns texts into vectors. Vectors of one embedder can be compared
+// with each other only.
+type Embedder interface {
+	// Name identifies the vector space, such as "hash-256" or
+	// "api:nvidia/nv-embedqa-e5-v5". An index built with one embedder must
+	// be queried with an embedder of the same name.
+	Name() string
+	// Embed returns one L2-normalized vector per text, in order.
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// Normalize scales v to unit length in place. A zero vector is left as is.
+func Normalize(v []float32) {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return
+	}
+	norm := float32(1 / math.Sqrt(sum))
+	for i := range v {
+		v[i] *= norm
+	}
+}
+
+// Dot returns the dot product of a and b, the cosine similarity of
+// normalized vectors. Extra components of the longer vector are ignored.
+func Dot(a, b []float32) float32 {
+	n := min(len(a), len(b))
+	var sum float32
+	for i := 0; i < n; i++ {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
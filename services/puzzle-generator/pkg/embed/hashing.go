@@ -0,0 +1,131 @@
+package embed
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"unicode"
+)
+
+// Hashing is a local embedder: words and word pairs are hashed into a fixed
+// number of dimensions (the hashing trick). It runs offline and needs no
+// model, so it only matches words, not meanings. Chess words are folded to
+// one spelling first ("checkmate" and "mating" to "mate", "horse" to
+// "knight") so prompts and puzzle descriptions share a vocabulary.
+type Hashing struct {
+	dims int
+}
+
+// NewHashing returns a Hashing embedder producing vectors of dims
+// components.
+func NewHashing(dims int) *Hashing {
+	return &Hashing{dims: dims}
+}
+
+// Name returns "hash-<dims>".
+func (h *Hashing) Name() string {
+	return fmt.Sprintf("hash-%d", h.dims)
+}
+
+// Embed hashes each text into a normalized vector.
+func (h *Hashing) Embed(_ context.Context, texts []string) ([][]float32, error) {
+	if h.dims <= 0 {
+		return nil, fmt.Errorf("embed: hashing dimensions must be positive, got %d", h.dims)
+	}
+	out := make([][]float32, len(texts))
+	for i, text := range texts {
+		v := make([]float32, h.dims)
+		words := Tokenize(text)
+		for j, w := range words {
+			h.add(v, w, 1)
+			if j > 0 {
+				h.add(v, words[j-1]+" "+w, 0.5)
+			}
+		}
+		Normalize(v)
+		out[i] = v
+	}
+	return out, nil
+}
+
+// add hashes a feature into v. The top bit of the hash picks the sign, so
+// colliding features tend to cancel out instead of adding up.
+func (h *Hashing) add(v []float32, feature string, weight float32) {
+	f := fnv.New64a()
+	_, _ = f.Write([]byte(feature))
+	sum := f.Sum64()
+	if sum>>63 == 1 {
+		weight = -weight
+	}
+	v[sum%uint64(h.dims)] += weight
+}
+
+// stopWords carry no meaning for puzzle search. "puzzle" is in every
+// description.
+var stopWords = map[string]bool{
+	"a": true, "an": true, "the": true, "of": true, "and": true, "or": true, "to": true,
+	"for": true, "with": true, "in": true, "on": true, "at": true, "by": true, "from": true,
+	"is": true, "it": true, "its": true, "that": true, "this": true, "where": true, "which": true,
+	"i": true, "me": true, "my": true, "some": true, "want": true, "give": true, "find": true,
+	"show": true, "please": true, "like": true, "about": true, "puzzle": true,
+}
+
+// synonyms folds chess words to the spelling puzzle descriptions use.
+var synonyms = map[string]string{
+	"checkmate": "mate", "mating": "mate", "mated": "mate",
+	"horse": "knight",
+	"sac":   "sacrifice", "sacrificing": "sacrifice", "sacrificial": "sacrifice",
+	"forking": "fork", "forked": "fork",
+	"pinned": "pin", "pinning": "pin",
+	"skewering": "skewer", "skewered": "skewer",
+	"promote": "promotion", "promoting": "promotion", "promoted": "promotion", "queening": "promotion",
+	"underpromote": "underpromotion", "underpromoting": "underpromotion",
+	"ending": "endgame", "endgames": "endgame",
+	"castle": "castling", "castled": "castling",
+	"zwischenzug": "intermezzo",
+	"discovery":   "discovered",
+	"beginner":    "easy", "simple": "easy",
+	"intermediate": "medium",
+	"difficult":    "hard", "tough": "hard", "expert": "hard", "challenging": "hard",
+	"one": "1", "two": "2", "three": "3", "four": "4", "five": "5",
+}
+
+// Tokenize splits text into the words Hashing embeds: camelCase keys such as
+// "mateIn2" are split, words are lowercased, folded with synonyms and
+// singularized, and stop words are dropped.
+func Tokenize(text string) []string {
+	var sb strings.Builder
+	prev := rune(0)
+	for _, r := range text {
+		switch {
+		case unicode.IsUpper(r) && unicode.IsLower(prev),
+			unicode.IsDigit(r) && unicode.IsLetter(prev),
+			unicode.IsLetter(r) && unicode.IsDigit(prev):
+			sb.WriteByte(' ')
+		}
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			sb.WriteRune(unicode.ToLower(r))
+		} else {
+			sb.WriteByte(' ')
+		}
+		prev = r
+	}
+
+	var words []string
+	for _, w := range strings.Fields(sb.String()) {
+		if s, ok := synonyms[w]; ok {
+			w = s
+		} else if len(w) > 3 && strings.HasSuffix(w, "s") && !strings.HasSuffix(w, "ss") {
+			w = strings.TrimSuffix(w, "s")
+			if s, ok := synonyms[w]; ok {
+				w = s
+			}
+		}
+		if stopWords[w] {
+			continue
+		}
+		words = append(words, w)
+	}
+	return words
+}
@@ -0,0 +1,144 @@
+package embed
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// OpenAI embeds texts with an OpenAI-compatible /embeddings endpoint.
+type OpenAI struct {
+	httpClient *http.Client
+	baseURL    string
+	apiKey     string
+	model      string
+	batchSize  int
+}
+
+// Option configures the OpenAI embedder.
+type Option func(*OpenAI)
+
+func WithAPIKey(v string) Option {
+	return func(c *OpenAI) { c.apiKey = v }
+}
+
+func WithTimeout(v time.Duration) Option {
+	return func(c *OpenAI) { c.httpClient.Timeout = v }
+}
+
+func WithTransport(rt http.RoundTripper) Option {
+	return func(c *OpenAI) { c.httpClient.Transport = rt }
+}
+
+// WithBatchSize sets how many texts are sent per request.
+func WithBatchSize(n int) Option {
+	return func(c *OpenAI) { c.batchSize = n }
+}
+
+// NewOpenAI returns an embedder calling baseURL/embeddings with model. The
+// API key is optional, for local servers.
+func NewOpenAI(baseURL, model string, opts ...Option) *OpenAI {
+	c := &OpenAI{
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		model:      model,
+		batchSize:  64,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Name returns "api:<model>".
+func (c *OpenAI) Name() string {
+	return "api:" + c.model
+}
+
+type embeddingsRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type embeddingsResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+}
+
+// Embed sends the texts in batches and normalizes the vectors returned.
+func (c *OpenAI) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if c.baseURL == "" || c.model == "" {
+		return nil, fmt.Errorf("embed: base URL and model are required")
+	}
+	out := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += c.batchSize {
+		batch := texts[start:min(start+c.batchSize, len(texts))]
+		vectors, err := c.embedBatch(ctx, batch)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, vectors...)
+	}
+	return out, nil
+}
+
+func (c *OpenAI) embedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	body, err := json.Marshal(embeddingsRequest{Model: c.model, Input: texts})
+	if err != nil {
+		return nil, fmt.Errorf("embed: marshal request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/embeddings", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("embed: build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("embed: http request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("embed: read body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("embed: unexpected status %d: %s", resp.StatusCode, truncate(string(respBody), 300))
+	}
+
+	var decoded embeddingsResponse
+	if err := json.Unmarshal(respBody, &decoded); err != nil {
+		return nil, fmt.Errorf("embed: decode response: %w", err)
+	}
+	if len(decoded.Data) != len(texts) {
+		return nil, fmt.Errorf("embed: got %d embeddings for %d texts", len(decoded.Data), len(texts))
+	}
+	out := make([][]float32, len(texts))
+	for _, d := range decoded.Data {
+		if d.Index < 0 || d.Index >= len(texts) || out[d.Index] != nil {
+			return nil, fmt.Errorf("embed: bad embedding index %d", d.Index)
+		}
+		Normalize(d.Embedding)
+		out[d.Index] = d.Embedding
+	}
+	return out, nil
+}
+
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max] + "…"
+}
@@ -63,6 +63,10 @@ func WithTimeout(v time.Duration) Option {
 	return func(c *Client) { c.httpClient.Timeout = v }
 }
 
+func WithTransport(rt http.RoundTripper) Option {
+	return func(c *Client) { c.httpClient.Transport = rt }
+}
+
 func New(opts ...Option) *Client {
 	c := &Client{
 		httpClient: &http.Client{Timeout: 15 * time.Second},
@@ -102,7 +106,7 @@ func (c *Client) GetRandomPuzzle(ctx context.Context, difficulty models.Difficul
 			return nil, err
 		}
 
-		puzzle, err := toPuzzle(row)
+		puzzle, err := ParseRow(row)
 		if err != nil {
 			continue
 		}
@@ -118,6 +122,12 @@ func (c *Client) GetRandomPuzzle(ctx context.Context, difficulty models.Difficul
 	return nil, fmt.Errorf("huggingface: no valid puzzle row found")
 }
 
+// Ping checks that the datasets server answers and knows the configured split.
+func (c *Client) Ping(ctx context.Context) error {
+	_, err := c.getRowsCount(ctx)
+	return err
+}
+
 func (c *Client) getRowsCount(ctx context.Context) (int, error) {
 	params := url.Values{}
 	params.Set("dataset", c.dataset)
@@ -185,9 +195,32 @@ func (c *Client) fetchRows(ctx context.Context, offset, length int) ([]map[strin
 	return result, nil
 }
 
-// GetCandidatePuzzles fetches a batch of random puzzles from the dataset,
-// optionally filtering by difficulty. Used by the RAG pipeline.
-func (c *Client) GetCandidatePuzzles(ctx context.Context, difficulty models.DifficultyLevel, count int) ([]*models.Puzzle, error) {
+// Count returns the number of rows in the configured split.
+func (c *Client) Count(ctx context.Context) (int, error) {
+	return c.getRowsCount(ctx)
+}
+
+// Puzzles returns the valid puzzles among the length rows starting at offset.
+// The datasets server returns at most 100 rows per call.
+func (c *Client) Puzzles(ctx context.Context, offset, length int) ([]*models.Puzzle, error) {
+	rows, err := c.fetchRows(ctx, offset, length)
+	if err != nil {
+		return nil, err
+	}
+	puzzles := make([]*models.Puzzle, 0, len(rows))
+	for _, row := range rows {
+		if p, err := ParseRow(row); err == nil {
+			puzzles = append(puzzles, p)
+		}
+	}
+	return puzzles, nil
+}
+
+// FindPuzzles scans random batches of the dataset and returns up to `count`
+// puzzles accepted by match. It gives up after maxBatches batches and returns
+// whatever it found so far. Used for themed selections such as the weekly
+// challenge.
+func (c *Client) FindPuzzles(ctx context.Context, match func(*models.Puzzle) bool, count, maxBatches int) ([]*models.Puzzle, error) {
 	totalRows, err := c.getRowsCount(ctx)
 	if err != nil {
 		return nil, err
@@ -196,44 +229,36 @@ func (c *Client) GetCandidatePuzzles(ctx context.Context, difficulty models.Diff
 		return nil, fmt.Errorf("huggingface: dataset split is empty")
 	}
 
-	// Fetch a larger batch in one API call, then filter.
-	batchSize := count * 10
-	if batchSize > 100 {
-		batchSize = 100
-	}
-
+	const batchSize = 100
 	maxBound := totalRows - batchSize
 	if maxBound < 1 {
 		maxBound = 1
 	}
 
-	offset, err := randomInt(maxBound)
-	if err != nil {
-		return nil, fmt.Errorf("huggingface: random offset: %w", err)
-	}
-
-	rows, err := c.fetchRows(ctx, offset, batchSize)
-	if err != nil {
-		return nil, err
-	}
-
+	seen := make(map[string]bool)
 	var puzzles []*models.Puzzle
-	for _, row := range rows {
-		puzzle, err := toPuzzle(row)
+	for i := 0; i < maxBatches && len(puzzles) < count; i++ {
+		offset, err := randomInt(maxBound)
 		if err != nil {
-			continue
-		}
-		if difficulty != "" && puzzle.Difficulty != difficulty {
-			continue
+			return nil, fmt.Errorf("huggingface: random offset: %w", err)
 		}
-		puzzles = append(puzzles, puzzle)
-		if len(puzzles) >= count {
-			break
+
+		rows, err := c.fetchRows(ctx, offset, batchSize)
+		if err != nil {
+			return nil, err
 		}
-	}
 
-	if len(puzzles) == 0 {
-		return nil, fmt.Errorf("huggingface: no candidate puzzles found for difficulty %q", difficulty)
+		for _, row := range rows {
+			puzzle, err := ParseRow(row)
+			if err != nil || seen[puzzle.ID] || !match(puzzle) {
+				continue
+			}
+			seen[puzzle.ID] = true
+			puzzles = append(puzzles, puzzle)
+			if len(puzzles) >= count {
+				break
+			}
+		}
 	}
 
 	return puzzles, nil
@@ -260,7 +285,9 @@ func (c *Client) doGet(ctx context.Context, endpoint string) (string, int, error
 	return string(body), resp.StatusCode, nil
 }
 
-func toPuzzle(row map[string]any) (*models.Puzzle, error) {
+// ParseRow converts a dataset row into a puzzle. Rows of the Lichess puzzle
+// CSV export have the same columns.
+func ParseRow(row map[string]any) (*models.Puzzle, error) {
 	id := asString(row["PuzzleId"])
 	fen := asString(row["FEN"])
 	movesRaw := strings.Fields(asString(row["Moves"]))
@@ -281,6 +308,7 @@ func toPuzzle(row map[string]any) (*models.Puzzle, error) {
 		Popularity:      asInt(row["Popularity"]),
 		NbPlays:         asInt(row["NbPlays"]),
 		Themes:          themesRaw,
+		OpeningTags:     parseThemes(asString(row["OpeningTags"])),
 		GameURL:         asString(row["GameUrl"]),
 		Difficulty:      models.RatingToDifficulty(rating),
 		Source:          "huggingface-lichess",
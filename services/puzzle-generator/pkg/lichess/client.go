@@ -44,6 +44,11 @@ func WithTimeout(d time.Duration) Option {
 	return func(c *Client) { c.httpClient.Timeout = d }
 }
 
+// WithTransport overrides the HTTP transport (instrumentation, retries).
+func WithTransport(rt http.RoundTripper) Option {
+	return func(c *Client) { c.httpClient.Transport = rt }
+}
+
 // New returns a ready-to-use Lichess client.
 func New(opts ...Option) *Client {
 	c := &Client{
@@ -64,6 +69,12 @@ func (c *Client) GetDailyPuzzle(ctx context.Context) (*models.LichessPuzzleRespo
 	return c.fetchPuzzle(ctx, "/api/puzzle/daily", nil, true)
 }
 
+// Ping checks that Lichess answers by fetching the daily puzzle.
+func (c *Client) Ping(ctx context.Context) error {
+	_, err := c.GetDailyPuzzle(ctx)
+	return err
+}
+
 // GetPuzzleByID fetches a puzzle by its Lichess puzzle ID (no auth required).
 func (c *Client) GetPuzzleByID(ctx context.Context, id string) (*models.LichessPuzzleResponse, error) {
 	return c.fetchPuzzle(ctx, "/api/puzzle/"+id, nil, true)
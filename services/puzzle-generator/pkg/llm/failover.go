@@ -0,0 +1,130 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Failover is a Provider that tries its providers in order and answers with
+// the first completion. Providers that are not configured are skipped.
+type Failover struct {
+	providers []Provider
+
+	// OnFailure, when set, is called for each provider that failed before
+	// another one is tried.
+	OnFailure func(ctx context.Context, provider string, err error)
+
+	// Prices sets the Usage.Cost of completions by the model that answered.
+	Prices Prices
+}
+
+// NewFailover returns a Failover over providers, in priority order.
+func NewFailover(providers ...Provider) *Failover {
+	return &Failover{providers: providers}
+}
+
+// Name returns "failover".
+func (f *Failover) Name() string {
+	return "failover"
+}
+
+// Providers returns the configured providers in priority order.
+func (f *Failover) Providers() []Provider {
+	var out []Provider
+	for _, p := range f.providers {
+		if p.Configured() {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// Configured reports whether at least one provider is configured.
+func (f *Failover) Configured() bool {
+	return len(f.Providers()) > 0
+}
+
+// Chat asks each configured provider in turn until one answers. The error
+// joins every provider's error when all of them fail.
+func (f *Failover) Chat(ctx context.Context, r Request) (*Response, error) {
+	resp, err := try(ctx, f, func(p Provider) (*Response, error) {
+		return p.Chat(ctx, r)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return f.price(resp), nil
+}
+
+// ChatStream streams from each configured provider in turn until one
+// answers. Once a provider has sent a token, its failure ends the request
+// instead of moving on, so onToken never mixes two completions.
+func (f *Failover) ChatStream(ctx context.Context, r Request, onToken func(string)) (*Response, error) {
+	providers := f.Providers()
+	if len(providers) == 0 {
+		return nil, ErrNotConfigured
+	}
+	var errs []error
+	for i, p := range providers {
+		streamed := false
+		resp, err := ChatStream(ctx, p, r, func(token string) {
+			streamed = true
+			onToken(token)
+		})
+		if err == nil {
+			return f.price(resp), nil
+		}
+		errs = append(errs, err)
+		if streamed || ctx.Err() != nil {
+			break
+		}
+		if f.OnFailure != nil && i < len(providers)-1 {
+			f.OnFailure(ctx, p.Name(), err)
+		}
+	}
+	return nil, joinErrors(errs)
+}
+
+// Ping succeeds when any configured provider answers.
+func (f *Failover) Ping(ctx context.Context) error {
+	_, err := try(ctx, f, func(p Provider) (*Response, error) {
+		return nil, p.Ping(ctx)
+	})
+	return err
+}
+
+// price sets the cost of resp's usage.
+func (f *Failover) price(resp *Response) *Response {
+	resp.Usage.Cost = f.Prices.Cost(resp.Model, resp.Usage)
+	return resp
+}
+
+func try(ctx context.Context, f *Failover, op func(Provider) (*Response, error)) (*Response, error) {
+	providers := f.Providers()
+	if len(providers) == 0 {
+		return nil, ErrNotConfigured
+	}
+	var errs []error
+	for i, p := range providers {
+		resp, err := op(p)
+		if err == nil {
+			return resp, nil
+		}
+		errs = append(errs, err)
+		if ctx.Err() != nil {
+			break
+		}
+		if f.OnFailure != nil && i < len(providers)-1 {
+			f.OnFailure(ctx, p.Name(), err)
+		}
+	}
+	return nil, joinErrors(errs)
+}
+
+func joinErrors(errs []error) error {
+	if len(errs) == 1 {
+		return errs[0]
+	}
+	return fmt.Errorf("llm: all providers failed: %w", errors.Join(errs...))
+}
@@ -0,0 +1,84 @@
+package llm
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+)
+
+// Fake is a deterministic Provider for tests and offline development: it
+// answers every request of a task with the same reply and never fails, unless
+// the request's prompt cannot be rendered.
+type Fake struct {
+	replies  map[string]string
+	fallback string
+	calls    atomic.Int64
+}
+
+// NewFake returns a Fake answering each task of replies with its reply, and
+// other tasks with fallback.
+func NewFake(replies map[string]string, fallback string) *Fake {
+	return &Fake{replies: replies, fallback: fallback}
+}
+
+// Name returns "fake".
+func (f *Fake) Name() string {
+	return "fake"
+}
+
+// Configured always reports true.
+func (f *Fake) Configured() bool {
+	return true
+}
+
+// Ping always succeeds.
+func (f *Fake) Ping(context.Context) error {
+	return nil
+}
+
+// Chat returns the reply of the request's task. Token counts are estimated
+// at four characters per token.
+func (f *Fake) Chat(_ context.Context, r Request) (*Response, error) {
+	f.calls.Add(1)
+	version, err := r.render(f.Name(), "fake")
+	if err != nil {
+		return nil, err
+	}
+	content, ok := f.replies[r.Task]
+	if !ok {
+		content = f.fallback
+	}
+	prompt := 0
+	for _, m := range r.Messages {
+		prompt += len(m.Content)
+	}
+	return &Response{
+		Content:  content,
+		Provider: f.Name(),
+		Model:    "fake",
+		Usage: Usage{
+			PromptTokens:     (prompt + 3) / 4,
+			CompletionTokens: (len(content) + 3) / 4,
+		},
+		PromptVersion: version,
+	}, nil
+}
+
+// Calls returns how many requests the Fake answered.
+func (f *Fake) Calls() int64 {
+	return f.calls.Load()
+}
+
+// ChatStream answers like Chat, sending the reply word by word.
+func (f *Fake) ChatStream(ctx context.Context, r Request, onToken func(string)) (*Response, error) {
+	resp, err := f.Chat(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	for _, word := range strings.SplitAfter(resp.Content, " ") {
+		if word != "" {
+			onToken(word)
+		}
+	}
+	return resp, nil
+}
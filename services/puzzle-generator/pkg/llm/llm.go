@@ -0,0 +1,100 @@
+// Package llm is a provider-neutral chat completion layer. Providers are
+// NVIDIA Inference, OpenRouter, any OpenAI-compatible endpoint (a local
+// Ollama, vLLM or llama.cpp server) and a deterministic fake. Failover tries
+// several providers in order.
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Message roles.
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+// Message is a chat message.
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// Request is a chat completion request.
+type Request struct {
+	// Task names what the completion is for, such as "filter". Providers
+	// may use a different model per task.
+	Task      string
+	Messages  []Message
+	MaxTokens int // 0 keeps the provider default
+	// Render, when set, builds Messages for the provider and model about to
+	// answer, so a prompt can differ between them, and returns the version
+	// of the prompt it used. A failed render fails that provider's call.
+	Render func(provider, model string) ([]Message, string, error)
+}
+
+// Usage counts the tokens of a completion and what they cost.
+type Usage struct {
+	PromptTokens     int
+	CompletionTokens int
+	Cost             float64 // USD, set by a Failover with Prices; 0 for unpriced models
+}
+
+// Response is a chat completion.
+type Response struct {
+	Content  string
+	Provider string // name of the provider that answered
+	Model    string
+	Usage    Usage
+	// PromptVersion is the prompt version returned by Request.Render.
+	PromptVersion string
+}
+
+// Provider answers chat completions.
+type Provider interface {
+	// Name identifies the provider in logs, metrics and responses.
+	Name() string
+	// Configured reports whether the provider has what it needs to answer.
+	Configured() bool
+	Chat(ctx context.Context, req Request) (*Response, error)
+	// Ping checks that the provider is reachable without running inference.
+	Ping(ctx context.Context) error
+}
+
+// ErrNotConfigured is returned by providers missing an API key or model, and
+// by Failover when none of its providers is configured.
+var ErrNotConfigured = errors.New("llm: provider not configured")
+
+// render fills r.Messages from r.Render for the provider and model about to
+// answer and returns the prompt version.
+func (r *Request) render(provider, model string) (string, error) {
+	if r.Render == nil {
+		return "", nil
+	}
+	messages, version, err := r.Render(provider, model)
+	if err != nil {
+		return "", fmt.Errorf("%s: render prompt: %w", provider, err)
+	}
+	r.Messages = messages
+	return version, nil
+}
+
+var thinkingTagRe = regexp.MustCompile(`(?s)<think>.*?</think>`)
+
+// StripThinkingTags removes <think>…</think> blocks that some models emit.
+func StripThinkingTags(content string) string {
+	content = thinkingTagRe.ReplaceAllString(content, "")
+	return strings.TrimSpace(content)
+}
+
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max] + "…"
+}
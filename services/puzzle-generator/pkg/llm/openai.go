@@ -0,0 +1,264 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Client talks to an OpenAI-compatible chat completions API.
+type Client struct {
+	name        string
+	httpClient  *http.Client
+	baseURL     string
+	apiKey      string
+	keyOptional bool // local endpoints usually need no key
+	model       string
+	taskModels  map[string]string
+	headers     map[string]string
+	temperature float64
+	maxTokens   int
+}
+
+// Option configures the Client.
+type Option func(*Client)
+
+type chatCompletionRequest struct {
+	Model       string    `json:"model"`
+	Messages    []Message `json:"messages"`
+	Temperature float64   `json:"temperature"`
+	MaxTokens   int       `json:"max_tokens,omitempty"`
+	Stream      bool      `json:"stream"`
+}
+
+type chatCompletionResponse struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+	} `json:"choices"`
+	Usage struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+}
+
+func WithBaseURL(v string) Option {
+	return func(c *Client) { c.baseURL = strings.TrimRight(v, "/") }
+}
+
+func WithAPIKey(v string) Option {
+	return func(c *Client) { c.apiKey = v }
+}
+
+// WithModel sets the model used for tasks without their own model.
+func WithModel(v string) Option {
+	return func(c *Client) { c.model = v }
+}
+
+// WithTaskModels sets the model of each listed task.
+func WithTaskModels(models map[string]string) Option {
+	return func(c *Client) {
+		for task, model := range models {
+			c.taskModels[task] = model
+		}
+	}
+}
+
+func WithTimeout(v time.Duration) Option {
+	return func(c *Client) { c.httpClient.Timeout = v }
+}
+
+func WithTransport(rt http.RoundTripper) Option {
+	return func(c *Client) { c.httpClient.Transport = rt }
+}
+
+// WithHeader adds a header to every request.
+func WithHeader(key, value string) Option {
+	return func(c *Client) { c.headers[key] = value }
+}
+
+func WithTemperature(v float64) Option {
+	return func(c *Client) { c.temperature = v }
+}
+
+// WithMaxTokens sets the completion limit of requests that set none.
+func WithMaxTokens(v int) Option {
+	return func(c *Client) { c.maxTokens = v }
+}
+
+func newClient(name, baseURL, model string, defaults, opts []Option) *Client {
+	c := &Client{
+		name:        name,
+		httpClient:  &http.Client{Timeout: 30 * time.Second},
+		baseURL:     baseURL,
+		model:       model,
+		taskModels:  make(map[string]string),
+		headers:     make(map[string]string),
+		temperature: 0.2,
+		maxTokens:   1024,
+	}
+	for _, opt := range defaults {
+		opt(c)
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// NewNVIDIA returns a client for the NVIDIA Inference API.
+func NewNVIDIA(opts ...Option) *Client {
+	return newClient("nvidia", "https://integrate.api.nvidia.com/v1", "meta/llama-3.3-70b-instruct", nil, opts)
+}
+
+// NewOpenRouter returns a client for OpenRouter.
+func NewOpenRouter(opts ...Option) *Client {
+	return newClient("openrouter", "https://openrouter.ai/api/v1", "", []Option{
+		WithHeader("HTTP-Referer", "https://github.com/aminammar1/chess-puzzle-next-go"),
+		WithHeader("X-Title", "chess-puzzle-next"),
+	}, opts)
+}
+
+// NewOpenAICompatible returns a client named name for any OpenAI-compatible
+// endpoint, such as a local Ollama (http://localhost:11434/v1). The API key
+// is optional.
+func NewOpenAICompatible(name string, opts ...Option) *Client {
+	c := newClient(name, "", "", nil, opts)
+	c.keyOptional = true
+	return c
+}
+
+// Name returns the provider name.
+func (c *Client) Name() string {
+	return c.name
+}
+
+// Configured reports whether the base URL, model and, unless optional, API
+// key are set.
+func (c *Client) Configured() bool {
+	return c.baseURL != "" && c.model != "" && (c.apiKey != "" || c.keyOptional)
+}
+
+// Model returns the model used for task.
+func (c *Client) Model(task string) string {
+	if m := c.taskModels[task]; m != "" {
+		return m
+	}
+	return c.model
+}
+
+// Ping checks that the API answers and accepts the key by listing models,
+// which costs no inference.
+func (c *Client) Ping(ctx context.Context) error {
+	if !c.Configured() {
+		return fmt.Errorf("%s: %w", c.name, ErrNotConfigured)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/models", nil)
+	if err != nil {
+		return fmt.Errorf("%s: build request: %w", c.name, err)
+	}
+	c.setHeaders(req)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s: http request: %w", c.name, err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: models endpoint status %d", c.name, resp.StatusCode)
+	}
+	return nil
+}
+
+// Chat sends a non-streaming chat completion request. Thinking tags
+// (<think>…</think>) are stripped from the content.
+func (c *Client) Chat(ctx context.Context, r Request) (*Response, error) {
+	if !c.Configured() {
+		return nil, fmt.Errorf("%s: %w", c.name, ErrNotConfigured)
+	}
+	version, err := r.render(c.name, c.Model(r.Task))
+	if err != nil {
+		return nil, err
+	}
+
+	payload := chatCompletionRequest{
+		Model:       c.Model(r.Task),
+		Messages:    r.Messages,
+		Temperature: c.temperature,
+		MaxTokens:   r.MaxTokens,
+	}
+	if payload.MaxTokens == 0 {
+		payload.MaxTokens = c.maxTokens
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("%s: marshal request: %w", c.name, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("%s: build request: %w", c.name, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	c.setHeaders(req)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s: http request: %w", c.name, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%s: read body: %w", c.name, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: unexpected status %d: %s", c.name, resp.StatusCode, truncate(string(respBody), 300))
+	}
+
+	var decoded chatCompletionResponse
+	if err := json.Unmarshal(respBody, &decoded); err != nil {
+		return nil, fmt.Errorf("%s: decode response: %w", c.name, err)
+	}
+	if len(decoded.Choices) == 0 {
+		return nil, fmt.Errorf("%s: empty choices in response", c.name)
+	}
+	content := StripThinkingTags(decoded.Choices[0].Message.Content)
+	if content == "" {
+		return nil, fmt.Errorf("%s: empty content in response", c.name)
+	}
+
+	model := decoded.Model
+	if model == "" {
+		model = payload.Model
+	}
+	return &Response{
+		Content:  content,
+		Provider: c.name,
+		Model:    model,
+		Usage: Usage{
+			PromptTokens:     decoded.Usage.PromptTokens,
+			CompletionTokens: decoded.Usage.CompletionTokens,
+		},
+		PromptVersion: version,
+	}, nil
+}
+
+func (c *Client) setHeaders(req *http.Request) {
+	req.Header.Set("Accept", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	for k, v := range c.headers {
+		req.Header.Set(k, v)
+	}
+}
@@ -0,0 +1,84 @@
+package llm
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Price is what a model charges, in USD per million tokens.
+type Price struct {
+	Prompt     float64
+	Completion float64
+}
+
+// PriceRule prices the models matching Model, a path.Match pattern such as
+// "meta/llama-3.1-*".
+type PriceRule struct {
+	Model string
+	Price Price
+}
+
+// Prices is a price table. The first rule matching a model prices it.
+type Prices []PriceRule
+
+// ParsePrices parses a price table such as
+// "meta/llama-3.3-70b-instruct=0.88/0.88,deepseek-ai/*=0.5/1.5,*=0/0", each
+// rule giving the prompt and completion price in USD per million tokens.
+func ParsePrices(s string) (Prices, error) {
+	var out Prices
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		i := strings.LastIndex(part, "=")
+		if i < 1 {
+			return nil, fmt.Errorf("llm: price %q: expected model=prompt/completion", part)
+		}
+		model, spec := strings.TrimSpace(part[:i]), strings.TrimSpace(part[i+1:])
+		if _, err := path.Match(model, ""); err != nil {
+			return nil, fmt.Errorf("llm: price %q: model pattern: %w", part, err)
+		}
+		prompt, completion, ok := strings.Cut(spec, "/")
+		if !ok {
+			return nil, fmt.Errorf("llm: price %q: expected model=prompt/completion", part)
+		}
+		var p Price
+		var err error
+		if p.Prompt, err = parsePrice(prompt); err != nil {
+			return nil, fmt.Errorf("llm: price %q: %w", part, err)
+		}
+		if p.Completion, err = parsePrice(completion); err != nil {
+			return nil, fmt.Errorf("llm: price %q: %w", part, err)
+		}
+		out = append(out, PriceRule{Model: model, Price: p})
+	}
+	return out, nil
+}
+
+func parsePrice(s string) (float64, error) {
+	v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("invalid price %q", s)
+	}
+	return v, nil
+}
+
+// Lookup returns the price of model and whether the table has one.
+func (ps Prices) Lookup(model string) (Price, bool) {
+	for _, r := range ps {
+		if ok, _ := path.Match(r.Model, model); ok {
+			return r.Price, true
+		}
+	}
+	return Price{}, false
+}
+
+// Cost returns the cost of u in USD for model, 0 when the model has no
+// price.
+func (ps Prices) Cost(model string, u Usage) float64 {
+	p, _ := ps.Lookup(model)
+	return (float64(u.PromptTokens)*p.Prompt + float64(u.CompletionTokens)*p.Completion) / 1e6
+}
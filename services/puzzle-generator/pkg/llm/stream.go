@@ -0,0 +1,143 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Streamer is implemented by providers that can stream completion tokens.
+type Streamer interface {
+	// ChatStream calls onToken with each piece of content as the model
+	// produces it, and returns the whole completion like Chat.
+	ChatStream(ctx context.Context, req Request, onToken func(string)) (*Response, error)
+}
+
+// ChatStream streams from p when it is a Streamer, and otherwise sends the
+// whole completion to onToken at once.
+func ChatStream(ctx context.Context, p Provider, req Request, onToken func(string)) (*Response, error) {
+	if s, ok := p.(Streamer); ok {
+		return s.ChatStream(ctx, req, onToken)
+	}
+	resp, err := p.Chat(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	onToken(resp.Content)
+	return resp, nil
+}
+
+type streamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type chatCompletionChunk struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+}
+
+// ChatStream sends a chat completion request with stream: true and reads the
+// server-sent chunks. Thinking tags are streamed as produced but stripped
+// from the returned content.
+func (c *Client) ChatStream(ctx context.Context, r Request, onToken func(string)) (*Response, error) {
+	if !c.Configured() {
+		return nil, fmt.Errorf("%s: %w", c.name, ErrNotConfigured)
+	}
+	version, err := r.render(c.name, c.Model(r.Task))
+	if err != nil {
+		return nil, err
+	}
+
+	payload := struct {
+		chatCompletionRequest
+		StreamOptions streamOptions `json:"stream_options"`
+	}{
+		chatCompletionRequest: chatCompletionRequest{
+			Model:       c.Model(r.Task),
+			Messages:    r.Messages,
+			Temperature: c.temperature,
+			MaxTokens:   r.MaxTokens,
+			Stream:      true,
+		},
+		StreamOptions: streamOptions{IncludeUsage: true},
+	}
+	if payload.MaxTokens == 0 {
+		payload.MaxTokens = c.maxTokens
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("%s: marshal request: %w", c.name, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("%s: build request: %w", c.name, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	c.setHeaders(req)
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s: http request: %w", c.name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("%s: unexpected status %d: %s", c.name, resp.StatusCode, truncate(string(respBody), 300))
+	}
+
+	out := &Response{Provider: c.name, Model: payload.Model, PromptVersion: version}
+	var content strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue // blank separators, comments and other fields
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			break
+		}
+		var chunk chatCompletionChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return nil, fmt.Errorf("%s: decode stream chunk: %w", c.name, err)
+		}
+		if chunk.Model != "" {
+			out.Model = chunk.Model
+		}
+		if chunk.Usage != nil {
+			out.Usage = Usage{PromptTokens: chunk.Usage.PromptTokens, CompletionTokens: chunk.Usage.CompletionTokens}
+		}
+		for _, choice := range chunk.Choices {
+			if choice.Delta.Content != "" {
+				content.WriteString(choice.Delta.Content)
+				onToken(choice.Delta.Content)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%s: read stream: %w", c.name, err)
+	}
+
+	out.Content = StripThinkingTags(content.String())
+	if out.Content == "" {
+		return nil, fmt.Errorf("%s: empty content in response", c.name)
+	}
+	return out, nil
+}
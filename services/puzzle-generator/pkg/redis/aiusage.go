@@ -0,0 +1,174 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// AIUsage is the AI usage of a user, a model or everyone over one day.
+type AIUsage struct {
+	Calls            int64
+	PromptTokens     int64
+	CompletionTokens int64
+	CostMicros       int64 // millionths of a USD
+}
+
+// UserAIUsage is the AI usage of one user over one day.
+type UserAIUsage struct {
+	UserID string
+	AIUsage
+}
+
+const (
+	aiUsageCalls      = "calls"
+	aiUsagePrompt     = "prompt_tokens"
+	aiUsageCompletion = "completion_tokens"
+	aiUsageCost       = "cost_micros"
+)
+
+// aiUsageKey is the hash of the day's usage by userID, or of everyone's
+// when userID is empty.
+func aiUsageKey(day, userID string) string {
+	if userID == "" {
+		return "aiusage:" + day
+	}
+	return "aiusage:" + day + ":user:" + userID
+}
+
+// aiUsageModelsKey is the hash of the day's usage by model, with fields
+// <model>|<counter>.
+func aiUsageModelsKey(day string) string {
+	return "aiusage:" + day + ":models"
+}
+
+// aiUsageUsersKey ranks the day's users by cost.
+func aiUsageUsersKey(day string) string {
+	return "aiusage:" + day + ":users"
+}
+
+// RecordAIUsage adds u to the day's usage of userID (when set), of model and
+// of everyone, and returns everyone's cost of the day so far. Every key
+// expires ttl after its day was first recorded.
+func (c *Client) RecordAIUsage(ctx context.Context, day, userID, model string, u AIUsage, ttl time.Duration) (int64, error) {
+	if c == nil {
+		return 0, nil
+	}
+	var total *redis.IntCmd
+	_, err := c.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		incr := func(key, prefix string) *redis.IntCmd {
+			pipe.HIncrBy(ctx, key, prefix+aiUsageCalls, u.Calls)
+			pipe.HIncrBy(ctx, key, prefix+aiUsagePrompt, u.PromptTokens)
+			pipe.HIncrBy(ctx, key, prefix+aiUsageCompletion, u.CompletionTokens)
+			cost := pipe.HIncrBy(ctx, key, prefix+aiUsageCost, u.CostMicros)
+			pipe.ExpireNX(ctx, key, ttl)
+			return cost
+		}
+		total = incr(aiUsageKey(day, ""), "")
+		incr(aiUsageModelsKey(day), model+"|")
+		if userID != "" {
+			incr(aiUsageKey(day, userID), "")
+			pipe.ZIncrBy(ctx, aiUsageUsersKey(day), float64(u.CostMicros), userID)
+			pipe.ExpireNX(ctx, aiUsageUsersKey(day), ttl)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("redis: record AI usage: %w", err)
+	}
+	return total.Val(), nil
+}
+
+// GetAIUsage returns the usage of userID, or of everyone when userID is
+// empty, on each of days. Days without usage are zero.
+func (c *Client) GetAIUsage(ctx context.Context, userID string, days ...string) ([]AIUsage, error) {
+	out := make([]AIUsage, len(days))
+	if c == nil || len(days) == 0 {
+		return out, nil
+	}
+	cmds := make([]*redis.MapStringStringCmd, len(days))
+	_, err := c.rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, day := range days {
+			cmds[i] = pipe.HGetAll(ctx, aiUsageKey(day, userID))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("redis: get AI usage: %w", err)
+	}
+	for i, cmd := range cmds {
+		for field, v := range cmd.Val() {
+			addAIUsageField(&out[i], field, v)
+		}
+	}
+	return out, nil
+}
+
+// AIModelUsage returns the day's usage by model.
+func (c *Client) AIModelUsage(ctx context.Context, day string) (map[string]AIUsage, error) {
+	out := make(map[string]AIUsage)
+	if c == nil {
+		return out, nil
+	}
+	vals, err := c.rdb.HGetAll(ctx, aiUsageModelsKey(day)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redis: get AI model usage: %w", err)
+	}
+	for field, v := range vals {
+		i := strings.LastIndex(field, "|")
+		if i < 0 {
+			continue
+		}
+		u := out[field[:i]]
+		addAIUsageField(&u, field[i+1:], v)
+		out[field[:i]] = u
+	}
+	return out, nil
+}
+
+// TopAIUsers returns the n users who cost the most on day, most first.
+func (c *Client) TopAIUsers(ctx context.Context, day string, n int) ([]UserAIUsage, error) {
+	if c == nil || n < 1 {
+		return nil, nil
+	}
+	ids, err := c.rdb.ZRevRange(ctx, aiUsageUsersKey(day), 0, int64(n-1)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redis: top AI users: %w", err)
+	}
+	out := make([]UserAIUsage, len(ids))
+	cmds := make([]*redis.MapStringStringCmd, len(ids))
+	_, err = c.rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, id := range ids {
+			cmds[i] = pipe.HGetAll(ctx, aiUsageKey(day, id))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("redis: top AI users: %w", err)
+	}
+	for i, cmd := range cmds {
+		out[i].UserID = ids[i]
+		for field, v := range cmd.Val() {
+			addAIUsageField(&out[i].AIUsage, field, v)
+		}
+	}
+	return out, nil
+}
+
+func addAIUsageField(u *AIUsage, field, value string) {
+	n, _ := strconv.ParseInt(value, 10, 64)
+	switch field {
+	case aiUsageCalls:
+		u.Calls += n
+	case aiUsagePrompt:
+		u.PromptTokens += n
+	case aiUsageCompletion:
+		u.CompletionTokens += n
+	case aiUsageCost:
+		u.CostMicros += n
+	}
+}
@@ -0,0 +1,34 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+func billingEventKey(eventID string) string {
+	return "billing:event:" + eventID
+}
+
+// ClaimBillingEvent marks a webhook event as being processed. It returns
+// false when the event was already claimed, which makes redelivered events
+// no-ops.
+func (c *Client) ClaimBillingEvent(ctx context.Context, eventID string, ttl time.Duration) (bool, error) {
+	if c == nil {
+		return false, nil
+	}
+	ok, err := c.rdb.SetNX(ctx, billingEventKey(eventID), time.Now().Unix(), ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("redis: claim billing event: %w", err)
+	}
+	return ok, nil
+}
+
+// ReleaseBillingEvent forgets a claimed event so a failed delivery can be
+// processed again when the provider retries it.
+func (c *Client) ReleaseBillingEvent(ctx context.Context, eventID string) error {
+	if c == nil {
+		return nil
+	}
+	return c.rdb.Del(ctx, billingEventKey(eventID)).Err()
+}
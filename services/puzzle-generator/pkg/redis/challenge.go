@@ -0,0 +1,201 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/chess-puzzle-next/puzzle-generator/internal/models"
+	"github.com/redis/go-redis/v9"
+)
+
+func weeklyChallengeKey(id string) string {
+	return "challenge:weekly:" + id
+}
+
+func weeklyEntriesKey(id string) string {
+	return weeklyChallengeKey(id) + ":entries"
+}
+
+func weeklyScoresKey(id string) string {
+	return weeklyChallengeKey(id) + ":scores"
+}
+
+func weeklyAttemptsKey(id string) string {
+	return weeklyChallengeKey(id) + ":attempts"
+}
+
+// SaveWeeklyChallenge stores a weekly challenge unless one already exists for
+// the same week. It reports whether this call stored it, so concurrent
+// replicas building the same week agree on a single puzzle set.
+func (c *Client) SaveWeeklyChallenge(ctx context.Context, ch *models.WeeklyChallenge, ttl time.Duration) (bool, error) {
+	if c == nil {
+		return false, nil
+	}
+	data, err := json.Marshal(ch)
+	if err != nil {
+		return false, fmt.Errorf("redis: marshal weekly challenge: %w", err)
+	}
+	ok, err := c.rdb.SetNX(ctx, weeklyChallengeKey(ch.ID), data, ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("redis: save weekly challenge: %w", err)
+	}
+	return ok, nil
+}
+
+// GetWeeklyChallenge retrieves a weekly challenge by ISO week. Returns nil if
+// not found.
+func (c *Client) GetWeeklyChallenge(ctx context.Context, id string) (*models.WeeklyChallenge, error) {
+	if c == nil {
+		return nil, nil
+	}
+	data, err := c.rdb.Get(ctx, weeklyChallengeKey(id)).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("redis: get weekly challenge: %w", err)
+	}
+	var ch models.WeeklyChallenge
+	if err := json.Unmarshal(data, &ch); err != nil {
+		return nil, fmt.Errorf("redis: unmarshal weekly challenge: %w", err)
+	}
+	return &ch, nil
+}
+
+// recordAttemptScript claims a player's attempt at a challenge puzzle and,
+// if it is the first, adds it to the player's entry and the leaderboard in
+// one step, so concurrent attempts at different puzzles all count.
+//
+// KEYS[1] = attempts hash, KEYS[2] = entries hash, KEYS[3] = scores sorted set
+// ARGV[1] = player ID, ARGV[2] = puzzle ID, ARGV[3] = attempt JSON,
+// ARGV[4] = points, ARGV[5] = 1 if solved, ARGV[6] = display name (empty
+// keeps the current one), ARGV[7] = puzzles in the challenge,
+// ARGV[8] = attempt time (RFC 3339), ARGV[9] = attempt time (Unix seconds),
+// ARGV[10] = TTL in seconds
+// Returns the entry JSON, or nil if the puzzle was attempted before.
+var recordAttemptScript = redis.NewScript(`
+if redis.call('HSETNX', KEYS[1], ARGV[1] .. ':' .. ARGV[2], ARGV[9]) == 0 then
+  return false
+end
+local entry
+local raw = redis.call('HGET', KEYS[2], ARGV[1])
+if raw then
+  entry = cjson.decode(raw)
+else
+  entry = {playerId = ARGV[1], name = '', score = 0, solved = 0}
+end
+if type(entry.attempts) ~= 'table' then
+  entry.attempts = {}
+end
+if ARGV[6] ~= '' then
+  entry.name = ARGV[6]
+end
+if entry.name == '' then
+  entry.name = 'Anonymous'
+end
+table.insert(entry.attempts, cjson.decode(ARGV[3]))
+entry.attempted = #entry.attempts
+entry.score = entry.score + tonumber(ARGV[4])
+if ARGV[5] == '1' then
+  entry.solved = entry.solved + 1
+end
+entry.completed = entry.attempted >= tonumber(ARGV[7])
+entry.updatedAt = ARGV[8]
+local data = cjson.encode(entry)
+redis.call('HSET', KEYS[2], ARGV[1], data)
+redis.call('ZADD', KEYS[3], entry.score, ARGV[1])
+for _, key in ipairs(KEYS) do
+  redis.call('EXPIRE', key, ARGV[10])
+end
+return data
+`)
+
+// RecordChallengeAttempt adds a player's attempt at a challenge puzzle to
+// their entry and the leaderboard. Only the first attempt at each puzzle
+// counts: it returns nil, and changes nothing, if the player already
+// attempted it. name replaces the entry's display name unless empty.
+func (c *Client) RecordChallengeAttempt(ctx context.Context, challengeID, playerID, name string, attempt models.ChallengeAttempt, puzzles int, ttl time.Duration) (*models.ChallengeEntry, error) {
+	if c == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(attempt)
+	if err != nil {
+		return nil, fmt.Errorf("redis: marshal challenge attempt: %w", err)
+	}
+	solved := 0
+	if attempt.Solved {
+		solved = 1
+	}
+	keys := []string{weeklyAttemptsKey(challengeID), weeklyEntriesKey(challengeID), weeklyScoresKey(challengeID)}
+	raw, err := recordAttemptScript.Run(ctx, c.rdb, keys,
+		playerID, attempt.PuzzleID, data, attempt.Points, solved, name, puzzles,
+		attempt.AttemptedAt.Format(time.RFC3339Nano), attempt.AttemptedAt.Unix(), int64(ttl/time.Second),
+	).Text()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("redis: record challenge attempt: %w", err)
+	}
+	var entry models.ChallengeEntry
+	if err := json.Unmarshal([]byte(raw), &entry); err != nil {
+		return nil, fmt.Errorf("redis: unmarshal challenge entry: %w", err)
+	}
+	return &entry, nil
+}
+
+// GetChallengeEntry retrieves a player's challenge progress. Returns nil if
+// the player has not attempted the challenge.
+func (c *Client) GetChallengeEntry(ctx context.Context, challengeID, playerID string) (*models.ChallengeEntry, error) {
+	if c == nil {
+		return nil, nil
+	}
+	data, err := c.rdb.HGet(ctx, weeklyEntriesKey(challengeID), playerID).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("redis: get challenge entry: %w", err)
+	}
+	var entry models.ChallengeEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, fmt.Errorf("redis: unmarshal challenge entry: %w", err)
+	}
+	return &entry, nil
+}
+
+// ListChallengeEntries returns every player entry ordered by score, highest
+// first.
+func (c *Client) ListChallengeEntries(ctx context.Context, challengeID string) ([]*models.ChallengeEntry, error) {
+	if c == nil {
+		return nil, nil
+	}
+	ids, err := c.rdb.ZRevRange(ctx, weeklyScoresKey(challengeID), 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redis: list challenge scores: %w", err)
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	raw, err := c.rdb.HMGet(ctx, weeklyEntriesKey(challengeID), ids...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redis: list challenge entries: %w", err)
+	}
+
+	entries := make([]*models.ChallengeEntry, 0, len(raw))
+	for _, v := range raw {
+		s, ok := v.(string)
+		if !ok {
+			continue
+		}
+		var entry models.ChallengeEntry
+		if err := json.Unmarshal([]byte(s), &entry); err != nil {
+			continue
+		}
+		entries = append(entries, &entry)
+	}
+	return entries, nil
+}
@@ -3,9 +3,11 @@ package redis
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/redis/go-redis/extra/redisotel/v9"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -17,17 +19,34 @@ type Client struct {
 // Session represents an active puzzle-solving session.
 type Session struct {
 	ID         string    `json:"id"`
+	UserID     string    `json:"user_id,omitempty"`
 	PuzzleID   string    `json:"puzzle_id"`
 	Source     string    `json:"source"`
 	Difficulty string    `json:"difficulty"`
 	FEN        string    `json:"fen"`
 	Moves      []string  `json:"moves"`
+	Verified   bool      `json:"verified"` // FEN and moves come from the puzzle store; only these are rated
 	MoveIndex  int       `json:"move_index"`
 	Solved     bool      `json:"solved"`
 	Failed     bool      `json:"failed"`
 	HintsUsed  int       `json:"hints_used"`
 	StartedAt  time.Time `json:"started_at"`
 	UpdatedAt  time.Time `json:"updated_at"`
+
+	// Filled in by the server.
+	Hints        []SessionHint `json:"hints,omitempty"`         // hints revealed, in order
+	Themes       []string      `json:"themes,omitempty"`        // puzzle themes, looked up for hints
+	PuzzleRating int           `json:"puzzle_rating,omitempty"` // looked up for scoring
+	Score        int           `json:"score"`                   // points earned, set once finished
+	RatingChange *int          `json:"rating_change,omitempty"` // owner's rating change, set once finished
+	ScoredAt     *time.Time    `json:"scored_at,omitempty"`
+}
+
+// SessionHint records one hint revealed in a session.
+type SessionHint struct {
+	Level     int       `json:"level"`      // 1 theme, 2 piece, 3 source square, 4 full move
+	MoveIndex int       `json:"move_index"` // index in Moves of the move the hint is about
+	At        time.Time `json:"at"`
 }
 
 // DailyPuzzle represents a cached daily puzzle.
@@ -53,6 +72,11 @@ func New(redisURL string) (*Client, error) {
 	if err := rdb.Ping(ctx).Err(); err != nil {
 		return nil, fmt.Errorf("redis: ping failed: %w", err)
 	}
+	// Commands become spans of the global tracer provider, so tracing must be
+	// set up before the client is created.
+	if err := redisotel.InstrumentTracing(rdb); err != nil {
+		return nil, fmt.Errorf("redis: instrument tracing: %w", err)
+	}
 	return &Client{rdb: rdb}, nil
 }
 
@@ -109,6 +133,67 @@ func (c *Client) GetSession(ctx context.Context, sessionID string) (*Session, er
 	return &s, nil
 }
 
+// ErrSessionConflict is returned by UpdateSession when other writers keep
+// changing the session.
+var ErrSessionConflict = errors.New("redis: session changed concurrently")
+
+// sessionUpdateRetries bounds how often UpdateSession re-reads a session that
+// changed under it.
+const sessionUpdateRetries = 5
+
+// UpdateSession reads a session, lets fn change it and saves it with the
+// given TTL, all under WATCH: if the session changes in between, it is read
+// again and fn runs again, so fn must not have side effects. An error from
+// fn aborts the update and is returned as is. Returns nil if the session is
+// not found; fn is not called then.
+func (c *Client) UpdateSession(ctx context.Context, sessionID string, ttl time.Duration, fn func(*Session) error) (*Session, error) {
+	if c == nil {
+		return nil, nil
+	}
+	key := sessionKey(sessionID)
+	var s *Session
+	txf := func(tx *redis.Tx) error {
+		s = nil
+		data, err := tx.Get(ctx, key).Bytes()
+		if err == redis.Nil {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("redis: get session: %w", err)
+		}
+		var cur Session
+		if err := json.Unmarshal(data, &cur); err != nil {
+			return fmt.Errorf("redis: unmarshal session: %w", err)
+		}
+		if err := fn(&cur); err != nil {
+			return err
+		}
+		cur.UpdatedAt = time.Now()
+		if data, err = json.Marshal(&cur); err != nil {
+			return fmt.Errorf("redis: marshal session: %w", err)
+		}
+		if _, err := tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, key, data, ttl)
+			return nil
+		}); err != nil {
+			return err
+		}
+		s = &cur
+		return nil
+	}
+	for range sessionUpdateRetries {
+		err := c.rdb.Watch(ctx, txf, key)
+		if errors.Is(err, redis.TxFailedErr) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	}
+	return nil, ErrSessionConflict
+}
+
 // DeleteSession removes a session.
 func (c *Client) DeleteSession(ctx context.Context, sessionID string) error {
 	if c == nil {
@@ -117,6 +202,23 @@ func (c *Client) DeleteSession(ctx context.Context, sessionID string) error {
 	return c.rdb.Del(ctx, sessionKey(sessionID)).Err()
 }
 
+// CountSessions returns the number of stored sessions. It walks the keyspace
+// with SCAN, so callers should not run it per request.
+func (c *Client) CountSessions(ctx context.Context) (int64, error) {
+	if c == nil {
+		return 0, nil
+	}
+	var n int64
+	iter := c.rdb.Scan(ctx, 0, sessionKey("*"), 1000).Iterator()
+	for iter.Next(ctx) {
+		n++
+	}
+	if err := iter.Err(); err != nil {
+		return 0, fmt.Errorf("redis: count sessions: %w", err)
+	}
+	return n, nil
+}
+
 const dailyPuzzleKey = "daily-puzzle"
 
 // CacheDailyPuzzle stores the daily puzzle with a TTL.
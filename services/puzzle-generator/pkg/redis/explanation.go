@@ -0,0 +1,50 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/chess-puzzle-next/puzzle-generator/internal/models"
+	"github.com/redis/go-redis/v9"
+)
+
+func explanationKey(puzzleID, lang string) string {
+	return "explanation:" + puzzleID + ":" + lang
+}
+
+// CacheExplanation stores a puzzle explanation for ttl.
+func (c *Client) CacheExplanation(ctx context.Context, e *models.PuzzleExplanation, ttl time.Duration) error {
+	if c == nil {
+		return nil
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("redis: marshal explanation: %w", err)
+	}
+	if err := c.rdb.Set(ctx, explanationKey(e.PuzzleID, e.Language), data, ttl).Err(); err != nil {
+		return fmt.Errorf("redis: cache explanation: %w", err)
+	}
+	return nil
+}
+
+// GetCachedExplanation retrieves the explanation of a puzzle in lang.
+// Returns nil if not found.
+func (c *Client) GetCachedExplanation(ctx context.Context, puzzleID, lang string) (*models.PuzzleExplanation, error) {
+	if c == nil {
+		return nil, nil
+	}
+	data, err := c.rdb.Get(ctx, explanationKey(puzzleID, lang)).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("redis: get cached explanation: %w", err)
+	}
+	var e models.PuzzleExplanation
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, fmt.Errorf("redis: unmarshal cached explanation: %w", err)
+	}
+	return &e, nil
+}
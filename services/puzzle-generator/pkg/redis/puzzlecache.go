@@ -0,0 +1,49 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/chess-puzzle-next/puzzle-generator/internal/models"
+	"github.com/redis/go-redis/v9"
+)
+
+func cachedPuzzleKey(source, id string) string {
+	return "puzzle:" + source + ":" + id
+}
+
+// CachePuzzle stores an enriched puzzle served by source for ttl.
+func (c *Client) CachePuzzle(ctx context.Context, source string, p *models.Puzzle, ttl time.Duration) error {
+	if c == nil {
+		return nil
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("redis: marshal cached puzzle: %w", err)
+	}
+	if err := c.rdb.Set(ctx, cachedPuzzleKey(source, p.ID), data, ttl).Err(); err != nil {
+		return fmt.Errorf("redis: cache puzzle: %w", err)
+	}
+	return nil
+}
+
+// GetCachedPuzzle retrieves a cached puzzle. Returns nil if not found.
+func (c *Client) GetCachedPuzzle(ctx context.Context, source, id string) (*models.Puzzle, error) {
+	if c == nil {
+		return nil, nil
+	}
+	data, err := c.rdb.Get(ctx, cachedPuzzleKey(source, id)).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("redis: get cached puzzle: %w", err)
+	}
+	var p models.Puzzle
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("redis: unmarshal cached puzzle: %w", err)
+	}
+	return &p, nil
+}
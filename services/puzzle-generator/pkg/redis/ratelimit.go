@@ -0,0 +1,58 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// tokenBucketScript refills and takes from a token bucket atomically. Time
+// comes from the Redis server so replicas with skewed clocks agree.
+//
+// KEYS[1] bucket key; ARGV[1] capacity; ARGV[2] refill tokens per second.
+// Returns {allowed (0/1), tokens left}.
+var tokenBucketScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local refill = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) + tonumber(t[2]) / 1000000
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1]) or capacity
+local ts = tonumber(state[2]) or now
+tokens = math.min(capacity, tokens + math.max(0, now - ts) * refill)
+
+local allowed = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(now))
+redis.call('PEXPIRE', KEYS[1], math.ceil((capacity - tokens) / refill * 1000) + 1000)
+return {allowed, tostring(tokens)}
+`)
+
+// TakeToken takes one token from the bucket at key. See
+// ratelimit.BucketStore.
+func (c *Client) TakeToken(ctx context.Context, key string, capacity int, refill float64) (bool, float64, error) {
+	if c == nil {
+		return true, float64(capacity), nil
+	}
+	res, err := tokenBucketScript.Run(ctx, c.rdb, []string{key}, capacity, refill).Slice()
+	if err != nil {
+		return false, 0, fmt.Errorf("redis: take token: %w", err)
+	}
+	if len(res) != 2 {
+		return false, 0, fmt.Errorf("redis: take token: unexpected reply %v", res)
+	}
+	allowed, _ := res[0].(int64)
+	s, _ := res[1].(string)
+	tokens, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return false, 0, fmt.Errorf("redis: take token: %w", err)
+	}
+	return allowed == 1, tokens, nil
+}
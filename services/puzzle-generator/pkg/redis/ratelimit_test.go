@@ -0,0 +1,69 @@
+package redis
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// testClient connects to the Redis of TEST_REDIS_URL, or skips the test.
+func testClient(t *testing.T) *Client {
+	t.Helper()
+	url := os.Getenv("TEST_REDIS_URL")
+	if url == "" {
+		t.Skip("TEST_REDIS_URL not set")
+	}
+	c, err := New(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestTakeToken(t *testing.T) {
+	c := testClient(t)
+	ctx := context.Background()
+	const capacity = 3
+
+	tests := []struct {
+		name   string
+		refill float64
+		wait   time.Duration // before the last take
+		want   bool
+		under  float64 // tokens left after the last take are below this
+	}{
+		{name: "limit", refill: 0.001, want: false, under: 1},
+		{name: "refill", refill: 20, wait: 100 * time.Millisecond, want: true, under: capacity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := "ratelimit:test:" + uuid.NewString()
+			t.Cleanup(func() { c.rdb.Del(context.Background(), key) })
+
+			for i := range capacity {
+				allowed, left, err := c.TakeToken(ctx, key, capacity, tt.refill)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !allowed || left >= float64(capacity-i) {
+					t.Fatalf("take %d: TakeToken() = %v, %v; want a token", i, allowed, left)
+				}
+			}
+			time.Sleep(tt.wait)
+			allowed, left, err := c.TakeToken(ctx, key, capacity, tt.refill)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if allowed != tt.want || left < 0 || left >= tt.under {
+				t.Fatalf("last take: TakeToken() = %v, %v; want %v with less than %v tokens left", allowed, left, tt.want, tt.under)
+			}
+			if ttl := c.rdb.PTTL(ctx, key).Val(); ttl <= 0 {
+				t.Fatalf("bucket TTL = %v, want it to expire", ttl)
+			}
+		})
+	}
+}
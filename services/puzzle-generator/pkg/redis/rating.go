@@ -0,0 +1,75 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// PlayerRating is a user's puzzle rating.
+type PlayerRating struct {
+	Rating int `json:"rating"`
+	Games  int `json:"games"` // rated sessions
+}
+
+func ratingKey(userID string) string {
+	return "rating:" + userID
+}
+
+func ratedPuzzlesKey(userID string) string {
+	return "rating:" + userID + ":puzzles"
+}
+
+// GetPlayerRating returns a user's puzzle rating. Returns nil for users
+// without rated sessions.
+func (c *Client) GetPlayerRating(ctx context.Context, userID string) (*PlayerRating, error) {
+	if c == nil {
+		return nil, nil
+	}
+	vals, err := c.rdb.HGetAll(ctx, ratingKey(userID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redis: get player rating: %w", err)
+	}
+	if len(vals) == 0 {
+		return nil, nil
+	}
+	rating, _ := strconv.Atoi(vals["rating"])
+	games, _ := strconv.Atoi(vals["games"])
+	return &PlayerRating{Rating: rating, Games: games}, nil
+}
+
+// adjustRatingScript records a puzzle as rated for a user and, the first
+// time only, applies the rating change.
+//
+// KEYS[1] = rating hash, KEYS[2] = set of rated puzzle IDs
+// ARGV[1] = initial rating, ARGV[2] = delta, ARGV[3] = puzzle ID
+// Returns {rating, games}, or nil if the puzzle was rated before.
+var adjustRatingScript = redis.NewScript(`
+if redis.call('SADD', KEYS[2], ARGV[3]) == 0 then
+  return false
+end
+redis.call('HSETNX', KEYS[1], 'rating', ARGV[1])
+local rating = redis.call('HINCRBY', KEYS[1], 'rating', ARGV[2])
+local games = redis.call('HINCRBY', KEYS[1], 'games', 1)
+return {rating, games}
+`)
+
+// AdjustPlayerRating adds delta to a user's rating and counts one more rated
+// session. Unrated users start from initial. Each puzzle counts once per
+// user: it returns nil, and changes nothing, for a puzzle rated before.
+func (c *Client) AdjustPlayerRating(ctx context.Context, userID, puzzleID string, initial, delta int) (*PlayerRating, error) {
+	if c == nil {
+		return nil, nil
+	}
+	keys := []string{ratingKey(userID), ratedPuzzlesKey(userID)}
+	vals, err := adjustRatingScript.Run(ctx, c.rdb, keys, initial, delta, puzzleID).Int64Slice()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("redis: adjust player rating: %w", err)
+	}
+	return &PlayerRating{Rating: int(vals[0]), Games: int(vals[1])}, nil
+}
@@ -0,0 +1,55 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Puzzles served to a viewer are kept in a sorted set scored by the time
+// they were served, so the oldest can be trimmed.
+func seenKey(viewer string) string {
+	return "seen:" + viewer
+}
+
+// SeenPuzzles reports, for each of ids, whether viewer was served it.
+func (c *Client) SeenPuzzles(ctx context.Context, viewer string, ids ...string) ([]bool, error) {
+	if c == nil || len(ids) == 0 {
+		return make([]bool, len(ids)), nil
+	}
+	key := seenKey(viewer)
+	pipe := c.rdb.Pipeline()
+	scores := make([]*redis.FloatCmd, len(ids))
+	for i, id := range ids {
+		scores[i] = pipe.ZScore(ctx, key, id)
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("redis: read seen puzzles: %w", err)
+	}
+	seen := make([]bool, len(ids))
+	for i, cmd := range scores {
+		seen[i] = cmd.Err() == nil
+	}
+	return seen, nil
+}
+
+// MarkPuzzleSeen adds id to the puzzles served to viewer, keeps the limit most
+// recent ones and expires the set after ttl without a new puzzle.
+func (c *Client) MarkPuzzleSeen(ctx context.Context, viewer, id string, limit int, ttl time.Duration) error {
+	if c == nil {
+		return nil
+	}
+	key := seenKey(viewer)
+	pipe := c.rdb.TxPipeline()
+	pipe.ZAdd(ctx, key, redis.Z{Score: float64(time.Now().UnixMilli()), Member: id})
+	pipe.ZRemRangeByRank(ctx, key, 0, int64(-limit-1))
+	if ttl > 0 {
+		pipe.Expire(ctx, key, ttl)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("redis: mark puzzle seen: %w", err)
+	}
+	return nil
+}
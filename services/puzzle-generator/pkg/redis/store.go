@@ -0,0 +1,160 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/chess-puzzle-next/puzzle-generator/internal/models"
+	"github.com/redis/go-redis/v9"
+)
+
+// The puzzle store keeps puzzles already served by an upstream so they can be
+// served again while that upstream is down.
+
+func storePuzzleKey(id string) string {
+	return "store:puzzle:" + id
+}
+
+func storeDifficultyKey(d models.DifficultyLevel) string {
+	return "store:puzzles:" + string(d)
+}
+
+const (
+	storeDailyKey      = "store:daily"
+	disabledSourcesKey = "sources:disabled"
+)
+
+// StorePuzzle adds p to the puzzle store, evicting random puzzles of the same
+// difficulty beyond limit.
+func (c *Client) StorePuzzle(ctx context.Context, p *models.Puzzle, limit int64) error {
+	if c == nil || p.ID == "" {
+		return nil
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("redis: marshal stored puzzle: %w", err)
+	}
+	setKey := storeDifficultyKey(p.Difficulty)
+	pipe := c.rdb.TxPipeline()
+	pipe.Set(ctx, storePuzzleKey(p.ID), data, 0)
+	pipe.SAdd(ctx, setKey, p.ID)
+	size := pipe.SCard(ctx, setKey)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("redis: store puzzle: %w", err)
+	}
+	if over := size.Val() - limit; limit > 0 && over > 0 {
+		evicted, err := c.rdb.SPopN(ctx, setKey, over).Result()
+		if err != nil {
+			return fmt.Errorf("redis: evict stored puzzles: %w", err)
+		}
+		keys := make([]string, len(evicted))
+		for i, id := range evicted {
+			keys[i] = storePuzzleKey(id)
+		}
+		if err := c.rdb.Del(ctx, keys...).Err(); err != nil {
+			return fmt.Errorf("redis: evict stored puzzles: %w", err)
+		}
+	}
+	return nil
+}
+
+// GetStoredPuzzle returns a stored puzzle by ID. Returns nil if not found.
+func (c *Client) GetStoredPuzzle(ctx context.Context, id string) (*models.Puzzle, error) {
+	if c == nil {
+		return nil, nil
+	}
+	return c.getStoredPuzzle(ctx, storePuzzleKey(id))
+}
+
+// RandomStoredPuzzle returns a random stored puzzle of the given difficulty,
+// or of any difficulty when it is empty. Returns nil if none is stored.
+func (c *Client) RandomStoredPuzzle(ctx context.Context, d models.DifficultyLevel) (*models.Puzzle, error) {
+	if c == nil {
+		return nil, nil
+	}
+	levels := []models.DifficultyLevel{d}
+	if d == "" {
+		levels = []models.DifficultyLevel{models.DifficultyEasy, models.DifficultyMedium, models.DifficultyHard}
+	}
+	for _, level := range levels {
+		id, err := c.rdb.SRandMember(ctx, storeDifficultyKey(level)).Result()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("redis: random stored puzzle: %w", err)
+		}
+		return c.getStoredPuzzle(ctx, storePuzzleKey(id))
+	}
+	return nil, nil
+}
+
+// StoreDailyPuzzle keeps the latest daily puzzle.
+func (c *Client) StoreDailyPuzzle(ctx context.Context, p *models.Puzzle) error {
+	if c == nil {
+		return nil
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("redis: marshal daily puzzle: %w", err)
+	}
+	if err := c.rdb.Set(ctx, storeDailyKey, data, 0).Err(); err != nil {
+		return fmt.Errorf("redis: store daily puzzle: %w", err)
+	}
+	return nil
+}
+
+// GetStoredDailyPuzzle returns the latest stored daily puzzle. Returns nil if
+// none is stored.
+func (c *Client) GetStoredDailyPuzzle(ctx context.Context) (*models.Puzzle, error) {
+	if c == nil {
+		return nil, nil
+	}
+	return c.getStoredPuzzle(ctx, storeDailyKey)
+}
+
+func (c *Client) getStoredPuzzle(ctx context.Context, key string) (*models.Puzzle, error) {
+	data, err := c.rdb.Get(ctx, key).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("redis: get stored puzzle: %w", err)
+	}
+	var p models.Puzzle
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("redis: unmarshal stored puzzle: %w", err)
+	}
+	return &p, nil
+}
+
+// SetSourceDisabled adds or removes a puzzle source from the set of sources
+// switched off by an admin.
+func (c *Client) SetSourceDisabled(ctx context.Context, source string, disabled bool) error {
+	if c == nil {
+		return nil
+	}
+	var err error
+	if disabled {
+		err = c.rdb.SAdd(ctx, disabledSourcesKey, source).Err()
+	} else {
+		err = c.rdb.SRem(ctx, disabledSourcesKey, source).Err()
+	}
+	if err != nil {
+		return fmt.Errorf("redis: set source state: %w", err)
+	}
+	return nil
+}
+
+// DisabledSources lists the puzzle sources switched off by an admin.
+func (c *Client) DisabledSources(ctx context.Context) ([]string, error) {
+	if c == nil {
+		return nil, nil
+	}
+	sources, err := c.rdb.SMembers(ctx, disabledSourcesKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redis: list disabled sources: %w", err)
+	}
+	return sources, nil
+}
@@ -0,0 +1,41 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func stormRunKey(runID string) string {
+	return "storm:run:" + runID
+}
+
+// StartStormRun records a Puzzle Storm run started by a user, so its result
+// can be reported once before ttl passes.
+func (c *Client) StartStormRun(ctx context.Context, runID, userID string, ttl time.Duration) error {
+	if c == nil {
+		return nil
+	}
+	if err := c.rdb.Set(ctx, stormRunKey(runID), userID, ttl).Err(); err != nil {
+		return fmt.Errorf("redis: start storm run: %w", err)
+	}
+	return nil
+}
+
+// FinishStormRun deletes a started storm run and returns the user who
+// started it. Returns "" if the run is unknown, expired or already reported.
+func (c *Client) FinishStormRun(ctx context.Context, runID string) (string, error) {
+	if c == nil {
+		return "", nil
+	}
+	userID, err := c.rdb.GetDel(ctx, stormRunKey(runID)).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("redis: finish storm run: %w", err)
+	}
+	return userID, nil
+}
@@ -0,0 +1,56 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+func usageKey(key string) string {
+	return "usage:" + key
+}
+
+// IncrUsage increments a usage counter and returns its new value. The TTL is
+// set when the counter is created so it disappears after its period.
+func (c *Client) IncrUsage(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	if c == nil {
+		return 0, nil
+	}
+	pipe := c.rdb.TxPipeline()
+	incr := pipe.Incr(ctx, usageKey(key))
+	pipe.ExpireNX(ctx, usageKey(key), ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("redis: incr usage: %w", err)
+	}
+	return incr.Val(), nil
+}
+
+// DecrUsage decrements a usage counter.
+func (c *Client) DecrUsage(ctx context.Context, key string) error {
+	if c == nil {
+		return nil
+	}
+	return c.rdb.Decr(ctx, usageKey(key)).Err()
+}
+
+// GetUsage returns the value of each usage counter, 0 for missing ones.
+func (c *Client) GetUsage(ctx context.Context, keys ...string) ([]int64, error) {
+	out := make([]int64, len(keys))
+	if c == nil || len(keys) == 0 {
+		return out, nil
+	}
+	full := make([]string, len(keys))
+	for i, k := range keys {
+		full[i] = usageKey(k)
+	}
+	vals, err := c.rdb.MGet(ctx, full...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redis: get usage: %w", err)
+	}
+	for i, v := range vals {
+		if s, ok := v.(string); ok {
+			fmt.Sscan(s, &out[i])
+		}
+	}
+	return out, nil
+}
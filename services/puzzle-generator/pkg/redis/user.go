@@ -0,0 +1,275 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrEmailTaken is returned by CreateUser when the email is already registered.
+var ErrEmailTaken = errors.New("redis: email already registered")
+
+// User is a registered account.
+type User struct {
+	ID           string    `json:"id"`
+	Email        string    `json:"email"`
+	PasswordHash string    `json:"password_hash"`
+	Plan         string    `json:"plan"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+
+	// Subscription lifecycle, maintained from billing webhooks.
+	PlanStatus            string     `json:"plan_status,omitempty"` // active, trialing, past_due, canceled
+	TrialEndsAt           *time.Time `json:"trial_ends_at,omitempty"`
+	GraceUntil            *time.Time `json:"grace_until,omitempty"`
+	BillingCustomerID     string     `json:"billing_customer_id,omitempty"`
+	BillingSubscriptionID string     `json:"billing_subscription_id,omitempty"`
+	BillingEventAt        int64      `json:"billing_event_at,omitempty"` // creation time of the last applied event
+}
+
+func userKey(userID string) string {
+	return "user:" + userID
+}
+
+func userEmailKey(email string) string {
+	return "user:email:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func userSessionsKey(userID string) string {
+	return userKey(userID) + ":sessions"
+}
+
+func userCustomerKey(customerID string) string {
+	return "user:customer:" + customerID
+}
+
+func refreshTokenKey(tokenID string) string {
+	return "auth:refresh:" + tokenID
+}
+
+// CreateUser stores a new user. The email index is claimed first so two
+// concurrent registrations for the same address cannot both succeed.
+func (c *Client) CreateUser(ctx context.Context, u *User) error {
+	if c == nil {
+		return nil
+	}
+	ok, err := c.rdb.SetNX(ctx, userEmailKey(u.Email), u.ID, 0).Result()
+	if err != nil {
+		return fmt.Errorf("redis: claim user email: %w", err)
+	}
+	if !ok {
+		return ErrEmailTaken
+	}
+	if err := c.SaveUser(ctx, u); err != nil {
+		c.rdb.Del(ctx, userEmailKey(u.Email))
+		return err
+	}
+	return nil
+}
+
+// SaveUser stores (or overwrites) a user record.
+func (c *Client) SaveUser(ctx context.Context, u *User) error {
+	if c == nil {
+		return nil
+	}
+	u.UpdatedAt = time.Now()
+	data, err := json.Marshal(u)
+	if err != nil {
+		return fmt.Errorf("redis: marshal user: %w", err)
+	}
+	return c.rdb.Set(ctx, userKey(u.ID), data, 0).Err()
+}
+
+// ErrUserConflict is returned by UpdateUser when other writers keep changing
+// the user.
+var ErrUserConflict = errors.New("redis: user changed concurrently")
+
+// userUpdateRetries bounds how often UpdateUser re-reads a user that changed
+// under it.
+const userUpdateRetries = 5
+
+// UpdateUser reads a user, lets fn change it and saves it, all under WATCH:
+// if the user changes in between, it is read again and fn runs again, so fn
+// must not have side effects. An error from fn aborts the update and is
+// returned as is. Returns nil if the user is not found; fn is not called
+// then.
+func (c *Client) UpdateUser(ctx context.Context, userID string, fn func(*User) error) (*User, error) {
+	if c == nil {
+		return nil, nil
+	}
+	key := userKey(userID)
+	var u *User
+	txf := func(tx *redis.Tx) error {
+		u = nil
+		data, err := tx.Get(ctx, key).Bytes()
+		if err == redis.Nil {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("redis: get user: %w", err)
+		}
+		var cur User
+		if err := json.Unmarshal(data, &cur); err != nil {
+			return fmt.Errorf("redis: unmarshal user: %w", err)
+		}
+		if err := fn(&cur); err != nil {
+			return err
+		}
+		cur.UpdatedAt = time.Now()
+		if data, err = json.Marshal(&cur); err != nil {
+			return fmt.Errorf("redis: marshal user: %w", err)
+		}
+		if _, err := tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, key, data, 0)
+			return nil
+		}); err != nil {
+			return err
+		}
+		u = &cur
+		return nil
+	}
+	for range userUpdateRetries {
+		err := c.rdb.Watch(ctx, txf, key)
+		if errors.Is(err, redis.TxFailedErr) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return u, nil
+	}
+	return nil, ErrUserConflict
+}
+
+// GetUser retrieves a user by ID. Returns nil if not found.
+func (c *Client) GetUser(ctx context.Context, userID string) (*User, error) {
+	if c == nil {
+		return nil, nil
+	}
+	data, err := c.rdb.Get(ctx, userKey(userID)).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("redis: get user: %w", err)
+	}
+	var u User
+	if err := json.Unmarshal(data, &u); err != nil {
+		return nil, fmt.Errorf("redis: unmarshal user: %w", err)
+	}
+	return &u, nil
+}
+
+// GetUserByEmail retrieves a user by email address. Returns nil if not found.
+func (c *Client) GetUserByEmail(ctx context.Context, email string) (*User, error) {
+	if c == nil {
+		return nil, nil
+	}
+	id, err := c.rdb.Get(ctx, userEmailKey(email)).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("redis: get user by email: %w", err)
+	}
+	return c.GetUser(ctx, id)
+}
+
+// LinkBillingCustomer maps a billing provider customer ID to a user.
+func (c *Client) LinkBillingCustomer(ctx context.Context, customerID, userID string) error {
+	if c == nil {
+		return nil
+	}
+	return c.rdb.Set(ctx, userCustomerKey(customerID), userID, 0).Err()
+}
+
+// GetUserByBillingCustomer retrieves the user linked to a billing customer.
+// Returns nil if no user is linked.
+func (c *Client) GetUserByBillingCustomer(ctx context.Context, customerID string) (*User, error) {
+	if c == nil {
+		return nil, nil
+	}
+	id, err := c.rdb.Get(ctx, userCustomerKey(customerID)).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("redis: get user by billing customer: %w", err)
+	}
+	return c.GetUser(ctx, id)
+}
+
+// SaveRefreshToken records an issued refresh token so it can be rotated or
+// revoked before it expires.
+func (c *Client) SaveRefreshToken(ctx context.Context, tokenID, userID string, ttl time.Duration) error {
+	if c == nil {
+		return nil
+	}
+	return c.rdb.Set(ctx, refreshTokenKey(tokenID), userID, ttl).Err()
+}
+
+// ConsumeRefreshToken deletes a refresh token and returns the user it was
+// issued to. Returns "" if the token is unknown, expired or already used.
+func (c *Client) ConsumeRefreshToken(ctx context.Context, tokenID string) (string, error) {
+	if c == nil {
+		return "", nil
+	}
+	userID, err := c.rdb.GetDel(ctx, refreshTokenKey(tokenID)).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("redis: consume refresh token: %w", err)
+	}
+	return userID, nil
+}
+
+// AddUserSession links a puzzle session to its owner.
+func (c *Client) AddUserSession(ctx context.Context, userID, sessionID string, ttl time.Duration) error {
+	if c == nil {
+		return nil
+	}
+	key := userSessionsKey(userID)
+	pipe := c.rdb.TxPipeline()
+	pipe.ZAdd(ctx, key, redis.Z{Score: float64(time.Now().Unix()), Member: sessionID})
+	pipe.ZRemRangeByScore(ctx, key, "-inf", fmt.Sprint(time.Now().Add(-ttl).Unix()))
+	pipe.Expire(ctx, key, ttl)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// RemoveUserSession unlinks a puzzle session from its owner.
+func (c *Client) RemoveUserSession(ctx context.Context, userID, sessionID string) error {
+	if c == nil {
+		return nil
+	}
+	return c.rdb.ZRem(ctx, userSessionsKey(userID), sessionID).Err()
+}
+
+// ListUserSessions returns a user's live sessions, most recent first.
+func (c *Client) ListUserSessions(ctx context.Context, userID string) ([]*Session, error) {
+	if c == nil {
+		return nil, nil
+	}
+	ids, err := c.rdb.ZRevRange(ctx, userSessionsKey(userID), 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redis: list user sessions: %w", err)
+	}
+
+	sessions := make([]*Session, 0, len(ids))
+	for _, id := range ids {
+		s, err := c.GetSession(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if s != nil {
+			sessions = append(sessions, s)
+		}
+	}
+	return sessions, nil
+}
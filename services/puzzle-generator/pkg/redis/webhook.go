@@ -0,0 +1,256 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// WebhookEndpoint is a URL registered to receive outbound events.
+type WebhookEndpoint struct {
+	ID        string    `json:"id"`
+	OwnerID   string    `json:"owner_id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Secret    string    `json:"secret"` // HMAC key; needed in clear to sign deliveries
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WebhookDelivery is one event sent (or being sent) to an endpoint.
+type WebhookDelivery struct {
+	ID            string          `json:"id"`
+	EndpointID    string          `json:"endpoint_id"`
+	EventID       string          `json:"event_id"`
+	EventType     string          `json:"event_type"`
+	Payload       json.RawMessage `json:"payload"`
+	Status        string          `json:"status"` // pending, succeeded, failed
+	Attempts      int             `json:"attempts"`
+	ResponseCode  int             `json:"response_code,omitempty"`
+	LastError     string          `json:"last_error,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	LastAttemptAt *time.Time      `json:"last_attempt_at,omitempty"`
+	ReplayOf      string          `json:"replay_of,omitempty"`
+}
+
+// webhookDeliveryLogSize caps the delivery log kept per endpoint.
+const webhookDeliveryLogSize = 100
+
+func webhookKey(id string) string {
+	return "webhook:" + id
+}
+
+func userWebhooksKey(userID string) string {
+	return userKey(userID) + ":webhooks"
+}
+
+func webhookEventKey(event string) string {
+	return "webhooks:event:" + event
+}
+
+func webhookDeliveriesKey(endpointID string) string {
+	return webhookKey(endpointID) + ":deliveries"
+}
+
+func webhookDeliveryKey(id string) string {
+	return "webhook:delivery:" + id
+}
+
+// ErrWebhookLimit is returned by CreateWebhookEndpoint when the owner already
+// has as many endpoints as allowed.
+var ErrWebhookLimit = errors.New("redis: webhook endpoint limit reached")
+
+// createWebhookScript stores an endpoint unless its owner is at the limit,
+// so concurrent creates cannot exceed it.
+//
+// KEYS[1] endpoint key; KEYS[2] owner's index; KEYS[3..] event indexes.
+// ARGV[1] endpoint JSON; ARGV[2] endpoint ID; ARGV[3] limit.
+// Returns 1 when stored, 0 when the owner is at the limit.
+var createWebhookScript = redis.NewScript(`
+if redis.call('SCARD', KEYS[2]) >= tonumber(ARGV[3]) then
+  return 0
+end
+redis.call('SET', KEYS[1], ARGV[1])
+for i = 2, #KEYS do
+  redis.call('SADD', KEYS[i], ARGV[2])
+end
+return 1
+`)
+
+// CreateWebhookEndpoint stores an endpoint and indexes it by owner and
+// event. It returns ErrWebhookLimit when the owner already has limit
+// endpoints.
+func (c *Client) CreateWebhookEndpoint(ctx context.Context, ep *WebhookEndpoint, limit int) error {
+	if c == nil {
+		return nil
+	}
+	data, err := json.Marshal(ep)
+	if err != nil {
+		return fmt.Errorf("redis: marshal webhook: %w", err)
+	}
+	keys := []string{webhookKey(ep.ID), userWebhooksKey(ep.OwnerID)}
+	for _, ev := range ep.Events {
+		keys = append(keys, webhookEventKey(ev))
+	}
+	stored, err := createWebhookScript.Run(ctx, c.rdb, keys, data, ep.ID, limit).Int()
+	if err != nil {
+		return fmt.Errorf("redis: save webhook: %w", err)
+	}
+	if stored == 0 {
+		return ErrWebhookLimit
+	}
+	return nil
+}
+
+// GetWebhookEndpoint retrieves an endpoint by ID. Returns nil if not found.
+func (c *Client) GetWebhookEndpoint(ctx context.Context, id string) (*WebhookEndpoint, error) {
+	if c == nil {
+		return nil, nil
+	}
+	data, err := c.rdb.Get(ctx, webhookKey(id)).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("redis: get webhook: %w", err)
+	}
+	var ep WebhookEndpoint
+	if err := json.Unmarshal(data, &ep); err != nil {
+		return nil, fmt.Errorf("redis: unmarshal webhook: %w", err)
+	}
+	return &ep, nil
+}
+
+// DeleteWebhookEndpoint removes an endpoint, its indexes and delivery log.
+func (c *Client) DeleteWebhookEndpoint(ctx context.Context, ep *WebhookEndpoint) error {
+	if c == nil {
+		return nil
+	}
+	pipe := c.rdb.TxPipeline()
+	pipe.Del(ctx, webhookKey(ep.ID), webhookDeliveriesKey(ep.ID))
+	pipe.SRem(ctx, userWebhooksKey(ep.OwnerID), ep.ID)
+	for _, ev := range ep.Events {
+		pipe.SRem(ctx, webhookEventKey(ev), ep.ID)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("redis: delete webhook: %w", err)
+	}
+	return nil
+}
+
+// ListUserWebhookEndpoints returns the endpoints registered by a user.
+func (c *Client) ListUserWebhookEndpoints(ctx context.Context, userID string) ([]*WebhookEndpoint, error) {
+	if c == nil {
+		return nil, nil
+	}
+	ids, err := c.rdb.SMembers(ctx, userWebhooksKey(userID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redis: list webhooks: %w", err)
+	}
+	return c.getWebhookEndpoints(ctx, ids)
+}
+
+// ListEventWebhookEndpoints returns every endpoint subscribed to an event.
+func (c *Client) ListEventWebhookEndpoints(ctx context.Context, event string) ([]*WebhookEndpoint, error) {
+	if c == nil {
+		return nil, nil
+	}
+	ids, err := c.rdb.SMembers(ctx, webhookEventKey(event)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redis: list webhooks for %s: %w", event, err)
+	}
+	return c.getWebhookEndpoints(ctx, ids)
+}
+
+func (c *Client) getWebhookEndpoints(ctx context.Context, ids []string) ([]*WebhookEndpoint, error) {
+	eps := make([]*WebhookEndpoint, 0, len(ids))
+	for _, id := range ids {
+		ep, err := c.GetWebhookEndpoint(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if ep != nil {
+			eps = append(eps, ep)
+		}
+	}
+	return eps, nil
+}
+
+// SaveWebhookDelivery stores a delivery and, when it is new, adds it to the
+// endpoint's log. The log keeps the latest deliveries for ttl.
+func (c *Client) SaveWebhookDelivery(ctx context.Context, d *WebhookDelivery, ttl time.Duration) error {
+	if c == nil {
+		return nil
+	}
+	data, err := json.Marshal(d)
+	if err != nil {
+		return fmt.Errorf("redis: marshal webhook delivery: %w", err)
+	}
+	logKey := webhookDeliveriesKey(d.EndpointID)
+	pipe := c.rdb.TxPipeline()
+	pipe.Set(ctx, webhookDeliveryKey(d.ID), data, ttl)
+	pipe.ZAddNX(ctx, logKey, redis.Z{Score: float64(d.CreatedAt.UnixNano()), Member: d.ID})
+	pipe.ZRemRangeByRank(ctx, logKey, 0, -webhookDeliveryLogSize-1)
+	pipe.Expire(ctx, logKey, ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("redis: save webhook delivery: %w", err)
+	}
+	return nil
+}
+
+// GetWebhookDelivery retrieves a delivery by ID. Returns nil if not found.
+func (c *Client) GetWebhookDelivery(ctx context.Context, id string) (*WebhookDelivery, error) {
+	if c == nil {
+		return nil, nil
+	}
+	data, err := c.rdb.Get(ctx, webhookDeliveryKey(id)).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("redis: get webhook delivery: %w", err)
+	}
+	var d WebhookDelivery
+	if err := json.Unmarshal(data, &d); err != nil {
+		return nil, fmt.Errorf("redis: unmarshal webhook delivery: %w", err)
+	}
+	return &d, nil
+}
+
+// ListWebhookDeliveries returns an endpoint's delivery log, newest first.
+func (c *Client) ListWebhookDeliveries(ctx context.Context, endpointID string) ([]*WebhookDelivery, error) {
+	if c == nil {
+		return nil, nil
+	}
+	ids, err := c.rdb.ZRevRange(ctx, webhookDeliveriesKey(endpointID), 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redis: list webhook deliveries: %w", err)
+	}
+	out := make([]*WebhookDelivery, 0, len(ids))
+	for _, id := range ids {
+		d, err := c.GetWebhookDelivery(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if d != nil {
+			out = append(out, d)
+		}
+	}
+	return out, nil
+}
+
+// ClaimWebhookEvent marks a once-only event (such as the daily puzzle being
+// published) as sent. It returns false when it was already claimed.
+func (c *Client) ClaimWebhookEvent(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	if c == nil {
+		return false, nil
+	}
+	ok, err := c.rdb.SetNX(ctx, "webhooks:once:"+key, time.Now().Unix(), ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("redis: claim webhook event: %w", err)
+	}
+	return ok, nil
+}
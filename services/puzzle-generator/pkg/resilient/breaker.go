@@ -0,0 +1,125 @@
+package resilient
+
+import (
+	"sync"
+	"time"
+)
+
+// State is the state of a circuit breaker.
+type State int
+
+const (
+	// StateClosed lets every request through.
+	StateClosed State = iota
+	// StateHalfOpen lets a single probe request through after the open
+	// period; its outcome closes or re-opens the circuit.
+	StateHalfOpen
+	// StateOpen rejects requests without calling the upstream.
+	StateOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateHalfOpen:
+		return "half-open"
+	case StateOpen:
+		return "open"
+	default:
+		return "closed"
+	}
+}
+
+// Breaker is a consecutive-failure circuit breaker. It opens after
+// threshold failures in a row, stays open for openFor, then admits one probe
+// at a time until a probe succeeds.
+type Breaker struct {
+	threshold int
+	openFor   time.Duration
+	onChange  func(from, to State)
+	now       func() time.Time
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// NewBreaker returns a closed breaker. onChange, when set, is called on every
+// state transition with the breaker's lock released.
+func NewBreaker(threshold int, openFor time.Duration, onChange func(from, to State)) *Breaker {
+	return &Breaker{threshold: max(threshold, 1), openFor: openFor, onChange: onChange, now: time.Now}
+}
+
+// State returns the current state, reporting an open breaker whose open
+// period has elapsed as half-open.
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == StateOpen && b.now().Sub(b.openedAt) >= b.openFor {
+		return StateHalfOpen
+	}
+	return b.state
+}
+
+// Allow reports whether a request may go out. A true result must be followed
+// by exactly one call to Done or Release.
+func (b *Breaker) Allow() bool {
+	b.mu.Lock()
+	from := b.state
+	switch b.state {
+	case StateOpen:
+		if b.now().Sub(b.openedAt) < b.openFor {
+			b.mu.Unlock()
+			return false
+		}
+		b.state = StateHalfOpen
+		fallthrough
+	case StateHalfOpen:
+		if b.probing {
+			b.mu.Unlock()
+			return false
+		}
+		b.probing = true
+	}
+	to := b.state
+	b.mu.Unlock()
+	b.notify(from, to)
+	return true
+}
+
+// Done records the outcome of a request admitted by Allow.
+func (b *Breaker) Done(success bool) {
+	b.mu.Lock()
+	from := b.state
+	b.probing = false
+	switch {
+	case success:
+		b.failures = 0
+		b.state = StateClosed
+	case b.state == StateHalfOpen:
+		b.state, b.openedAt = StateOpen, b.now()
+	default:
+		b.failures++
+		if b.failures >= b.threshold {
+			b.state, b.openedAt = StateOpen, b.now()
+		}
+	}
+	to := b.state
+	b.mu.Unlock()
+	b.notify(from, to)
+}
+
+// Release frees a request admitted by Allow without recording an outcome,
+// for calls abandoned by their caller.
+func (b *Breaker) Release() {
+	b.mu.Lock()
+	b.probing = false
+	b.mu.Unlock()
+}
+
+func (b *Breaker) notify(from, to State) {
+	if from != to && b.onChange != nil {
+		b.onChange(from, to)
+	}
+}
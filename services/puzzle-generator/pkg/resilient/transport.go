@@ -0,0 +1,243 @@
+// Package resilient is an http.RoundTripper for upstream APIs: it retries
+// idempotent calls with jittered exponential backoff, honours Retry-After,
+// keeps every call inside the deadline of its request and stops calling an
+// upstream that keeps failing through a circuit breaker.
+//
+// The deadline of the request is the budget for all attempts and the waits
+// between them. Clients get one by setting http.Client.Timeout, which covers
+// the whole Do call.
+package resilient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ErrCircuitOpen is returned, wrapped with the upstream name, for calls
+// rejected because the upstream's circuit is open.
+var ErrCircuitOpen = errors.New("circuit open")
+
+// Config tunes a Transport. Zero values fall back to the defaults.
+type Config struct {
+	MaxRetries int           // extra attempts for idempotent calls (default 2, negative disables)
+	BaseDelay  time.Duration // first backoff step (default 200ms)
+	MaxDelay   time.Duration // backoff cap (default 2s)
+
+	FailureThreshold int           // consecutive failures that open the circuit (default 5)
+	OpenTimeout      time.Duration // time the circuit stays open before a probe (default 30s)
+
+	// OnRetry is called before waiting for attempt+1. reason is timeout,
+	// transport or http_<status>.
+	OnRetry func(ctx context.Context, attempt int, reason string, delay time.Duration)
+	// OnStateChange is called when the circuit changes state.
+	OnStateChange func(from, to State)
+}
+
+const (
+	defaultMaxRetries       = 2
+	defaultBaseDelay        = 200 * time.Millisecond
+	defaultMaxDelay         = 2 * time.Second
+	defaultFailureThreshold = 5
+	defaultOpenTimeout      = 30 * time.Second
+)
+
+// Transport is the resilient RoundTripper of one upstream.
+type Transport struct {
+	name    string
+	cfg     Config
+	next    http.RoundTripper
+	breaker *Breaker
+}
+
+// New wraps next (http.DefaultTransport when nil) for the upstream called
+// name.
+func New(name string, cfg Config, next http.RoundTripper) *Transport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	switch {
+	case cfg.MaxRetries == 0:
+		cfg.MaxRetries = defaultMaxRetries
+	case cfg.MaxRetries < 0:
+		cfg.MaxRetries = 0
+	}
+	if cfg.BaseDelay <= 0 {
+		cfg.BaseDelay = defaultBaseDelay
+	}
+	if cfg.MaxDelay <= 0 {
+		cfg.MaxDelay = defaultMaxDelay
+	}
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = defaultFailureThreshold
+	}
+	if cfg.OpenTimeout <= 0 {
+		cfg.OpenTimeout = defaultOpenTimeout
+	}
+	return &Transport{
+		name:    name,
+		cfg:     cfg,
+		next:    next,
+		breaker: NewBreaker(cfg.FailureThreshold, cfg.OpenTimeout, cfg.OnStateChange),
+	}
+}
+
+// State returns the state of the upstream's circuit.
+func (t *Transport) State() State {
+	return t.breaker.State()
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	attempts := 1
+	if replayable(req) {
+		attempts += t.cfg.MaxRetries
+	}
+
+	for attempt := 1; ; attempt++ {
+		if !t.breaker.Allow() {
+			return nil, fmt.Errorf("%s: %w", t.name, ErrCircuitOpen)
+		}
+		resp, err := t.attempt(req, attempt, attempts-attempt+1)
+		// A caller that went away says nothing about the upstream; running
+		// out of budget does.
+		if errors.Is(ctx.Err(), context.Canceled) {
+			t.breaker.Release()
+		} else {
+			t.breaker.Done(err == nil && resp.StatusCode < 500)
+		}
+
+		reason := retryReason(resp, err)
+		if attempt == attempts || reason == "" || ctx.Err() != nil {
+			return resp, err
+		}
+		delay := t.backoff(attempt)
+		if resp != nil {
+			if d, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+				delay = d
+			}
+		}
+		// Give up early rather than wait past the deadline or for longer
+		// than the upstream is worth without one.
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= delay {
+			return resp, err
+		} else if !ok && delay > t.cfg.MaxDelay {
+			return resp, err
+		}
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+
+		if t.cfg.OnRetry != nil {
+			t.cfg.OnRetry(ctx, attempt, reason, delay)
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// attempt sends one try. With attempts left after it, the try gets a share of
+// the remaining deadline so a hung connection cannot use up the whole budget.
+func (t *Transport) attempt(req *http.Request, n, left int) (*http.Response, error) {
+	ctx, cancel := req.Context(), context.CancelFunc(func() {})
+	if deadline, ok := ctx.Deadline(); ok && left > 1 {
+		ctx, cancel = context.WithTimeout(ctx, time.Until(deadline)/time.Duration(left))
+	}
+
+	r := req.Clone(ctx)
+	if n > 1 && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		r.Body = body
+	}
+
+	resp, err := t.next.RoundTrip(r)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	// The attempt's context must outlive RoundTrip until the body is read.
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// backoff returns a full-jitter delay for the wait after attempt n.
+func (t *Transport) backoff(n int) time.Duration {
+	d := t.cfg.MaxDelay
+	if shift := n - 1; shift < 30 {
+		d = min(t.cfg.BaseDelay<<shift, t.cfg.MaxDelay)
+	}
+	return time.Duration(rand.Int64N(int64(d) + 1))
+}
+
+// replayable reports whether req may be sent again: an idempotent method and
+// a body that can be rebuilt.
+func replayable(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+	default:
+		return false
+	}
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// retryReason returns why the outcome is worth another attempt, or "".
+func retryReason(resp *http.Response, err error) string {
+	if err != nil {
+		var netErr net.Error
+		switch {
+		case errors.Is(err, context.Canceled):
+			return ""
+		case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+			return "timeout"
+		default:
+			return "transport"
+		}
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return "http_" + strconv.Itoa(resp.StatusCode)
+	}
+	return ""
+}
+
+// retryAfter parses a Retry-After value in seconds or as an HTTP date.
+func retryAfter(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		return time.Duration(max(secs, 0)) * time.Second, true
+	}
+	if at, err := http.ParseTime(v); err == nil {
+		return max(time.Until(at), 0), true
+	}
+	return 0, false
+}
+
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}